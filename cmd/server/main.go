@@ -8,6 +8,7 @@ import (
     "os/signal"
     "syscall"
     "time"
+    _ "time/tzdata" // embed the zone database so user time zones load on hosts without one
     "shifa/internal/api"
     "shifa/pkg/database"
     "github.com/sirupsen/logrus"
@@ -53,7 +54,10 @@ func main() {
     log.Info("Successfully connected to database")
 
     // Setup API routes - pass db, log, and jwtSecret
-    router := api.NewRouter(db, log, jwtSecret)
+    router, jobs := api.NewRouter(db, log, jwtSecret)
+
+    // Start scheduled jobs: reminders, no-show detection and waitlist housekeeping
+    jobs.Start()
 
     // Apply CORS middleware
     corsHandler := middleware.CORSMiddleware()(router)
@@ -91,6 +95,9 @@ func main() {
     ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
     defer cancel()
 
+    // Stop scheduled jobs before the database connection closes
+    jobs.Stop()
+
     // Attempt graceful shutdown
     if err := srv.Shutdown(ctx); err != nil {
         log.WithError(err).Error("Server forced to shutdown")
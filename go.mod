@@ -5,6 +5,7 @@ go 1.21.13
 require (
 	github.com/go-sql-driver/mysql v1.8.1
 	github.com/gorilla/mux v1.8.1
+	github.com/gorilla/websocket v1.5.3
 	github.com/joho/godotenv v1.5.1
 	github.com/sirupsen/logrus v1.9.3
 	github.com/stretchr/testify v1.9.0 // indirect
@@ -17,3 +18,8 @@ require (
 	github.com/rs/cors v1.11.1
 	golang.org/x/sys v0.26.0 // indirect
 )
+
+require (
+	github.com/jung-kurt/gofpdf v1.16.2
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+)
@@ -2,6 +2,7 @@ package handlers
 
 import (
     "encoding/json"
+    "errors"
     "net/http"
     "strconv"
     "time"
@@ -22,6 +23,12 @@ func NewAppointmentHandler(appointmentService *service.AppointmentService) *Appo
 
 
 func (h *AppointmentHandler) CreateAppointment(w http.ResponseWriter, r *http.Request) {
+    loc, err := callerLocation(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
     var appointment models.Appointment
     if err := json.NewDecoder(r.Body).Decode(&appointment); err != nil {
         http.Error(w, err.Error(), http.StatusBadRequest)
@@ -34,6 +41,7 @@ func (h *AppointmentHandler) CreateAppointment(w http.ResponseWriter, r *http.Re
         return
     }
 
+    localizeAppointments(loc, createdAppointment)
     w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(http.StatusCreated)
     json.NewEncoder(w).Encode(createdAppointment)
@@ -47,11 +55,18 @@ func (h *AppointmentHandler) GetAppointment(w http.ResponseWriter, r *http.Reque
         return
     }
 
+    loc, err := callerLocation(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
     appointment, err := h.appointmentService.GetAppointment(r.Context(), appointmentID)
 	if err != nil {
         http.Error(w, err.Error(), http.StatusNotFound)
         return
     }
+    localizeAppointments(loc, appointment)
 
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(appointment)
@@ -65,6 +80,12 @@ func (h *AppointmentHandler) UpdateAppointment(w http.ResponseWriter, r *http.Re
         return
     }
 
+    loc, err := callerLocation(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
     var appointment models.Appointment
     if err := json.NewDecoder(r.Body).Decode(&appointment); err != nil {
         http.Error(w, err.Error(), http.StatusBadRequest)
@@ -74,9 +95,14 @@ func (h *AppointmentHandler) UpdateAppointment(w http.ResponseWriter, r *http.Re
     appointment.ID = appointmentID
     updatedAppointment, err := h.appointmentService.UpdateAppointment(r.Context(), &appointment)
     if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        status := http.StatusInternalServerError
+        if errors.Is(err, service.ErrSlotUnavailable) {
+            status = http.StatusConflict
+        }
+        http.Error(w, err.Error(), status)
         return
     }
+    localizeAppointments(loc, updatedAppointment)
 
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(updatedAppointment)
@@ -100,44 +126,100 @@ func (h *AppointmentHandler) DeleteAppointment(w http.ResponseWriter, r *http.Re
 }
 
 // File: internal/api/handlers/appointment_handler.go
+// ListAppointments lists appointments page by page. Patients only see their own appointments
+// and providers only those booked with them; only admins see all.
 func (h *AppointmentHandler) ListAppointments(w http.ResponseWriter, r *http.Request) {
     ctx := r.Context()
-
-    // Parse query parameters
-    offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
-    limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-    if limit == 0 {
-        limit = 10 // default limit
+    loc, err := callerLocation(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
     }
 
-    // Create filter from query parameters
+    query := r.URL.Query()
     filter := repository.AppointmentFilter{
-        Status:       r.URL.Query().Get("status"),
-        ProviderType: r.URL.Query().Get("providerType"),
+        Status:       query.Get("status"),
+        ProviderType: query.Get("providerType"),
+        SortBy:       query.Get("sort"),
+    }
+    switch query.Get("order") {
+    case "", "asc":
+    case "desc":
+        filter.Descending = true
+    default:
+        http.Error(w, "Invalid order, expected asc or desc", http.StatusBadRequest)
+        return
+    }
+
+    ids := map[string]*int{
+        "patientId":     &filter.PatientID,
+        "providerId":    &filter.ProviderID,
+        "serviceTypeId": &filter.ServiceTypeID,
+    }
+    for name, target := range ids {
+        if *target, err = parseIntQuery(r, name); err != nil {
+            http.Error(w, "Invalid "+name, http.StatusBadRequest)
+            return
+        }
+    }
+    limit, err := parseIntQuery(r, "limit")
+    if err != nil {
+        http.Error(w, "Invalid limit", http.StatusBadRequest)
+        return
     }
 
     // Parse date filters if provided
-    if startDateStr := r.URL.Query().Get("startDate"); startDateStr != "" {
-        startDate, err := time.Parse("2006-01-02", startDateStr)
-        if err == nil {
-            filter.StartDate = &startDate
+    if query.Get("startDate") != "" {
+        startDate, err := parseDateQuery(r, "startDate", time.Time{})
+        if err != nil {
+            http.Error(w, "Invalid startDate, expected YYYY-MM-DD", http.StatusBadRequest)
+            return
+        }
+        filter.StartDate = &startDate
+    }
+    if query.Get("endDate") != "" {
+        endDate, err := parseDateQuery(r, "endDate", time.Time{})
+        if err != nil {
+            http.Error(w, "Invalid endDate, expected YYYY-MM-DD", http.StatusBadRequest)
+            return
         }
+        filter.EndDate = &endDate
     }
-    if endDateStr := r.URL.Query().Get("endDate"); endDateStr != "" {
-        endDate, err := time.Parse("2006-01-02", endDateStr)
-        if err == nil {
-            filter.EndDate = &endDate
+
+    // Scope patients and providers to their own appointments
+    userID, _ := ctx.Value("userID").(int)
+    switch role, _ := ctx.Value("userRole").(string); models.Role(role) {
+    case models.RoleAdmin:
+    case models.RolePatient:
+        if filter.PatientID != 0 && filter.PatientID != userID {
+            http.Error(w, "Patients can only list their own appointments", http.StatusForbidden)
+            return
         }
+        filter.PatientID = userID
+    case models.RoleDoctor, models.RoleHomeCareProvider:
+        if filter.ProviderID != 0 && filter.ProviderID != userID {
+            http.Error(w, "Providers can only list their own appointments", http.StatusForbidden)
+            return
+        }
+        filter.ProviderID = userID
+    default:
+        http.Error(w, "Unknown role", http.StatusForbidden)
+        return
     }
 
-    appointments, err := h.appointmentService.ListAppointments(ctx, filter, offset, limit)
+    page, err := h.appointmentService.ListAppointments(ctx, filter, query.Get("cursor"), limit)
     if err != nil {
+        if errors.Is(err, service.ErrInvalidCursor) || errors.Is(err, service.ErrInvalidSort) {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
         http.Error(w, "Failed to list appointments", http.StatusInternalServerError)
         return
     }
+    localizeAppointments(loc, page.Items...)
 
     w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(appointments)
+    json.NewEncoder(w).Encode(page)
 }
 
 func (h *AppointmentHandler) GetAppointmentsByProvider(w http.ResponseWriter, r *http.Request) {
@@ -153,12 +235,43 @@ func (h *AppointmentHandler) GetAppointmentsByProvider(w http.ResponseWriter, r
         http.Error(w, "Invalid provider type", http.StatusBadRequest)
         return
     }
+    loc, err := callerLocation(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    // Scoped like ListAppointments: providers see their own calendar, patients their own
+    // appointments with the provider
+    userID, _ := r.Context().Value("userID").(int)
+    role, _ := r.Context().Value("userRole").(string)
+    switch models.Role(role) {
+    case models.RoleAdmin, models.RolePatient:
+    case models.RoleDoctor, models.RoleHomeCareProvider:
+        if providerID != userID {
+            http.Error(w, "Providers can only list their own appointments", http.StatusForbidden)
+            return
+        }
+    default:
+        http.Error(w, "Unknown role", http.StatusForbidden)
+        return
+    }
 
     appointments, err := h.appointmentService.GetAppointmentsByProvider(r.Context(), providerID, providerType)
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
         return
     }
+    if models.Role(role) == models.RolePatient {
+        own := make([]*models.Appointment, 0, len(appointments))
+        for _, appointment := range appointments {
+            if appointment.PatientID == userID {
+                own = append(own, appointment)
+            }
+        }
+        appointments = own
+    }
+    localizeAppointments(loc, appointments...)
 
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(appointments)
@@ -179,12 +292,18 @@ func (h *AppointmentHandler) ListAppointmentsByPatient(w http.ResponseWriter, r
     if limit == 0 {
         limit = 10 // default limit
     }
+    loc, err := callerLocation(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
 
     appointments, err := h.appointmentService.GetAppointmentsByPatient(r.Context(), patientID, limit, offset)
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
         return
     }
+    localizeAppointments(loc, appointments...)
 
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(appointments)
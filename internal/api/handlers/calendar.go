@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"shifa/internal/service"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const calendarContentType = "text/calendar; charset=utf-8"
+
+type CalendarHandler struct {
+	service *service.CalendarService
+}
+
+func NewCalendarHandler(service *service.CalendarService) *CalendarHandler {
+	return &CalendarHandler{service: service}
+}
+
+// ExportAppointment downloads one appointment as an .ics file
+func (h *CalendarHandler) ExportAppointment(w http.ResponseWriter, r *http.Request) {
+	appointmentID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid appointment ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+
+	body, err := h.service.ExportAppointment(r.Context(), appointmentID, userID, isAdmin(r))
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, service.ErrNotAppointmentParticipant) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", calendarContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="appointment-%d.ics"`, appointmentID))
+	w.Write([]byte(body))
+}
+
+// Feed serves the subscribable calendar for the secret token in the URL
+func (h *CalendarHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	body, err := h.service.Feed(r.Context(), mux.Vars(r)["token"])
+	if err != nil {
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", calendarContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write([]byte(body))
+}
+
+// GetFeedToken returns the user's feed token and URL, creating them if needed
+func (h *CalendarHandler) GetFeedToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := feedTokenOwner(w, r)
+	if !ok {
+		return
+	}
+
+	token, err := h.service.GetFeedToken(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeFeedToken(w, http.StatusOK, token.Token)
+}
+
+// RotateFeedToken replaces the user's feed token so the old URL stops working
+func (h *CalendarHandler) RotateFeedToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := feedTokenOwner(w, r)
+	if !ok {
+		return
+	}
+
+	token, err := h.service.RotateFeedToken(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeFeedToken(w, http.StatusCreated, token.Token)
+}
+
+func (h *CalendarHandler) RevokeFeedToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := feedTokenOwner(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.RevokeFeedToken(r.Context(), userID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// feedTokenOwner reads the user whose feed token is managed; only that user or an admin may
+// see or change it
+func feedTokenOwner(w http.ResponseWriter, r *http.Request) (int, bool) {
+	return pathUserID(w, r, "id", "You can only manage your own calendar feed")
+}
+
+func writeFeedToken(w http.ResponseWriter, status int, token string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"token": token,
+		"url":   "/api/calendar/" + token + ".ics",
+	})
+}
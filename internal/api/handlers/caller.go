@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// pathUserID reads the user ID in the named path variable and checks that the authenticated
+// caller is that user or an admin. It writes the error response and reports false otherwise;
+// forbidden is the message for other callers.
+func pathUserID(w http.ResponseWriter, r *http.Request, name, forbidden string) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return 0, false
+	}
+	callerID, _ := r.Context().Value("userID").(int)
+	if id != callerID && !isAdmin(r) {
+		http.Error(w, forbidden, http.StatusForbidden)
+		return 0, false
+	}
+	return id, true
+}
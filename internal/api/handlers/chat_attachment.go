@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"shifa/internal/models"
+	"shifa/pkg/fileutils"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// chatUploadLimit bounds a whole attachment request; each file is also checked against its
+// type's limit
+const chatUploadLimit = 2*fileutils.MaxDocumentSize + 1<<20
+
+// SendAttachments sends a chat message with files, as multipart form data: one or more
+// images or PDFs in files, and optionally message and client_id. The message is pushed to
+// the chat's sockets like any other.
+func (h *ChatMessageHandler) SendAttachments(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	// Check before storing anything
+	if _, err := h.chatService.Participant(r.Context(), consultationID, userID); err != nil {
+		http.Error(w, err.Error(), chatErrorStatus(err))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, chatUploadLimit)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	headers := r.MultipartForm.File["files"]
+	if len(headers) == 0 {
+		http.Error(w, "At least one file is required", http.StatusBadRequest)
+		return
+	}
+
+	var attachments []*models.ChatAttachment
+	for _, header := range headers {
+		attachment, err := saveChatAttachment(header)
+		if err != nil {
+			discardChatAttachments(attachments)
+			http.Error(w, fmt.Sprintf("%s: %v", header.Filename, err), http.StatusBadRequest)
+			return
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	message, err := h.chatService.PostWithAttachments(r.Context(), consultationID, userID, r.FormValue("message"), r.FormValue("client_id"), attachments)
+	if err != nil {
+		discardChatAttachments(attachments)
+		http.Error(w, err.Error(), chatErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+// GetAttachment serves an attachment to the consultation's participants
+func (h *ChatMessageHandler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := h.authorizedAttachment(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename=%q`, attachment.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeFile(w, r, fileutils.GetPrivatePath(attachment.FilePath))
+}
+
+// GetAttachmentThumbnail serves the JPEG thumbnail of an image attachment
+func (h *ChatMessageHandler) GetAttachmentThumbnail(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := h.authorizedAttachment(w, r)
+	if !ok {
+		return
+	}
+	if !attachment.HasThumbnail {
+		http.Error(w, "Attachment has no thumbnail", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeFile(w, r, fileutils.GetPrivatePath(attachment.ThumbnailPath))
+}
+
+func (h *ChatMessageHandler) authorizedAttachment(w http.ResponseWriter, r *http.Request) (*models.ChatAttachment, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return nil, false
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	attachment, err := h.chatService.Attachment(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, err.Error(), chatErrorStatus(err))
+		return nil, false
+	}
+	return attachment, true
+}
+
+// saveChatAttachment validates and stores an uploaded file, with a thumbnail for images
+// whose format can be decoded
+func saveChatAttachment(header *multipart.FileHeader) (*models.ChatAttachment, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, errors.New("error retrieving file")
+	}
+	defer file.Close()
+
+	contentType, err := fileutils.ValidateAttachment(file, header.Size)
+	if err != nil {
+		return nil, err
+	}
+	path, err := fileutils.SavePrivateFile(file, header.Filename, fileutils.ChatAttachments)
+	if err != nil {
+		return nil, errors.New("failed to save file")
+	}
+	attachment := &models.ChatAttachment{
+		FileName:    header.Filename,
+		ContentType: contentType,
+		Size:        header.Size,
+		FilePath:    path,
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return attachment, nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		fileutils.DeletePrivateFile(path)
+		return nil, errors.New("failed to read file")
+	}
+	thumbnail, err := fileutils.Thumbnail(file, fileutils.ThumbnailSize)
+	if errors.Is(err, fileutils.ErrNoThumbnail) {
+		return attachment, nil
+	}
+	if err != nil {
+		fileutils.DeletePrivateFile(path)
+		return nil, err
+	}
+	name := strings.TrimSuffix(header.Filename, filepath.Ext(header.Filename)) + "_thumb.jpg"
+	if attachment.ThumbnailPath, err = fileutils.SavePrivateFile(bytes.NewReader(thumbnail), name, fileutils.ChatAttachments); err != nil {
+		fileutils.DeletePrivateFile(path)
+		return nil, errors.New("failed to save thumbnail")
+	}
+	attachment.HasThumbnail = true
+	return attachment, nil
+}
+
+// discardChatAttachments removes stored files whose message was not sent
+func discardChatAttachments(attachments []*models.ChatAttachment) {
+	for _, attachment := range attachments {
+		fileutils.DeletePrivateFile(attachment.FilePath)
+		if attachment.ThumbnailPath != "" {
+			fileutils.DeletePrivateFile(attachment.ThumbnailPath)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// ListConversations returns the caller's consultation chats with their last message and
+// unread count, most recently active first; limit and offset page through them
+func (h *ChatMessageHandler) ListConversations(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+
+	limit, err := parseIntQuery(r, "limit")
+	if err != nil || limit < 0 {
+		http.Error(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+	offset, err := parseIntQuery(r, "offset")
+	if err != nil || offset < 0 {
+		http.Error(w, "Invalid offset", http.StatusBadRequest)
+		return
+	}
+
+	conversations, err := h.chatService.ListConversations(r.Context(), userID, limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conversations)
+}
+
+// MarkConversationRead marks every message the other participant sent in the consultation's
+// chat as read by the caller
+func (h *ChatMessageHandler) MarkConversationRead(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	marked, err := h.chatService.MarkAllRead(r.Context(), consultationID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), chatErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"marked_read": marked})
+}
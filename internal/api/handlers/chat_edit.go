@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"shifa/internal/models"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// EditMessage replaces the text of the caller's message within the policy's edit window
+func (h *ChatMessageHandler) EditMessage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.chatService.Edit(r.Context(), id, userID, body.Message)
+	if err != nil {
+		http.Error(w, err.Error(), chatErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(message)
+}
+
+// DeleteMessage deletes the caller's message for everyone within the policy's delete window
+func (h *ChatMessageHandler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	message, err := h.chatService.Delete(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, err.Error(), chatErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(message)
+}
+
+// GetMessageHistory returns a message's edit history to the participants. Administrators
+// see deleted messages in full for the medical record.
+func (h *ChatMessageHandler) GetMessageHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	history, err := h.chatService.History(r.Context(), id, userID, isAdmin(r))
+	if err != nil {
+		http.Error(w, err.Error(), chatErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+func (h *ChatMessageHandler) GetRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := h.chatService.GetRetentionPolicy(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// UpdateRetentionPolicy replaces the chat retention policy; administrators only
+func (h *ChatMessageHandler) UpdateRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		http.Error(w, "Only administrators can change the chat retention policy", http.StatusForbidden)
+		return
+	}
+
+	var policy models.ChatRetentionPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.chatService.UpdateRetentionPolicy(r.Context(), &policy); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
@@ -5,7 +5,6 @@ import (
     "net/http"
     "strconv"
     "shifa/internal/models"
-    "shifa/internal/repository"
     "shifa/internal/service"
 )
 
@@ -23,22 +22,16 @@ func (h *ChatMessageHandler) SendMessage(w http.ResponseWriter, r *http.Request)
         http.Error(w, "Invalid request body", http.StatusBadRequest)
         return
     }
+    // The sender is the authenticated user, whatever the body says
+    msg.SenderID, _ = r.Context().Value("userID").(int)
     ctx := r.Context()
-    // Convert models.ChatMessage to repository.ChatMessage
-    repoMessage := repository.ChatMessage{
-        ID:             msg.ID,
-        ConsultationID: msg.ConsultationID,
-        SenderType:     msg.SenderType,
-        SenderID:       msg.SenderID,
-        Message:        msg.Message,
-        SentAt:         msg.SentAt,
-        IsRead:         msg.IsRead,
-    }
-    if err := h.chatService.SendMessage(ctx, &repoMessage); err != nil {
-        http.Error(w, "Failed to send message", http.StatusInternalServerError)
+    if err := h.chatService.SendMessage(ctx, &msg); err != nil {
+        http.Error(w, err.Error(), chatErrorStatus(err))
         return
     }
+    w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(http.StatusCreated)
+    json.NewEncoder(w).Encode(msg)
 }
 
 func (h *ChatMessageHandler) GetMessagesByConsultation(w http.ResponseWriter, r *http.Request) {
@@ -47,8 +40,31 @@ func (h *ChatMessageHandler) GetMessagesByConsultation(w http.ResponseWriter, r
         http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
         return
     }
+    // page and page_size default to the newest service.DefaultChatPageSize messages; search
+    // results give the page holding each match
+    page, err := parseIntQuery(r, "page")
+    if err != nil || page < 0 {
+        http.Error(w, "Invalid page", http.StatusBadRequest)
+        return
+    }
+    pageSize, err := parseIntQuery(r, "page_size")
+    if err != nil || pageSize < 0 || pageSize > 100 {
+        http.Error(w, "Invalid page size", http.StatusBadRequest)
+        return
+    }
+    if page == 0 {
+        page = 1
+    }
+    if pageSize == 0 {
+        pageSize = service.DefaultChatPageSize
+    }
     ctx := r.Context()
-    messages, err := h.chatService.GetMessagesByConsultationID(ctx, consultationID, 1, 10)
+    userID, _ := ctx.Value("userID").(int)
+    if _, err := h.chatService.Participant(ctx, consultationID, userID); err != nil {
+        http.Error(w, err.Error(), chatErrorStatus(err))
+        return
+    }
+    messages, err := h.chatService.GetMessagesByConsultationID(ctx, consultationID, page, pageSize)
     if err != nil {
         http.Error(w, "Failed to fetch messages", http.StatusInternalServerError)
         return
@@ -59,15 +75,18 @@ func (h *ChatMessageHandler) GetMessagesByConsultation(w http.ResponseWriter, r
 
 func (h *ChatMessageHandler) MarkMessageAsRead(w http.ResponseWriter, r *http.Request, messageID int) {
     ctx := r.Context()
-    if err := h.chatService.MarkMessageAsRead(ctx, messageID); err != nil {
-        http.Error(w, "Failed to mark message as read", http.StatusInternalServerError)
+    userID, _ := ctx.Value("userID").(int)
+    if err := h.chatService.MarkMessageAsRead(ctx, messageID, userID); err != nil {
+        http.Error(w, err.Error(), chatErrorStatus(err))
         return
     }
     w.WriteHeader(http.StatusOK)
 }
 
-func (h *ChatMessageHandler) GetUnreadMessageCount(w http.ResponseWriter, r *http.Request, userID int) {
+// GetUnreadMessageCount counts the caller's unread messages
+func (h *ChatMessageHandler) GetUnreadMessageCount(w http.ResponseWriter, r *http.Request) {
     ctx := r.Context()
+    userID, _ := ctx.Value("userID").(int)
     count, err := h.chatService.GetUnreadMessageCount(ctx, userID)
     if err != nil {
         http.Error(w, "Failed to get unread message count", http.StatusInternalServerError)
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"shifa/internal/models"
+	"time"
+)
+
+// SearchMessages searches the messages of the caller's consultations. q holds the words to
+// find; consultation_id, sender (doctor or patient) and the from and to dates (inclusive, in
+// the caller's time zone) narrow the search, and limit and offset page through the results.
+// Each result gives the page of GET /chat/messages holding the message for page_size, which
+// defaults to that endpoint's page size.
+func (h *ChatMessageHandler) SearchMessages(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	query := r.URL.Query()
+
+	loc, err := callerLocation(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	from, err := parseDateQuery(r, "from", time.Time{})
+	if err != nil {
+		http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	to, err := parseDateQuery(r, "to", time.Time{})
+	if err != nil {
+		http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	filter := models.ChatSearchFilter{
+		Query:      query.Get("q"),
+		UserID:     userID,
+		SenderType: query.Get("sender"),
+	}
+	if !from.IsZero() {
+		filter.From = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	}
+	if !to.IsZero() {
+		filter.To = time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, loc)
+	}
+	for name, target := range map[string]*int{
+		"consultation_id": &filter.ConsultationID,
+		"limit":           &filter.Limit,
+		"offset":          &filter.Offset,
+		"page_size":       &filter.ListPageSize,
+	} {
+		if *target, err = parseIntQuery(r, name); err != nil || *target < 0 {
+			http.Error(w, "Invalid "+name, http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := h.chatService.Search(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), chatErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
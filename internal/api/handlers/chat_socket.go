@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"shifa/internal/models"
+	"shifa/internal/service"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	chatMaxMessageSize = 16 * 1024
+	// Events queued for a connection that is not reading them; a client that falls this far
+	// behind is disconnected and catches up when it reconnects
+	chatEventBuffer = 256
+	chatCatchUpPage = 100
+)
+
+// Socket upgrades to the consultation's chat WebSocket. Messages after the since query
+// parameter (a message ID) are sent first, so a reconnecting client passes the last ID it
+// has; after that new messages, edits, deletions, read receipts and typing indicators are
+// pushed as they happen. Edits and deletions made while disconnected are not replayed, so a
+// reconnecting client refreshes the messages it shows.
+func (h *ChatMessageHandler) Socket(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	since, err := parseIntQuery(r, "since")
+	if err != nil || since < 0 {
+		http.Error(w, "Invalid since", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+	ctx := r.Context()
+
+	if _, err := h.chatService.Participant(ctx, consultationID, userID); err != nil {
+		http.Error(w, err.Error(), chatErrorStatus(err))
+		return
+	}
+
+	conn, err := signalUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an error
+		return
+	}
+	peer := &chatPeer{conn: conn, events: make(chan *models.ChatEvent, chatEventBuffer), done: make(chan struct{})}
+	defer peer.Close()
+
+	// Subscribe before catching up so that nothing sent in between is missed; the writer
+	// drops the duplicates
+	unsubscribe, err := h.chatService.Subscribe(consultationID, func(event *models.ChatEvent) {
+		if event.Type == models.ChatEventTyping && event.UserID == userID {
+			return
+		}
+		peer.Push(event)
+	})
+	if err != nil {
+		peer.write(&models.ChatEvent{Type: models.ChatEventError, ConsultationID: consultationID, Error: err.Error()})
+		return
+	}
+	defer unsubscribe()
+
+	for {
+		messages, err := h.chatService.MessagesSince(ctx, consultationID, since, chatCatchUpPage)
+		if err != nil {
+			peer.write(&models.ChatEvent{Type: models.ChatEventError, ConsultationID: consultationID, Error: err.Error()})
+			return
+		}
+		for _, message := range messages {
+			if peer.write(&models.ChatEvent{Type: models.ChatEventMessage, ConsultationID: consultationID, Message: message}) != nil {
+				return
+			}
+			since = message.ID
+		}
+		if len(messages) < chatCatchUpPage {
+			break
+		}
+	}
+	go peer.writeLoop(since)
+
+	conn.SetReadLimit(chatMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(signalPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(signalPongWait))
+	})
+	for {
+		var cmd models.ChatCommand
+		if err := conn.ReadJSON(&cmd); err != nil {
+			return
+		}
+		switch cmd.Type {
+		case models.ChatCommandSend:
+			_, err = h.chatService.Post(ctx, consultationID, userID, cmd.Message, cmd.ClientID)
+		case models.ChatCommandRead:
+			err = h.chatService.MarkReadUpTo(ctx, consultationID, userID, cmd.MessageID)
+		case models.ChatCommandTyping:
+			err = h.chatService.SetTyping(ctx, consultationID, userID, cmd.Typing)
+		case models.ChatCommandEdit:
+			_, err = h.chatService.Edit(ctx, cmd.MessageID, userID, cmd.Message)
+		case models.ChatCommandDelete:
+			_, err = h.chatService.Delete(ctx, cmd.MessageID, userID)
+		default:
+			err = errors.New("unknown command type")
+		}
+		if err != nil {
+			peer.Push(&models.ChatEvent{Type: models.ChatEventError, ConsultationID: consultationID, ClientID: cmd.ClientID, Error: err.Error()})
+		}
+	}
+}
+
+// chatPeer is a chat WebSocket connection. Events from the broker are queued and written by
+// a single goroutine, so a slow client never holds up the publisher.
+type chatPeer struct {
+	conn      *websocket.Conn
+	events    chan *models.ChatEvent
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Push queues an event, closing the connection when the client has fallen too far behind
+func (p *chatPeer) Push(event *models.ChatEvent) {
+	select {
+	case p.events <- event:
+	case <-p.done:
+	default:
+		p.Close()
+	}
+}
+
+func (p *chatPeer) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		p.conn.Close()
+	})
+}
+
+func (p *chatPeer) write(event *models.ChatEvent) error {
+	p.conn.SetWriteDeadline(time.Now().Add(signalWriteWait))
+	return p.conn.WriteJSON(event)
+}
+
+// writeLoop writes queued events and pings the client so that dead connections time out on
+// the read side. Messages up to lastSent went out during catch-up and are skipped.
+func (p *chatPeer) writeLoop(lastSent int) {
+	ticker := time.NewTicker(signalPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case event := <-p.events:
+			if event.Type == models.ChatEventMessage && event.Message != nil {
+				if event.Message.ID <= lastSent {
+					continue
+				}
+				lastSent = event.Message.ID
+			}
+			if err := p.write(event); err != nil {
+				p.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := p.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(signalWriteWait)); err != nil {
+				p.Close()
+				return
+			}
+		}
+	}
+}
+
+func chatErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrNotChatParticipant), errors.Is(err, service.ErrNotChatMessageSender):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrChatEditWindowClosed), errors.Is(err, service.ErrChatArchived),
+		errors.Is(err, service.ErrChatTranscribed):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrChatMessageDeleted):
+		return http.StatusGone
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"shifa/internal/models"
+	"shifa/internal/service"
+	"shifa/pkg/fileutils"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// ChatTranscriptHandler exports consultation chats into the medical record
+type ChatTranscriptHandler struct {
+	service *service.ChatTranscriptService
+}
+
+func NewChatTranscriptHandler(service *service.ChatTranscriptService) *ChatTranscriptHandler {
+	return &ChatTranscriptHandler{service: service}
+}
+
+// Export signs and stores the chat transcript of a completed consultation as its doctor
+func (h *ChatTranscriptHandler) Export(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	transcript, err := h.service.Export(r.Context(), consultationID, userID, r.RemoteAddr)
+	if err != nil {
+		http.Error(w, err.Error(), chatTranscriptErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transcript)
+}
+
+// GetTranscript returns the transcript's signature and hashes
+func (h *ChatTranscriptHandler) GetTranscript(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	transcript, err := h.service.Get(r.Context(), consultationID, userID, isAdmin(r))
+	if err != nil {
+		http.Error(w, err.Error(), chatTranscriptErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transcript)
+}
+
+// Download returns the transcript document; format is pdf (the default) or json
+func (h *ChatTranscriptHandler) Download(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = models.ChatTranscriptFormatPDF
+	}
+
+	path, err := h.service.File(r.Context(), consultationID, userID, isAdmin(r), format)
+	if err != nil {
+		http.Error(w, err.Error(), chatTranscriptErrorStatus(err))
+		return
+	}
+
+	contentType := "application/pdf"
+	if format == models.ChatTranscriptFormatJSON {
+		contentType = "application/json"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="consultation-%d-chat-transcript.%s"`, consultationID, format))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeFile(w, r, fileutils.GetPrivatePath(path))
+}
+
+// Verify checks the stored transcript and its attachments against the signed hashes
+func (h *ChatTranscriptHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	verification, err := h.service.Verify(r.Context(), consultationID, userID, isAdmin(r))
+	if err != nil {
+		http.Error(w, err.Error(), chatTranscriptErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verification)
+}
+
+func chatTranscriptErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrNotTranscriptSigner), errors.Is(err, service.ErrChatTranscriptAccessDenied):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrChatTranscriptNotCompleted), errors.Is(err, service.ErrChatTranscriptExists):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrChatTranscriptNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"shifa/internal/models"
+	"shifa/internal/service"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// ClinicalNoteHandler handles the SOAP notes of consultations. Every route needs the caller's
+// identity: doctors write and sign, patients and doctors read.
+type ClinicalNoteHandler struct {
+	service *service.ClinicalNoteService
+}
+
+func NewClinicalNoteHandler(service *service.ClinicalNoteService) *ClinicalNoteHandler {
+	return &ClinicalNoteHandler{service: service}
+}
+
+// CreateDraft starts the consultation's clinical note as a draft
+func (h *ClinicalNoteHandler) CreateDraft(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	var note models.ClinicalNote
+	if err := json.NewDecoder(r.Body).Decode(&note); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.CreateDraft(r.Context(), consultationID, userID, &note); err != nil {
+		http.Error(w, err.Error(), clinicalNoteErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(note)
+}
+
+// ListVersions returns every version of the consultation's note, including superseded ones
+func (h *ClinicalNoteHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	notes, err := h.service.ListVersions(r.Context(), consultationID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), clinicalNoteErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(notes)
+}
+
+// GetCurrent returns the signed note in force, or the draft when nothing is signed yet
+func (h *ClinicalNoteHandler) GetCurrent(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	note, err := h.service.GetCurrent(r.Context(), consultationID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), clinicalNoteErrorStatus(err))
+		return
+	}
+	if note == nil {
+		http.Error(w, "The consultation has no clinical note", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(note)
+}
+
+func (h *ClinicalNoteHandler) GetNote(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid clinical note ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	note, err := h.service.GetNote(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, err.Error(), clinicalNoteErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(note)
+}
+
+// UpdateDraft replaces the content of a draft note
+func (h *ClinicalNoteHandler) UpdateDraft(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid clinical note ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	var content models.ClinicalNote
+	if err := json.NewDecoder(r.Body).Decode(&content); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	note, err := h.service.UpdateDraft(r.Context(), id, userID, &content)
+	if err != nil {
+		http.Error(w, err.Error(), clinicalNoteErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(note)
+}
+
+// SignNote e-signs a draft as the authenticated doctor
+func (h *ClinicalNoteHandler) SignNote(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid clinical note ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	note, err := h.service.Sign(r.Context(), id, userID, r.RemoteAddr)
+	if err != nil {
+		http.Error(w, err.Error(), clinicalNoteErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(note)
+}
+
+// AmendNote opens a draft amendment of a signed note; the body is the corrected note with
+// its amendment_reason
+func (h *ClinicalNoteHandler) AmendNote(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid clinical note ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	var content models.ClinicalNote
+	if err := json.NewDecoder(r.Body).Decode(&content); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	amendment, err := h.service.Amend(r.Context(), id, userID, content.AmendmentReason, &content)
+	if err != nil {
+		http.Error(w, err.Error(), clinicalNoteErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(amendment)
+}
+
+func clinicalNoteErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrNotConsultationDoctor), errors.Is(err, service.ErrNoteAccessDenied):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrNoteNotDraft):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
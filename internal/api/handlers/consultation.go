@@ -25,7 +25,7 @@ func (h *ConsultationHandler) StartConsultation(w http.ResponseWriter, r *http.R
         return
     }
 
-    err := h.consultationService.StartConsultation(r.Context(), consultation)
+    err := h.consultationService.StartConsultation(r.Context(), &consultation)
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
         return
@@ -36,7 +36,8 @@ func (h *ConsultationHandler) StartConsultation(w http.ResponseWriter, r *http.R
     json.NewEncoder(w).Encode(consultation)
 }
 
-// CompleteConsultation handles completing an existing consultation
+// CompleteConsultation handles completing an existing consultation along with its appointment.
+// An optional body with follow_up_days (and follow_up_note) recommends a follow-up visit.
 func (h *ConsultationHandler) CompleteConsultation(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     consultationID, err := strconv.Atoi(vars["id"])
@@ -45,21 +46,29 @@ func (h *ConsultationHandler) CompleteConsultation(w http.ResponseWriter, r *htt
         return
     }
 
-    var consultation models.Consultation
-    if err := json.NewDecoder(r.Body).Decode(&consultation); err != nil {
-        http.Error(w, err.Error(), http.StatusBadRequest)
-        return
+    var plan *models.FollowUpPlan
+    if r.ContentLength != 0 {
+        var body models.FollowUpPlan
+        if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        if body.IntervalDays != 0 {
+            plan = &body
+        }
     }
-    consultation.ID = consultationID
 
-    err = h.consultationService.CompleteConsultation(r.Context(), consultation)
+    consultation, followUp, err := h.consultationService.CompleteConsultation(r.Context(), consultationID, plan)
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
         return
     }
 
     w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(consultation)
+    json.NewEncoder(w).Encode(struct {
+        *models.Consultation
+        FollowUp *models.FollowUp `json:"follow_up,omitempty"`
+    }{consultation, followUp})
 }
 
 // GetConsultation handles retrieving a single consultation by ID
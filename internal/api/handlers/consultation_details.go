@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -29,7 +30,9 @@ func (h *ConsultationDetailsHandler) CreateDetails(w http.ResponseWriter, r *htt
 		return
 	}
 
-	err := h.detailsService.CreateDetails(r.Context(), details)
+	userID, _ := r.Context().Value("userID").(int)
+
+	err := h.detailsService.CreateDetails(r.Context(), details, userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -68,9 +71,18 @@ func (h *ConsultationDetailsHandler) GetDetailsByConsultation(w http.ResponseWri
 		return
 	}
 
-	details, err := h.detailsService.GetDetailsByConsultationID(r.Context(), consultationID)
+	userID, _ := r.Context().Value("userID").(int)
+
+	details, err := h.detailsService.GetDetailsByConsultationID(r.Context(), consultationID, userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrNoteAccessDenied) && userID == 0:
+			status = http.StatusUnauthorized
+		case errors.Is(err, service.ErrNoteAccessDenied):
+			status = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), status)
 		return
 	}
 
@@ -94,7 +106,9 @@ func (h *ConsultationDetailsHandler) UpdateDetails(w http.ResponseWriter, r *htt
 	}
 	details.ID = detailsID
 
-	err = h.detailsService.UpdateDetails(r.Context(), details)
+	userID, _ := r.Context().Value("userID").(int)
+
+	err = h.detailsService.UpdateDetails(r.Context(), details, userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"shifa/internal/models"
+	"shifa/internal/service"
+	"shifa/pkg/fileutils"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// ConsultationSummaryHandler serves the take-home summaries of completed consultations
+type ConsultationSummaryHandler struct {
+	service *service.ConsultationSummaryService
+}
+
+func NewConsultationSummaryHandler(service *service.ConsultationSummaryService) *ConsultationSummaryHandler {
+	return &ConsultationSummaryHandler{service: service}
+}
+
+// DownloadSummary returns the consultation summary. format is pdf (the default) or html; lang
+// is en or ar and defaults to the patient's preferred language.
+func (h *ConsultationSummaryHandler) DownloadSummary(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = models.SummaryFormatPDF
+	}
+
+	summary, err := h.service.Summary(r.Context(), consultationID, userID, r.URL.Query().Get("lang"), format)
+	if err != nil {
+		http.Error(w, err.Error(), summaryErrorStatus(err))
+		return
+	}
+
+	contentType := "application/pdf"
+	if summary.Format == models.SummaryFormatHTML {
+		contentType = "text/html; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Language", summary.Language)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="consultation-%d-summary-%s.%s"`,
+		consultationID, summary.Language, summary.Format))
+	http.ServeFile(w, r, fileutils.GetPrivatePath(summary.FilePath))
+}
+
+func summaryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrSummaryAccessDenied):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrConsultationNotCompleted):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
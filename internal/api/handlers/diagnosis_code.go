@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"shifa/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// DiagnosisCodeHandler serves the ICD-10 catalogue: public autocomplete and lookup, admin import
+type DiagnosisCodeHandler struct {
+	service *service.DiagnosisCodeService
+}
+
+func NewDiagnosisCodeHandler(service *service.DiagnosisCodeService) *DiagnosisCodeHandler {
+	return &DiagnosisCodeHandler{service: service}
+}
+
+// SearchCodes autocompletes on ?q=, matching code prefixes before descriptions
+func (h *DiagnosisCodeHandler) SearchCodes(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseIntQuery(r, "limit")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.service.Search(r.Context(), r.URL.Query().Get("q"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(codes)
+}
+
+func (h *DiagnosisCodeHandler) GetCode(w http.ResponseWriter, r *http.Request) {
+	code, err := h.service.Get(r.Context(), mux.Vars(r)["code"])
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrUnknownDiagnosisCode) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(code)
+}
+
+// ImportCodes loads a CSV or JSON ICD-10 dataset from the request body
+func (h *DiagnosisCodeHandler) ImportCodes(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		http.Error(w, "Only admins can import diagnosis codes", http.StatusForbidden)
+		return
+	}
+
+	n, err := h.service.Import(r.Context(), importFormat(r), http.MaxBytesReader(w, r.Body, maxDatasetSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"imported": n})
+}
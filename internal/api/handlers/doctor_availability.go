@@ -6,6 +6,7 @@ import (
 	"shifa/internal/models"
 	"shifa/internal/service"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -106,3 +107,158 @@ func (h *DoctorAvailabilityHandler) ListAllAvailability(w http.ResponseWriter, r
 	}
 	json.NewEncoder(w).Encode(availabilities)
 }
+
+func (h *DoctorAvailabilityHandler) CreateException(w http.ResponseWriter, r *http.Request) {
+	doctorID, ok := pathUserID(w, r, "doctorId", "Only the doctor or an admin can change the doctor's exceptions")
+	if !ok {
+		return
+	}
+
+	var exception models.AvailabilityException
+	if err := json.NewDecoder(r.Body).Decode(&exception); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	exception.DoctorID = doctorID
+
+	if err := h.service.AddException(r.Context(), &exception); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(exception)
+}
+
+// ListExceptions returns a doctor's exceptions between the from and to query dates, defaulting to the next 90 days
+func (h *DoctorAvailabilityHandler) ListExceptions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	doctorID, err := strconv.Atoi(vars["doctorId"])
+	if err != nil {
+		http.Error(w, "Invalid doctor ID", http.StatusBadRequest)
+		return
+	}
+
+	from := time.Now()
+	to := from.AddDate(0, 0, 90)
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		if from, err = time.Parse("2006-01-02", fromStr); err != nil {
+			http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		if to, err = time.Parse("2006-01-02", toStr); err != nil {
+			http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	exceptions, err := h.service.ListExceptions(r.Context(), doctorID, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exceptions)
+}
+
+func (h *DoctorAvailabilityHandler) DeleteException(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	doctorID, ok := pathUserID(w, r, "doctorId", "Only the doctor or an admin can change the doctor's exceptions")
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid exception ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteException(r.Context(), doctorID, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CancelAppointmentsInException cancels and notifies every appointment inside a blocked exception
+func (h *DoctorAvailabilityHandler) CancelAppointmentsInException(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	doctorID, ok := pathUserID(w, r, "doctorId", "Only the doctor or an admin can change the doctor's exceptions")
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid exception ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	// The body is optional; the exception's own reason is used when it is missing
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	cancelled, err := h.service.CancelAppointmentsInException(r.Context(), doctorID, id, req.Reason)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cancelled_count": len(cancelled),
+		"appointments":    cancelled,
+	})
+}
+
+// GetAvailableSlots returns free slots for a doctor on the date query parameter, a date in
+// the doctor's time zone. The optional duration parameter sets the slot length in minutes
+// (default 30); starts_at and ends_at are rendered in the caller's zone.
+func (h *DoctorAvailabilityHandler) GetAvailableSlots(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	doctorID, err := strconv.Atoi(vars["doctorId"])
+	if err != nil {
+		http.Error(w, "Invalid doctor ID", http.StatusBadRequest)
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", r.URL.Query().Get("date"))
+	if err != nil {
+		http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	loc, err := callerLocation(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duration := 30
+	if durationStr := r.URL.Query().Get("duration"); durationStr != "" {
+		if duration, err = strconv.Atoi(durationStr); err != nil || duration <= 0 {
+			http.Error(w, "Invalid duration", http.StatusBadRequest)
+			return
+		}
+	}
+
+	slots, err := h.service.GetAvailableSlots(r.Context(), doctorID, date, time.Duration(duration)*time.Minute)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	localizeSlots(loc, slots)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slots)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"shifa/internal/models"
+	"shifa/internal/service"
+	"strings"
+)
+
+// maxDatasetSize bounds an uploaded dataset
+const maxDatasetSize = 20 << 20
+
+// DrugDataHandler imports the interaction and drug class datasets used by prescription safety
+// checks. Imports are for admins; the body is the CSV or JSON file itself.
+type DrugDataHandler struct {
+	service *service.DrugSafetyService
+}
+
+func NewDrugDataHandler(service *service.DrugSafetyService) *DrugDataHandler {
+	return &DrugDataHandler{service: service}
+}
+
+func (h *DrugDataHandler) ImportInteractions(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		http.Error(w, "Only admins can import drug data", http.StatusForbidden)
+		return
+	}
+
+	n, err := h.service.ImportInteractions(r.Context(), importFormat(r), http.MaxBytesReader(w, r.Body, maxDatasetSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"imported": n})
+}
+
+func (h *DrugDataHandler) ImportClasses(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		http.Error(w, "Only admins can import drug data", http.StatusForbidden)
+		return
+	}
+
+	n, err := h.service.ImportClasses(r.Context(), importFormat(r), http.MaxBytesReader(w, r.Body, maxDatasetSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"imported": n})
+}
+
+// importFormat takes the format from ?format=, falling back to the Content-Type
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.ToLower(format)
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		return "csv"
+	}
+	return "json"
+}
+
+func isAdmin(r *http.Request) bool {
+	role, _ := r.Context().Value("userRole").(string)
+	return models.Role(role) == models.RoleAdmin
+}
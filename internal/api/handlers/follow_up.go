@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"shifa/internal/models"
+	"shifa/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// FollowUpHandler handles the follow-ups recommended at the end of consultations
+type FollowUpHandler struct {
+	service *service.FollowUpService
+}
+
+func NewFollowUpHandler(service *service.FollowUpService) *FollowUpHandler {
+	return &FollowUpHandler{service: service}
+}
+
+// ListMyFollowUps returns the authenticated patient's follow-ups
+func (h *FollowUpHandler) ListMyFollowUps(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+
+	followUps, err := h.service.ListForPatient(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(followUps)
+}
+
+// ListOverdue returns a doctor's overdue follow-ups, to the doctor or an admin
+func (h *FollowUpHandler) ListOverdue(w http.ResponseWriter, r *http.Request) {
+	doctorID, err := strconv.Atoi(mux.Vars(r)["doctorId"])
+	if err != nil {
+		http.Error(w, "Invalid doctor ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+	if userID != doctorID && !isAdmin(r) {
+		http.Error(w, "Only the doctor or an admin can view overdue follow-ups", http.StatusForbidden)
+		return
+	}
+
+	followUps, err := h.service.ListOverdue(r.Context(), doctorID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(followUps)
+}
+
+func (h *FollowUpHandler) GetFollowUp(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid follow-up ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	followUp, err := h.service.GetFollowUp(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, err.Error(), followUpErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(followUp)
+}
+
+// GetAvailableSlots lists the doctor's free slots for a pending follow-up. date (in the
+// doctor's time zone) defaults to the due date and days to a week; duration is the slot
+// length in minutes (default 30). starts_at and ends_at are rendered in the caller's zone.
+func (h *FollowUpHandler) GetAvailableSlots(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid follow-up ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	from, err := parseDateQuery(r, "date", time.Time{})
+	if err != nil {
+		http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	days, err := parseIntQuery(r, "days")
+	if err != nil || days < 0 {
+		http.Error(w, "Invalid days", http.StatusBadRequest)
+		return
+	}
+	loc, err := callerLocation(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duration := 30
+	if durationStr := r.URL.Query().Get("duration"); durationStr != "" {
+		if duration, err = strconv.Atoi(durationStr); err != nil || duration <= 0 {
+			http.Error(w, "Invalid duration", http.StatusBadRequest)
+			return
+		}
+	}
+
+	slots, err := h.service.AvailableSlots(r.Context(), id, userID, from, days, time.Duration(duration)*time.Minute)
+	if err != nil {
+		http.Error(w, err.Error(), followUpErrorStatus(err))
+		return
+	}
+
+	localizeSlots(loc, slots)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slots)
+}
+
+// BookFollowUp books the follow-up in one of the doctor's slots
+func (h *FollowUpHandler) BookFollowUp(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid follow-up ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	var slot models.Appointment
+	if err := json.NewDecoder(r.Body).Decode(&slot); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	appointment, err := h.service.Book(r.Context(), id, userID, &slot)
+	if err != nil {
+		http.Error(w, err.Error(), followUpErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(appointment)
+}
+
+// DismissFollowUp closes a follow-up as its doctor
+func (h *FollowUpHandler) DismissFollowUp(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid follow-up ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	followUp, err := h.service.Dismiss(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, err.Error(), followUpErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(followUp)
+}
+
+func followUpErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrFollowUpAccessDenied), errors.Is(err, service.ErrNotFollowUpDoctor):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrFollowUpNotPending):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
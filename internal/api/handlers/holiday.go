@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"shifa/internal/models"
+	"shifa/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type HolidayHandler struct {
+	service *service.HolidayService
+}
+
+func NewHolidayHandler(service *service.HolidayService) *HolidayHandler {
+	return &HolidayHandler{service: service}
+}
+
+func (h *HolidayHandler) CreateHoliday(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		http.Error(w, "Only admins can change the holiday calendar", http.StatusForbidden)
+		return
+	}
+	var req struct {
+		HolidayDate string `json:"holiday_date"`
+		Name        string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", req.HolidayDate)
+	if err != nil {
+		http.Error(w, "Invalid holiday_date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	holiday := models.Holiday{HolidayDate: date, Name: req.Name}
+	if err := h.service.CreateHoliday(r.Context(), &holiday); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(holiday)
+}
+
+// ListHolidays returns holidays between the from and to query dates, defaulting to the coming year
+func (h *HolidayHandler) ListHolidays(w http.ResponseWriter, r *http.Request) {
+	from := time.Now()
+	to := from.AddDate(1, 0, 0)
+
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		parsed, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		parsed, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+
+	holidays, err := h.service.ListHolidays(r.Context(), from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holidays)
+}
+
+func (h *HolidayHandler) DeleteHoliday(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		http.Error(w, "Only admins can change the holiday calendar", http.StatusForbidden)
+		return
+	}
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid holiday ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteHoliday(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
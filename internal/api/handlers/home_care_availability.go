@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"shifa/internal/models"
+	"shifa/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type HomeCareAvailabilityHandler struct {
+	service *service.HomeCareAvailabilityService
+}
+
+func NewHomeCareAvailabilityHandler(service *service.HomeCareAvailabilityService) *HomeCareAvailabilityHandler {
+	return &HomeCareAvailabilityHandler{service: service}
+}
+
+func (h *HomeCareAvailabilityHandler) SetAvailability(w http.ResponseWriter, r *http.Request) {
+	providerID, ok := pathUserID(w, r, "providerId", "Only the provider or an admin can change the provider's availability")
+	if !ok {
+		return
+	}
+
+	availability, err := decodeWeeklyAvailability(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	availability.ProviderID = providerID
+
+	if err := h.service.SetAvailability(r.Context(), availability); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(availability)
+}
+
+func (h *HomeCareAvailabilityHandler) GetAvailability(w http.ResponseWriter, r *http.Request) {
+	providerID, err := strconv.Atoi(mux.Vars(r)["providerId"])
+	if err != nil {
+		http.Error(w, "Invalid provider ID", http.StatusBadRequest)
+		return
+	}
+
+	availabilities, err := h.service.ListAvailability(r.Context(), providerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(availabilities)
+}
+
+func (h *HomeCareAvailabilityHandler) UpdateAvailability(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	providerID, ok := pathUserID(w, r, "providerId", "Only the provider or an admin can change the provider's availability")
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid availability ID", http.StatusBadRequest)
+		return
+	}
+
+	availability, err := decodeWeeklyAvailability(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	availability.ID = id
+	availability.ProviderID = providerID
+
+	if err := h.service.UpdateAvailability(r.Context(), availability); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(availability)
+}
+
+func (h *HomeCareAvailabilityHandler) DeleteAvailability(w http.ResponseWriter, r *http.Request) {
+	providerID, ok := pathUserID(w, r, "providerId", "Only the provider or an admin can change the provider's availability")
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid availability ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteAvailability(r.Context(), providerID, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HomeCareAvailabilityHandler) CreateException(w http.ResponseWriter, r *http.Request) {
+	providerID, ok := pathUserID(w, r, "providerId", "Only the provider or an admin can change the provider's availability")
+	if !ok {
+		return
+	}
+
+	var exception models.HomeCareAvailabilityException
+	if err := json.NewDecoder(r.Body).Decode(&exception); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	exception.ProviderID = providerID
+
+	if err := h.service.AddException(r.Context(), &exception); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(exception)
+}
+
+// ListExceptions returns a provider's exceptions between the from and to query dates, defaulting to the next 90 days
+func (h *HomeCareAvailabilityHandler) ListExceptions(w http.ResponseWriter, r *http.Request) {
+	providerID, err := strconv.Atoi(mux.Vars(r)["providerId"])
+	if err != nil {
+		http.Error(w, "Invalid provider ID", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	from, err := parseDateQuery(r, "from", now)
+	if err != nil {
+		http.Error(w, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	to, err := parseDateQuery(r, "to", now.AddDate(0, 0, 90))
+	if err != nil {
+		http.Error(w, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	exceptions, err := h.service.ListExceptions(r.Context(), providerID, from, to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(exceptions)
+}
+
+func (h *HomeCareAvailabilityHandler) DeleteException(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	providerID, ok := pathUserID(w, r, "providerId", "Only the provider or an admin can change the provider's availability")
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid exception ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteException(r.Context(), providerID, id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HomeCareAvailabilityHandler) GetServiceArea(w http.ResponseWriter, r *http.Request) {
+	providerID, err := strconv.Atoi(mux.Vars(r)["providerId"])
+	if err != nil {
+		http.Error(w, "Invalid provider ID", http.StatusBadRequest)
+		return
+	}
+
+	area, err := h.service.GetServiceArea(r.Context(), providerID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if area == nil {
+		http.Error(w, "Service area not configured", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(area)
+}
+
+func (h *HomeCareAvailabilityHandler) SetServiceArea(w http.ResponseWriter, r *http.Request) {
+	providerID, ok := pathUserID(w, r, "providerId", "Only the provider or an admin can change the provider's availability")
+	if !ok {
+		return
+	}
+
+	var area models.HomeCareServiceArea
+	if err := json.NewDecoder(r.Body).Decode(&area); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	area.ProviderID = providerID
+
+	if err := h.service.SetServiceArea(r.Context(), &area); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(area)
+}
+
+// GetAvailableSlots returns visit slots for a provider on the date query parameter.
+// duration is the visit length in minutes (default 60); lat and lng, when both set,
+// enable travel-time and service-area checks for that address. The date is in the
+// provider's time zone; starts_at and ends_at are rendered in the caller's zone.
+func (h *HomeCareAvailabilityHandler) GetAvailableSlots(w http.ResponseWriter, r *http.Request) {
+	providerID, err := strconv.Atoi(mux.Vars(r)["providerId"])
+	if err != nil {
+		http.Error(w, "Invalid provider ID", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	date, err := time.Parse("2006-01-02", query.Get("date"))
+	if err != nil {
+		http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	loc, err := callerLocation(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	duration := 60
+	if durationStr := query.Get("duration"); durationStr != "" {
+		if duration, err = strconv.Atoi(durationStr); err != nil || duration <= 0 {
+			http.Error(w, "Invalid duration", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var lat, lng *float64
+	if query.Get("lat") != "" && query.Get("lng") != "" {
+		latValue, latErr := strconv.ParseFloat(query.Get("lat"), 64)
+		lngValue, lngErr := strconv.ParseFloat(query.Get("lng"), 64)
+		if latErr != nil || lngErr != nil {
+			http.Error(w, "Invalid coordinates", http.StatusBadRequest)
+			return
+		}
+		lat, lng = &latValue, &lngValue
+	}
+
+	slots, err := h.service.GetAvailableSlots(r.Context(), providerID, date, time.Duration(duration)*time.Minute, lat, lng)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	localizeSlots(loc, slots)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slots)
+}
+
+// decodeWeeklyAvailability reads a weekly window with clock times given as "15:04" or "15:04:05"
+func decodeWeeklyAvailability(r *http.Request) (*models.HomeCareAvailability, error) {
+	var req struct {
+		DayOfWeek int    `json:"day_of_week"`
+		StartTime string `json:"start_time"`
+		EndTime   string `json:"end_time"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err
+	}
+
+	start, err := parseClockTime(req.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClockTime(req.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.HomeCareAvailability{DayOfWeek: req.DayOfWeek, StartTime: start, EndTime: end}, nil
+}
+
+func parseClockTime(value string) (time.Time, error) {
+	if t, err := time.Parse("15:04:05", value); err == nil {
+		return t, nil
+	}
+	return time.Parse("15:04", value)
+}
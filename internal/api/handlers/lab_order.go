@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"shifa/internal/models"
+	"shifa/internal/service"
+	"shifa/pkg/fileutils"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// LabOrderHandler handles lab and imaging orders. Doctors and patients act as the authenticated
+// user; performing labs upload results with the number and access code on the requisition.
+type LabOrderHandler struct {
+	service *service.LabOrderService
+}
+
+func NewLabOrderHandler(service *service.LabOrderService) *LabOrderHandler {
+	return &LabOrderHandler{service: service}
+}
+
+// labUpload is a batch of results. It is sent as JSON, or as multipart form data with the
+// results as a JSON form value and the report PDF in the "report" file field.
+type labUpload struct {
+	Code    string             `json:"code"`
+	Lab     string             `json:"lab"`
+	Results []models.LabResult `json:"results"`
+	report  *models.LabReport
+}
+
+// PlaceOrder orders tests for the consultation as its doctor
+func (h *LabOrderHandler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	var order models.LabOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Order(r.Context(), consultationID, userID, &order); err != nil {
+		http.Error(w, err.Error(), labOrderErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *LabOrderHandler) ListConsultationOrders(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	orders, err := h.service.ListByConsultation(r.Context(), consultationID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), labOrderErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orders)
+}
+
+// ListPatientOrders returns a patient's orders with their results, as the patient or as a
+// doctor who ordered for them
+func (h *LabOrderHandler) ListPatientOrders(w http.ResponseWriter, r *http.Request) {
+	patientID, err := strconv.Atoi(mux.Vars(r)["patientId"])
+	if err != nil {
+		http.Error(w, "Invalid patient ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	orders, err := h.service.ListForPatient(r.Context(), patientID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), labOrderErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orders)
+}
+
+func (h *LabOrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid lab order ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	order, err := h.service.GetOrder(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, err.Error(), labOrderErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// DownloadRequisition returns the printable requisition for the lab
+func (h *LabOrderHandler) DownloadRequisition(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid lab order ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	data, order, err := h.service.RenderRequisition(r.Context(), id, userID, requestBaseURL(r)+"/api/lab-orders/results")
+	if err != nil {
+		http.Error(w, err.Error(), labOrderErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s.pdf"`, order.Number))
+	w.Write(data)
+}
+
+// CancelOrder cancels an order without results; the body carries the reason
+func (h *LabOrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid lab order ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.service.Cancel(r.Context(), id, userID, body.Reason)
+	if err != nil {
+		http.Error(w, err.Error(), labOrderErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// RecordResults enters results as the ordering doctor
+func (h *LabOrderHandler) RecordResults(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid lab order ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	upload, err := readLabUpload(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.service.RecordResults(r.Context(), id, userID, upload.Results, upload.report)
+	if err != nil {
+		discardLabReport(upload)
+		http.Error(w, err.Error(), labOrderErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// IngestResults is the public endpoint the performing lab uploads results to
+func (h *LabOrderHandler) IngestResults(w http.ResponseWriter, r *http.Request) {
+	number := mux.Vars(r)["number"]
+
+	upload, err := readLabUpload(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.service.IngestResults(r.Context(), number, upload.Code, upload.Lab, upload.Results, upload.report)
+	if err != nil {
+		discardLabReport(upload)
+		http.Error(w, err.Error(), labOrderErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// DownloadReport returns an uploaded result document
+func (h *LabOrderHandler) DownloadReport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "Invalid lab order ID", http.StatusBadRequest)
+		return
+	}
+	reportID, err := strconv.Atoi(vars["reportId"])
+	if err != nil {
+		http.Error(w, "Invalid report ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	report, err := h.service.ReportFile(r.Context(), id, reportID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), labOrderErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename=%q`, report.FileName))
+	http.ServeFile(w, r, fileutils.GetPrivatePath(report.FilePath))
+}
+
+// readLabUpload decodes a batch of results and stores the attached report, if any
+func readLabUpload(w http.ResponseWriter, r *http.Request) (*labUpload, error) {
+	var upload labUpload
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := json.NewDecoder(r.Body).Decode(&upload); err != nil {
+			return nil, err
+		}
+		return &upload, nil
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, fileutils.MaxDocumentSize+1<<20)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		return nil, errors.New("failed to parse form")
+	}
+	upload.Code = r.FormValue("code")
+	upload.Lab = r.FormValue("lab")
+	if results := r.FormValue("results"); results != "" {
+		if err := json.Unmarshal([]byte(results), &upload.Results); err != nil {
+			return nil, fmt.Errorf("invalid results: %w", err)
+		}
+	}
+
+	file, header, err := r.FormFile("report")
+	if err == http.ErrMissingFile {
+		return &upload, nil
+	}
+	if err != nil {
+		return nil, errors.New("error retrieving report")
+	}
+	defer file.Close()
+
+	if err := fileutils.ValidatePDF(file, header.Size); err != nil {
+		return nil, err
+	}
+	path, err := fileutils.SavePrivateFile(file, header.Filename, fileutils.LabReports)
+	if err != nil {
+		return nil, errors.New("failed to save report")
+	}
+	upload.report = &models.LabReport{FileName: header.Filename, FilePath: path}
+	return &upload, nil
+}
+
+// discardLabReport removes a stored report whose results were rejected
+func discardLabReport(upload *labUpload) {
+	if upload.report != nil {
+		fileutils.DeletePrivateFile(upload.report.FilePath)
+	}
+}
+
+func labOrderErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrNotOrderingDoctor), errors.Is(err, service.ErrLabOrderAccessDenied):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrLabOrderNotVerified):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrLabOrderCancelled), errors.Is(err, service.ErrLabOrderNotCancelable):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
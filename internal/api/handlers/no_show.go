@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"shifa/internal/models"
+	"shifa/internal/service"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type NoShowHandler struct {
+	service *service.NoShowService
+}
+
+func NewNoShowHandler(service *service.NoShowService) *NoShowHandler {
+	return &NoShowHandler{service: service}
+}
+
+func (h *NoShowHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := h.service.GetPolicy(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+func (h *NoShowHandler) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		http.Error(w, "Only admins can change the no-show policy", http.StatusForbidden)
+		return
+	}
+	var policy models.NoShowPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.UpdatePolicy(r.Context(), &policy); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// DetectNoShows runs detection immediately instead of waiting for the background job
+func (h *NoShowHandler) DetectNoShows(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		http.Error(w, "Only admins can run no-show detection", http.StatusForbidden)
+		return
+	}
+	flagged, err := h.service.DetectNoShows(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"flagged": flagged})
+}
+
+func (h *NoShowHandler) ListPatientNoShows(w http.ResponseWriter, r *http.Request) {
+	patientID, ok := pathUserID(w, r, "patientId", "Patients can only see their own no-shows")
+	if !ok {
+		return
+	}
+
+	records, err := h.service.ListPatientNoShows(r.Context(), patientID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+func (h *NoShowHandler) GetPatientStats(w http.ResponseWriter, r *http.Request) {
+	patientID, ok := pathUserID(w, r, "patientId", "Patients can only see their own no-show statistics")
+	if !ok {
+		return
+	}
+
+	stats, err := h.service.GetPatientStats(r.Context(), patientID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+func (h *NoShowHandler) ClearPrepaymentRequirement(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		http.Error(w, "Only admins can clear a prepayment requirement", http.StatusForbidden)
+		return
+	}
+	patientID, err := strconv.Atoi(mux.Vars(r)["patientId"])
+	if err != nil {
+		http.Error(w, "Invalid patient ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.ClearPrepaymentRequirement(r.Context(), patientID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetProviderStats returns the no-show rate for a provider; type is doctor or home_care_provider
+func (h *NoShowHandler) GetProviderStats(w http.ResponseWriter, r *http.Request) {
+	providerID, err := strconv.Atoi(mux.Vars(r)["providerId"])
+	if err != nil {
+		http.Error(w, "Invalid provider ID", http.StatusBadRequest)
+		return
+	}
+
+	providerType := r.URL.Query().Get("type")
+	if providerType == "" {
+		providerType = "doctor"
+	}
+	if providerType != "doctor" && providerType != "home_care_provider" {
+		http.Error(w, "Invalid provider type", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.service.GetProviderStats(r.Context(), providerID, providerType)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"shifa/internal/models"
+	"shifa/internal/service"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// PrescriptionHandler handles e-prescriptions. Prescribers and patients act as the authenticated
+// user; pharmacies verify and dispense with the number and code printed on the prescription.
+type PrescriptionHandler struct {
+	service *service.PrescriptionService
+}
+
+func NewPrescriptionHandler(service *service.PrescriptionService) *PrescriptionHandler {
+	return &PrescriptionHandler{service: service}
+}
+
+// IssuePrescription writes a prescription for the consultation as its doctor
+func (h *PrescriptionHandler) IssuePrescription(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	var prescription models.Prescription
+	if err := json.NewDecoder(r.Body).Decode(&prescription); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Issue(r.Context(), consultationID, userID, &prescription); err != nil {
+		if errors.Is(err, service.ErrUnresolvedWarnings) {
+			writeUnresolvedWarnings(w, err, prescription.Warnings)
+			return
+		}
+		http.Error(w, err.Error(), prescriptionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(prescription)
+}
+
+// CheckPrescription runs the safety checks on draft medication lines without issuing them
+func (h *PrescriptionHandler) CheckPrescription(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	var draft models.Prescription
+	if err := json.NewDecoder(r.Body).Decode(&draft); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	warnings, err := h.service.Check(r.Context(), consultationID, userID, draft.Items)
+	if err != nil {
+		http.Error(w, err.Error(), prescriptionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(warnings)
+}
+
+func (h *PrescriptionHandler) ListConsultationPrescriptions(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	prescriptions, err := h.service.ListByConsultation(r.Context(), consultationID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), prescriptionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prescriptions)
+}
+
+func (h *PrescriptionHandler) GetPrescription(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid prescription ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	prescription, err := h.service.GetPrescription(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, err.Error(), prescriptionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prescription)
+}
+
+// DownloadPDF returns the printable prescription with its verification QR code
+func (h *PrescriptionHandler) DownloadPDF(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid prescription ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	data, prescription, err := h.service.RenderPDF(r.Context(), id, userID, requestBaseURL(r)+"/api/prescriptions/verify")
+	if err != nil {
+		http.Error(w, err.Error(), prescriptionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s.pdf"`, prescription.Number))
+	w.Write(data)
+}
+
+// VoidPrescription cancels an active prescription; the body carries the reason
+func (h *PrescriptionHandler) VoidPrescription(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid prescription ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	prescription, err := h.service.Void(r.Context(), id, userID, body.Reason)
+	if err != nil {
+		http.Error(w, err.Error(), prescriptionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prescription)
+}
+
+// VerifyPrescription is the public endpoint behind the QR code
+func (h *PrescriptionHandler) VerifyPrescription(w http.ResponseWriter, r *http.Request) {
+	number := mux.Vars(r)["number"]
+
+	verification, err := h.service.Verify(r.Context(), number, r.URL.Query().Get("code"))
+	if err != nil {
+		http.Error(w, err.Error(), prescriptionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verification)
+}
+
+// DispensePrescription records the pharmacy that filled the prescription
+func (h *PrescriptionHandler) DispensePrescription(w http.ResponseWriter, r *http.Request) {
+	number := mux.Vars(r)["number"]
+
+	var body struct {
+		Code     string `json:"code"`
+		Pharmacy string `json:"pharmacy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	verification, err := h.service.Dispense(r.Context(), number, body.Code, body.Pharmacy)
+	if err != nil {
+		http.Error(w, err.Error(), prescriptionErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verification)
+}
+
+// writeUnresolvedWarnings answers 409 with the warnings, so the client can show them and
+// resubmit with overrides
+func writeUnresolvedWarnings(w http.ResponseWriter, err error, warnings []models.PrescriptionWarning) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":    err.Error(),
+		"warnings": warnings,
+	})
+}
+
+// requestBaseURL rebuilds the scheme and host the client used, honouring a TLS-terminating proxy
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto == "https" || proto == "http" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+func prescriptionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrNotPrescriber), errors.Is(err, service.ErrPrescriptionAccessDenied):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrPrescriptionNotVerified):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrPrescriptionNotActive):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
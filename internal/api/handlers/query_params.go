@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// parseDateQuery reads a YYYY-MM-DD query parameter, returning def when it is absent
+func parseDateQuery(r *http.Request, name string, def time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// parseIntQuery reads an integer query parameter, returning 0 when it is absent
+func parseIntQuery(r *http.Request, name string) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"shifa/internal/models"
+	"shifa/internal/service"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// ReferralHandler handles referrals. Referring doctors create and track them, receivers answer
+// them from their inbox and patients book the accepted ones.
+type ReferralHandler struct {
+	service *service.ReferralService
+}
+
+func NewReferralHandler(service *service.ReferralService) *ReferralHandler {
+	return &ReferralHandler{service: service}
+}
+
+// CreateReferral refers the consultation's patient as its doctor
+func (h *ReferralHandler) CreateReferral(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	var referral models.Referral
+	if err := json.NewDecoder(r.Body).Decode(&referral); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Create(r.Context(), consultationID, userID, &referral); err != nil {
+		http.Error(w, err.Error(), referralErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(referral)
+}
+
+func (h *ReferralHandler) GetReferral(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid referral ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	referral, err := h.service.GetReferral(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, err.Error(), referralErrorStatus(err))
+		return
+	}
+
+	h.writeReferral(w, r, referral)
+}
+
+// ListSentReferrals returns the caller's referrals as the referring doctor, optionally by ?status=
+func (h *ReferralHandler) ListSentReferrals(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+
+	referrals, err := h.service.ListSent(r.Context(), userID, r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeReferrals(w, r, referrals)
+}
+
+// ListReferralInbox returns the referrals the calling doctor or home care provider can answer
+func (h *ReferralHandler) ListReferralInbox(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+	role, _ := r.Context().Value("userRole").(string)
+
+	referrals, err := h.service.ListInbox(r.Context(), userID, models.Role(role), r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeReferrals(w, r, referrals)
+}
+
+// ListMyReferrals returns the calling patient's referrals with their booking links
+func (h *ReferralHandler) ListMyReferrals(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("userID").(int)
+
+	referrals, err := h.service.ListForPatient(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeReferrals(w, r, referrals)
+}
+
+// AcceptReferral takes on a referral; the body may carry a note for the referring doctor
+func (h *ReferralHandler) AcceptReferral(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, r, h.service.Accept)
+}
+
+// DeclineReferral turns down a referral; the body carries the reason as its note
+func (h *ReferralHandler) DeclineReferral(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, r, h.service.Decline)
+}
+
+// CancelReferral withdraws a referral as its referring doctor
+func (h *ReferralHandler) CancelReferral(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid referral ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	referral, err := h.service.Cancel(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, err.Error(), referralErrorStatus(err))
+		return
+	}
+
+	h.writeReferral(w, r, referral)
+}
+
+// BookReferral books an accepted referral; the body is the slot, as starts_at and ends_at or as
+// appointment_date with start_time and end_time
+func (h *ReferralHandler) BookReferral(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid referral ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	var slot models.Appointment
+	if err := json.NewDecoder(r.Body).Decode(&slot); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	appointment, err := h.service.Book(r.Context(), id, userID, &slot)
+	if err != nil {
+		http.Error(w, err.Error(), referralErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(appointment)
+}
+
+func (h *ReferralHandler) respond(w http.ResponseWriter, r *http.Request,
+	answer func(ctx context.Context, id, userID int, note string) (*models.Referral, error)) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid referral ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	var body struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	referral, err := answer(r.Context(), id, userID, body.Note)
+	if err != nil {
+		http.Error(w, err.Error(), referralErrorStatus(err))
+		return
+	}
+
+	h.writeReferral(w, r, referral)
+}
+
+func (h *ReferralHandler) writeReferral(w http.ResponseWriter, r *http.Request, referral *models.Referral) {
+	referral.BookingURL = service.ReferralBookingURL(requestBaseURL(r)+"/api", referral)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(referral)
+}
+
+func (h *ReferralHandler) writeReferrals(w http.ResponseWriter, r *http.Request, referrals []*models.Referral) {
+	base := requestBaseURL(r) + "/api"
+	for _, referral := range referrals {
+		referral.BookingURL = service.ReferralBookingURL(base, referral)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(referrals)
+}
+
+func referralErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrNotReferrer), errors.Is(err, service.ErrReferralAccessDenied),
+		errors.Is(err, service.ErrNotReferralReceiver):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrReferralNotPending), errors.Is(err, service.ErrReferralNotBookable),
+		errors.Is(err, service.ErrReferralNotCancelable):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
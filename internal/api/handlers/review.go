@@ -81,6 +81,8 @@ func (h *ReviewHandler) CreateReview(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing or invalid doctor_id or home_care_provider_id", http.StatusBadRequest)
 		return
 	}
+	// The reviewer is the authenticated patient, whatever the body says
+	review.PatientID, _ = r.Context().Value("userID").(int)
 
 	if err := h.reviewService.CreateReview(r.Context(), &review); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
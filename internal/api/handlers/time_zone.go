@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"shifa/internal/models"
+)
+
+// callerLocation returns the zone absolute times are rendered in for this request: the tz
+// query parameter, then the X-Time-Zone header, defaulting to UTC
+func callerLocation(r *http.Request) (*time.Location, error) {
+	name := r.URL.Query().Get("tz")
+	if name == "" {
+		name = r.Header.Get("X-Time-Zone")
+	}
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q", name)
+	}
+	return loc, nil
+}
+
+// localizeAppointments renders the starts_at and ends_at of each appointment in loc; the
+// date and clock fields stay in the provider's zone
+func localizeAppointments(loc *time.Location, appointments ...*models.Appointment) {
+	for _, appointment := range appointments {
+		appointment.StartsAt.Time = appointment.StartsAt.Time.In(loc)
+		appointment.EndsAt.Time = appointment.EndsAt.Time.In(loc)
+	}
+}
+
+// localizeSlots renders the starts_at and ends_at of each slot in loc
+func localizeSlots(loc *time.Location, slots []models.TimeSlot) {
+	for i := range slots {
+		slots[i].StartsAt = slots[i].StartsAt.In(loc)
+		slots[i].EndsAt = slots[i].EndsAt.In(loc)
+	}
+}
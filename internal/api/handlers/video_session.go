@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"shifa/internal/models"
+	"shifa/internal/service"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	signalWriteWait  = 10 * time.Second
+	signalPongWait   = 60 * time.Second
+	signalPingPeriod = 25 * time.Second
+	// SDP offers are the largest signals and stay well below this
+	signalMaxMessageSize = 64 * 1024
+)
+
+// The join token, not a cookie, authenticates the socket, so a page on another origin
+// cannot ride on the user's session and any origin may connect
+var signalUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+type VideoSessionHandler struct {
+	service *service.VideoSessionService
+}
+
+func NewVideoSessionHandler(service *service.VideoSessionService) *VideoSessionHandler {
+	return &VideoSessionHandler{service: service}
+}
+
+// IssueJoinToken returns a join token for the authenticated patient or doctor of the consultation
+func (h *VideoSessionHandler) IssueJoinToken(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	token, err := h.service.IssueJoinToken(r.Context(), consultationID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), videoErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+// GetSession returns the state of the consultation's video session
+func (h *VideoSessionHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	session, err := h.service.GetSession(r.Context(), consultationID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), videoErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// EndSession ends the consultation's video session for both participants
+func (h *VideoSessionHandler) EndSession(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, _ := r.Context().Value("userID").(int)
+
+	session, err := h.service.End(r.Context(), consultationID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), videoErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// Signal upgrades to the signaling WebSocket. Browsers cannot set headers on WebSocket
+// requests, so the join token comes in the token query parameter.
+func (h *VideoSessionHandler) Signal(w http.ResponseWriter, r *http.Request) {
+	consultationID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid consultation ID", http.StatusBadRequest)
+		return
+	}
+	userID, role, err := h.service.VerifyJoinToken(r.URL.Query().Get("token"), consultationID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := signalUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an error
+		return
+	}
+	peer := &signalPeer{conn: conn, done: make(chan struct{})}
+	defer peer.Close()
+
+	ctx := r.Context()
+	if err := h.service.Join(ctx, consultationID, userID, role, peer); err != nil {
+		peer.Send(&models.SignalMessage{Type: models.SignalError, Error: err.Error()})
+		return
+	}
+	defer h.service.Leave(ctx, consultationID, userID, peer)
+
+	go peer.keepAlive()
+
+	conn.SetReadLimit(signalMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(signalPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(signalPongWait))
+	})
+	for {
+		var msg models.SignalMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if msg.Type == models.SignalEnd {
+			if _, err := h.service.End(ctx, consultationID, userID); err != nil {
+				peer.Send(&models.SignalMessage{Type: models.SignalError, Error: err.Error()})
+			}
+			return
+		}
+		if err := h.service.Relay(consultationID, userID, &msg); err != nil {
+			peer.Send(&models.SignalMessage{Type: models.SignalError, Error: err.Error()})
+		}
+	}
+}
+
+// signalPeer is a WebSocket connection to one participant. Writes are serialized because
+// state broadcasts and relayed signals arrive from other participants' goroutines.
+type signalPeer struct {
+	conn      *websocket.Conn
+	mu        sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (p *signalPeer) Send(msg *models.SignalMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.conn.SetWriteDeadline(time.Now().Add(signalWriteWait))
+	return p.conn.WriteJSON(msg)
+}
+
+func (p *signalPeer) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		p.conn.Close()
+	})
+}
+
+// keepAlive pings the participant so that dead connections time out on the read side
+func (p *signalPeer) keepAlive() {
+	ticker := time.NewTicker(signalPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			if err := p.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(signalWriteWait)); err != nil {
+				p.Close()
+				return
+			}
+		}
+	}
+}
+
+func videoErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrNotConsultationParticipant):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrVideoSessionEnded):
+		return http.StatusGone
+	}
+	return http.StatusBadRequest
+}
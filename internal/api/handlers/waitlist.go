@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"shifa/internal/models"
+	"shifa/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type WaitlistHandler struct {
+	service *service.WaitlistService
+}
+
+func NewWaitlistHandler(service *service.WaitlistService) *WaitlistHandler {
+	return &WaitlistHandler{service: service}
+}
+
+func (h *WaitlistHandler) JoinWaitlist(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ProviderID   int    `json:"provider_id"`
+		ProviderType string `json:"provider_type"`
+		FromDate     string `json:"from_date"`
+		ToDate       string `json:"to_date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	from, err := time.Parse("2006-01-02", req.FromDate)
+	if err != nil {
+		http.Error(w, "Invalid from_date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	to, err := time.Parse("2006-01-02", req.ToDate)
+	if err != nil {
+		http.Error(w, "Invalid to_date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	// The waitlist entry is always the caller's own
+	userID, _ := r.Context().Value("userID").(int)
+
+	entry := models.WaitlistEntry{
+		PatientID:    userID,
+		ProviderID:   req.ProviderID,
+		ProviderType: req.ProviderType,
+		FromDate:     from,
+		ToDate:       to,
+	}
+	if err := h.service.JoinWaitlist(r.Context(), &entry); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+func (h *WaitlistHandler) LeaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid waitlist entry ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+
+	if err := h.service.LeaveWaitlist(r.Context(), id, userID); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrNotWaitlisted) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WaitlistHandler) ListPatientEntries(w http.ResponseWriter, r *http.Request) {
+	patientID, ok := pathUserID(w, r, "patientId", "You can only view your own waitlist entries")
+	if !ok {
+		return
+	}
+
+	entries, err := h.service.ListPatientEntries(r.Context(), patientID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+func (h *WaitlistHandler) ListPatientOffers(w http.ResponseWriter, r *http.Request) {
+	patientID, ok := pathUserID(w, r, "patientId", "You can only view your own waitlist offers")
+	if !ok {
+		return
+	}
+
+	offers, err := h.service.ListPatientOffers(r.Context(), patientID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(offers)
+}
+
+// AcceptOffer books the held slot and returns the new appointment
+func (h *WaitlistHandler) AcceptOffer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid offer ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+
+	appointment, err := h.service.AcceptOffer(r.Context(), id, userID)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, service.ErrOfferExpired):
+			status = http.StatusGone
+		case errors.Is(err, service.ErrNotOfferee):
+			status = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(appointment)
+}
+
+func (h *WaitlistHandler) DeclineOffer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid offer ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+
+	if err := h.service.DeclineOffer(r.Context(), id, userID); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrNotOfferee) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"shifa/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// queueStreamRefresh resends the queue periodically, keeping the connection alive and picking
+// up changes made through other server instances
+const queueStreamRefresh = 30 * time.Second
+
+type WalkInQueueHandler struct {
+	service *service.WalkInQueueService
+}
+
+func NewWalkInQueueHandler(service *service.WalkInQueueService) *WalkInQueueHandler {
+	return &WalkInQueueHandler{service: service}
+}
+
+// JoinQueue adds a patient to the doctor's queue for today, remotely or at reception. Patients
+// join themselves; the doctor or an admin may add the patient named in patient_id.
+func (h *WalkInQueueHandler) JoinQueue(w http.ResponseWriter, r *http.Request) {
+	doctorID, err := strconv.Atoi(mux.Vars(r)["doctorId"])
+	if err != nil {
+		http.Error(w, "Invalid doctor ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		PatientID int    `json:"patient_id"`
+		Source    string `json:"source"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+	if userID != doctorID && !isAdmin(r) {
+		req.PatientID = userID
+	}
+
+	entry, err := h.service.Join(r.Context(), doctorID, req.PatientID, req.Source)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrAlreadyQueued) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// GetQueue returns the doctor's queue on the date query parameter, today by default
+func (h *WalkInQueueHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	doctorID, date, err := h.queueParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	snapshot, err := h.service.Snapshot(r.Context(), doctorID, date)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// StreamQueue pushes the doctor's queue as Server-Sent Events: once on connect and again
+// after every change, so patients see their position move without polling
+func (h *WalkInQueueHandler) StreamQueue(w http.ResponseWriter, r *http.Request) {
+	doctorID, date, err := h.queueParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	// The stream outlives the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	updates, unsubscribe := h.service.Subscribe(doctorID, date)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	send := func() bool {
+		snapshot, err := h.service.Snapshot(r.Context(), doctorID, date)
+		if err != nil {
+			fmt.Fprintf(w, "event: error\ndata: %q\n\n", err.Error())
+			flusher.Flush()
+			return true
+		}
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "event: queue\ndata: %s\n\n", data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	ticker := time.NewTicker(queueStreamRefresh)
+	defer ticker.Stop()
+
+	if !send() {
+		return
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-updates:
+		case <-ticker.C:
+		}
+		if !send() {
+			return
+		}
+	}
+}
+
+// CallNext finishes the current patient and calls the next; 204 means nobody is waiting
+func (h *WalkInQueueHandler) CallNext(w http.ResponseWriter, r *http.Request) {
+	doctorID, err := strconv.Atoi(mux.Vars(r)["doctorId"])
+	if err != nil {
+		http.Error(w, "Invalid doctor ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+
+	entry, err := h.service.CallNext(r.Context(), doctorID, userID)
+	if err != nil {
+		http.Error(w, err.Error(), queueErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	if entry == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// GetEntry returns a queue entry with its current position and estimated wait
+func (h *WalkInQueueHandler) GetEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid queue entry ID", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.service.GetEntry(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+func (h *WalkInQueueHandler) LeaveQueue(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid queue entry ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+
+	if err := h.service.Leave(r.Context(), id, userID); err != nil {
+		http.Error(w, err.Error(), queueErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WalkInQueueHandler) CompleteEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid queue entry ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value("userID").(int)
+
+	if err := h.service.Complete(r.Context(), id, userID); err != nil {
+		http.Error(w, err.Error(), queueErrorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// queueErrorStatus maps callers acting on someone else's queue entry to 403
+func queueErrorStatus(err error, fallback int) int {
+	if errors.Is(err, service.ErrNotQueueDoctor) || errors.Is(err, service.ErrNotQueuePatient) {
+		return http.StatusForbidden
+	}
+	return fallback
+}
+
+// queueParams reads the doctor ID and the date query parameter, defaulting to today in the doctor's zone
+func (h *WalkInQueueHandler) queueParams(r *http.Request) (int, time.Time, error) {
+	doctorID, err := strconv.Atoi(mux.Vars(r)["doctorId"])
+	if err != nil {
+		return 0, time.Time{}, errors.New("Invalid doctor ID")
+	}
+
+	if r.URL.Query().Get("date") == "" {
+		today, err := h.service.Today(r.Context(), doctorID)
+		return doctorID, today, err
+	}
+	date, err := parseDateQuery(r, "date", time.Time{})
+	if err != nil {
+		return 0, time.Time{}, errors.New("Invalid date, expected YYYY-MM-DD")
+	}
+	return doctorID, date, nil
+}
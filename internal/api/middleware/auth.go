@@ -41,6 +41,12 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
             http.Error(w, "Invalid token claims", http.StatusUnauthorized)
             return
         }
+        // Tokens issued for one purpose, such as joining a video session, are signed with the
+        // same secret but are not login tokens
+        if _, scoped := claims["purpose"]; scoped {
+            http.Error(w, "Invalid token", http.StatusUnauthorized)
+            return
+        }
 
         // Add claims to context
         ctx := context.WithValue(r.Context(), "userID", int(claims["user_id"].(float64)))
@@ -48,4 +54,30 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 
         next.ServeHTTP(w, r.WithContext(ctx))
     })
-}
\ No newline at end of file
+}
+
+// OptionalAuth is RequireAuth for routes that are also open to anonymous callers: requests
+// without an Authorization header pass through without a user in the context.
+func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
+    auth := m.RequireAuth(next)
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Header.Get("Authorization") == "" {
+            next.ServeHTTP(w, r)
+            return
+        }
+        auth.ServeHTTP(w, r)
+    })
+}
+
+// RequireSocketAuth is RequireAuth for WebSocket endpoints. Browsers cannot set headers on
+// WebSocket requests, so the JWT may come in the token query parameter instead.
+func (m *AuthMiddleware) RequireSocketAuth(next http.Handler) http.Handler {
+    auth := m.RequireAuth(next)
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if token := r.URL.Query().Get("token"); token != "" && r.Header.Get("Authorization") == "" {
+            r = r.Clone(r.Context())
+            r.Header.Set("Authorization", "Bearer "+token)
+        }
+        auth.ServeHTTP(w, r)
+    })
+}
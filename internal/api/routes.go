@@ -1,21 +1,26 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 	"shifa/internal/api/handlers"
 	"shifa/internal/api/middleware"
+	"shifa/internal/pubsub"
 	"shifa/internal/repository/mysql"
+	"shifa/internal/scheduler"
 	"shifa/internal/service"
 	"shifa/pkg/fileutils"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
 
-// NewRouter creates and configures a new router with all application routes
-func NewRouter(db *sql.DB, log *logrus.Logger, jwtSecret string) *mux.Router {
+// NewRouter creates and configures a new router with all application routes,
+// along with the job scheduler that shares its services
+func NewRouter(db *sql.DB, log *logrus.Logger, jwtSecret string) (*mux.Router, *scheduler.Scheduler) {
 	router := mux.NewRouter()
 
 	// Serve static files from the uploads directory
@@ -44,30 +49,141 @@ func NewRouter(db *sql.DB, log *logrus.Logger, jwtSecret string) *mux.Router {
 	systemLogRepo := mysql.NewSystemLogRepo(db)                   // Create SystemLogRepo first
 	doctorAvailabilityRepo := mysql.NewDoctorAvailabilityRepo(db) // Add this line
 	consultationDetailsRepo := mysql.NewConsultationDetailsRepo(db)
+	availabilityExceptionRepo := mysql.NewAvailabilityExceptionRepo(db)
+	holidayRepo := mysql.NewHolidayRepo(db)
+	homeCareAvailabilityRepo := mysql.NewHomeCareAvailabilityRepo(db)
+	waitlistRepo := mysql.NewWaitlistRepo(db)
+	noShowRepo := mysql.NewNoShowRepo(db)
+	reminderRepo := mysql.NewReminderRepo(db)
+	jobLeaseRepo := mysql.NewJobLeaseRepo(db)
+	calendarRepo := mysql.NewCalendarRepo(db)
+	walkInQueueRepo := mysql.NewWalkInQueueRepo(db)
+	videoSessionRepo := mysql.NewVideoSessionRepo(db)
+	clinicalNoteRepo := mysql.NewClinicalNoteRepo(db)
+	prescriptionRepo := mysql.NewPrescriptionRepo(db)
+	referralRepo := mysql.NewReferralRepo(db)
+	labOrderRepo := mysql.NewLabOrderRepo(db)
+	drugDataRepo := mysql.NewDrugDataRepo(db)
+	diagnosisCodeRepo := mysql.NewDiagnosisCodeRepo(db)
+	followUpRepo := mysql.NewFollowUpRepo(db)
+	consultationSummaryRepo := mysql.NewConsultationSummaryRepo(db)
+	chatTranscriptRepo := mysql.NewChatTranscriptRepo(db)
 
 	// Initialize services
+	timeZoneService := service.NewTimeZoneService(userRepo, log)
+	notificationService := service.NewNotificationService(notificationRepo, appointmentRepo, timeZoneService, log)
+	doctorAvailabilityService := service.NewDoctorAvailabilityService(
+		doctorAvailabilityRepo,
+		availabilityExceptionRepo,
+		holidayRepo,
+		appointmentRepo,
+		notificationService,
+		timeZoneService,
+		log,
+	)
+	holidayService := service.NewHolidayService(holidayRepo, log)
+	noShowService := service.NewNoShowService(noShowRepo, notificationService, log)
+	reminderService := service.NewReminderService(reminderRepo, notificationService, timeZoneService, log)
+	calendarService := service.NewCalendarService(calendarRepo, log)
+	homeCareAvailabilityService := service.NewHomeCareAvailabilityService(
+		homeCareAvailabilityRepo,
+		holidayRepo,
+		homeCareVisitRepo,
+		appointmentRepo,
+		homeCareProviderRepo,
+		timeZoneService,
+		log,
+	)
 	appointmentService := service.NewAppointmentService(
 		appointmentRepo,
 		doctorRepo,
 		homeCareProviderRepo,
+		doctorAvailabilityService,
+		homeCareAvailabilityService,
+		noShowService,
+		reminderService,
+		timeZoneService,
+		log,
+	)
+	waitlistService := service.NewWaitlistService(waitlistRepo, appointmentService, notificationService, timeZoneService, log)
+	walkInQueueService := service.NewWalkInQueueService(
+		walkInQueueRepo,
+		appointmentRepo,
+		doctorRepo,
+		notificationService,
+		timeZoneService,
 		log,
 	)
 	userService := service.NewUserService(userRepo)
 	doctorService := service.NewDoctorService(doctorRepo, log)
 	serviceTypeService := service.NewServiceTypeService(serviceTypeRepo, log)
 	patientService := service.NewPatientService(patientRepo, log)
-	consultationService := service.NewConsultationService(consultationRepo, log)
-	reviewService := service.NewReviewService(reviewRepo, log)
+	followUpService := service.NewFollowUpService(
+		followUpRepo,
+		doctorRepo,
+		userRepo,
+		doctorAvailabilityService,
+		appointmentService,
+		notificationService,
+		timeZoneService,
+		log,
+	)
+	consultationService := service.NewConsultationService(consultationRepo, appointmentRepo, doctorRepo, followUpService, log)
+	videoSessionService := service.NewVideoSessionService(videoSessionRepo, consultationRepo, jwtSecret, log)
+	reviewService := service.NewReviewService(reviewRepo, consultationRepo, log)
 	homeCareProviderService := service.NewHomeCareProviderService(homeCareProviderRepo, log)
-	medicalHistoryService := service.NewMedicalHistoryService(medicalHistoryRepo, log) // Add this line
-	chatMessageService := service.NewChatService(chatMessageRepo, log)                 // Where logger is an instance of your custom logger
+	diagnosisCodeService := service.NewDiagnosisCodeService(diagnosisCodeRepo, log)
+	medicalHistoryService := service.NewMedicalHistoryService(medicalHistoryRepo, diagnosisCodeService, log)
+	// Chat events fan out through the broker; a shared implementation replaces the in-memory
+	// one when several instances serve the API
+	chatBroker := pubsub.NewMemory()
+	chatMessageService := service.NewChatService(chatMessageRepo, consultationRepo, chatTranscriptRepo, chatBroker, log) // Where logger is an instance of your custom logger
 	paymentService := service.NewPaymentService(paymentRepo, log)
-	notificationService := service.NewNotificationService(notificationRepo, log)
-	homeCareVisitService := service.NewHomeCareVisitService(homeCareVisitRepo, log)
+	homeCareVisitService := service.NewHomeCareVisitService(homeCareVisitRepo, homeCareAvailabilityService, log)
 	authService := service.NewAuthService(userRepo, jwtSecret)
-	systemLogService := service.NewSystemLogService(systemLogRepo)                                 // Pass systemLogRepo to NewSystemLogService
-	doctorAvailabilityService := service.NewDoctorAvailabilityService(doctorAvailabilityRepo, log) // Add this line
-	consultationDetailsService := service.NewConsultationDetailsService(consultationDetailsRepo, log)
+	systemLogService := service.NewSystemLogService(systemLogRepo) // Pass systemLogRepo to NewSystemLogService
+	clinicalNoteService := service.NewClinicalNoteService(
+		clinicalNoteRepo,
+		consultationRepo,
+		userRepo,
+		diagnosisCodeService,
+		medicalHistoryService,
+		log,
+	)
+	consultationDetailsService := service.NewConsultationDetailsService(
+		consultationDetailsRepo,
+		consultationRepo,
+		clinicalNoteService,
+		diagnosisCodeService,
+		medicalHistoryService,
+		log,
+	)
+	drugSafetyService := service.NewDrugSafetyService(drugDataRepo, prescriptionRepo, medicalHistoryRepo, log)
+	prescriptionService := service.NewPrescriptionService(prescriptionRepo, consultationRepo, doctorRepo, userRepo, drugSafetyService, log)
+	referralService := service.NewReferralService(
+		referralRepo,
+		consultationRepo,
+		consultationDetailsRepo,
+		doctorRepo,
+		homeCareProviderRepo,
+		appointmentService,
+		notificationService,
+		log,
+	)
+	labOrderService := service.NewLabOrderService(labOrderRepo, consultationRepo, userRepo, notificationService, log)
+	consultationSummaryService := service.NewConsultationSummaryService(
+		consultationSummaryRepo,
+		consultationRepo,
+		consultationDetailsRepo,
+		clinicalNoteService,
+		prescriptionRepo,
+		followUpRepo,
+		doctorRepo,
+		userRepo,
+		timeZoneService,
+		log,
+	)
+	chatTranscriptService := service.NewChatTranscriptService(chatTranscriptRepo, chatMessageRepo, consultationRepo, userRepo, log)
 
 	// Initialize handlers
 	appointmentHandler := handlers.NewAppointmentHandler(appointmentService)
@@ -86,27 +202,59 @@ func NewRouter(db *sql.DB, log *logrus.Logger, jwtSecret string) *mux.Router {
 	authHandler := handlers.NewAuthHandler(authService)
 	doctorAvailabilityHandler := handlers.NewDoctorAvailabilityHandler(doctorAvailabilityService) // Add this line
 	consultationDetailsHandler := handlers.NewConsultationDetailsHandler(consultationDetailsService)
+	holidayHandler := handlers.NewHolidayHandler(holidayService)
+	homeCareAvailabilityHandler := handlers.NewHomeCareAvailabilityHandler(homeCareAvailabilityService)
+	waitlistHandler := handlers.NewWaitlistHandler(waitlistService)
+	noShowHandler := handlers.NewNoShowHandler(noShowService)
+	calendarHandler := handlers.NewCalendarHandler(calendarService)
+	walkInQueueHandler := handlers.NewWalkInQueueHandler(walkInQueueService)
+	videoSessionHandler := handlers.NewVideoSessionHandler(videoSessionService)
+	clinicalNoteHandler := handlers.NewClinicalNoteHandler(clinicalNoteService)
+	prescriptionHandler := handlers.NewPrescriptionHandler(prescriptionService)
+	referralHandler := handlers.NewReferralHandler(referralService)
+	labOrderHandler := handlers.NewLabOrderHandler(labOrderService)
+	drugDataHandler := handlers.NewDrugDataHandler(drugSafetyService)
+	diagnosisCodeHandler := handlers.NewDiagnosisCodeHandler(diagnosisCodeService)
+	followUpHandler := handlers.NewFollowUpHandler(followUpService)
+	consultationSummaryHandler := handlers.NewConsultationSummaryHandler(consultationSummaryService)
+	chatTranscriptHandler := handlers.NewChatTranscriptHandler(chatTranscriptService)
 
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(jwtSecret)
 	logMiddleware := middleware.NewSystemLogMiddleware(systemLogService)
 
 	// Register routes
-	registerAppointmentRoutes(apiRouter, appointmentHandler)
+	registerAppointmentRoutes(apiRouter, appointmentHandler, authMiddleware)
 	registerUserRoutes(apiRouter, userHandler)
 	registerDoctorRoutes(apiRouter, doctorHandler)
 	registerServiceTypeRoutes(apiRouter, serviceTypeHandler)
 	registerPatientRoutes(apiRouter, patientHandler)
 	registerConsultationRoutes(apiRouter, consultationHandler)
-	registerReviewRoutes(apiRouter, reviewHandler)
+	registerReviewRoutes(apiRouter, reviewHandler, authMiddleware)
 	registerHomeCareProviderRoutes(apiRouter, homeCareProviderHandler) // Add this line
 	registerMedicalHistoryRoutes(apiRouter, medicalHistoryHandler)     // Add this line
-	registerChatMessageRoutes(apiRouter, chatMessageHandler)
+	registerChatMessageRoutes(apiRouter, chatMessageHandler, authMiddleware)
 	registerPaymentRoutes(apiRouter, paymentHandler)
 	registerNotificationRoutes(apiRouter, notificationHandler)
 	registerHomeCareVisitRoutes(apiRouter, homeCareVisitHandler)
-	registerDoctorAvailabilityRoutes(apiRouter, doctorAvailabilityHandler) // Add this line
-	registerConsultationDetailsRoutes(apiRouter, consultationDetailsHandler)
+	registerDoctorAvailabilityRoutes(apiRouter, doctorAvailabilityHandler, authMiddleware) // Add this line
+	registerConsultationDetailsRoutes(apiRouter, consultationDetailsHandler, authMiddleware)
+	registerHolidayRoutes(apiRouter, holidayHandler, authMiddleware)
+	registerHomeCareAvailabilityRoutes(apiRouter, homeCareAvailabilityHandler, authMiddleware)
+	registerWaitlistRoutes(apiRouter, waitlistHandler, authMiddleware)
+	registerNoShowRoutes(apiRouter, noShowHandler, authMiddleware)
+	registerCalendarRoutes(apiRouter, calendarHandler, authMiddleware)
+	registerWalkInQueueRoutes(apiRouter, walkInQueueHandler, authMiddleware)
+	registerVideoSessionRoutes(apiRouter, videoSessionHandler, authMiddleware)
+	registerClinicalNoteRoutes(apiRouter, clinicalNoteHandler, authMiddleware)
+	registerPrescriptionRoutes(apiRouter, prescriptionHandler, authMiddleware)
+	registerReferralRoutes(apiRouter, referralHandler, authMiddleware)
+	registerLabOrderRoutes(apiRouter, labOrderHandler, authMiddleware)
+	registerDrugDataRoutes(apiRouter, drugDataHandler, authMiddleware)
+	registerDiagnosisCodeRoutes(apiRouter, diagnosisCodeHandler, authMiddleware)
+	registerFollowUpRoutes(apiRouter, followUpHandler, authMiddleware)
+	registerConsultationSummaryRoutes(apiRouter, consultationSummaryHandler, authMiddleware)
+	registerChatTranscriptRoutes(apiRouter, chatTranscriptHandler, authMiddleware)
 	// Register public routes (no auth required)
 	registerAuthRoutes(apiRouter, authHandler)
 
@@ -125,7 +273,36 @@ func NewRouter(db *sql.DB, log *logrus.Logger, jwtSecret string) *mux.Router {
 		http.Error(w, "404 page not found", http.StatusNotFound)
 	})
 
-	return router
+	jobs := scheduler.New(jobLeaseRepo, log)
+	registerJobs(jobs, reminderService, noShowService, waitlistService, followUpService, chatMessageService)
+
+	return router, jobs
+}
+
+// registerJobs schedules the periodic housekeeping jobs
+func registerJobs(jobs *scheduler.Scheduler, reminderService *service.ReminderService,
+	noShowService *service.NoShowService, waitlistService *service.WaitlistService,
+	followUpService *service.FollowUpService, chatService service.ChatService) {
+	jobs.Register("appointment-reminders", 5*time.Minute, func(ctx context.Context) error {
+		_, err := reminderService.SendDueReminders(ctx)
+		return err
+	})
+	jobs.Register("no-show-detection", 5*time.Minute, func(ctx context.Context) error {
+		_, err := noShowService.DetectNoShows(ctx)
+		return err
+	})
+	jobs.Register("waitlist-offer-expiry", time.Minute, func(ctx context.Context) error {
+		_, err := waitlistService.ExpireOffers(ctx)
+		return err
+	})
+	jobs.Register("follow-up-reminders", time.Hour, func(ctx context.Context) error {
+		_, err := followUpService.SendReminders(ctx)
+		return err
+	})
+	jobs.Register("chat-retention", time.Hour, func(ctx context.Context) error {
+		_, err := chatService.ArchiveExpiredChats(ctx)
+		return err
+	})
 }
 
 func registerProtectedRoutes(protected *mux.Router, userHandler *handlers.UserHandler, appointmentHandler *handlers.AppointmentHandler, doctorHandler *handlers.DoctorHandler, serviceTypeHandler *handlers.ServiceTypeHandler, patientHandler *handlers.PatientHandler, consultationHandler *handlers.ConsultationHandler, reviewHandler *handlers.ReviewHandler, homeCareProviderHandler *handlers.HomeCareProviderHandler, medicalHistoryHandler *handlers.MedicalHistoryHandler, chatMessageHandler *handlers.ChatMessageHandler, paymentHandler *handlers.PaymentHandler, notificationHandler *handlers.NotificationHandler, homeCareVisitHandler *handlers.HomeCareVisitHandler) {
@@ -133,13 +310,14 @@ func registerProtectedRoutes(protected *mux.Router, userHandler *handlers.UserHa
 }
 
 // registerAppointmentRoutes sets up all appointment-related routes
-func registerAppointmentRoutes(router *mux.Router, handler *handlers.AppointmentHandler) {
+func registerAppointmentRoutes(router *mux.Router, handler *handlers.AppointmentHandler, authMiddleware *middleware.AuthMiddleware) {
 	appointmentRouter := router.PathPrefix("/appointments").Subrouter()
 
-	// List/Search appointments with query parameters
-	appointmentRouter.HandleFunc("", handler.GetAppointmentsByProvider).
+	// List/Search appointments with query parameters, scoped to the caller
+	appointmentRouter.Handle("", authMiddleware.RequireAuth(http.HandlerFunc(handler.GetAppointmentsByProvider))).
 		Methods("GET").
 		Queries("type", "{type}", "providerId", "{providerId}")
+	appointmentRouter.Handle("", authMiddleware.RequireAuth(http.HandlerFunc(handler.ListAppointments))).Methods("GET")
 
 	// Other routes
 	appointmentRouter.HandleFunc("", handler.CreateAppointment).Methods("POST")
@@ -211,11 +389,11 @@ func registerConsultationRoutes(router *mux.Router, handler *handlers.Consultati
 }
 
 // Add a new function to register review routes
-func registerReviewRoutes(router *mux.Router, handler *handlers.ReviewHandler) {
+func registerReviewRoutes(router *mux.Router, handler *handlers.ReviewHandler, authMiddleware *middleware.AuthMiddleware) {
 	reviewRouter := router.PathPrefix("/reviews").Subrouter()
 
-	// Core CRUD operations
-	reviewRouter.HandleFunc("", handler.CreateReview).Methods("POST")
+	// Core CRUD operations; reviews are written as the authenticated patient
+	reviewRouter.Handle("", authMiddleware.RequireAuth(http.HandlerFunc(handler.CreateReview))).Methods("POST")
 	reviewRouter.HandleFunc("/{id}", handler.GetReview).Methods("GET")
 	reviewRouter.HandleFunc("", handler.ListReviews).Methods("GET")
 	reviewRouter.HandleFunc("/{id}", handler.UpdateReview).Methods("PUT")
@@ -277,17 +455,35 @@ func registerMedicalHistoryRoutes(router *mux.Router, handler *handlers.MedicalH
 }
 
 // registerChatMessageRoutes sets up all chat message-related routes
-func registerChatMessageRoutes(router *mux.Router, handler *handlers.ChatMessageHandler) {
+func registerChatMessageRoutes(router *mux.Router, handler *handlers.ChatMessageHandler, authMiddleware *middleware.AuthMiddleware) {
+	// Real-time chat for one consultation; the socket may authenticate with a token query parameter
+	router.Handle("/consultations/{id}/chat/ws", authMiddleware.RequireSocketAuth(http.HandlerFunc(handler.Socket))).Methods("GET")
+
+	// Attachments are only served to the consultation's participants
+	router.Handle("/consultations/{id}/chat/attachments", authMiddleware.RequireAuth(http.HandlerFunc(handler.SendAttachments))).Methods("POST")
+	router.Handle("/chat/attachments/{id}", authMiddleware.RequireAuth(http.HandlerFunc(handler.GetAttachment))).Methods("GET")
+	router.Handle("/chat/attachments/{id}/thumbnail", authMiddleware.RequireAuth(http.HandlerFunc(handler.GetAttachmentThumbnail))).Methods("GET")
+
+	// Editing and deleting act as the sender, within the retention policy's windows
+	router.Handle("/chat/messages/{id:[0-9]+}", authMiddleware.RequireAuth(http.HandlerFunc(handler.EditMessage))).Methods("PUT")
+	router.Handle("/chat/messages/{id:[0-9]+}", authMiddleware.RequireAuth(http.HandlerFunc(handler.DeleteMessage))).Methods("DELETE")
+	router.Handle("/chat/messages/{id:[0-9]+}/history", authMiddleware.RequireAuth(http.HandlerFunc(handler.GetMessageHistory))).Methods("GET")
+	router.Handle("/chat/retention-policy", authMiddleware.RequireAuth(http.HandlerFunc(handler.GetRetentionPolicy))).Methods("GET")
+	router.Handle("/chat/retention-policy", authMiddleware.RequireAuth(http.HandlerFunc(handler.UpdateRetentionPolicy))).Methods("PUT")
+	router.Handle("/chat/search", authMiddleware.RequireAuth(http.HandlerFunc(handler.SearchMessages))).Methods("GET")
+	router.Handle("/chat/conversations", authMiddleware.RequireAuth(http.HandlerFunc(handler.ListConversations))).Methods("GET")
+	router.Handle("/consultations/{id}/chat/read", authMiddleware.RequireAuth(http.HandlerFunc(handler.MarkConversationRead))).Methods("POST")
+
 	chatRouter := router.PathPrefix("/chat").Subrouter()
 
-	// Route to send a new message
-	chatRouter.HandleFunc("/messages", handler.SendMessage).Methods("POST")
+	// Route to send a new message as the authenticated user
+	chatRouter.Handle("/messages", authMiddleware.RequireAuth(http.HandlerFunc(handler.SendMessage))).Methods("POST")
 
-	// Route to get messages for a specific consultation
-	chatRouter.HandleFunc("/messages", handler.GetMessagesByConsultation).Methods("GET")
+	// Route to get messages for a specific consultation, for its participants
+	chatRouter.Handle("/messages", authMiddleware.RequireAuth(http.HandlerFunc(handler.GetMessagesByConsultation))).Methods("GET")
 
-	// Route to mark a message as read
-	chatRouter.HandleFunc("/messages/{id}/read", func(w http.ResponseWriter, r *http.Request) {
+	// Route to mark a message as read by the authenticated participant
+	chatRouter.Handle("/messages/{id}/read", authMiddleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		messageID, err := strconv.Atoi(vars["id"])
 		if err != nil {
@@ -295,18 +491,10 @@ func registerChatMessageRoutes(router *mux.Router, handler *handlers.ChatMessage
 			return
 		}
 		handler.MarkMessageAsRead(w, r, messageID)
-	}).Methods("PUT")
+	}))).Methods("PUT")
 
-	// Route to get unread message count
-	chatRouter.HandleFunc("/unread-count", func(w http.ResponseWriter, r *http.Request) {
-		userIDStr := r.URL.Query().Get("user_id")
-		userID, err := strconv.Atoi(userIDStr)
-		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
-			return
-		}
-		handler.GetUnreadMessageCount(w, r, userID)
-	}).Methods("GET")
+	// Route to get the authenticated user's unread message count
+	chatRouter.Handle("/unread-count", authMiddleware.RequireAuth(http.HandlerFunc(handler.GetUnreadMessageCount))).Methods("GET")
 }
 
 // registerPaymentRoutes sets up all payment-related routes
@@ -379,11 +567,98 @@ func registerAuthRoutes(router *mux.Router, handler *handlers.AuthHandler) {
 }
 
 // Add this new function to register doctor availability routes
-func registerDoctorAvailabilityRoutes(router *mux.Router, handler *handlers.DoctorAvailabilityHandler) {
+func registerDoctorAvailabilityRoutes(router *mux.Router, handler *handlers.DoctorAvailabilityHandler, authMiddleware *middleware.AuthMiddleware) {
 	router.HandleFunc("/doctors/{doctorId}/availability", handler.SetAvailability).Methods("POST")
 	router.HandleFunc("/doctors/{doctorId}/availability", handler.GetAvailability).Methods("GET")
 	router.HandleFunc("/doctors/{doctorId}/availability/{id}", handler.UpdateAvailability).Methods("PUT")
 	router.HandleFunc("/doctors/{doctorId}/availability/{id}", handler.DeleteAvailability).Methods("DELETE")
+
+	// Date-specific exceptions (vacations, one-off extra hours); only the doctor or an admin
+	// changes them or cancels the appointments they block
+	router.Handle("/doctors/{doctorId}/availability-exceptions",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.CreateException))).Methods("POST")
+	router.HandleFunc("/doctors/{doctorId}/availability-exceptions", handler.ListExceptions).Methods("GET")
+	router.Handle("/doctors/{doctorId}/availability-exceptions/{id}",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.DeleteException))).Methods("DELETE")
+	router.Handle("/doctors/{doctorId}/availability-exceptions/{id}/cancel-appointments",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.CancelAppointmentsInException))).Methods("POST")
+
+	// Bookable slots for a given date
+	router.HandleFunc("/doctors/{doctorId}/slots", handler.GetAvailableSlots).Methods("GET")
+}
+
+// registerHomeCareAvailabilityRoutes sets up weekly availability, exceptions and service area routes for home care providers
+func registerHomeCareAvailabilityRoutes(router *mux.Router, handler *handlers.HomeCareAvailabilityHandler, authMiddleware *middleware.AuthMiddleware) {
+	// Reads are public for booking; changes are made by the provider or an admin
+	router.Handle("/providers/{providerId}/availability", authMiddleware.RequireAuth(http.HandlerFunc(handler.SetAvailability))).Methods("POST")
+	router.HandleFunc("/providers/{providerId}/availability", handler.GetAvailability).Methods("GET")
+	router.Handle("/providers/{providerId}/availability/{id}", authMiddleware.RequireAuth(http.HandlerFunc(handler.UpdateAvailability))).Methods("PUT")
+	router.Handle("/providers/{providerId}/availability/{id}", authMiddleware.RequireAuth(http.HandlerFunc(handler.DeleteAvailability))).Methods("DELETE")
+
+	router.Handle("/providers/{providerId}/availability-exceptions", authMiddleware.RequireAuth(http.HandlerFunc(handler.CreateException))).Methods("POST")
+	router.HandleFunc("/providers/{providerId}/availability-exceptions", handler.ListExceptions).Methods("GET")
+	router.Handle("/providers/{providerId}/availability-exceptions/{id}", authMiddleware.RequireAuth(http.HandlerFunc(handler.DeleteException))).Methods("DELETE")
+
+	router.HandleFunc("/providers/{providerId}/service-area", handler.GetServiceArea).Methods("GET")
+	router.Handle("/providers/{providerId}/service-area", authMiddleware.RequireAuth(http.HandlerFunc(handler.SetServiceArea))).Methods("PUT")
+
+	router.HandleFunc("/providers/{providerId}/slots", handler.GetAvailableSlots).Methods("GET")
+}
+
+// registerWaitlistRoutes sets up waitlist entry and slot offer routes
+func registerWaitlistRoutes(router *mux.Router, handler *handlers.WaitlistHandler, authMiddleware *middleware.AuthMiddleware) {
+	router.Handle("/waitlist", authMiddleware.RequireAuth(http.HandlerFunc(handler.JoinWaitlist))).Methods("POST")
+	router.Handle("/waitlist/{id}", authMiddleware.RequireAuth(http.HandlerFunc(handler.LeaveWaitlist))).Methods("DELETE")
+	router.Handle("/patients/{patientId}/waitlist",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.ListPatientEntries))).Methods("GET")
+	router.Handle("/patients/{patientId}/waitlist-offers",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.ListPatientOffers))).Methods("GET")
+
+	// Only the offered patient can answer an offer
+	router.Handle("/waitlist-offers/{id}/accept",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.AcceptOffer))).Methods("POST")
+	router.Handle("/waitlist-offers/{id}/decline",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.DeclineOffer))).Methods("POST")
+}
+
+// registerNoShowRoutes sets up no-show policy, detection and statistics routes
+func registerNoShowRoutes(router *mux.Router, handler *handlers.NoShowHandler, authMiddleware *middleware.AuthMiddleware) {
+	router.HandleFunc("/no-show-policy", handler.GetPolicy).Methods("GET")
+	// Changing the policy, running detection and clearing restrictions are admin actions; a
+	// patient's record is shown to that patient and admins
+	router.Handle("/no-show-policy", authMiddleware.RequireAuth(http.HandlerFunc(handler.UpdatePolicy))).Methods("PUT")
+	router.Handle("/no-shows/detect", authMiddleware.RequireAuth(http.HandlerFunc(handler.DetectNoShows))).Methods("POST")
+
+	router.Handle("/patients/{patientId}/no-shows", authMiddleware.RequireAuth(http.HandlerFunc(handler.ListPatientNoShows))).Methods("GET")
+	router.Handle("/patients/{patientId}/no-show-stats", authMiddleware.RequireAuth(http.HandlerFunc(handler.GetPatientStats))).Methods("GET")
+	router.Handle("/patients/{patientId}/prepayment-requirement",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.ClearPrepaymentRequirement))).Methods("DELETE")
+	router.HandleFunc("/providers/{providerId}/no-show-stats", handler.GetProviderStats).Methods("GET")
+}
+
+// registerCalendarRoutes sets up iCalendar export, feed token and token-authenticated feed routes
+func registerCalendarRoutes(router *mux.Router, handler *handlers.CalendarHandler, authMiddleware *middleware.AuthMiddleware) {
+	router.Handle("/appointments/{id}/ics", authMiddleware.RequireAuth(http.HandlerFunc(handler.ExportAppointment))).Methods("GET")
+
+	router.Handle("/users/{id}/calendar-token",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.GetFeedToken))).Methods("GET")
+	router.Handle("/users/{id}/calendar-token",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.RotateFeedToken))).Methods("POST")
+	router.Handle("/users/{id}/calendar-token",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.RevokeFeedToken))).Methods("DELETE")
+
+	// The token in the path is the only credential, so calendar apps can subscribe directly
+	router.HandleFunc("/calendar/{token:[A-Za-z0-9_=-]+}.ics", handler.Feed).Methods("GET")
+}
+
+// registerHolidayRoutes sets up the clinic-wide holiday calendar routes
+func registerHolidayRoutes(router *mux.Router, handler *handlers.HolidayHandler, authMiddleware *middleware.AuthMiddleware) {
+	holidayRouter := router.PathPrefix("/holidays").Subrouter()
+
+	// Admins maintain the holiday calendar
+	holidayRouter.Handle("", authMiddleware.RequireAuth(http.HandlerFunc(handler.CreateHoliday))).Methods("POST")
+	holidayRouter.HandleFunc("", handler.ListHolidays).Methods("GET")
+	holidayRouter.Handle("/{id}", authMiddleware.RequireAuth(http.HandlerFunc(handler.DeleteHoliday))).Methods("DELETE")
 }
 
 // Add new route for listing all availability slots
@@ -393,16 +668,160 @@ func registerAvailabilityRoutes(router *mux.Router, handler *handlers.DoctorAvai
 
 
 
-func registerConsultationDetailsRoutes(router *mux.Router, handler *handlers.ConsultationDetailsHandler) {
-	// Route to get details by consultation ID
-	router.HandleFunc("/consultations/{consultationId}/details", handler.GetDetailsByConsultation).Methods("GET")
+func registerConsultationDetailsRoutes(router *mux.Router, handler *handlers.ConsultationDetailsHandler, authMiddleware *middleware.AuthMiddleware) {
+	// Route to get details by consultation ID; the fallback to the signed clinical note is
+	// only served to the consultation's participants, so a token is read when present
+	router.Handle("/consultations/{consultationId}/details",
+		authMiddleware.OptionalAuth(http.HandlerFunc(handler.GetDetailsByConsultation))).Methods("GET")
 	
 	// Route to create details for a consultation
-	router.HandleFunc("/consultations/{consultationId}/details", handler.CreateDetails).Methods("POST")
+	// Writes can add the diagnosis to the patient's medical history, so they need the author
+	router.Handle("/consultations/{consultationId}/details",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.CreateDetails))).Methods("POST")
 	
 	// Routes for direct access to consultation details
 	detailsRouter := router.PathPrefix("/consultation-details").Subrouter()
 	detailsRouter.HandleFunc("/{id}", handler.GetDetails).Methods("GET")
-	detailsRouter.HandleFunc("/{id}", handler.UpdateDetails).Methods("PUT")
+	detailsRouter.Handle("/{id}", authMiddleware.RequireAuth(http.HandlerFunc(handler.UpdateDetails))).Methods("PUT")
 	detailsRouter.HandleFunc("/{id}", handler.DeleteDetails).Methods("DELETE")
 }
+
+// registerWalkInQueueRoutes sets up the per-doctor walk-in queue routes
+func registerWalkInQueueRoutes(router *mux.Router, handler *handlers.WalkInQueueHandler, authMiddleware *middleware.AuthMiddleware) {
+	// Patients join and leave as themselves; only the doctor moves their queue along
+	router.Handle("/doctors/{doctorId}/queue", authMiddleware.RequireAuth(http.HandlerFunc(handler.JoinQueue))).Methods("POST")
+	router.HandleFunc("/doctors/{doctorId}/queue", handler.GetQueue).Methods("GET")
+	router.HandleFunc("/doctors/{doctorId}/queue/events", handler.StreamQueue).Methods("GET")
+	router.Handle("/doctors/{doctorId}/queue/call-next", authMiddleware.RequireAuth(http.HandlerFunc(handler.CallNext))).Methods("POST")
+
+	router.HandleFunc("/queue-entries/{id}", handler.GetEntry).Methods("GET")
+	router.Handle("/queue-entries/{id}/leave", authMiddleware.RequireAuth(http.HandlerFunc(handler.LeaveQueue))).Methods("POST")
+	router.Handle("/queue-entries/{id}/complete", authMiddleware.RequireAuth(http.HandlerFunc(handler.CompleteEntry))).Methods("POST")
+}
+
+// registerVideoSessionRoutes sets up consultation video signaling. Tokens and session state
+// need the caller's identity; the WebSocket authenticates with its join token instead.
+func registerVideoSessionRoutes(router *mux.Router, handler *handlers.VideoSessionHandler, authMiddleware *middleware.AuthMiddleware) {
+	videoRouter := router.PathPrefix("/consultations/{id}/video").Subrouter()
+
+	videoRouter.Handle("/token", authMiddleware.RequireAuth(http.HandlerFunc(handler.IssueJoinToken))).Methods("POST")
+	videoRouter.Handle("", authMiddleware.RequireAuth(http.HandlerFunc(handler.GetSession))).Methods("GET")
+	videoRouter.Handle("/end", authMiddleware.RequireAuth(http.HandlerFunc(handler.EndSession))).Methods("POST")
+	videoRouter.HandleFunc("/ws", handler.Signal).Methods("GET")
+}
+
+// registerClinicalNoteRoutes sets up SOAP note routes; all of them act as the authenticated user
+func registerClinicalNoteRoutes(router *mux.Router, handler *handlers.ClinicalNoteHandler, authMiddleware *middleware.AuthMiddleware) {
+	notesRouter := router.NewRoute().Subrouter()
+	notesRouter.Use(authMiddleware.RequireAuth)
+
+	notesRouter.HandleFunc("/consultations/{id}/notes", handler.CreateDraft).Methods("POST")
+	notesRouter.HandleFunc("/consultations/{id}/notes", handler.ListVersions).Methods("GET")
+	notesRouter.HandleFunc("/consultations/{id}/notes/current", handler.GetCurrent).Methods("GET")
+
+	notesRouter.HandleFunc("/clinical-notes/{id}", handler.GetNote).Methods("GET")
+	notesRouter.HandleFunc("/clinical-notes/{id}", handler.UpdateDraft).Methods("PUT")
+	notesRouter.HandleFunc("/clinical-notes/{id}/sign", handler.SignNote).Methods("POST")
+	notesRouter.HandleFunc("/clinical-notes/{id}/amendments", handler.AmendNote).Methods("POST")
+}
+
+// registerPrescriptionRoutes sets up e-prescription routes. Verification and dispensing are
+// public, authorised by the code printed on the prescription; the rest act as the authenticated user.
+func registerPrescriptionRoutes(router *mux.Router, handler *handlers.PrescriptionHandler, authMiddleware *middleware.AuthMiddleware) {
+	router.HandleFunc("/prescriptions/verify/{number}", handler.VerifyPrescription).Methods("GET")
+	router.HandleFunc("/prescriptions/verify/{number}/dispense", handler.DispensePrescription).Methods("POST")
+
+	prescriptionRouter := router.NewRoute().Subrouter()
+	prescriptionRouter.Use(authMiddleware.RequireAuth)
+
+	prescriptionRouter.HandleFunc("/consultations/{id}/prescriptions", handler.IssuePrescription).Methods("POST")
+	prescriptionRouter.HandleFunc("/consultations/{id}/prescriptions", handler.ListConsultationPrescriptions).Methods("GET")
+	prescriptionRouter.HandleFunc("/consultations/{id}/prescriptions/check", handler.CheckPrescription).Methods("POST")
+	prescriptionRouter.HandleFunc("/prescriptions/{id:[0-9]+}", handler.GetPrescription).Methods("GET")
+	prescriptionRouter.HandleFunc("/prescriptions/{id:[0-9]+}/pdf", handler.DownloadPDF).Methods("GET")
+	prescriptionRouter.HandleFunc("/prescriptions/{id:[0-9]+}/void", handler.VoidPrescription).Methods("POST")
+}
+
+// registerReferralRoutes sets up referral routes; all of them act as the authenticated user
+func registerReferralRoutes(router *mux.Router, handler *handlers.ReferralHandler, authMiddleware *middleware.AuthMiddleware) {
+	referralRouter := router.NewRoute().Subrouter()
+	referralRouter.Use(authMiddleware.RequireAuth)
+
+	referralRouter.HandleFunc("/consultations/{id}/referrals", handler.CreateReferral).Methods("POST")
+	referralRouter.HandleFunc("/referrals/sent", handler.ListSentReferrals).Methods("GET")
+	referralRouter.HandleFunc("/referrals/inbox", handler.ListReferralInbox).Methods("GET")
+	referralRouter.HandleFunc("/referrals/mine", handler.ListMyReferrals).Methods("GET")
+	referralRouter.HandleFunc("/referrals/{id:[0-9]+}", handler.GetReferral).Methods("GET")
+	referralRouter.HandleFunc("/referrals/{id:[0-9]+}/accept", handler.AcceptReferral).Methods("POST")
+	referralRouter.HandleFunc("/referrals/{id:[0-9]+}/decline", handler.DeclineReferral).Methods("POST")
+	referralRouter.HandleFunc("/referrals/{id:[0-9]+}/cancel", handler.CancelReferral).Methods("POST")
+	referralRouter.HandleFunc("/referrals/{id:[0-9]+}/book", handler.BookReferral).Methods("POST")
+}
+
+// registerLabOrderRoutes sets up lab and imaging order routes. Result ingestion is public,
+// authorised by the access code on the requisition; the rest act as the authenticated user.
+func registerLabOrderRoutes(router *mux.Router, handler *handlers.LabOrderHandler, authMiddleware *middleware.AuthMiddleware) {
+	router.HandleFunc("/lab-orders/results/{number}", handler.IngestResults).Methods("POST")
+
+	labRouter := router.NewRoute().Subrouter()
+	labRouter.Use(authMiddleware.RequireAuth)
+
+	labRouter.HandleFunc("/consultations/{id}/lab-orders", handler.PlaceOrder).Methods("POST")
+	labRouter.HandleFunc("/consultations/{id}/lab-orders", handler.ListConsultationOrders).Methods("GET")
+	labRouter.HandleFunc("/patients/{patientId}/lab-orders", handler.ListPatientOrders).Methods("GET")
+	labRouter.HandleFunc("/lab-orders/{id:[0-9]+}", handler.GetOrder).Methods("GET")
+	labRouter.HandleFunc("/lab-orders/{id:[0-9]+}/requisition", handler.DownloadRequisition).Methods("GET")
+	labRouter.HandleFunc("/lab-orders/{id:[0-9]+}/cancel", handler.CancelOrder).Methods("POST")
+	labRouter.HandleFunc("/lab-orders/{id:[0-9]+}/results", handler.RecordResults).Methods("POST")
+	labRouter.HandleFunc("/lab-orders/{id:[0-9]+}/reports/{reportId:[0-9]+}", handler.DownloadReport).Methods("GET")
+}
+
+// registerDrugDataRoutes sets up the admin imports of the drug interaction and class datasets
+func registerDrugDataRoutes(router *mux.Router, handler *handlers.DrugDataHandler, authMiddleware *middleware.AuthMiddleware) {
+	drugDataRouter := router.PathPrefix("/drug-data").Subrouter()
+	drugDataRouter.Use(authMiddleware.RequireAuth)
+
+	drugDataRouter.HandleFunc("/interactions", handler.ImportInteractions).Methods("POST")
+	drugDataRouter.HandleFunc("/classes", handler.ImportClasses).Methods("POST")
+}
+
+// registerDiagnosisCodeRoutes sets up the ICD-10 catalogue routes; only the import needs a login
+func registerDiagnosisCodeRoutes(router *mux.Router, handler *handlers.DiagnosisCodeHandler, authMiddleware *middleware.AuthMiddleware) {
+	codeRouter := router.PathPrefix("/codes/icd10").Subrouter()
+
+	codeRouter.HandleFunc("", handler.SearchCodes).Methods("GET")
+	codeRouter.Handle("/import", authMiddleware.RequireAuth(http.HandlerFunc(handler.ImportCodes))).Methods("POST")
+	codeRouter.HandleFunc("/{code}", handler.GetCode).Methods("GET")
+}
+
+// registerFollowUpRoutes sets up follow-up routes for patients and their doctors
+func registerFollowUpRoutes(router *mux.Router, handler *handlers.FollowUpHandler, authMiddleware *middleware.AuthMiddleware) {
+	followUpRouter := router.NewRoute().Subrouter()
+	followUpRouter.Use(authMiddleware.RequireAuth)
+
+	followUpRouter.HandleFunc("/follow-ups/mine", handler.ListMyFollowUps).Methods("GET")
+	followUpRouter.HandleFunc("/follow-ups/{id:[0-9]+}", handler.GetFollowUp).Methods("GET")
+	followUpRouter.HandleFunc("/follow-ups/{id:[0-9]+}/slots", handler.GetAvailableSlots).Methods("GET")
+	followUpRouter.HandleFunc("/follow-ups/{id:[0-9]+}/book", handler.BookFollowUp).Methods("POST")
+	followUpRouter.HandleFunc("/follow-ups/{id:[0-9]+}/dismiss", handler.DismissFollowUp).Methods("POST")
+	followUpRouter.HandleFunc("/doctors/{doctorId}/follow-ups/overdue", handler.ListOverdue).Methods("GET")
+}
+
+// registerConsultationSummaryRoutes sets up the take-home consultation summary download
+func registerConsultationSummaryRoutes(router *mux.Router, handler *handlers.ConsultationSummaryHandler, authMiddleware *middleware.AuthMiddleware) {
+	router.Handle("/consultations/{id}/summary",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.DownloadSummary))).Methods("GET")
+}
+
+// registerChatTranscriptRoutes sets up the signed chat transcripts of consultations; the
+// doctor exports one, and its participants and admins read and verify it
+func registerChatTranscriptRoutes(router *mux.Router, handler *handlers.ChatTranscriptHandler, authMiddleware *middleware.AuthMiddleware) {
+	router.Handle("/consultations/{id}/chat/transcript",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.Export))).Methods("POST")
+	router.Handle("/consultations/{id}/chat/transcript",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.GetTranscript))).Methods("GET")
+	router.Handle("/consultations/{id}/chat/transcript/download",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.Download))).Methods("GET")
+	router.Handle("/consultations/{id}/chat/transcript/verify",
+		authMiddleware.RequireAuth(http.HandlerFunc(handler.Verify))).Methods("GET")
+}
@@ -22,7 +22,7 @@ type Config struct {
 func LoadConfig() (*Config, error) {
 	err := godotenv.Load()
 	if err != nil {
-		logrus.Warn("Error loading .env file: %v", err)
+		logrus.Warnf("Error loading .env file: %v", err)
 	}
 
 	logLevelStr := os.Getenv("LOG_LEVEL")
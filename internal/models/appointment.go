@@ -74,11 +74,21 @@ func (ct *CustomTime) Scan(value interface{}) error {
 		}
 		*ct = CustomTime(t)
 		return nil
+	case []byte:
+		// The MySQL driver returns TIME columns as raw bytes
+		t, err := time.Parse("15:04:05", string(v))
+		if err != nil {
+			return err
+		}
+		*ct = CustomTime(t)
+		return nil
 	}
 	return fmt.Errorf("cannot scan %T into CustomTime", value)
 }
 
-// Appointment represents an appointment entity
+// Appointment represents an appointment entity. AppointmentDate, StartTime and EndTime are
+// wall-clock values in TimeZone, the provider's zone at booking; StartsAt and EndsAt are the
+// same moments as absolute instants.
 type Appointment struct {
 	ID                 int        `json:"id"`
 	PatientID          int        `json:"patient_id"`
@@ -88,6 +98,9 @@ type Appointment struct {
 	AppointmentDate    time.Time  `json:"appointment_date"`
 	StartTime          CustomTime `json:"start_time"`
 	EndTime            CustomTime `json:"end_time"`
+	StartsAt           NullTime   `json:"starts_at"`
+	EndsAt             NullTime   `json:"ends_at"`
+	TimeZone           string     `json:"time_zone,omitempty"`
 	Status             string     `json:"status"`
 	CancellationReason *string    `json:"cancellation_reason,omitempty"`
 	ProviderType       string     `json:"provider_type"`
@@ -95,3 +108,11 @@ type Appointment struct {
 	CreatedAt          time.Time  `json:"created_at"`
 	UpdatedAt          time.Time  `json:"updated_at"`
 }
+
+// AppointmentPage is one page of an appointment listing. Total counts every match;
+// NextCursor is empty on the last page.
+type AppointmentPage struct {
+	Items      []*Appointment `json:"items"`
+	Total      int            `json:"total"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,39 @@
+// File: internal/models/availability_exception.go
+package models
+
+import "time"
+
+// Availability exception types
+const (
+	ExceptionTypeBlocked = "blocked" // provider is unavailable during the range (vacation, leave)
+	ExceptionTypeExtra   = "extra"   // provider is available outside their weekly schedule
+)
+
+// AvailabilityException is a date-specific override of a doctor's weekly availability
+type AvailabilityException struct {
+	ID            int       `json:"id" db:"id"`
+	DoctorID      int       `json:"doctor_id" db:"doctor_id"`
+	ExceptionType string    `json:"exception_type" db:"exception_type"`
+	StartAt       time.Time `json:"start_at" db:"start_at"`
+	EndAt         time.Time `json:"end_at" db:"end_at"`
+	Reason        string    `json:"reason" db:"reason"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// Holiday is a clinic-wide closed day that overrides every weekly schedule
+type Holiday struct {
+	ID          int       `json:"id" db:"id"`
+	HolidayDate time.Time `json:"holiday_date" db:"holiday_date"`
+	Name        string    `json:"name" db:"name"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// TimeSlot is a bookable slot produced by slot generation. Date, StartTime and EndTime are
+// wall-clock values in the provider's zone; StartsAt and EndsAt are the absolute instants.
+type TimeSlot struct {
+	Date      time.Time  `json:"date"`
+	StartTime CustomTime `json:"start_time"`
+	EndTime   CustomTime `json:"end_time"`
+	StartsAt  time.Time  `json:"starts_at"`
+	EndsAt    time.Time  `json:"ends_at"`
+}
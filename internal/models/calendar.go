@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+const (
+	CalendarItemAppointment   = "appointment"
+	CalendarItemHomeCareVisit = "home_care_visit"
+)
+
+// CalendarFeedToken is the secret that authorises a user's subscribable calendar feed
+type CalendarFeedToken struct {
+	UserID    int       `json:"user_id"`
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CalendarItem is an appointment or scheduled home care visit as it appears in a calendar.
+// Sequence grows with every update so calendar clients replace their stale copy.
+type CalendarItem struct {
+	Kind         string
+	ID           int
+	Sequence     int
+	Start        time.Time
+	End          time.Time
+	Status       string
+	PatientID    int
+	PatientName  string
+	ProviderID   int
+	ProviderName string
+	ProviderType string
+	Location     string
+	UpdatedAt    time.Time
+}
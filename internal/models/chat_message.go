@@ -12,4 +12,151 @@ type ChatMessage struct {
 	Message        string    `json:"message" db:"message"`
 	SentAt         time.Time `json:"sent_at" db:"sent_at"`
 	IsRead         bool      `json:"is_read" db:"is_read"`
-}
\ No newline at end of file
+	// Attachments are the files sent with the message; the text may be empty when there are any
+	Attachments []*ChatAttachment `json:"attachments,omitempty"`
+	EditedAt    NullTime          `json:"edited_at"`
+	// Deleted messages keep their content for the medical record; participants only see that
+	// the message was deleted
+	DeletedAt NullTime `json:"deleted_at"`
+	DeletedBy *int     `json:"deleted_by,omitempty"`
+	// ArchivedAt is set when the retention policy archives the chat, which makes it read-only
+	ArchivedAt NullTime `json:"archived_at"`
+}
+
+// ChatMessageEdit records a message's text before one of its edits
+type ChatMessageEdit struct {
+	ID              int       `json:"id"`
+	MessageID       int       `json:"message_id"`
+	PreviousMessage string    `json:"previous_message"`
+	EditedBy        int       `json:"edited_by"`
+	EditedAt        time.Time `json:"edited_at"`
+}
+
+// ChatMessageHistory is a message with its edits, oldest first
+type ChatMessageHistory struct {
+	Message *ChatMessage       `json:"message"`
+	Edits   []*ChatMessageEdit `json:"edits"`
+}
+
+// ChatRetentionPolicy limits how long after sending a message its sender can edit or delete
+// it, and archives the chats of consultations completed more than ArchiveAfterDays ago.
+// A window of zero disables editing or deleting; ArchiveAfterDays of zero keeps chats open.
+type ChatRetentionPolicy struct {
+	EditWindowMinutes   int       `json:"edit_window_minutes"`
+	DeleteWindowMinutes int       `json:"delete_window_minutes"`
+	ArchiveAfterDays    int       `json:"archive_after_days"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// ChatAttachment is an image or PDF sent in a consultation's chat. Files are stored privately
+// and only served to the consultation's participants.
+type ChatAttachment struct {
+	ID             int       `json:"id"`
+	MessageID      int       `json:"message_id"`
+	ConsultationID int       `json:"consultation_id"`
+	FileName       string    `json:"file_name"`
+	ContentType    string    `json:"content_type"`
+	Size           int64     `json:"size"`
+	FilePath       string    `json:"-"`
+	ThumbnailPath  string    `json:"-"`
+	HasThumbnail   bool      `json:"has_thumbnail"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Chat socket event and command types
+const (
+	ChatEventMessage = "message"
+	ChatEventRead    = "read"
+	ChatEventTyping  = "typing"
+	ChatEventEdited  = "edited"
+	ChatEventDeleted = "deleted"
+	ChatEventError   = "error"
+
+	ChatCommandSend   = "send"
+	ChatCommandRead   = "read"
+	ChatCommandTyping = "typing"
+	ChatCommandEdit   = "edit"
+	ChatCommandDelete = "delete"
+)
+
+// ChatEvent is pushed to the participants connected to a consultation's chat. Message events
+// carry the new message, edited and deleted events the message as it now reads, read events
+// the reader and the latest message they have read, and typing events whether the user is
+// typing.
+type ChatEvent struct {
+	Type           string       `json:"type"`
+	ConsultationID int          `json:"consultation_id"`
+	Message        *ChatMessage `json:"message,omitempty"`
+	UserID         int          `json:"user_id,omitempty"`
+	MessageID      int          `json:"message_id,omitempty"`
+	Typing         bool         `json:"typing,omitempty"`
+	// ClientID echoes the id the sender gave a sent message, so it can match its pending copy
+	ClientID string `json:"client_id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ChatCommand is sent by a participant over the chat socket
+type ChatCommand struct {
+	Type      string `json:"type"`
+	Message   string `json:"message,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	MessageID int    `json:"message_id,omitempty"`
+	Typing    bool   `json:"typing,omitempty"`
+}
+
+// ChatSearchFilter narrows a search of the caller's chat messages. Zero values do not filter;
+// To is exclusive.
+type ChatSearchFilter struct {
+	Query          string
+	UserID         int
+	ConsultationID int
+	SenderType     string
+	From           time.Time
+	To             time.Time
+	Limit          int
+	Offset         int
+	// ListPageSize is the page size the result positions are computed for
+	ListPageSize int
+}
+
+// ChatSearchResult is a message matching a search, with the fragment that matched and its
+// position in the consultation's message list, newest first
+type ChatSearchResult struct {
+	Message *ChatMessage `json:"message"`
+	// Fragment is an excerpt of the message; Highlights are the matches in it, as rune
+	// offsets into Fragment
+	Fragment   string      `json:"fragment"`
+	Highlights []TextRange `json:"highlights"`
+	// Position is the number of newer messages in the consultation; Page and PageSize locate
+	// the message in the consultation's paginated message list
+	Position int `json:"position"`
+	Page     int `json:"page"`
+	PageSize int `json:"page_size"`
+}
+
+// TextRange is the half-open range [Start, End) of rune offsets into a text
+type TextRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// ChatConversation is a consultation's chat as listed for one of its participants
+type ChatConversation struct {
+	ConsultationID     int             `json:"consultation_id"`
+	ConsultationStatus string          `json:"consultation_status"`
+	Participant        ChatParticipant `json:"participant"`
+	// LastMessage is nil while the chat is empty; deleted messages have no content
+	LastMessage *ChatMessage `json:"last_message"`
+	// UnreadCount counts the messages the other participant sent that the user has not read
+	UnreadCount int `json:"unread_count"`
+	// LastActivityAt is when the last message was sent, or when the consultation started
+	LastActivityAt time.Time `json:"last_activity_at"`
+}
+
+// ChatParticipant is the other side of a conversation. Only doctors have a profile picture.
+type ChatParticipant struct {
+	UserID            int    `json:"user_id"`
+	Name              string `json:"name"`
+	Role              string `json:"role"`
+	ProfilePictureURL string `json:"profile_picture_url,omitempty"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+const (
+	ChatTranscriptFormatPDF  = "pdf"
+	ChatTranscriptFormatJSON = "json"
+)
+
+// ChatTranscript is the chat of a completed consultation exported into its medical record.
+// It is signed by the consultation's doctor and never changes: the JSON document and its PDF
+// rendering are stored with their SHA-256 hashes, so later changes to either file can be
+// detected. Signature.ContentHash is the hash of the JSON document.
+type ChatTranscript struct {
+	ID             int               `json:"id"`
+	ConsultationID int               `json:"consultation_id"`
+	MessageCount   int               `json:"message_count"`
+	Signature      ClinicalSignature `json:"signature"`
+	PDFHash        string            `json:"pdf_hash"`
+	JSONPath       string            `json:"-"`
+	PDFPath        string            `json:"-"`
+}
+
+// ChatTranscriptDocument is the content of a transcript, stored as JSON. Deleted messages and
+// replaced texts are included, as the transcript is the clinical record of the chat.
+type ChatTranscriptDocument struct {
+	ConsultationID int                   `json:"consultation_id"`
+	Patient        ChatTranscriptParty   `json:"patient"`
+	Doctor         ChatTranscriptParty   `json:"doctor"`
+	StartedAt      NullTime              `json:"started_at"`
+	CompletedAt    NullTime              `json:"completed_at"`
+	SignedBy       int                   `json:"signed_by"`
+	SignerName     string                `json:"signer_name"`
+	SignedAt       time.Time             `json:"signed_at"`
+	Messages       []ChatTranscriptEntry `json:"messages"`
+}
+
+type ChatTranscriptParty struct {
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+// ChatTranscriptEntry is a message as it stood when the transcript was signed, with the
+// texts it replaced, oldest first
+type ChatTranscriptEntry struct {
+	MessageID   int                        `json:"message_id"`
+	SenderType  string                     `json:"sender_type"`
+	SenderID    int                        `json:"sender_id"`
+	SentAt      time.Time                  `json:"sent_at"`
+	Message     string                     `json:"message"`
+	EditedAt    NullTime                   `json:"edited_at"`
+	Edits       []*ChatMessageEdit         `json:"edits,omitempty"`
+	DeletedAt   NullTime                   `json:"deleted_at"`
+	DeletedBy   *int                       `json:"deleted_by,omitempty"`
+	Attachments []ChatTranscriptAttachment `json:"attachments,omitempty"`
+}
+
+// ChatTranscriptAttachment references a file sent in the chat by its SHA-256, which ties the
+// stored file to the transcript without copying it
+type ChatTranscriptAttachment struct {
+	AttachmentID int    `json:"attachment_id"`
+	FileName     string `json:"file_name"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	SHA256       string `json:"sha256"`
+}
+
+// ChatTranscriptVerification compares a transcript's stored files, and the attachments it
+// references, with their recorded hashes
+type ChatTranscriptVerification struct {
+	ConsultationID    int       `json:"consultation_id"`
+	ContentHash       string    `json:"content_hash"`
+	JSONIntact        bool      `json:"json_intact"`
+	PDFIntact         bool      `json:"pdf_intact"`
+	AttachmentsIntact bool      `json:"attachments_intact"`
+	Valid             bool      `json:"valid"`
+	VerifiedAt        time.Time `json:"verified_at"`
+}
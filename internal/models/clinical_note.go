@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+const (
+	ClinicalNoteDraft  = "draft"
+	ClinicalNoteSigned = "signed"
+	// ClinicalNoteAmended marks a signed note superseded by a signed amendment; its content is kept
+	ClinicalNoteAmended = "amended"
+
+	DiagnosisCodeSystemICD10 = "ICD-10"
+)
+
+// ClinicalNote is one version of a consultation's SOAP note. Drafts can be edited until the
+// doctor signs them; signed notes never change and are corrected by signing an amendment,
+// a new version that points at the note it amends.
+type ClinicalNote struct {
+	ID              int                `json:"id"`
+	ConsultationID  int                `json:"consultation_id"`
+	AuthorID        int                `json:"author_id"`
+	Version         int                `json:"version"`
+	Status          string             `json:"status"`
+	AmendsNoteID    *int               `json:"amends_note_id,omitempty"`
+	AmendmentReason string             `json:"amendment_reason,omitempty"`
+	Subjective      SubjectiveSection  `json:"subjective"`
+	Objective       ObjectiveSection   `json:"objective"`
+	Assessment      AssessmentSection  `json:"assessment"`
+	Plan            PlanSection        `json:"plan"`
+	Signature       *ClinicalSignature `json:"signature,omitempty"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+}
+
+// SubjectiveSection is what the patient reports
+type SubjectiveSection struct {
+	ChiefComplaint string `json:"chief_complaint"`
+	History        string `json:"history,omitempty"`
+	Symptoms       string `json:"symptoms,omitempty"`
+}
+
+// ObjectiveSection is what the doctor measures and observes
+type ObjectiveSection struct {
+	Vitals      Vitals `json:"vitals"`
+	Examination string `json:"examination,omitempty"`
+}
+
+// Vitals are optional; a nil field was not measured
+type Vitals struct {
+	TemperatureC     *float64 `json:"temperature_c,omitempty"`
+	HeartRate        *int     `json:"heart_rate,omitempty"`
+	RespiratoryRate  *int     `json:"respiratory_rate,omitempty"`
+	SystolicBP       *int     `json:"systolic_bp,omitempty"`
+	DiastolicBP      *int     `json:"diastolic_bp,omitempty"`
+	OxygenSaturation *int     `json:"oxygen_saturation,omitempty"`
+	WeightKg         *float64 `json:"weight_kg,omitempty"`
+	HeightCm         *float64 `json:"height_cm,omitempty"`
+}
+
+// AssessmentSection holds the coded diagnoses and the doctor's reasoning
+type AssessmentSection struct {
+	Diagnoses []CodedDiagnosis `json:"diagnoses"`
+	Summary   string           `json:"summary,omitempty"`
+}
+
+type CodedDiagnosis struct {
+	System      string `json:"system"`
+	Code        string `json:"code"`
+	Description string `json:"description,omitempty"`
+	Primary     bool   `json:"primary"`
+}
+
+// PlanSection is what happens next
+type PlanSection struct {
+	Treatment   string `json:"treatment,omitempty"`
+	Medications string `json:"medications,omitempty"`
+	FollowUp    string `json:"follow_up,omitempty"`
+}
+
+// ClinicalSignature records who signed a note and when. ContentHash is the SHA-256 of the
+// signed content, so later changes to a signed note can be detected.
+type ClinicalSignature struct {
+	SignedBy    int       `json:"signed_by"`
+	SignerName  string    `json:"signer_name"`
+	SignedAt    time.Time `json:"signed_at"`
+	ContentHash string    `json:"content_hash"`
+	IPAddress   string    `json:"ip_address,omitempty"`
+}
@@ -3,14 +3,18 @@ package models
 
 import "time"
 
+// Consultation is held for an appointment; consultations from before the link have no AppointmentID
 type Consultation struct {
-	ID          int      `json:"id" db:"id"`
-	PatientID   int      `json:"patient_id" db:"patient_id"`
-	DoctorID    int      `json:"doctor_id" db:"doctor_id"`
-	Status      string   `json:"status" db:"status"`
-	StartedAt   NullTime `json:"started_at" db:"started_at"`
-	CompletedAt NullTime `json:"completed_at" db:"completed_at"`
-	Fee         float64  `json:"fee" db:"fee"`
+	ID            int      `json:"id" db:"id"`
+	AppointmentID *int     `json:"appointment_id,omitempty" db:"appointment_id"`
+	PatientID     int      `json:"patient_id" db:"patient_id"`
+	DoctorID      int      `json:"doctor_id" db:"doctor_id"`
+	Status        string   `json:"status" db:"status"`
+	StartedAt     NullTime `json:"started_at" db:"started_at"`
+	CompletedAt   NullTime `json:"completed_at" db:"completed_at"`
+	Fee           float64  `json:"fee" db:"fee"`
+	// VideoDurationSeconds is the time both participants spent in the video session
+	VideoDurationSeconds int `json:"video_duration_seconds" db:"video_duration_seconds"`
 }
 
 // ConsultationFilter represents the filtering options for consultations
@@ -7,6 +7,8 @@ type ConsultationDetails struct {
 	RequestDetails  string  `json:"request_details" db:"request_details"`
 	Symptoms        string  `json:"symptoms" db:"symptoms"`
 	Diagnosis       string  `json:"diagnosis" db:"diagnosis"`
+	// DiagnosisCode is the ICD-10 code of the primary diagnosis
+	DiagnosisCode   string  `json:"diagnosis_code,omitempty" db:"diagnosis_code"`
 	Prescription    string  `json:"prescription" db:"prescription"`
 	Notes           string  `json:"notes" db:"notes"`
 }
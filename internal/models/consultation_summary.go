@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+const (
+	SummaryFormatPDF  = "pdf"
+	SummaryFormatHTML = "html"
+)
+
+// ConsultationSummary is a take-home summary document stored for a consultation, one per
+// language and format. ContentHash identifies what it was rendered from, so it is only
+// rendered again once the consultation's record changes.
+type ConsultationSummary struct {
+	ID             int       `json:"id"`
+	ConsultationID int       `json:"consultation_id"`
+	Language       string    `json:"language"`
+	Format         string    `json:"format"`
+	FilePath       string    `json:"-"`
+	ContentHash    string    `json:"-"`
+	GeneratedAt    time.Time `json:"generated_at"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// DiagnosisCode is an entry of the locally imported ICD-10 catalogue. Codes are stored with
+// their dot, e.g. "J06.9"; Category is the three-character block, e.g. "J06".
+type DiagnosisCode struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Warning severities, from informational to never-prescribe-together
+const (
+	SeverityMinor           = "minor"
+	SeverityModerate        = "moderate"
+	SeverityMajor           = "major"
+	SeverityContraindicated = "contraindicated"
+)
+
+const (
+	WarningInteraction      = "interaction"
+	WarningDuplicateTherapy = "duplicate_therapy"
+	WarningAllergy          = "allergy"
+)
+
+// DrugInteraction is one row of the interaction dataset. DrugA and DrugB are drug or drug class
+// names, so one row can cover a whole class.
+type DrugInteraction struct {
+	DrugA       string `json:"drug_a"`
+	DrugB       string `json:"drug_b"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+// DrugClass places a drug in a therapeutic class, used for duplicate therapy and class allergies
+type DrugClass struct {
+	DrugName  string `json:"drug_name"`
+	ClassName string `json:"class_name"`
+}
+
+// PrescriptionWarning is a safety finding on a prescription. Key identifies the finding so the
+// doctor can override it when resubmitting the prescription.
+type PrescriptionWarning struct {
+	Key         string           `json:"key"`
+	Type        string           `json:"type"`
+	Severity    string           `json:"severity"`
+	Drug        string           `json:"drug"`
+	Conflict    string           `json:"conflict"`
+	Description string           `json:"description"`
+	Override    *WarningOverride `json:"override,omitempty"`
+}
+
+// WarningOverride records that the prescriber went ahead despite a warning, and why
+type WarningOverride struct {
+	ID             int       `json:"id"`
+	PrescriptionID int       `json:"prescription_id"`
+	WarningKey     string    `json:"warning_key"`
+	WarningType    string    `json:"warning_type"`
+	Severity       string    `json:"severity"`
+	Description    string    `json:"description"`
+	Reason         string    `json:"reason"`
+	DoctorID       int       `json:"doctor_id"`
+	OverriddenAt   time.Time `json:"overridden_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+const (
+	FollowUpPending   = "pending"
+	FollowUpBooked    = "booked"
+	FollowUpDismissed = "dismissed"
+)
+
+// FollowUpPlan is what the doctor asks for when completing a consultation
+type FollowUpPlan struct {
+	IntervalDays int    `json:"follow_up_days"`
+	Note         string `json:"follow_up_note"`
+}
+
+// FollowUp recommends that the patient see the same doctor again by DueDate, a calendar date
+// in the doctor's time zone. It stays pending until booked through it or dismissed by the doctor.
+type FollowUp struct {
+	ID             int       `json:"id"`
+	ConsultationID int       `json:"consultation_id"`
+	PatientID      int       `json:"patient_id"`
+	DoctorID       int       `json:"doctor_id"`
+	PatientName    string    `json:"patient_name,omitempty"`
+	IntervalDays   int       `json:"interval_days"`
+	DueDate        time.Time `json:"due_date"`
+	Note           string    `json:"note,omitempty"`
+	Status         string    `json:"status"`
+	AppointmentID  *int      `json:"appointment_id,omitempty"`
+	RemindersSent  int       `json:"reminders_sent"`
+	LastRemindedAt NullTime  `json:"last_reminded_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
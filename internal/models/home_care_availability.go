@@ -0,0 +1,33 @@
+// File: internal/models/home_care_availability.go
+package models
+
+import "time"
+
+// HomeCareAvailability is a recurring weekly window in which a home care provider takes visits
+type HomeCareAvailability struct {
+	ID         int       `json:"id" db:"id"`
+	ProviderID int       `json:"provider_id" db:"provider_id"`
+	DayOfWeek  int       `json:"day_of_week" db:"day_of_week"`
+	StartTime  time.Time `json:"start_time" db:"start_time"`
+	EndTime    time.Time `json:"end_time" db:"end_time"`
+}
+
+// HomeCareAvailabilityException is a date-specific override of a provider's weekly availability
+type HomeCareAvailabilityException struct {
+	ID            int       `json:"id" db:"id"`
+	ProviderID    int       `json:"provider_id" db:"provider_id"`
+	ExceptionType string    `json:"exception_type" db:"exception_type"`
+	StartAt       time.Time `json:"start_at" db:"start_at"`
+	EndAt         time.Time `json:"end_at" db:"end_at"`
+	Reason        string    `json:"reason" db:"reason"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// HomeCareServiceArea limits where a provider travels to. The centre is the provider's
+// own Latitude/Longitude; RadiusKm is measured from there.
+type HomeCareServiceArea struct {
+	ProviderID          int       `json:"provider_id" db:"provider_id"`
+	RadiusKm            float64   `json:"radius_km" db:"radius_km"`
+	TravelBufferMinutes int       `json:"travel_buffer_minutes" db:"travel_buffer_minutes"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+const (
+	LabOrderOrdered   = "ordered"
+	LabOrderResulted  = "resulted"
+	LabOrderCancelled = "cancelled"
+)
+
+const (
+	LabOrderLab     = "lab"
+	LabOrderImaging = "imaging"
+)
+
+const (
+	LabPriorityRoutine = "routine"
+	LabPriorityUrgent  = "urgent"
+	LabPriorityStat    = "stat"
+)
+
+// Result flags, set by comparing a value with its reference range. A result without a range
+// has no flag.
+const (
+	ResultFlagNormal = "normal"
+	ResultFlagLow    = "low"
+	ResultFlagHigh   = "high"
+)
+
+// LabOrder is a laboratory or imaging order written during a consultation. Number and
+// AccessCode are printed on the requisition; the performing lab needs both to upload results.
+type LabOrder struct {
+	ID                 int            `json:"id"`
+	Number             string         `json:"number"`
+	AccessCode         string         `json:"access_code,omitempty"`
+	ConsultationID     int            `json:"consultation_id"`
+	DoctorID           int            `json:"doctor_id"`
+	PatientID          int            `json:"patient_id"`
+	Kind               string         `json:"kind"`
+	Priority           string         `json:"priority"`
+	ClinicalIndication string         `json:"clinical_indication"`
+	Notes              string         `json:"notes,omitempty"`
+	Status             string         `json:"status"`
+	Tests              []LabOrderTest `json:"tests"`
+	Results            []LabResult    `json:"results,omitempty"`
+	Reports            []LabReport    `json:"reports,omitempty"`
+	// AbnormalCount is the number of results flagged low or high
+	AbnormalCount int       `json:"abnormal_count"`
+	OrderedAt     time.Time `json:"ordered_at"`
+	ResultedAt    NullTime  `json:"resulted_at"`
+	CancelledAt   NullTime  `json:"cancelled_at"`
+	CancelReason  string    `json:"cancel_reason,omitempty"`
+}
+
+// LabOrderTest is one test or study on an order
+type LabOrderTest struct {
+	ID           int    `json:"id"`
+	OrderID      int    `json:"order_id"`
+	Code         string `json:"code,omitempty"`
+	Name         string `json:"name"`
+	Specimen     string `json:"specimen,omitempty"`
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// LabResult is one structured numeric result. TestID links it to the ordered test when the
+// lab names it; a panel may report more analytes than were ordered.
+type LabResult struct {
+	ID            int       `json:"id"`
+	OrderID       int       `json:"order_id"`
+	TestID        *int      `json:"test_id,omitempty"`
+	Analyte       string    `json:"analyte"`
+	Value         float64   `json:"value"`
+	Unit          string    `json:"unit"`
+	ReferenceLow  *float64  `json:"reference_low,omitempty"`
+	ReferenceHigh *float64  `json:"reference_high,omitempty"`
+	Flag          string    `json:"flag,omitempty"`
+	Comment       string    `json:"comment,omitempty"`
+	ResultedAt    time.Time `json:"resulted_at"`
+}
+
+// Abnormal reports whether the result falls outside its reference range
+func (r LabResult) Abnormal() bool {
+	return r.Flag == ResultFlagLow || r.Flag == ResultFlagHigh
+}
+
+// LabReport is an uploaded result document, stored as a private file
+type LabReport struct {
+	ID         int       `json:"id"`
+	OrderID    int       `json:"order_id"`
+	FileName   string    `json:"file_name"`
+	FilePath   string    `json:"-"`
+	UploadedBy string    `json:"uploaded_by"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
@@ -10,4 +10,10 @@ type MedicalHistory struct {
 	DiagnosisDate  time.Time `json:"diagnosis_date" db:"diagnosis_date"`
 	Treatment      string    `json:"treatment" db:"treatment"`
 	IsCurrent      bool      `json:"is_current" db:"is_current"`
+	// Allergen names the drug or drug class when the entry documents an allergy
+	Allergen string `json:"allergen,omitempty" db:"allergen"`
+	// ConditionCode is the ICD-10 code of the condition, when coded
+	ConditionCode string `json:"condition_code,omitempty" db:"condition_code"`
+	// ConsultationID is set on entries recorded from a consultation's diagnoses
+	ConsultationID *int `json:"consultation_id,omitempty" db:"consultation_id"`
 }
\ No newline at end of file
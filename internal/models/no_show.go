@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// NoShowPolicy controls when a scheduled appointment counts as a no-show and what follows.
+// A threshold of zero disables that consequence.
+type NoShowPolicy struct {
+	GracePeriodMinutes  int       `json:"grace_period_minutes"`
+	WarningThreshold    int       `json:"warning_threshold"`
+	PrepaymentThreshold int       `json:"prepayment_threshold"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// NoShowRecord is a missed appointment attributed to a patient
+type NoShowRecord struct {
+	ID              int       `json:"id"`
+	AppointmentID   int       `json:"appointment_id"`
+	PatientID       int       `json:"patient_id"`
+	ProviderID      int       `json:"provider_id"`
+	ProviderType    string    `json:"provider_type"`
+	AppointmentDate time.Time `json:"appointment_date"`
+	RecordedAt      time.Time `json:"recorded_at"`
+}
+
+// NoShowStats summarises attendance for a patient or a provider. Appointments counts
+// past appointments that were either completed or missed.
+type NoShowStats struct {
+	SubjectType        string  `json:"subject_type"`
+	SubjectID          int     `json:"subject_id"`
+	Appointments       int     `json:"appointments"`
+	NoShows            int     `json:"no_shows"`
+	Rate               float64 `json:"rate"`
+	PrepaymentRequired bool    `json:"prepayment_required,omitempty"`
+}
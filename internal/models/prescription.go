@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+const (
+	PrescriptionActive    = "active"
+	PrescriptionDispensed = "dispensed"
+	PrescriptionVoid      = "void"
+)
+
+// Prescription is an e-prescription written during a consultation. The prescriber and patient
+// are copied when it is issued, so the printed and verified prescription never changes.
+// VerificationCode is printed with the QR code; a pharmacy needs it to verify and dispense.
+// It stays active until every line's refills are used; DispensedAt and DispensedBy are those
+// of the latest fill.
+type Prescription struct {
+	ID                int                `json:"id"`
+	Number            string             `json:"number"`
+	VerificationCode  string             `json:"verification_code"`
+	ConsultationID    int                `json:"consultation_id"`
+	DoctorID          int                `json:"doctor_id"`
+	PatientID         int                `json:"patient_id"`
+	PrescriberName    string             `json:"prescriber_name"`
+	PrescriberLicense string             `json:"prescriber_license"`
+	PatientName       string             `json:"patient_name"`
+	Status            string             `json:"status"`
+	Notes             string             `json:"notes,omitempty"`
+	Items             []PrescriptionItem `json:"items"`
+	IssuedAt          time.Time          `json:"issued_at"`
+	DispensedAt       NullTime           `json:"dispensed_at"`
+	DispensedBy       string             `json:"dispensed_by,omitempty"`
+	FillsDispensed    int                `json:"fills_dispensed"`
+	Fills             []PrescriptionFill `json:"fills,omitempty"`
+	VoidedAt          NullTime           `json:"voided_at"`
+	VoidReason        string             `json:"void_reason,omitempty"`
+	// Warnings are the safety findings of the latest check; they are not stored
+	Warnings []PrescriptionWarning `json:"warnings,omitempty"`
+	// Overrides carry the doctor's reasons for going ahead despite warnings, keyed by warning key
+	Overrides []WarningOverride `json:"overrides,omitempty"`
+}
+
+// PrescriptionItem is one medication line
+type PrescriptionItem struct {
+	ID             int    `json:"id"`
+	PrescriptionID int    `json:"prescription_id"`
+	DrugName       string `json:"drug_name"`
+	Strength       string `json:"strength"`
+	Form           string `json:"form"`
+	Dose           string `json:"dose"`
+	Frequency      string `json:"frequency"`
+	DurationDays   int    `json:"duration_days"`
+	Quantity       int    `json:"quantity"`
+	Refills        int    `json:"refills"`
+	Instructions   string `json:"instructions,omitempty"`
+}
+
+// PrescriptionFill is one dispensing of a prescription. Fill 1 is the original supply and each
+// later fill uses a refill; a line is included while it has refills left.
+type PrescriptionFill struct {
+	ID             int       `json:"id"`
+	PrescriptionID int       `json:"prescription_id"`
+	FillNumber     int       `json:"fill_number"`
+	DispensedBy    string    `json:"dispensed_by"`
+	DispensedAt    time.Time `json:"dispensed_at"`
+}
+
+// PrescriptionVerification is what the public verification endpoint discloses: enough for a
+// pharmacy to match the paper against the record, with the patient reduced to initials.
+type PrescriptionVerification struct {
+	Number            string             `json:"number"`
+	Status            string             `json:"status"`
+	PrescriberName    string             `json:"prescriber_name"`
+	PrescriberLicense string             `json:"prescriber_license"`
+	PatientInitials   string             `json:"patient_initials"`
+	Items             []PrescriptionItem `json:"items"`
+	IssuedAt          time.Time          `json:"issued_at"`
+	DispensedAt       NullTime           `json:"dispensed_at"`
+	DispensedBy       string             `json:"dispensed_by,omitempty"`
+	FillsDispensed    int                `json:"fills_dispensed"`
+	FillsAllowed      int                `json:"fills_allowed"`
+	VoidedAt          NullTime           `json:"voided_at"`
+}
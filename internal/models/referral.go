@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+const (
+	ReferralPending   = "pending"
+	ReferralAccepted  = "accepted"
+	ReferralDeclined  = "declined"
+	ReferralBooked    = "booked"
+	ReferralCancelled = "cancelled"
+)
+
+const (
+	ReferralRoutine   = "routine"
+	ReferralUrgent    = "urgent"
+	ReferralEmergency = "emergency"
+)
+
+// Referral targets, matching Appointment.ProviderType
+const (
+	ReferralToDoctor   = "doctor"
+	ReferralToHomeCare = "home_care_provider"
+)
+
+// Referral sends a patient from a consultation to another doctor or to a home care provider.
+// It is addressed either to one receiver (RecipientID) or to everyone in a pool: the doctors of
+// TargetSpecialty or the home care providers of TargetServiceTypeID. The first receiver to
+// answer becomes ResponderID; once they accept, the patient is booked with them.
+type Referral struct {
+	ID                  int      `json:"id"`
+	ConsultationID      int      `json:"consultation_id"`
+	PatientID           int      `json:"patient_id"`
+	ReferringDoctorID   int      `json:"referring_doctor_id"`
+	TargetType          string   `json:"target_type"`
+	TargetSpecialty     string   `json:"target_specialty,omitempty"`
+	TargetServiceTypeID int      `json:"target_service_type_id,omitempty"`
+	RecipientID         *int     `json:"recipient_id,omitempty"`
+	Reason              string   `json:"reason"`
+	Urgency             string   `json:"urgency"`
+	ClinicalSummary     string   `json:"clinical_summary"`
+	Status              string   `json:"status"`
+	ResponderID         *int     `json:"responder_id,omitempty"`
+	ResponseNote        string   `json:"response_note,omitempty"`
+	RespondedAt         NullTime `json:"responded_at"`
+	AppointmentID       *int     `json:"appointment_id,omitempty"`
+	// BookingURL lists the accepting receiver's free slots; it is set once the referral is accepted
+	BookingURL string    `json:"booking_url,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ReferralInbox selects the referrals a receiver can see: those addressed to them, those they
+// answered and, while still pending, those sent to their pool
+type ReferralInbox struct {
+	UserID        int
+	TargetType    string
+	Specialty     string
+	ServiceTypeID int
+	Status        string
+}
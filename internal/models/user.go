@@ -11,6 +11,8 @@ type User struct {
 	PasswordHash string    `json:"-"`
 	Name         string    `json:"name"`
 	Role         string    `json:"role"`
+	TimeZone     string    `json:"time_zone"` // IANA zone name, e.g. "Asia/Riyadh"
+	Language     string    `json:"language"`  // preferred language for documents, "en" or "ar"
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -23,3 +25,8 @@ const (
 	RoleHomeCareProvider  Role = "home_care_provider"
 	RoleAdmin             Role = "admin"
 )
+
+const (
+	LanguageEnglish = "en"
+	LanguageArabic  = "ar"
+)
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	VideoSessionWaiting   = "waiting"
+	VideoSessionConnected = "connected"
+	VideoSessionEnded     = "ended"
+
+	// Signals relayed between the two participants
+	SignalOffer        = "offer"
+	SignalAnswer       = "answer"
+	SignalICECandidate = "ice_candidate"
+	// Signals between a participant and the server
+	SignalEnd   = "end"
+	SignalState = "state"
+	SignalError = "error"
+)
+
+// VideoSession is the video room of a consultation. DurationSeconds counts the time both
+// participants were connected, across reconnects.
+type VideoSession struct {
+	ID              int       `json:"id"`
+	ConsultationID  int       `json:"consultation_id"`
+	Status          string    `json:"status"`
+	ConnectedAt     NullTime  `json:"connected_at"`
+	EndedAt         NullTime  `json:"ended_at"`
+	DurationSeconds int       `json:"duration_seconds"`
+	CreatedAt       time.Time `json:"created_at"`
+	// Participants lists the roles currently in the room
+	Participants []string `json:"participants"`
+}
+
+// VideoJoinToken admits one participant to a consultation's video room
+type VideoJoinToken struct {
+	Token     string    `json:"token"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SignalMessage is a signaling message on the video WebSocket. Payload carries the SDP or
+// ICE candidate untouched; From is the sender's role on relayed messages.
+type SignalMessage struct {
+	Type    string          `json:"type"`
+	From    string          `json:"from,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Session *VideoSession   `json:"session,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+const (
+	WaitlistStatusWaiting   = "waiting"
+	WaitlistStatusOffered   = "offered"
+	WaitlistStatusBooked    = "booked"
+	WaitlistStatusCancelled = "cancelled"
+
+	OfferStatusPending  = "pending"
+	OfferStatusAccepted = "accepted"
+	OfferStatusDeclined = "declined"
+	OfferStatusExpired  = "expired"
+)
+
+// WaitlistEntry is a patient's request to be offered any freed slot with a provider between FromDate and ToDate
+type WaitlistEntry struct {
+	ID           int       `json:"id"`
+	PatientID    int       `json:"patient_id"`
+	ProviderID   int       `json:"provider_id"`
+	ProviderType string    `json:"provider_type"`
+	FromDate     time.Time `json:"from_date"`
+	ToDate       time.Time `json:"to_date"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// WaitlistOffer holds a freed slot for one waitlisted patient until ExpiresAt
+type WaitlistOffer struct {
+	ID              int        `json:"id"`
+	EntryID         int        `json:"entry_id"`
+	PatientID       int        `json:"patient_id"`
+	ProviderID      int        `json:"provider_id"`
+	ProviderType    string     `json:"provider_type"`
+	AppointmentDate time.Time  `json:"appointment_date"`
+	StartTime       CustomTime `json:"start_time"`
+	EndTime         CustomTime `json:"end_time"`
+	Status          string     `json:"status"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	AppointmentID   *int       `json:"appointment_id,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
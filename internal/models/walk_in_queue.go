@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+const (
+	QueueStatusWaiting = "waiting"
+	QueueStatusCalled  = "called"
+	QueueStatusServed  = "served"
+	QueueStatusLeft    = "left"
+
+	QueueSourceRemote    = "remote"
+	QueueSourceReception = "reception"
+)
+
+// QueueEntry is one walk-in patient in a doctor's virtual queue for a day
+type QueueEntry struct {
+	ID           int       `json:"id"`
+	DoctorID     int       `json:"doctor_id"`
+	PatientID    int       `json:"patient_id"`
+	QueueDate    time.Time `json:"queue_date"`
+	TicketNumber int       `json:"ticket_number"`
+	Source       string    `json:"source"`
+	Status       string    `json:"status"`
+	JoinedAt     time.Time `json:"joined_at"`
+	CalledAt     NullTime  `json:"called_at"`
+	FinishedAt   NullTime  `json:"finished_at"`
+	// Position counts waiting patients from 1, the next to be called; it is 0 once called
+	Position             int `json:"position"`
+	EstimatedWaitMinutes int `json:"estimated_wait_minutes"`
+}
+
+// QueueSnapshot is the live state of a doctor's queue, pushed to subscribers on every change
+type QueueSnapshot struct {
+	DoctorID                   int           `json:"doctor_id"`
+	QueueDate                  time.Time     `json:"queue_date"`
+	AverageConsultationMinutes float64       `json:"average_consultation_minutes"`
+	Serving                    *QueueEntry   `json:"serving"`
+	Waiting                    []*QueueEntry `json:"waiting"`
+}
@@ -0,0 +1,67 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory is a Broker within a single process, for single-instance deployments and
+// development
+type Memory struct {
+	mu     sync.RWMutex
+	nextID int
+	topics map[string]map[int]*memorySubscription
+}
+
+var _ Broker = (*Memory)(nil)
+
+type memorySubscription struct {
+	mu      sync.Mutex
+	handler func(payload []byte)
+}
+
+func NewMemory() *Memory {
+	return &Memory{topics: make(map[string]map[int]*memorySubscription)}
+}
+
+// Publish calls the topic's handlers before returning
+func (m *Memory) Publish(ctx context.Context, topic string, payload []byte) error {
+	m.mu.RLock()
+	subscriptions := make([]*memorySubscription, 0, len(m.topics[topic]))
+	for _, sub := range m.topics[topic] {
+		subscriptions = append(subscriptions, sub)
+	}
+	m.mu.RUnlock()
+
+	for _, sub := range subscriptions {
+		// Each handler gets its own copy, as it may hold on to the payload
+		data := append([]byte(nil), payload...)
+		sub.mu.Lock()
+		sub.handler(data)
+		sub.mu.Unlock()
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(topic string, handler func(payload []byte)) (func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	id := m.nextID
+	if m.topics[topic] == nil {
+		m.topics[topic] = make(map[int]*memorySubscription)
+	}
+	m.topics[topic][id] = &memorySubscription{handler: handler}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			delete(m.topics[topic], id)
+			if len(m.topics[topic]) == 0 {
+				delete(m.topics, topic)
+			}
+		})
+	}, nil
+}
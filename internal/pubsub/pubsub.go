@@ -0,0 +1,18 @@
+// Package pubsub carries events between server instances. Every event is published through a
+// Broker, even when its subscribers are connected to the same instance, so that with several
+// instances deployed a client sees the events produced on any of them.
+package pubsub
+
+import "context"
+
+// Broker publishes payloads to topics and delivers them to the subscribers of each topic.
+// Implementations backed by a shared message bus fan events out across instances; Memory
+// only reaches subscribers in this process.
+type Broker interface {
+	// Publish delivers payload to every current subscriber of topic
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe calls handler with each payload published to topic until the returned
+	// function is called. Handlers are called one at a time per subscription and must not
+	// block; slow consumers should buffer and drop.
+	Subscribe(topic string, handler func(payload []byte)) (unsubscribe func(), err error)
+}
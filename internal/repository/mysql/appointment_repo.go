@@ -38,7 +38,8 @@ func (r *AppointmentRepo) GetByProviderID(ctx context.Context, providerID int, p
             a.appointment_date, TIME_FORMAT(a.start_time, '%H:%i:%s') as start_time, 
             TIME_FORMAT(a.end_time, '%H:%i:%s') as end_time, 
             a.status, a.cancellation_reason, a.created_at, a.updated_at,
-            COALESCE(u.name, 'Unknown Patient') as patient_name
+            COALESCE(u.name, 'Unknown Patient') as patient_name,
+            a.starts_at, a.ends_at, a.time_zone
         FROM appointments a
         LEFT JOIN users u ON u.id = a.patient_id
         WHERE `
@@ -62,6 +63,45 @@ func (r *AppointmentRepo) GetByProviderID(ctx context.Context, providerID int, p
 	}
 	defer rows.Close()
 
+	return scanProviderAppointments(rows)
+}
+
+// GetByProviderAndDateRange retrieves a provider's appointments dated between startDate and endDate, inclusive
+func (r *AppointmentRepo) GetByProviderAndDateRange(ctx context.Context, providerID int, providerType string, startDate, endDate time.Time) ([]*models.Appointment, error) {
+	query := `
+        SELECT a.id, a.patient_id, a.provider_type, a.doctor_id, a.home_care_provider_id,
+            a.appointment_date, TIME_FORMAT(a.start_time, '%H:%i:%s') as start_time,
+            TIME_FORMAT(a.end_time, '%H:%i:%s') as end_time,
+            a.status, a.cancellation_reason, a.created_at, a.updated_at,
+            COALESCE(u.name, 'Unknown Patient') as patient_name,
+            a.starts_at, a.ends_at, a.time_zone
+        FROM appointments a
+        LEFT JOIN users u ON u.id = a.patient_id
+        WHERE `
+
+	if providerType == "doctor" {
+		query += "a.doctor_id = ?"
+	} else if providerType == "home_care_provider" {
+		query += "a.home_care_provider_id = ?"
+	} else {
+		return nil, fmt.Errorf("invalid provider type: %s", providerType)
+	}
+
+	query += " AND a.appointment_date BETWEEN ? AND ? ORDER BY a.appointment_date ASC, a.start_time ASC"
+
+	rows, err := r.db.QueryContext(ctx, query, providerID,
+		startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query appointments: %w", err)
+	}
+	defer rows.Close()
+
+	return scanProviderAppointments(rows)
+}
+
+// scanProviderAppointments scans rows selected with a formatted start/end time, a joined patient name
+// and the absolute starts_at, ends_at and time_zone columns
+func scanProviderAppointments(rows *sql.Rows) ([]*models.Appointment, error) {
 	var appointments []*models.Appointment
 	for rows.Next() {
 		var apt models.Appointment
@@ -83,6 +123,9 @@ func (r *AppointmentRepo) GetByProviderID(ctx context.Context, providerID int, p
 			&apt.CreatedAt,
 			&apt.UpdatedAt,
 			&patientName,
+			&apt.StartsAt,
+			&apt.EndsAt,
+			&apt.TimeZone,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan appointment: %w", err)
@@ -124,14 +167,14 @@ func (r *AppointmentRepo) GetByProviderID(ctx context.Context, providerID int, p
 func (r *AppointmentRepo) Create(ctx context.Context, appointment *models.Appointment) error {
 	query := `
 		INSERT INTO appointments (patient_id, provider_type, doctor_id, home_care_provider_id,
-			appointment_date, start_time, end_time, status)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			appointment_date, start_time, end_time, starts_at, ends_at, time_zone, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(NULLIF(?, ''), 'UTC'), ?)
 	`
 
 	result, err := r.db.ExecContext(ctx, query,
 		appointment.PatientID, appointment.ProviderType, appointment.DoctorID,
-		appointment.HomeCareProviderID, appointment.AppointmentDate, appointment.StartTime,
-		appointment.EndTime, appointment.Status)
+		appointment.HomeCareProviderID, appointment.AppointmentDate.Format("2006-01-02"), appointment.StartTime,
+		appointment.EndTime, appointment.StartsAt, appointment.EndsAt, appointment.TimeZone, appointment.Status)
 	if err != nil {
 		return err
 	}
@@ -152,7 +195,7 @@ func (r *AppointmentRepo) GetByID(ctx context.Context, id int) (*models.Appointm
             appointment_date, TIME_FORMAT(start_time, '%H:%i:%s') as start_time, 
             TIME_FORMAT(end_time, '%H:%i:%s') as end_time, 
             status, cancellation_reason,
-            created_at, updated_at
+            created_at, updated_at, starts_at, ends_at, time_zone
         FROM appointments
         WHERE id = ?
     `
@@ -173,6 +216,9 @@ func (r *AppointmentRepo) GetByID(ctx context.Context, id int) (*models.Appointm
 		&appointment.CancellationReason,
 		&appointment.CreatedAt,
 		&appointment.UpdatedAt,
+		&appointment.StartsAt,
+		&appointment.EndsAt,
+		&appointment.TimeZone,
 	)
 
 	if err != nil {
@@ -203,16 +249,17 @@ func (r *AppointmentRepo) Update(ctx context.Context, appointment *models.Appoin
 	query := `
 		UPDATE appointments
 		SET patient_id = ?, provider_type = ?, doctor_id = ?, home_care_provider_id = ?,
-			appointment_date = ?, start_time = ?, end_time = ?, status = ?,
-			cancellation_reason = ?, updated_at = ?
+			appointment_date = ?, start_time = ?, end_time = ?, starts_at = ?, ends_at = ?,
+			time_zone = COALESCE(NULLIF(?, ''), time_zone), status = ?,
+			cancellation_reason = ?, updated_at = ?, sequence = sequence + 1
 		WHERE id = ?
 	`
 
 	_, err := r.db.ExecContext(ctx, query,
 		appointment.PatientID, appointment.ProviderType, appointment.DoctorID,
-		appointment.HomeCareProviderID, appointment.AppointmentDate, appointment.StartTime,
-		appointment.EndTime, appointment.Status, appointment.CancellationReason,
-		time.Now(), appointment.ID)
+		appointment.HomeCareProviderID, appointment.AppointmentDate.Format("2006-01-02"), appointment.StartTime,
+		appointment.EndTime, appointment.StartsAt, appointment.EndsAt, appointment.TimeZone,
+		appointment.Status, appointment.CancellationReason, time.Now(), appointment.ID)
 
 	return err
 }
@@ -229,7 +276,7 @@ func (r *AppointmentRepo) GetByPatientID(ctx context.Context, patientID int, lim
 	query := `
         SELECT id, patient_id, provider_type, doctor_id, home_care_provider_id,
             appointment_date, start_time, end_time, status, cancellation_reason,
-            created_at, updated_at
+            created_at, updated_at, starts_at, ends_at, time_zone
         FROM appointments
         WHERE patient_id = ?
         ORDER BY appointment_date, start_time
@@ -244,7 +291,7 @@ func (r *AppointmentRepo) GetByDoctorID(ctx context.Context, doctorID int, limit
 	query := `
         SELECT id, patient_id, provider_type, doctor_id, home_care_provider_id,
             appointment_date, start_time, end_time, status, cancellation_reason,
-            created_at, updated_at
+            created_at, updated_at, starts_at, ends_at, time_zone
         FROM appointments
         WHERE doctor_id = ?
         ORDER BY appointment_date, start_time
@@ -259,7 +306,7 @@ func (r *AppointmentRepo) GetByHomeCareProviderID(ctx context.Context, providerI
 	query := `
         SELECT id, patient_id, provider_type, doctor_id, home_care_provider_id,
             appointment_date, start_time, end_time, status, cancellation_reason,
-            created_at, updated_at
+            created_at, updated_at, starts_at, ends_at, time_zone
         FROM appointments
         WHERE home_care_provider_id = ?
         ORDER BY appointment_date, start_time
@@ -300,6 +347,9 @@ func (r *AppointmentRepo) queryAppointments(ctx context.Context, query string, a
 			&cancellationReason,
 			&appointment.CreatedAt,
 			&appointment.UpdatedAt,
+			&appointment.StartsAt,
+			&appointment.EndsAt,
+			&appointment.TimeZone,
 		)
 		if err != nil {
 			return nil, err
@@ -329,39 +379,85 @@ func (r *AppointmentRepo) queryAppointments(ctx context.Context, query string, a
 	return appointments, nil
 }
 
-// List retrieves appointments with filtering, pagination
-func (r *AppointmentRepo) List(ctx context.Context, filter repository.AppointmentFilter, offset, limit int) ([]*models.Appointment, error) {
-	query := `
-        SELECT id, patient_id, provider_type, doctor_id, home_care_provider_id,
-            appointment_date, start_time, end_time, status, cancellation_reason,
-            created_at, updated_at
-        FROM appointments
+// appointmentSortColumns maps sort fields to the expressions they order by. Rows without
+// starts_at fall back to their wall-clock start, read as UTC like the service's cursors.
+var appointmentSortColumns = map[string]string{
+	repository.AppointmentSortStartsAt:  "COALESCE(a.starts_at, TIMESTAMP(a.appointment_date, a.start_time))",
+	repository.AppointmentSortCreatedAt: "a.created_at",
+	repository.AppointmentSortUpdatedAt: "a.updated_at",
+}
+
+// List retrieves appointments with filtering, sorting and keyset pagination
+func (r *AppointmentRepo) List(ctx context.Context, filter repository.AppointmentFilter, limit int) ([]*models.Appointment, int, error) {
+	sortColumn, ok := appointmentSortColumns[filter.SortBy]
+	if !ok {
+		sortColumn = appointmentSortColumns[repository.AppointmentSortStartsAt]
+	}
+
+	from := `
+        FROM appointments a
+        LEFT JOIN doctors d ON d.user_id = a.doctor_id
+        LEFT JOIN home_care_providers h ON h.user_id = a.home_care_provider_id
         WHERE 1=1
     `
 	args := []interface{}{}
 
 	// Add filter conditions
 	if filter.StartDate != nil {
-		query += " AND appointment_date >= ?"
-		args = append(args, filter.StartDate)
+		from += " AND a.appointment_date >= ?"
+		args = append(args, filter.StartDate.Format("2006-01-02"))
 	}
 	if filter.EndDate != nil {
-		query += " AND appointment_date <= ?"
-		args = append(args, filter.EndDate)
+		from += " AND a.appointment_date <= ?"
+		args = append(args, filter.EndDate.Format("2006-01-02"))
 	}
 	if filter.Status != "" {
-		query += " AND status = ?"
+		from += " AND a.status = ?"
 		args = append(args, filter.Status)
 	}
 	if filter.ProviderType != "" {
-		query += " AND provider_type = ?"
+		from += " AND a.provider_type = ?"
 		args = append(args, filter.ProviderType)
 	}
+	if filter.PatientID != 0 {
+		from += " AND a.patient_id = ?"
+		args = append(args, filter.PatientID)
+	}
+	if filter.ProviderID != 0 {
+		from += " AND (a.doctor_id = ? OR a.home_care_provider_id = ?)"
+		args = append(args, filter.ProviderID, filter.ProviderID)
+	}
+	if filter.ServiceTypeID != 0 {
+		from += " AND COALESCE(d.service_type_id, h.service_type_id) = ?"
+		args = append(args, filter.ServiceTypeID)
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) "+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
 
-	query += " ORDER BY appointment_date, start_time LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.After != nil {
+		from += fmt.Sprintf(" AND (%[1]s %[2]s ? OR (%[1]s = ? AND a.id %[2]s ?))", sortColumn, comparison)
+		args = append(args, filter.After.Value, filter.After.Value, filter.After.ID)
+	}
 
-	return r.queryAppointments(ctx, query, args...)
+	query := `
+        SELECT a.id, a.patient_id, a.provider_type, a.doctor_id, a.home_care_provider_id,
+            a.appointment_date, a.start_time, a.end_time, a.status, a.cancellation_reason,
+            a.created_at, a.updated_at, a.starts_at, a.ends_at, a.time_zone
+    ` + from + fmt.Sprintf(" ORDER BY %[1]s %[2]s, a.id %[2]s LIMIT ?", sortColumn, direction)
+	args = append(args, limit)
+
+	appointments, err := r.queryAppointments(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return appointments, total, nil
 }
 
 // NewAppointmentRepository creates a new instance of AppointmentRepo
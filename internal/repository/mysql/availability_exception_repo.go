@@ -0,0 +1,107 @@
+// File: internal/repository/mysql/availability_exception_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"shifa/internal/models"
+)
+
+// AvailabilityExceptionRepo represents the MySQL repository for doctor availability exceptions
+type AvailabilityExceptionRepo struct {
+	db *sql.DB
+}
+
+// NewAvailabilityExceptionRepo creates a new AvailabilityExceptionRepo instance
+func NewAvailabilityExceptionRepo(db *sql.DB) *AvailabilityExceptionRepo {
+	return &AvailabilityExceptionRepo{db: db}
+}
+
+// Create inserts a new availability exception into the database
+func (r *AvailabilityExceptionRepo) Create(ctx context.Context, exception *models.AvailabilityException) error {
+	query := `
+		INSERT INTO doctor_availability_exceptions (doctor_id, exception_type, start_at, end_at, reason)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		exception.DoctorID, exception.ExceptionType, exception.StartAt, exception.EndAt, exception.Reason)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	exception.ID = int(id)
+	return nil
+}
+
+// GetByID retrieves an availability exception by its ID
+func (r *AvailabilityExceptionRepo) GetByID(ctx context.Context, id int) (*models.AvailabilityException, error) {
+	query := `
+		SELECT id, doctor_id, exception_type, start_at, end_at, COALESCE(reason, ''), created_at
+		FROM doctor_availability_exceptions
+		WHERE id = ?
+	`
+
+	var exception models.AvailabilityException
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&exception.ID, &exception.DoctorID, &exception.ExceptionType,
+		&exception.StartAt, &exception.EndAt, &exception.Reason, &exception.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("availability exception not found")
+		}
+		return nil, err
+	}
+
+	return &exception, nil
+}
+
+// Delete removes an availability exception from the database
+func (r *AvailabilityExceptionRepo) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM doctor_availability_exceptions WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// ListByDoctorID retrieves a doctor's exceptions overlapping the [from, to) range
+func (r *AvailabilityExceptionRepo) ListByDoctorID(ctx context.Context, doctorID int, from, to time.Time) ([]*models.AvailabilityException, error) {
+	query := `
+		SELECT id, doctor_id, exception_type, start_at, end_at, COALESCE(reason, ''), created_at
+		FROM doctor_availability_exceptions
+		WHERE doctor_id = ? AND start_at < ? AND end_at > ?
+		ORDER BY start_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, doctorID, to, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exceptions []*models.AvailabilityException
+	for rows.Next() {
+		var exception models.AvailabilityException
+		err := rows.Scan(
+			&exception.ID, &exception.DoctorID, &exception.ExceptionType,
+			&exception.StartAt, &exception.EndAt, &exception.Reason, &exception.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		exceptions = append(exceptions, &exception)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return exceptions, nil
+}
@@ -0,0 +1,214 @@
+// File: internal/repository/mysql/calendar_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"shifa/internal/models"
+)
+
+const calendarAppointmentSelect = `
+		SELECT a.id, a.sequence, a.appointment_date,
+			TIME_FORMAT(a.start_time, '%H:%i:%s'), TIME_FORMAT(a.end_time, '%H:%i:%s'),
+			a.status, a.patient_id, COALESCE(pu.name, ''), a.provider_type,
+			COALESCE(a.doctor_id, a.home_care_provider_id), COALESCE(du.name, ''), a.updated_at,
+			a.starts_at, a.ends_at, a.time_zone
+		FROM appointments a
+		LEFT JOIN users pu ON pu.id = a.patient_id
+		LEFT JOIN users du ON du.id = COALESCE(a.doctor_id, a.home_care_provider_id)
+`
+
+// CalendarRepo represents the MySQL repository for calendar feeds
+type CalendarRepo struct {
+	db *sql.DB
+}
+
+// NewCalendarRepo creates a new CalendarRepo instance
+func NewCalendarRepo(db *sql.DB) *CalendarRepo {
+	return &CalendarRepo{db: db}
+}
+
+// GetToken retrieves the user's feed token, or nil when none exists
+func (r *CalendarRepo) GetToken(ctx context.Context, userID int) (*models.CalendarFeedToken, error) {
+	query := `SELECT user_id, token, created_at FROM calendar_feed_tokens WHERE user_id = ?`
+
+	var token models.CalendarFeedToken
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&token.UserID, &token.Token, &token.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetUserIDByToken resolves a feed token to its owner
+func (r *CalendarRepo) GetUserIDByToken(ctx context.Context, token string) (int, error) {
+	query := `SELECT user_id FROM calendar_feed_tokens WHERE token = ?`
+
+	var userID int
+	err := r.db.QueryRowContext(ctx, query, token).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errors.New("calendar feed not found")
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+// SaveToken stores the user's token, replacing any previous one
+func (r *CalendarRepo) SaveToken(ctx context.Context, token *models.CalendarFeedToken) error {
+	query := `
+		INSERT INTO calendar_feed_tokens (user_id, token)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE token = VALUES(token), created_at = CURRENT_TIMESTAMP
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, token.UserID, token.Token); err != nil {
+		return err
+	}
+	token.CreatedAt = time.Now()
+	return nil
+}
+
+// DeleteToken revokes the user's feed token
+func (r *CalendarRepo) DeleteToken(ctx context.Context, userID int) error {
+	query := `DELETE FROM calendar_feed_tokens WHERE user_id = ?`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
+// GetAppointmentItem retrieves one appointment in calendar form
+func (r *CalendarRepo) GetAppointmentItem(ctx context.Context, appointmentID int) (*models.CalendarItem, error) {
+	query := calendarAppointmentSelect + ` WHERE a.id = ?`
+
+	item, err := scanCalendarAppointment(r.db.QueryRowContext(ctx, query, appointmentID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("appointment not found")
+		}
+		return nil, err
+	}
+	return item, nil
+}
+
+// ListItemsForUser retrieves the user's appointments dated from the day of from onward,
+// followed by their home care visits scheduled from from onward
+func (r *CalendarRepo) ListItemsForUser(ctx context.Context, userID int, from time.Time) ([]*models.CalendarItem, error) {
+	query := calendarAppointmentSelect + `
+		WHERE (a.patient_id = ? OR a.doctor_id = ? OR a.home_care_provider_id = ?)
+		AND a.appointment_date >= ?
+		ORDER BY COALESCE(a.starts_at, TIMESTAMP(a.appointment_date, a.start_time))
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, userID, userID, from.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calendar appointments: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.CalendarItem
+	for rows.Next() {
+		item, err := scanCalendarAppointment(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	visits, err := r.listVisitsForUser(ctx, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	return append(items, visits...), nil
+}
+
+func (r *CalendarRepo) listVisitsForUser(ctx context.Context, userID int, from time.Time) ([]*models.CalendarItem, error) {
+	query := `
+		SELECT v.id, v.sequence, v.scheduled_start, v.duration_hours, v.status,
+			v.patient_id, COALESCE(pu.name, ''), v.provider_id, COALESCE(hu.name, ''), v.address
+		FROM home_care_visits v
+		LEFT JOIN users pu ON pu.id = v.patient_id
+		LEFT JOIN users hu ON hu.id = v.provider_id
+		WHERE (v.patient_id = ? OR v.provider_id = ?)
+		AND v.scheduled_start >= ?
+		ORDER BY v.scheduled_start
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, userID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calendar visits: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*models.CalendarItem
+	for rows.Next() {
+		item := models.CalendarItem{Kind: models.CalendarItemHomeCareVisit, ProviderType: "home_care_provider"}
+		var durationHours float64
+		err := rows.Scan(
+			&item.ID, &item.Sequence, &item.Start, &durationHours, &item.Status,
+			&item.PatientID, &item.PatientName, &item.ProviderID, &item.ProviderName, &item.Location,
+		)
+		if err != nil {
+			return nil, err
+		}
+		item.End = item.Start.Add(time.Duration(durationHours * float64(time.Hour)))
+		items = append(items, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func scanCalendarAppointment(row rowScanner) (*models.CalendarItem, error) {
+	item := models.CalendarItem{Kind: models.CalendarItemAppointment}
+	var date time.Time
+	var startStr, endStr string
+	var providerID sql.NullInt64
+	var startsAt, endsAt sql.NullTime
+	var timeZone string
+
+	err := row.Scan(
+		&item.ID, &item.Sequence, &date, &startStr, &endStr, &item.Status,
+		&item.PatientID, &item.PatientName, &item.ProviderType, &providerID, &item.ProviderName, &item.UpdatedAt,
+		&startsAt, &endsAt, &timeZone,
+	)
+	if err != nil {
+		return nil, err
+	}
+	item.ProviderID = int(providerID.Int64)
+
+	if startsAt.Valid && endsAt.Valid {
+		item.Start, item.End = startsAt.Time, endsAt.Time
+		return &item, nil
+	}
+
+	// Rows booked before instants were stored carry wall-clock times in their zone
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	start, err := time.Parse("15:04:05", startStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse start_time: %w", err)
+	}
+	end, err := time.Parse("15:04:05", endStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse end_time: %w", err)
+	}
+	item.Start = time.Date(date.Year(), date.Month(), date.Day(), start.Hour(), start.Minute(), start.Second(), 0, loc)
+	item.End = time.Date(date.Year(), date.Month(), date.Day(), end.Hour(), end.Minute(), end.Second(), 0, loc)
+	return &item, nil
+}
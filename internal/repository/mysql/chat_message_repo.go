@@ -5,10 +5,21 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
 
+	"shifa/internal/models"
 	"shifa/internal/repository"
 )
 
+const chatMessageColumns = `id, consultation_id, sender_type, sender_id, message, sent_at, is_read,
+	edited_at, deleted_at, deleted_by, archived_at`
+
+const chatAttachmentColumns = `id, message_id, consultation_id, file_name, content_type, size,
+	file_path, thumbnail_path, created_at`
+
 type mysqlChatMessageRepo struct {
 	db *sql.DB
 }
@@ -17,52 +28,487 @@ func NewMySQLChatMessageRepo(db *sql.DB) repository.ChatMessageRepository {
 	return &mysqlChatMessageRepo{db: db}
 }
 
-func (r *mysqlChatMessageRepo) Create(ctx context.Context, message *repository.ChatMessage) error {
+func (r *mysqlChatMessageRepo) Create(ctx context.Context, message *models.ChatMessage) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
 	query := `INSERT INTO chat_messages (consultation_id, sender_type, sender_id, message, sent_at, is_read)
 			  VALUES (?, ?, ?, ?, ?, ?)`
 	
-	_, err := r.db.ExecContext(ctx, query, message.ConsultationID, message.SenderType, message.SenderID,
+	result, err := tx.ExecContext(ctx, query, message.ConsultationID, message.SenderType, message.SenderID,
 		message.Message, message.SentAt, message.IsRead)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range message.Attachments {
+		result, err = tx.ExecContext(ctx, `
+			INSERT INTO chat_attachments (message_id, consultation_id, file_name, content_type, size,
+				file_path, thumbnail_path, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, id, message.ConsultationID, attachment.FileName, attachment.ContentType, attachment.Size,
+			attachment.FilePath, sql.NullString{String: attachment.ThumbnailPath, Valid: attachment.ThumbnailPath != ""}, message.SentAt)
+		if err != nil {
+			return err
+		}
+		attachmentID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		attachment.ID = int(attachmentID)
+		attachment.MessageID = int(id)
+		attachment.ConsultationID = message.ConsultationID
+		attachment.CreatedAt = message.SentAt
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	message.ID = int(id)
+	return nil
+}
+
+func (r *mysqlChatMessageRepo) GetByID(ctx context.Context, id int) (*models.ChatMessage, error) {
+	query := `SELECT ` + chatMessageColumns + ` FROM chat_messages WHERE id = ?`
+
+	message, err := scanChatMessage(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("chat message not found")
+		}
+		return nil, err
+	}
+	if err := r.loadAttachments(ctx, []*models.ChatMessage{message}); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (r *mysqlChatMessageRepo) GetAttachment(ctx context.Context, id int) (*models.ChatAttachment, error) {
+	query := `SELECT ` + chatAttachmentColumns + ` FROM chat_attachments WHERE id = ?`
+
+	attachment, err := scanChatAttachment(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("chat attachment not found")
+		}
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func (r *mysqlChatMessageRepo) GetByConsultationID(ctx context.Context, consultationID int, limit, offset int) ([]*models.ChatMessage, error) {
+	// id breaks ties between messages sent in the same second, so pages and search positions agree
+	query := `SELECT ` + chatMessageColumns + `
+			  FROM chat_messages WHERE consultation_id = ? ORDER BY sent_at DESC, id DESC LIMIT ? OFFSET ?`
+	
+	return r.list(ctx, query, consultationID, limit, offset)
+}
+
+func (r *mysqlChatMessageRepo) ListSince(ctx context.Context, consultationID, afterID, limit int) ([]*models.ChatMessage, error) {
+	query := `SELECT ` + chatMessageColumns + `
+			  FROM chat_messages WHERE consultation_id = ? AND id > ? ORDER BY id LIMIT ?`
+
+	return r.list(ctx, query, consultationID, afterID, limit)
+}
+
+func (r *mysqlChatMessageRepo) MarkAsRead(ctx context.Context, messageID int) error {
+	query := `UPDATE chat_messages SET is_read = true WHERE id = ?`
+	
+	_, err := r.db.ExecContext(ctx, query, messageID)
 	return err
 }
 
-func (r *mysqlChatMessageRepo) GetByConsultationID(ctx context.Context, consultationID int, limit, offset int) ([]*repository.ChatMessage, error) {
-	query := `SELECT id, consultation_id, sender_type, sender_id, message, sent_at, is_read
-			  FROM chat_messages WHERE consultation_id = ? ORDER BY sent_at DESC LIMIT ? OFFSET ?`
+func (r *mysqlChatMessageRepo) MarkReadUpTo(ctx context.Context, consultationID, readerID, upToID int) (int, error) {
+	query := `UPDATE chat_messages SET is_read = true
+			  WHERE consultation_id = ? AND sender_id <> ? AND id <= ? AND is_read = false`
+
+	result, err := r.db.ExecContext(ctx, query, consultationID, readerID, upToID)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+func (r *mysqlChatMessageRepo) GetUnreadCount(ctx context.Context, userID int) (int, error) {
+	query := `SELECT COUNT(*) FROM chat_messages m
+			  JOIN consultations c ON c.id = m.consultation_id
+			  WHERE (c.doctor_id = ? OR c.patient_id = ?)
+			    AND m.sender_id != ? AND m.is_read = false AND m.deleted_at IS NULL`
 	
-	rows, err := r.db.QueryContext(ctx, query, consultationID, limit, offset)
+	var count int
+	err := r.db.QueryRowContext(ctx, query, userID, userID, userID).Scan(&count)
+	return count, err
+}
+
+func (r *mysqlChatMessageRepo) ListConversations(ctx context.Context, userID, limit, offset int) ([]*models.ChatConversation, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT c.id, c.status, o.id, o.name, o.role, COALESCE(d.profile_picture_url, ''),
+			lm.id,
+			(SELECT COUNT(*) FROM chat_messages u
+			 WHERE u.consultation_id = c.id AND u.sender_id <> ? AND u.is_read = false AND u.deleted_at IS NULL),
+			COALESCE(lm.sent_at, c.started_at, c.created_at) AS last_activity
+		FROM consultations c
+		JOIN users o ON o.id = IF(c.doctor_id = ?, c.patient_id, c.doctor_id)
+		LEFT JOIN doctors d ON d.user_id = o.id
+		LEFT JOIN chat_messages lm ON lm.id = (
+			SELECT MAX(id) FROM chat_messages WHERE consultation_id = c.id
+		)
+		WHERE c.doctor_id = ? OR c.patient_id = ?
+		ORDER BY last_activity DESC, c.id DESC
+		LIMIT ? OFFSET ?
+	`, userID, userID, userID, userID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	
-	var messages []*repository.ChatMessage
+
+	conversations := []*models.ChatConversation{}
+	lastIDs := map[int]*models.ChatConversation{}
 	for rows.Next() {
-		var message repository.ChatMessage
+		var conversation models.ChatConversation
+		var lastID sql.NullInt64
+		err := rows.Scan(
+			&conversation.ConsultationID, &conversation.ConsultationStatus,
+			&conversation.Participant.UserID, &conversation.Participant.Name, &conversation.Participant.Role,
+			&conversation.Participant.ProfilePictureURL, &lastID, &conversation.UnreadCount, &conversation.LastActivityAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if lastID.Valid {
+			lastIDs[int(lastID.Int64)] = &conversation
+		}
+		conversations = append(conversations, &conversation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(lastIDs) == 0 {
+		return conversations, nil
+	}
+
+	ids := make([]string, 0, len(lastIDs))
+	for id := range lastIDs {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	// The IDs are integers formatted here, so they are safe to inline
+	messages, err := r.list(ctx, `SELECT `+chatMessageColumns+` FROM chat_messages WHERE id IN (`+strings.Join(ids, ", ")+`)`)
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range messages {
+		lastIDs[message.ID].LastMessage = message
+	}
+	return conversations, nil
+}
+
+func (r *mysqlChatMessageRepo) Edit(ctx context.Context, edit *models.ChatMessageEdit, newMessage string) (updated bool, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil || !updated {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE chat_messages SET message = ?, edited_at = ?
+		WHERE id = ? AND message = ? AND deleted_at IS NULL AND archived_at IS NULL
+	`, newMessage, edit.EditedAt, edit.MessageID, edit.PreviousMessage)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	result, err = tx.ExecContext(ctx, `
+		INSERT INTO chat_message_edits (message_id, previous_message, edited_by, edited_at)
+		VALUES (?, ?, ?, ?)
+	`, edit.MessageID, edit.PreviousMessage, edit.EditedBy, edit.EditedAt)
+	if err != nil {
+		return false, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+	edit.ID = int(id)
+	return true, nil
+}
+
+func (r *mysqlChatMessageRepo) ListEdits(ctx context.Context, messageID int) ([]*models.ChatMessageEdit, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, message_id, previous_message, edited_by, edited_at
+		FROM chat_message_edits
+		WHERE message_id = ?
+		ORDER BY id
+	`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []*models.ChatMessageEdit{}
+	for rows.Next() {
+		var edit models.ChatMessageEdit
+		if err := rows.Scan(&edit.ID, &edit.MessageID, &edit.PreviousMessage, &edit.EditedBy, &edit.EditedAt); err != nil {
+			return nil, err
+		}
+		edits = append(edits, &edit)
+	}
+	return edits, rows.Err()
+}
+
+func (r *mysqlChatMessageRepo) SoftDelete(ctx context.Context, messageID, deletedBy int, at time.Time) (bool, error) {
+	return r.update(ctx, `
+		UPDATE chat_messages SET deleted_at = ?, deleted_by = ?
+		WHERE id = ? AND deleted_at IS NULL AND archived_at IS NULL
+	`, at, deletedBy, messageID)
+}
+
+func (r *mysqlChatMessageRepo) ArchiveCompletedBefore(ctx context.Context, cutoff, at time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE chat_messages m
+		JOIN consultations c ON c.id = m.consultation_id
+		SET m.archived_at = ?
+		WHERE m.archived_at IS NULL AND c.status = 'completed' AND c.completed_at < ?
+	`, at, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+func (r *mysqlChatMessageRepo) GetRetentionPolicy(ctx context.Context) (*models.ChatRetentionPolicy, error) {
+	query := `
+		SELECT edit_window_minutes, delete_window_minutes, archive_after_days, updated_at
+		FROM chat_retention_policy
+		WHERE id = 1
+	`
+
+	var policy models.ChatRetentionPolicy
+	err := r.db.QueryRowContext(ctx, query).Scan(
+		&policy.EditWindowMinutes, &policy.DeleteWindowMinutes, &policy.ArchiveAfterDays, &policy.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *mysqlChatMessageRepo) SaveRetentionPolicy(ctx context.Context, policy *models.ChatRetentionPolicy) error {
+	query := `
+		INSERT INTO chat_retention_policy (id, edit_window_minutes, delete_window_minutes, archive_after_days)
+		VALUES (1, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			edit_window_minutes = VALUES(edit_window_minutes),
+			delete_window_minutes = VALUES(delete_window_minutes),
+			archive_after_days = VALUES(archive_after_days)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, policy.EditWindowMinutes, policy.DeleteWindowMinutes, policy.ArchiveAfterDays)
+	if err != nil {
+		return err
+	}
+	policy.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *mysqlChatMessageRepo) Search(ctx context.Context, booleanQuery string, filter models.ChatSearchFilter) ([]*models.ChatSearchResult, error) {
+	// Position counts the newer messages in the same order the consultation's list uses
+	query := `
+		SELECT m.id, m.consultation_id, m.sender_type, m.sender_id, m.message, m.sent_at, m.is_read,
+			m.edited_at, m.deleted_at, m.deleted_by, m.archived_at,
+			(SELECT COUNT(*) FROM chat_messages n
+			 WHERE n.consultation_id = m.consultation_id
+			   AND (n.sent_at > m.sent_at OR (n.sent_at = m.sent_at AND n.id > m.id))) AS position
+		FROM chat_messages m
+		JOIN consultations c ON c.id = m.consultation_id
+		WHERE MATCH(m.message) AGAINST (? IN BOOLEAN MODE)
+		  AND (c.doctor_id = ? OR c.patient_id = ?)
+		  AND m.deleted_at IS NULL`
+	args := []interface{}{booleanQuery, filter.UserID, filter.UserID}
+
+	if filter.ConsultationID != 0 {
+		query += " AND m.consultation_id = ?"
+		args = append(args, filter.ConsultationID)
+	}
+	if filter.SenderType != "" {
+		query += " AND m.sender_type = ?"
+		args = append(args, filter.SenderType)
+	}
+	if !filter.From.IsZero() {
+		query += " AND m.sent_at >= ?"
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		query += " AND m.sent_at < ?"
+		args = append(args, filter.To)
+	}
+	query += " ORDER BY MATCH(m.message) AGAINST (? IN BOOLEAN MODE) DESC, m.sent_at DESC LIMIT ? OFFSET ?"
+	args = append(args, booleanQuery, filter.Limit, filter.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*models.ChatSearchResult{}
+	for rows.Next() {
+		var result models.ChatSearchResult
+		var message models.ChatMessage
+		var deletedBy sql.NullInt64
 		err := rows.Scan(
 			&message.ID, &message.ConsultationID, &message.SenderType, &message.SenderID,
 			&message.Message, &message.SentAt, &message.IsRead,
+			&message.EditedAt, &message.DeletedAt, &deletedBy, &message.ArchivedAt,
+			&result.Position,
 		)
 		if err != nil {
 			return nil, err
 		}
-		messages = append(messages, &message)
+		result.Message = &message
+		results = append(results, &result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	messages := make([]*models.ChatMessage, len(results))
+	for i, result := range results {
+		messages[i] = result.Message
+	}
+	if err := r.loadAttachments(ctx, messages); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// update runs a conditional update, reporting whether a row matched
+func (r *mysqlChatMessageRepo) update(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (r *mysqlChatMessageRepo) list(ctx context.Context, query string, args ...interface{}) ([]*models.ChatMessage, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	messages := []*models.ChatMessage{}
+	for rows.Next() {
+		message, err := scanChatMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 	
+	if err := r.loadAttachments(ctx, messages); err != nil {
+		return nil, err
+	}
 	return messages, nil
 }
 
-func (r *mysqlChatMessageRepo) MarkAsRead(ctx context.Context, messageID int) error {
-	query := `UPDATE chat_messages SET is_read = true WHERE id = ?`
-	
-	_, err := r.db.ExecContext(ctx, query, messageID)
-	return err
+// loadAttachments reads the attachments of all the messages in one query
+func (r *mysqlChatMessageRepo) loadAttachments(ctx context.Context, messages []*models.ChatMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	byID := make(map[int]*models.ChatMessage, len(messages))
+	ids := make([]string, len(messages))
+	for i, message := range messages {
+		byID[message.ID] = message
+		ids[i] = strconv.Itoa(message.ID)
+	}
+
+	// The IDs are integers formatted here, so they are safe to inline
+	query := `SELECT ` + chatAttachmentColumns + ` FROM chat_attachments
+			  WHERE message_id IN (` + strings.Join(ids, ", ") + `) ORDER BY id`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		attachment, err := scanChatAttachment(rows)
+		if err != nil {
+			return err
+		}
+		message := byID[attachment.MessageID]
+		message.Attachments = append(message.Attachments, attachment)
+	}
+	return rows.Err()
 }
 
-func (r *mysqlChatMessageRepo) GetUnreadCount(ctx context.Context, userID int) (int, error) {
-	query := `SELECT COUNT(*) FROM chat_messages WHERE sender_id != ? AND is_read = false`
-	
-	var count int
-	err := r.db.QueryRowContext(ctx, query, userID).Scan(&count)
-	return count, err
-}
\ No newline at end of file
+func scanChatMessage(row rowScanner) (*models.ChatMessage, error) {
+	var message models.ChatMessage
+	var deletedBy sql.NullInt64
+	err := row.Scan(
+		&message.ID, &message.ConsultationID, &message.SenderType, &message.SenderID,
+		&message.Message, &message.SentAt, &message.IsRead,
+		&message.EditedAt, &message.DeletedAt, &deletedBy, &message.ArchivedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if deletedBy.Valid {
+		id := int(deletedBy.Int64)
+		message.DeletedBy = &id
+	}
+	return &message, nil
+}
+
+func scanChatAttachment(row rowScanner) (*models.ChatAttachment, error) {
+	var attachment models.ChatAttachment
+	var thumbnailPath sql.NullString
+	err := row.Scan(
+		&attachment.ID, &attachment.MessageID, &attachment.ConsultationID, &attachment.FileName,
+		&attachment.ContentType, &attachment.Size, &attachment.FilePath, &thumbnailPath, &attachment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	attachment.ThumbnailPath = thumbnailPath.String
+	attachment.HasThumbnail = thumbnailPath.Valid
+	return &attachment, nil
+}
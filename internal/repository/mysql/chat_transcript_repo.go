@@ -0,0 +1,71 @@
+// File: internal/repository/mysql/chat_transcript_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"shifa/internal/models"
+)
+
+// ChatTranscriptRepo represents the MySQL repository for signed chat transcripts
+type ChatTranscriptRepo struct {
+	db *sql.DB
+}
+
+// NewChatTranscriptRepo creates a new ChatTranscriptRepo instance
+func NewChatTranscriptRepo(db *sql.DB) *ChatTranscriptRepo {
+	return &ChatTranscriptRepo{db: db}
+}
+
+// Create stores the transcript; it fails when the consultation already has one
+func (r *ChatTranscriptRepo) Create(ctx context.Context, transcript *models.ChatTranscript) error {
+	query := `
+		INSERT INTO chat_transcripts (consultation_id, message_count, signed_by, signer_name, signed_at,
+			signer_ip, content_hash, pdf_hash, json_path, pdf_path)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	sig := transcript.Signature
+	result, err := r.db.ExecContext(ctx, query, transcript.ConsultationID, transcript.MessageCount,
+		sig.SignedBy, sig.SignerName, sig.SignedAt, sig.IPAddress, sig.ContentHash,
+		transcript.PDFHash, transcript.JSONPath, transcript.PDFPath)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	transcript.ID = int(id)
+	return nil
+}
+
+// GetByConsultationID retrieves the consultation's transcript, or nil when none exists
+func (r *ChatTranscriptRepo) GetByConsultationID(ctx context.Context, consultationID int) (*models.ChatTranscript, error) {
+	query := `
+		SELECT id, consultation_id, message_count, signed_by, signer_name, signed_at, signer_ip,
+			content_hash, pdf_hash, json_path, pdf_path
+		FROM chat_transcripts
+		WHERE consultation_id = ?
+	`
+
+	var transcript models.ChatTranscript
+	var signerIP sql.NullString
+	sig := &transcript.Signature
+	err := r.db.QueryRowContext(ctx, query, consultationID).Scan(
+		&transcript.ID, &transcript.ConsultationID, &transcript.MessageCount,
+		&sig.SignedBy, &sig.SignerName, &sig.SignedAt, &signerIP,
+		&sig.ContentHash, &transcript.PDFHash, &transcript.JSONPath, &transcript.PDFPath,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sig.IPAddress = signerIP.String
+	return &transcript, nil
+}
@@ -0,0 +1,256 @@
+// File: internal/repository/mysql/clinical_note_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"shifa/internal/models"
+)
+
+const clinicalNoteColumns = `id, consultation_id, author_id, version, status, amends_note_id, amendment_reason,
+		subjective, objective, assessment, plan,
+		signed_by, signer_name, signed_at, content_hash, signer_ip, created_at, updated_at`
+
+// ClinicalNoteRepo represents the MySQL repository for SOAP clinical notes
+type ClinicalNoteRepo struct {
+	db *sql.DB
+}
+
+// NewClinicalNoteRepo creates a new ClinicalNoteRepo instance
+func NewClinicalNoteRepo(db *sql.DB) *ClinicalNoteRepo {
+	return &ClinicalNoteRepo{db: db}
+}
+
+// Create locks the consultation's notes while numbering the new version
+func (r *ClinicalNoteRepo) Create(ctx context.Context, note *models.ClinicalNote) (err error) {
+	sections, err := marshalNoteSections(note)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var last int
+	err = tx.QueryRowContext(ctx,
+		`SELECT COALESCE(MAX(version), 0) FROM clinical_notes WHERE consultation_id = ? FOR UPDATE`,
+		note.ConsultationID).Scan(&last)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO clinical_notes (consultation_id, author_id, version, status, amends_note_id, amendment_reason,
+			subjective, objective, assessment, plan, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, note.ConsultationID, note.AuthorID, last+1, note.Status, note.AmendsNoteID, note.AmendmentReason,
+		sections[0], sections[1], sections[2], sections[3], now, now)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	note.ID = int(id)
+	note.Version = last + 1
+	note.CreatedAt = now
+	note.UpdatedAt = now
+	return nil
+}
+
+// GetByID retrieves a clinical note by its ID
+func (r *ClinicalNoteRepo) GetByID(ctx context.Context, id int) (*models.ClinicalNote, error) {
+	query := `SELECT ` + clinicalNoteColumns + ` FROM clinical_notes WHERE id = ?`
+
+	note, err := scanClinicalNote(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("clinical note not found")
+		}
+		return nil, err
+	}
+	return note, nil
+}
+
+// ListByConsultation retrieves all versions of a consultation's note in version order
+func (r *ClinicalNoteRepo) ListByConsultation(ctx context.Context, consultationID int) ([]*models.ClinicalNote, error) {
+	query := `SELECT ` + clinicalNoteColumns + ` FROM clinical_notes WHERE consultation_id = ? ORDER BY version`
+
+	rows, err := r.db.QueryContext(ctx, query, consultationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []*models.ClinicalNote
+	for rows.Next() {
+		note, err := scanClinicalNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// UpdateDraft replaces the sections of a draft; signed notes are left untouched
+func (r *ClinicalNoteRepo) UpdateDraft(ctx context.Context, note *models.ClinicalNote) error {
+	sections, err := marshalNoteSections(note)
+	if err != nil {
+		return err
+	}
+
+	note.UpdatedAt = time.Now()
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE clinical_notes
+		SET subjective = ?, objective = ?, assessment = ?, plan = ?, amendment_reason = ?, updated_at = ?
+		WHERE id = ? AND status = 'draft'
+	`, sections[0], sections[1], sections[2], sections[3], note.AmendmentReason, note.UpdatedAt, note.ID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("clinical note is no longer a draft")
+	}
+	return nil
+}
+
+// Sign stores the signature of a draft and supersedes the note it amends
+func (r *ClinicalNoteRepo) Sign(ctx context.Context, note *models.ClinicalNote) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	sig := note.Signature
+	result, err := tx.ExecContext(ctx, `
+		UPDATE clinical_notes
+		SET status = 'signed', signed_by = ?, signer_name = ?, signed_at = ?, content_hash = ?, signer_ip = ?, updated_at = ?
+		WHERE id = ? AND status = 'draft'
+	`, sig.SignedBy, sig.SignerName, sig.SignedAt, sig.ContentHash, sig.IPAddress, sig.SignedAt, note.ID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		err = errors.New("clinical note is no longer a draft")
+		return err
+	}
+
+	if note.AmendsNoteID != nil {
+		result, err = tx.ExecContext(ctx,
+			`UPDATE clinical_notes SET status = 'amended', updated_at = ? WHERE id = ? AND status = 'signed'`,
+			sig.SignedAt, *note.AmendsNoteID)
+		if err != nil {
+			return err
+		}
+		affected, err = result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			err = errors.New("the amended note has already been superseded")
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	note.Status = models.ClinicalNoteSigned
+	note.UpdatedAt = sig.SignedAt
+	return nil
+}
+
+// marshalNoteSections encodes the four SOAP sections for their JSON columns
+func marshalNoteSections(note *models.ClinicalNote) ([4][]byte, error) {
+	var sections [4][]byte
+	for i, section := range []interface{}{note.Subjective, note.Objective, note.Assessment, note.Plan} {
+		data, err := json.Marshal(section)
+		if err != nil {
+			return sections, err
+		}
+		sections[i] = data
+	}
+	return sections, nil
+}
+
+func scanClinicalNote(row rowScanner) (*models.ClinicalNote, error) {
+	var (
+		note                                    models.ClinicalNote
+		amendsNoteID, signedBy                  sql.NullInt64
+		subjective, objective, assessment, plan []byte
+		signerName, contentHash, signerIP       sql.NullString
+		signedAt                                sql.NullTime
+	)
+	err := row.Scan(
+		&note.ID, &note.ConsultationID, &note.AuthorID, &note.Version, &note.Status, &amendsNoteID,
+		&note.AmendmentReason, &subjective, &objective, &assessment, &plan,
+		&signedBy, &signerName, &signedAt, &contentHash, &signerIP, &note.CreatedAt, &note.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, section := range []struct {
+		data   []byte
+		target interface{}
+	}{
+		{subjective, &note.Subjective},
+		{objective, &note.Objective},
+		{assessment, &note.Assessment},
+		{plan, &note.Plan},
+	} {
+		if err := json.Unmarshal(section.data, section.target); err != nil {
+			return nil, err
+		}
+	}
+
+	if amendsNoteID.Valid {
+		id := int(amendsNoteID.Int64)
+		note.AmendsNoteID = &id
+	}
+	if signedAt.Valid {
+		note.Signature = &models.ClinicalSignature{
+			SignedBy:    int(signedBy.Int64),
+			SignerName:  signerName.String,
+			SignedAt:    signedAt.Time,
+			ContentHash: contentHash.String,
+			IPAddress:   signerIP.String,
+		}
+	}
+	return &note, nil
+}
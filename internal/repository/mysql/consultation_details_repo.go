@@ -25,8 +25,8 @@ func NewConsultationDetailsRepo(db *sql.DB) *ConsultationDetailsRepo {
 func (r *ConsultationDetailsRepo) Create(ctx context.Context, details *models.ConsultationDetails) error {
 	query := `
 		INSERT INTO consultation_details 
-		(consultation_id, request_details, symptoms, diagnosis, prescription, notes)
-		VALUES (?, ?, ?, ?, ?, ?)
+		(consultation_id, request_details, symptoms, diagnosis, diagnosis_code, prescription, notes)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
 	result, err := r.db.ExecContext(ctx, query,
@@ -34,6 +34,7 @@ func (r *ConsultationDetailsRepo) Create(ctx context.Context, details *models.Co
 		details.RequestDetails,
 		details.Symptoms,
 		details.Diagnosis,
+		details.DiagnosisCode,
 		details.Prescription,
 		details.Notes,
 	)
@@ -54,7 +55,7 @@ func (r *ConsultationDetailsRepo) Create(ctx context.Context, details *models.Co
 // GetByID retrieves consultation details by ID
 func (r *ConsultationDetailsRepo) GetByID(ctx context.Context, id int) (*models.ConsultationDetails, error) {
 	query := `
-		SELECT id, consultation_id, request_details, symptoms, diagnosis, prescription, notes
+		SELECT id, consultation_id, request_details, symptoms, diagnosis, diagnosis_code, prescription, notes
 		FROM consultation_details
 		WHERE id = ?
 	`
@@ -66,6 +67,7 @@ func (r *ConsultationDetailsRepo) GetByID(ctx context.Context, id int) (*models.
 		&details.RequestDetails,
 		&details.Symptoms,
 		&details.Diagnosis,
+		&details.DiagnosisCode,
 		&details.Prescription,
 		&details.Notes,
 	)
@@ -83,7 +85,7 @@ func (r *ConsultationDetailsRepo) GetByID(ctx context.Context, id int) (*models.
 // GetByConsultationID retrieves consultation details by consultation ID
 func (r *ConsultationDetailsRepo) GetByConsultationID(ctx context.Context, consultationID int) (*models.ConsultationDetails, error) {
 	query := `
-		SELECT id, consultation_id, request_details, symptoms, diagnosis, prescription, notes
+		SELECT id, consultation_id, request_details, symptoms, diagnosis, diagnosis_code, prescription, notes
 		FROM consultation_details
 		WHERE consultation_id = ?
 	`
@@ -95,6 +97,7 @@ func (r *ConsultationDetailsRepo) GetByConsultationID(ctx context.Context, consu
 		&details.RequestDetails,
 		&details.Symptoms,
 		&details.Diagnosis,
+		&details.DiagnosisCode,
 		&details.Prescription,
 		&details.Notes,
 	)
@@ -116,6 +119,7 @@ func (r *ConsultationDetailsRepo) Update(ctx context.Context, details *models.Co
 		SET request_details = ?, 
 			symptoms = ?, 
 			diagnosis = ?, 
+			diagnosis_code = ?,
 			prescription = ?, 
 			notes = ?
 		WHERE id = ?
@@ -125,6 +129,7 @@ func (r *ConsultationDetailsRepo) Update(ctx context.Context, details *models.Co
 		details.RequestDetails,
 		details.Symptoms,
 		details.Diagnosis,
+		details.DiagnosisCode,
 		details.Prescription,
 		details.Notes,
 		details.ID,
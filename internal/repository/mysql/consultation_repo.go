@@ -6,10 +6,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-
-	// "time"
+	"time"
 
 	"shifa/internal/models"
+	"shifa/internal/repository"
 )
 
 // ConsultationRepo represents the MySQL repository for consultation-related database operations
@@ -25,12 +25,12 @@ func NewConsultationRepo(db *sql.DB) *ConsultationRepo {
 // Create inserts a new consultation into the database
 func (r *ConsultationRepo) Create(ctx context.Context, consultation *models.Consultation) error {
 	query := `
-		INSERT INTO consultations (patient_id, doctor_id, status, started_at, completed_at, fee)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO consultations (appointment_id, patient_id, doctor_id, status, started_at, completed_at, fee)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
 
 	result, err := r.db.ExecContext(ctx, query,
-		consultation.PatientID, consultation.DoctorID,
+		consultation.AppointmentID, consultation.PatientID, consultation.DoctorID,
 		consultation.Status,
 		sql.NullTime{Time: consultation.StartedAt.Time, Valid: consultation.StartedAt.Valid},
 		sql.NullTime{Time: consultation.CompletedAt.Time, Valid: consultation.CompletedAt.Valid},
@@ -51,16 +51,16 @@ func (r *ConsultationRepo) Create(ctx context.Context, consultation *models.Cons
 // GetByID retrieves a consultation by its ID
 func (r *ConsultationRepo) GetByID(ctx context.Context, id int) (*models.Consultation, error) {
 	query := `
-		SELECT id, patient_id, doctor_id, status, started_at, completed_at, fee
+		SELECT id, appointment_id, patient_id, doctor_id, status, started_at, completed_at, fee, video_duration_seconds
 		FROM consultations
 		WHERE id = ?
 	`
 
 	var consultation models.Consultation
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&consultation.ID, &consultation.PatientID, &consultation.DoctorID,
+		&consultation.ID, &consultation.AppointmentID, &consultation.PatientID, &consultation.DoctorID,
 		&consultation.Status, &consultation.StartedAt, &consultation.CompletedAt,
-		&consultation.Fee,
+		&consultation.Fee, &consultation.VideoDurationSeconds,
 	)
 
 	if err != nil {
@@ -76,7 +76,7 @@ func (r *ConsultationRepo) GetByID(ctx context.Context, id int) (*models.Consult
 // GetByAppointmentID retrieves a consultation by its appointment ID
 func (r *ConsultationRepo) GetByAppointmentID(ctx context.Context, appointmentID int) (*models.Consultation, error) {
 	query := `
-        SELECT id, patient_id, doctor_id, status, started_at, completed_at, fee
+        SELECT id, appointment_id, patient_id, doctor_id, status, started_at, completed_at, fee, video_duration_seconds
         FROM consultations
         WHERE appointment_id = ?
         LIMIT 1
@@ -84,9 +84,9 @@ func (r *ConsultationRepo) GetByAppointmentID(ctx context.Context, appointmentID
 
 	var consultation models.Consultation
 	err := r.db.QueryRowContext(ctx, query, appointmentID).Scan(
-		&consultation.ID, &consultation.PatientID, &consultation.DoctorID,
+		&consultation.ID, &consultation.AppointmentID, &consultation.PatientID, &consultation.DoctorID,
 		&consultation.Status, &consultation.StartedAt, &consultation.CompletedAt,
-		&consultation.Fee,
+		&consultation.Fee, &consultation.VideoDurationSeconds,
 	)
 
 	if err != nil {
@@ -99,6 +99,116 @@ func (r *ConsultationRepo) GetByAppointmentID(ctx context.Context, appointmentID
 	return &consultation, nil
 }
 
+// Start creates an in-progress consultation for its appointment and moves the appointment to
+// in_progress, provided it is still scheduled with the same doctor and patient
+func (r *ConsultationRepo) Start(ctx context.Context, consultation *models.Consultation) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE appointments
+		SET status = 'in_progress', updated_at = ?, sequence = sequence + 1
+		WHERE id = ? AND status = 'scheduled' AND provider_type = 'doctor' AND doctor_id = ? AND patient_id = ?
+	`, time.Now(), consultation.AppointmentID, consultation.DoctorID, consultation.PatientID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		err = errors.New("appointment is not a scheduled appointment between this doctor and patient")
+		return err
+	}
+
+	result, err = tx.ExecContext(ctx, `
+		INSERT INTO consultations (appointment_id, patient_id, doctor_id, status, started_at, fee)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, consultation.AppointmentID, consultation.PatientID, consultation.DoctorID,
+		consultation.Status, consultation.StartedAt, consultation.Fee)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	consultation.ID = int(id)
+	return nil
+}
+
+// Complete closes an in-progress consultation, completes its appointment and records the
+// pending payment for it
+func (r *ConsultationRepo) Complete(ctx context.Context, consultation *models.Consultation, payment *repository.Payment) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE consultations
+		SET status = 'completed', completed_at = ?, fee = ?
+		WHERE id = ? AND status = 'in_progress'
+	`, consultation.CompletedAt, consultation.Fee, consultation.ID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		err = errors.New("consultation is no longer in progress")
+		return err
+	}
+
+	if consultation.AppointmentID != nil {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE appointments
+			SET status = 'completed', updated_at = ?, sequence = sequence + 1
+			WHERE id = ? AND status = 'in_progress'
+		`, time.Now(), *consultation.AppointmentID)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err = tx.ExecContext(ctx, `
+		INSERT INTO payments (consultation_id, amount, status, payment_date)
+		VALUES (?, ?, ?, ?)
+	`, consultation.ID, payment.Amount, payment.Status, payment.PaymentDate)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	payment.ID = int(id)
+	return nil
+}
+
 // Update updates an existing consultation's information
 func (r *ConsultationRepo) Update(ctx context.Context, consultation *models.Consultation) error {
 	query := `
@@ -126,8 +236,8 @@ func (r *ConsultationRepo) Delete(ctx context.Context, id int) error {
 // List retrieves a list of consultations with optional filtering and pagination
 func (r *ConsultationRepo) List(ctx context.Context, filter models.ConsultationFilter, offset, limit int) ([]*models.Consultation, error) {
 	query := `
-        SELECT id, patient_id, doctor_id,
-               status, started_at, completed_at, fee
+        SELECT id, appointment_id, patient_id, doctor_id,
+               status, started_at, completed_at, fee, video_duration_seconds
         FROM consultations
         WHERE 1=1
     `
@@ -181,9 +291,9 @@ func (r *ConsultationRepo) List(ctx context.Context, filter models.ConsultationF
 	for rows.Next() {
 		var consultation models.Consultation
 		err := rows.Scan(
-			&consultation.ID, &consultation.PatientID, &consultation.DoctorID,
+			&consultation.ID, &consultation.AppointmentID, &consultation.PatientID, &consultation.DoctorID,
 			&consultation.Status, &consultation.StartedAt, &consultation.CompletedAt,
-			&consultation.Fee,
+			&consultation.Fee, &consultation.VideoDurationSeconds,
 		)
 		if err != nil {
 			return nil, err
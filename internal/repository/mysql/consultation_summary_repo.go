@@ -0,0 +1,65 @@
+// File: internal/repository/mysql/consultation_summary_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"shifa/internal/models"
+)
+
+// ConsultationSummaryRepo represents the MySQL repository for consultation summary documents
+type ConsultationSummaryRepo struct {
+	db *sql.DB
+}
+
+// NewConsultationSummaryRepo creates a new ConsultationSummaryRepo instance
+func NewConsultationSummaryRepo(db *sql.DB) *ConsultationSummaryRepo {
+	return &ConsultationSummaryRepo{db: db}
+}
+
+// Get retrieves the stored summary in the given language and format, or nil when none exists
+func (r *ConsultationSummaryRepo) Get(ctx context.Context, consultationID int, language, format string) (*models.ConsultationSummary, error) {
+	query := `
+		SELECT id, consultation_id, language, format, file_path, content_hash, generated_at
+		FROM consultation_summaries
+		WHERE consultation_id = ? AND language = ? AND format = ?
+	`
+
+	var summary models.ConsultationSummary
+	err := r.db.QueryRowContext(ctx, query, consultationID, language, format).Scan(
+		&summary.ID, &summary.ConsultationID, &summary.Language, &summary.Format,
+		&summary.FilePath, &summary.ContentHash, &summary.GeneratedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// Save stores the summary, replacing the one in the same language and format
+func (r *ConsultationSummaryRepo) Save(ctx context.Context, summary *models.ConsultationSummary) error {
+	query := `
+		INSERT INTO consultation_summaries (consultation_id, language, format, file_path, content_hash, generated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), file_path = VALUES(file_path),
+			content_hash = VALUES(content_hash), generated_at = VALUES(generated_at)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, summary.ConsultationID, summary.Language, summary.Format,
+		summary.FilePath, summary.ContentHash, summary.GeneratedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	summary.ID = int(id)
+	return nil
+}
@@ -44,3 +44,8 @@ func NewMySQLDB(cfg *config.Config, log *logrus.Logger) (*sql.DB, error) {
 
 	return nil, fmt.Errorf("failed to connect to database after %d attempts: %v", maxRetries, err)
 }
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
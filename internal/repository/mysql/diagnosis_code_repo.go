@@ -0,0 +1,103 @@
+// File: internal/repository/mysql/diagnosis_code_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"shifa/internal/models"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// DiagnosisCodeRepo represents the MySQL repository for the ICD-10 catalogue
+type DiagnosisCodeRepo struct {
+	db *sql.DB
+}
+
+// NewDiagnosisCodeRepo creates a new DiagnosisCodeRepo instance
+func NewDiagnosisCodeRepo(db *sql.DB) *DiagnosisCodeRepo {
+	return &DiagnosisCodeRepo{db: db}
+}
+
+// Import upserts catalogue entries; a re-imported code takes the new description
+func (r *DiagnosisCodeRepo) Import(ctx context.Context, codes []models.DiagnosisCode) (n int, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO icd10_codes (code, description, category)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE description = VALUES(description), category = VALUES(category)
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, code := range codes {
+		if _, err = stmt.ExecContext(ctx, code.Code, code.Description, code.Category); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(codes), nil
+}
+
+// Search returns codes starting with the query, ignoring dots, followed by entries whose
+// description contains it
+func (r *DiagnosisCodeRepo) Search(ctx context.Context, query string, limit int) ([]models.DiagnosisCode, error) {
+	codePrefix := likeEscaper.Replace(strings.ReplaceAll(strings.ToUpper(query), ".", "")) + "%"
+	description := "%" + likeEscaper.Replace(query) + "%"
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT code, description, category
+		FROM icd10_codes
+		WHERE REPLACE(code, '.', '') LIKE ? OR description LIKE ?
+		ORDER BY REPLACE(code, '.', '') LIKE ? DESC, code
+		LIMIT ?
+	`, codePrefix, description, codePrefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanDiagnosisCodes(rows)
+}
+
+// GetByCodes returns the catalogue entries of the given codes
+func (r *DiagnosisCodeRepo) GetByCodes(ctx context.Context, codes []string) ([]models.DiagnosisCode, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	in, args := inClause(codes)
+	rows, err := r.db.QueryContext(ctx, `SELECT code, description, category FROM icd10_codes WHERE code IN `+in, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanDiagnosisCodes(rows)
+}
+
+func scanDiagnosisCodes(rows *sql.Rows) ([]models.DiagnosisCode, error) {
+	codes := []models.DiagnosisCode{}
+	for rows.Next() {
+		var code models.DiagnosisCode
+		if err := rows.Scan(&code.Code, &code.Description, &code.Category); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
@@ -0,0 +1,149 @@
+// File: internal/repository/mysql/drug_data_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"shifa/internal/models"
+)
+
+// DrugDataRepo represents the MySQL repository for the drug interaction and drug class datasets
+type DrugDataRepo struct {
+	db *sql.DB
+}
+
+// NewDrugDataRepo creates a new DrugDataRepo instance
+func NewDrugDataRepo(db *sql.DB) *DrugDataRepo {
+	return &DrugDataRepo{db: db}
+}
+
+// ImportInteractions upserts the interaction rows; a re-imported pair takes the new severity and description
+func (r *DrugDataRepo) ImportInteractions(ctx context.Context, interactions []models.DrugInteraction) (n int, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO drug_interactions (drug_a, drug_b, severity, description)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE severity = VALUES(severity), description = VALUES(description)
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, interaction := range interactions {
+		_, err = stmt.ExecContext(ctx, interaction.DrugA, interaction.DrugB, interaction.Severity, interaction.Description)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(interactions), nil
+}
+
+// ImportClasses inserts drug class rows, skipping those already present
+func (r *DrugDataRepo) ImportClasses(ctx context.Context, classes []models.DrugClass) (n int, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT IGNORE INTO drug_classes (drug_name, class_name) VALUES (?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, class := range classes {
+		if _, err = stmt.ExecContext(ctx, class.DrugName, class.ClassName); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(classes), nil
+}
+
+// FindInteractions returns the interactions between any two of the given names
+func (r *DrugDataRepo) FindInteractions(ctx context.Context, names []string) ([]models.DrugInteraction, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	in, args := inClause(names)
+	query := `
+		SELECT drug_a, drug_b, severity, description
+		FROM drug_interactions
+		WHERE drug_a IN ` + in + ` AND drug_b IN ` + in
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var interactions []models.DrugInteraction
+	for rows.Next() {
+		var interaction models.DrugInteraction
+		err := rows.Scan(&interaction.DrugA, &interaction.DrugB, &interaction.Severity, &interaction.Description)
+		if err != nil {
+			return nil, err
+		}
+		interactions = append(interactions, interaction)
+	}
+	return interactions, rows.Err()
+}
+
+// ListClasses returns the classes of the given drugs
+func (r *DrugDataRepo) ListClasses(ctx context.Context, drugNames []string) ([]models.DrugClass, error) {
+	if len(drugNames) == 0 {
+		return nil, nil
+	}
+	in, args := inClause(drugNames)
+	query := `SELECT drug_name, class_name FROM drug_classes WHERE drug_name IN ` + in
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var classes []models.DrugClass
+	for rows.Next() {
+		var class models.DrugClass
+		if err := rows.Scan(&class.DrugName, &class.ClassName); err != nil {
+			return nil, err
+		}
+		classes = append(classes, class)
+	}
+	return classes, rows.Err()
+}
+
+// inClause builds "(?, ?, ...)" and its arguments for the given values
+func inClause(values []string) (string, []interface{}) {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + ")", args
+}
@@ -0,0 +1,172 @@
+// File: internal/repository/mysql/follow_up_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"shifa/internal/models"
+)
+
+const followUpColumns = `f.id, f.consultation_id, f.patient_id, f.doctor_id, f.interval_days, f.due_date, f.note,
+		f.status, f.appointment_id, f.reminders_sent, f.last_reminded_at, f.created_at, f.updated_at`
+
+// notBookedElsewhere leaves out follow-ups whose patient has booked the doctor directly since
+// the recommendation, without going through the follow-up
+const notBookedElsewhere = ` AND NOT EXISTS (
+		SELECT 1 FROM appointments a
+		WHERE a.patient_id = f.patient_id AND a.doctor_id = f.doctor_id
+			AND a.status <> 'cancelled' AND a.starts_at >= f.created_at)`
+
+// FollowUpRepo represents the MySQL repository for follow-up recommendations
+type FollowUpRepo struct {
+	db *sql.DB
+}
+
+// NewFollowUpRepo creates a new FollowUpRepo instance
+func NewFollowUpRepo(db *sql.DB) *FollowUpRepo {
+	return &FollowUpRepo{db: db}
+}
+
+// Create inserts a new follow-up
+func (r *FollowUpRepo) Create(ctx context.Context, followUp *models.FollowUp) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO follow_ups (consultation_id, patient_id, doctor_id, interval_days, due_date, note, status,
+			created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, followUp.ConsultationID, followUp.PatientID, followUp.DoctorID, followUp.IntervalDays,
+		followUp.DueDate, followUp.Note, followUp.Status, now, now)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	followUp.ID = int(id)
+	followUp.CreatedAt = now
+	followUp.UpdatedAt = now
+	return nil
+}
+
+// GetByID retrieves a follow-up by its ID
+func (r *FollowUpRepo) GetByID(ctx context.Context, id int) (*models.FollowUp, error) {
+	return r.getOne(ctx, `SELECT `+followUpColumns+` FROM follow_ups f WHERE f.id = ?`, id)
+}
+
+// GetByConsultationID retrieves the follow-up recommended at the end of a consultation
+func (r *FollowUpRepo) GetByConsultationID(ctx context.Context, consultationID int) (*models.FollowUp, error) {
+	return r.getOne(ctx, `SELECT `+followUpColumns+` FROM follow_ups f WHERE f.consultation_id = ?`, consultationID)
+}
+
+// ListByPatient retrieves a patient's follow-ups, pending first and then by due date
+func (r *FollowUpRepo) ListByPatient(ctx context.Context, patientID int) ([]*models.FollowUp, error) {
+	query := `SELECT ` + followUpColumns + ` FROM follow_ups f
+		WHERE f.patient_id = ?
+		ORDER BY f.status = 'pending' DESC, f.due_date, f.id`
+	return r.list(ctx, query, false, patientID)
+}
+
+// ListOverdue retrieves the doctor's pending follow-ups that were due before the given date
+func (r *FollowUpRepo) ListOverdue(ctx context.Context, doctorID int, before time.Time) ([]*models.FollowUp, error) {
+	query := `SELECT ` + followUpColumns + `, u.name FROM follow_ups f
+		JOIN users u ON u.id = f.patient_id
+		WHERE f.doctor_id = ? AND f.status = 'pending' AND f.due_date < ?` + notBookedElsewhere + `
+		ORDER BY f.due_date, f.id`
+	return r.list(ctx, query, true, doctorID, before)
+}
+
+// ListToRemind retrieves the pending follow-ups that are due to be reminded of
+func (r *FollowUpRepo) ListToRemind(ctx context.Context, dueBy, remindedBefore time.Time, maxReminders int) ([]*models.FollowUp, error) {
+	query := `SELECT ` + followUpColumns + ` FROM follow_ups f
+		WHERE f.status = 'pending' AND f.due_date <= ? AND f.reminders_sent < ?
+			AND (f.last_reminded_at IS NULL OR f.last_reminded_at < ?)` + notBookedElsewhere + `
+		ORDER BY f.due_date, f.id`
+	return r.list(ctx, query, false, dueBy, maxReminders, remindedBefore)
+}
+
+// ClaimReminder counts the next reminder if the follow-up still has the given count
+func (r *FollowUpRepo) ClaimReminder(ctx context.Context, id, remindersSent int, at time.Time) (bool, error) {
+	return r.update(ctx, `
+		UPDATE follow_ups SET reminders_sent = reminders_sent + 1, last_reminded_at = ?, updated_at = ?
+		WHERE id = ? AND status = 'pending' AND reminders_sent = ?
+	`, at, at, id, remindersSent)
+}
+
+// MarkBooked links a pending follow-up to the appointment booked for it
+func (r *FollowUpRepo) MarkBooked(ctx context.Context, id, appointmentID int, at time.Time) (bool, error) {
+	return r.update(ctx, `
+		UPDATE follow_ups SET status = 'booked', appointment_id = ?, updated_at = ?
+		WHERE id = ? AND status = 'pending'
+	`, appointmentID, at, id)
+}
+
+// Dismiss closes a pending follow-up without a booking
+func (r *FollowUpRepo) Dismiss(ctx context.Context, id int, at time.Time) (bool, error) {
+	return r.update(ctx, `
+		UPDATE follow_ups SET status = 'dismissed', updated_at = ?
+		WHERE id = ? AND status = 'pending'
+	`, at, id)
+}
+
+func (r *FollowUpRepo) update(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (r *FollowUpRepo) getOne(ctx context.Context, query string, arg interface{}) (*models.FollowUp, error) {
+	followUp, err := scanFollowUp(r.db.QueryRowContext(ctx, query, arg), false)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("follow-up not found")
+		}
+		return nil, err
+	}
+	return followUp, nil
+}
+
+// list scans follow-ups; withPatientName is set when the query selects the patient's name last
+func (r *FollowUpRepo) list(ctx context.Context, query string, withPatientName bool, args ...interface{}) ([]*models.FollowUp, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	followUps := []*models.FollowUp{}
+	for rows.Next() {
+		followUp, err := scanFollowUp(rows, withPatientName)
+		if err != nil {
+			return nil, err
+		}
+		followUps = append(followUps, followUp)
+	}
+	return followUps, rows.Err()
+}
+
+func scanFollowUp(row rowScanner, withPatientName bool) (*models.FollowUp, error) {
+	var followUp models.FollowUp
+	dest := []interface{}{
+		&followUp.ID, &followUp.ConsultationID, &followUp.PatientID, &followUp.DoctorID, &followUp.IntervalDays,
+		&followUp.DueDate, &followUp.Note, &followUp.Status, &followUp.AppointmentID, &followUp.RemindersSent,
+		&followUp.LastRemindedAt, &followUp.CreatedAt, &followUp.UpdatedAt,
+	}
+	if withPatientName {
+		dest = append(dest, &followUp.PatientName)
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &followUp, nil
+}
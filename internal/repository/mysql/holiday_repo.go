@@ -0,0 +1,75 @@
+// File: internal/repository/mysql/holiday_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"shifa/internal/models"
+)
+
+// HolidayRepo represents the MySQL repository for the clinic-wide holiday calendar
+type HolidayRepo struct {
+	db *sql.DB
+}
+
+// NewHolidayRepo creates a new HolidayRepo instance
+func NewHolidayRepo(db *sql.DB) *HolidayRepo {
+	return &HolidayRepo{db: db}
+}
+
+// Create inserts a new holiday into the database
+func (r *HolidayRepo) Create(ctx context.Context, holiday *models.Holiday) error {
+	query := `INSERT INTO holidays (holiday_date, name) VALUES (?, ?)`
+
+	result, err := r.db.ExecContext(ctx, query, holiday.HolidayDate.Format("2006-01-02"), holiday.Name)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	holiday.ID = int(id)
+	return nil
+}
+
+// Delete removes a holiday from the database
+func (r *HolidayRepo) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM holidays WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// List retrieves holidays falling between from and to, inclusive
+func (r *HolidayRepo) List(ctx context.Context, from, to time.Time) ([]*models.Holiday, error) {
+	query := `
+		SELECT id, holiday_date, name, created_at
+		FROM holidays
+		WHERE holiday_date BETWEEN ? AND ?
+		ORDER BY holiday_date
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holidays []*models.Holiday
+	for rows.Next() {
+		var holiday models.Holiday
+		if err := rows.Scan(&holiday.ID, &holiday.HolidayDate, &holiday.Name, &holiday.CreatedAt); err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, &holiday)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return holidays, nil
+}
@@ -0,0 +1,225 @@
+// File: internal/repository/mysql/home_care_availability_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"shifa/internal/models"
+)
+
+// HomeCareAvailabilityRepo represents the MySQL repository for home care provider
+// weekly availability, availability exceptions and service areas
+type HomeCareAvailabilityRepo struct {
+	db *sql.DB
+}
+
+// NewHomeCareAvailabilityRepo creates a new HomeCareAvailabilityRepo instance
+func NewHomeCareAvailabilityRepo(db *sql.DB) *HomeCareAvailabilityRepo {
+	return &HomeCareAvailabilityRepo{db: db}
+}
+
+// Create inserts a new weekly availability window
+func (r *HomeCareAvailabilityRepo) Create(ctx context.Context, availability *models.HomeCareAvailability) error {
+	query := `
+		INSERT INTO home_care_availability (provider_id, day_of_week, start_time, end_time)
+		VALUES (?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		availability.ProviderID, availability.DayOfWeek,
+		availability.StartTime.Format("15:04:05"), availability.EndTime.Format("15:04:05"))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	availability.ID = int(id)
+	return nil
+}
+
+// Update updates an existing weekly availability window
+func (r *HomeCareAvailabilityRepo) Update(ctx context.Context, availability *models.HomeCareAvailability) error {
+	query := `
+		UPDATE home_care_availability
+		SET day_of_week = ?, start_time = ?, end_time = ?
+		WHERE id = ? AND provider_id = ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		availability.DayOfWeek,
+		availability.StartTime.Format("15:04:05"), availability.EndTime.Format("15:04:05"),
+		availability.ID, availability.ProviderID)
+	return err
+}
+
+// Delete removes a weekly availability window
+func (r *HomeCareAvailabilityRepo) Delete(ctx context.Context, providerID, id int) error {
+	query := `DELETE FROM home_care_availability WHERE id = ? AND provider_id = ?`
+	_, err := r.db.ExecContext(ctx, query, id, providerID)
+	return err
+}
+
+// ListByProviderID retrieves all weekly availability windows for a provider
+func (r *HomeCareAvailabilityRepo) ListByProviderID(ctx context.Context, providerID int) ([]*models.HomeCareAvailability, error) {
+	query := `
+		SELECT id, provider_id, day_of_week, start_time, end_time
+		FROM home_care_availability
+		WHERE provider_id = ?
+		ORDER BY day_of_week, start_time
+	`
+	rows, err := r.db.QueryContext(ctx, query, providerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var availabilities []*models.HomeCareAvailability
+	for rows.Next() {
+		var availability models.HomeCareAvailability
+		var startStr, endStr string
+		err := rows.Scan(&availability.ID, &availability.ProviderID, &availability.DayOfWeek, &startStr, &endStr)
+		if err != nil {
+			return nil, err
+		}
+		availability.StartTime, err = time.Parse("15:04:05", startStr)
+		if err != nil {
+			return nil, err
+		}
+		availability.EndTime, err = time.Parse("15:04:05", endStr)
+		if err != nil {
+			return nil, err
+		}
+		availabilities = append(availabilities, &availability)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return availabilities, nil
+}
+
+// CreateException inserts a new availability exception
+func (r *HomeCareAvailabilityRepo) CreateException(ctx context.Context, exception *models.HomeCareAvailabilityException) error {
+	query := `
+		INSERT INTO home_care_availability_exceptions (provider_id, exception_type, start_at, end_at, reason)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		exception.ProviderID, exception.ExceptionType, exception.StartAt, exception.EndAt, exception.Reason)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	exception.ID = int(id)
+	return nil
+}
+
+// GetExceptionByID retrieves an availability exception by its ID
+func (r *HomeCareAvailabilityRepo) GetExceptionByID(ctx context.Context, id int) (*models.HomeCareAvailabilityException, error) {
+	query := `
+		SELECT id, provider_id, exception_type, start_at, end_at, COALESCE(reason, ''), created_at
+		FROM home_care_availability_exceptions
+		WHERE id = ?
+	`
+
+	var exception models.HomeCareAvailabilityException
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&exception.ID, &exception.ProviderID, &exception.ExceptionType,
+		&exception.StartAt, &exception.EndAt, &exception.Reason, &exception.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("availability exception not found")
+		}
+		return nil, err
+	}
+
+	return &exception, nil
+}
+
+// DeleteException removes an availability exception
+func (r *HomeCareAvailabilityRepo) DeleteException(ctx context.Context, id int) error {
+	query := `DELETE FROM home_care_availability_exceptions WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// ListExceptions retrieves a provider's exceptions overlapping the [from, to) range
+func (r *HomeCareAvailabilityRepo) ListExceptions(ctx context.Context, providerID int, from, to time.Time) ([]*models.HomeCareAvailabilityException, error) {
+	query := `
+		SELECT id, provider_id, exception_type, start_at, end_at, COALESCE(reason, ''), created_at
+		FROM home_care_availability_exceptions
+		WHERE provider_id = ? AND start_at < ? AND end_at > ?
+		ORDER BY start_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, providerID, to, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exceptions []*models.HomeCareAvailabilityException
+	for rows.Next() {
+		var exception models.HomeCareAvailabilityException
+		err := rows.Scan(
+			&exception.ID, &exception.ProviderID, &exception.ExceptionType,
+			&exception.StartAt, &exception.EndAt, &exception.Reason, &exception.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		exceptions = append(exceptions, &exception)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return exceptions, nil
+}
+
+// GetServiceArea retrieves a provider's service area, returning nil when none is configured
+func (r *HomeCareAvailabilityRepo) GetServiceArea(ctx context.Context, providerID int) (*models.HomeCareServiceArea, error) {
+	query := `
+		SELECT provider_id, radius_km, travel_buffer_minutes, updated_at
+		FROM home_care_service_areas
+		WHERE provider_id = ?
+	`
+
+	var area models.HomeCareServiceArea
+	err := r.db.QueryRowContext(ctx, query, providerID).Scan(
+		&area.ProviderID, &area.RadiusKm, &area.TravelBufferMinutes, &area.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &area, nil
+}
+
+// UpsertServiceArea creates or replaces a provider's service area
+func (r *HomeCareAvailabilityRepo) UpsertServiceArea(ctx context.Context, area *models.HomeCareServiceArea) error {
+	query := `
+		INSERT INTO home_care_service_areas (provider_id, radius_km, travel_buffer_minutes)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE radius_km = VALUES(radius_km), travel_buffer_minutes = VALUES(travel_buffer_minutes)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, area.ProviderID, area.RadiusKm, area.TravelBufferMinutes)
+	return err
+}
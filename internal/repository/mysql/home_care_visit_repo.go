@@ -22,13 +22,13 @@ func (r *HomeVisitRepo) Create(ctx context.Context, visit *models.HomeCareVisit)
     query := `
         INSERT INTO home_care_visits (
             patient_id, provider_id, address, latitude, longitude,
-            duration_hours, special_requirements, status
-        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+            duration_hours, special_requirements, status, scheduled_start
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
     result, err := r.db.ExecContext(ctx, query,
         visit.PatientID, visit.ProviderID, visit.Address,
         visit.Latitude, visit.Longitude, visit.DurationHours,
-        visit.SpecialRequirements, visit.Status)
+        visit.SpecialRequirements, visit.Status, visit.ScheduledStart)
     if err != nil {
         return err
     }
@@ -46,7 +46,7 @@ func (r *HomeVisitRepo) Create(ctx context.Context, visit *models.HomeCareVisit)
 func (r *HomeVisitRepo) GetByID(ctx context.Context, id int) (*models.HomeCareVisit, error) {
     query := `
         SELECT id, patient_id, provider_id, address, latitude, longitude, 
-            duration_hours, special_requirements, status
+            duration_hours, special_requirements, status, scheduled_start
         FROM home_care_visits
         WHERE id = ?
     `
@@ -54,7 +54,7 @@ func (r *HomeVisitRepo) GetByID(ctx context.Context, id int) (*models.HomeCareVi
     var visit models.HomeCareVisit
     err := r.db.QueryRowContext(ctx, query, id).Scan(
         &visit.ID, &visit.PatientID, &visit.ProviderID, &visit.Address, &visit.Latitude, &visit.Longitude,
-        &visit.DurationHours, &visit.SpecialRequirements, &visit.Status)
+        &visit.DurationHours, &visit.SpecialRequirements, &visit.Status, &visit.ScheduledStart)
     if err != nil {
         return nil, err
     }
@@ -66,7 +66,7 @@ func (r *HomeVisitRepo) GetByID(ctx context.Context, id int) (*models.HomeCareVi
 func (r *HomeVisitRepo) List(ctx context.Context, filter models.HomeCareVisitFilter) ([]models.HomeCareVisit, error) {
     query := `
         SELECT id, patient_id, provider_id, address, latitude, longitude,
-               duration_hours, special_requirements, status
+               duration_hours, special_requirements, status, scheduled_start
         FROM home_care_visits
         WHERE 1=1
     `
@@ -97,7 +97,7 @@ func (r *HomeVisitRepo) List(ctx context.Context, filter models.HomeCareVisitFil
     for rows.Next() {
         var visit models.HomeCareVisit
         err := rows.Scan(&visit.ID, &visit.PatientID, &visit.ProviderID, &visit.Address, &visit.Latitude, &visit.Longitude,
-            &visit.DurationHours, &visit.SpecialRequirements, &visit.Status)
+            &visit.DurationHours, &visit.SpecialRequirements, &visit.Status, &visit.ScheduledStart)
         if err != nil {
             return nil, err
         }
@@ -137,13 +137,14 @@ func (r *HomeVisitRepo) Update(ctx context.Context, visit *models.HomeCareVisit)
     query := `
         UPDATE home_care_visits
         SET patient_id = ?, provider_id = ?, address = ?, latitude = ?, longitude = ?, 
-            duration_hours = ?, special_requirements = ?, status = ?
+            duration_hours = ?, special_requirements = ?, status = ?, scheduled_start = ?,
+            sequence = sequence + 1
         WHERE id = ?
     `
 
     _, err := r.db.ExecContext(ctx, query, 
         visit.PatientID, visit.ProviderID, visit.Address, visit.Latitude, visit.Longitude,
-        visit.DurationHours, visit.SpecialRequirements, visit.Status, visit.ID)
+        visit.DurationHours, visit.SpecialRequirements, visit.Status, visit.ScheduledStart, visit.ID)
     return err
 }
 
@@ -151,9 +152,10 @@ func (r *HomeVisitRepo) Update(ctx context.Context, visit *models.HomeCareVisit)
 func (r *HomeVisitRepo) GetByDateRange(ctx context.Context, startDate, endDate time.Time) ([]models.HomeCareVisit, error) {
     query := `
         SELECT id, patient_id, provider_id, address, latitude, longitude,
-               duration_hours, special_requirements, status
+               duration_hours, special_requirements, status, scheduled_start
         FROM home_care_visits
-        WHERE created_at BETWEEN ? AND ?
+        WHERE scheduled_start BETWEEN ? AND ?
+        ORDER BY scheduled_start
     `
 
     rows, err := r.db.QueryContext(ctx, query, startDate, endDate)
@@ -168,7 +170,7 @@ func (r *HomeVisitRepo) GetByDateRange(ctx context.Context, startDate, endDate t
         err := rows.Scan(
             &visit.ID, &visit.PatientID, &visit.ProviderID, &visit.Address,
             &visit.Latitude, &visit.Longitude, &visit.DurationHours,
-            &visit.SpecialRequirements, &visit.Status,
+            &visit.SpecialRequirements, &visit.Status, &visit.ScheduledStart,
         )
         if err != nil {
             return nil, err
@@ -187,7 +189,7 @@ func (r *HomeVisitRepo) GetByDateRange(ctx context.Context, startDate, endDate t
 func (r *HomeVisitRepo) GetByPatientID(ctx context.Context, patientID int) ([]models.HomeCareVisit, error) {
     query := `
         SELECT id, patient_id, provider_id, address, latitude, longitude,
-               duration_hours, special_requirements, status
+               duration_hours, special_requirements, status, scheduled_start
         FROM home_care_visits
         WHERE patient_id = ?
         ORDER BY id DESC
@@ -205,7 +207,7 @@ func (r *HomeVisitRepo) GetByPatientID(ctx context.Context, patientID int) ([]mo
         err := rows.Scan(
             &visit.ID, &visit.PatientID, &visit.ProviderID, &visit.Address,
             &visit.Latitude, &visit.Longitude, &visit.DurationHours,
-            &visit.SpecialRequirements, &visit.Status,
+            &visit.SpecialRequirements, &visit.Status, &visit.ScheduledStart,
         )
         if err != nil {
             return nil, err
@@ -224,7 +226,7 @@ func (r *HomeVisitRepo) GetByPatientID(ctx context.Context, patientID int) ([]mo
 func (r *HomeVisitRepo) GetByProviderID(ctx context.Context, providerID int) ([]models.HomeCareVisit, error) {
     query := `
         SELECT id, patient_id, provider_id, address, latitude, longitude,
-               duration_hours, special_requirements, status
+               duration_hours, special_requirements, status, scheduled_start
         FROM home_care_visits
         WHERE provider_id = ?
         ORDER BY id DESC
@@ -242,7 +244,7 @@ func (r *HomeVisitRepo) GetByProviderID(ctx context.Context, providerID int) ([]
         err := rows.Scan(
             &visit.ID, &visit.PatientID, &visit.ProviderID, &visit.Address,
             &visit.Latitude, &visit.Longitude, &visit.DurationHours,
-            &visit.SpecialRequirements, &visit.Status,
+            &visit.SpecialRequirements, &visit.Status, &visit.ScheduledStart,
         )
         if err != nil {
             return nil, err
@@ -255,4 +257,41 @@ func (r *HomeVisitRepo) GetByProviderID(ctx context.Context, providerID int) ([]
     }
 
     return visits, nil
-}
\ No newline at end of file
+}
+
+// GetByProviderAndTimeRange retrieves a provider's visits whose scheduled start falls in [from, to)
+func (r *HomeVisitRepo) GetByProviderAndTimeRange(ctx context.Context, providerID int, from, to time.Time) ([]models.HomeCareVisit, error) {
+    query := `
+        SELECT id, patient_id, provider_id, address, latitude, longitude,
+               duration_hours, special_requirements, status, scheduled_start
+        FROM home_care_visits
+        WHERE provider_id = ? AND scheduled_start >= ? AND scheduled_start < ?
+        ORDER BY scheduled_start
+    `
+
+    rows, err := r.db.QueryContext(ctx, query, providerID, from, to)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var visits []models.HomeCareVisit
+    for rows.Next() {
+        var visit models.HomeCareVisit
+        err := rows.Scan(
+            &visit.ID, &visit.PatientID, &visit.ProviderID, &visit.Address,
+            &visit.Latitude, &visit.Longitude, &visit.DurationHours,
+            &visit.SpecialRequirements, &visit.Status, &visit.ScheduledStart,
+        )
+        if err != nil {
+            return nil, err
+        }
+        visits = append(visits, visit)
+    }
+
+    if err = rows.Err(); err != nil {
+        return nil, err
+    }
+
+    return visits, nil
+}
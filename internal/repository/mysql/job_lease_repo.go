@@ -0,0 +1,41 @@
+// File: internal/repository/mysql/job_lease_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// JobLeaseRepo represents the MySQL repository for scheduled job leases
+type JobLeaseRepo struct {
+	db *sql.DB
+}
+
+// NewJobLeaseRepo creates a new JobLeaseRepo instance
+func NewJobLeaseRepo(db *sql.DB) *JobLeaseRepo {
+	return &JobLeaseRepo{db: db}
+}
+
+// TryAcquire takes over the lease when it is free or expired, renews it when holder already owns it,
+// and then reads back the owner so concurrent instances agree on a single winner
+func (r *JobLeaseRepo) TryAcquire(ctx context.Context, jobName, holder string, now, until time.Time) (bool, error) {
+	query := `
+		INSERT INTO job_leases (job_name, holder, lease_until)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			holder = IF(lease_until <= ? OR holder = VALUES(holder), VALUES(holder), holder),
+			lease_until = IF(holder = VALUES(holder), VALUES(lease_until), lease_until)
+	`
+	if _, err := r.db.ExecContext(ctx, query, jobName, holder, until, now); err != nil {
+		return false, err
+	}
+
+	var owner string
+	err := r.db.QueryRowContext(ctx, `SELECT holder FROM job_leases WHERE job_name = ?`, jobName).Scan(&owner)
+	if err != nil {
+		return false, err
+	}
+	return owner == holder, nil
+}
@@ -0,0 +1,352 @@
+// File: internal/repository/mysql/lab_order_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"shifa/internal/models"
+)
+
+const labOrderColumns = `id, number, access_code, consultation_id, doctor_id, patient_id, kind, priority,
+		clinical_indication, notes, status, ordered_at, resulted_at, cancelled_at, cancel_reason`
+
+// LabOrderRepo represents the MySQL repository for lab and imaging orders
+type LabOrderRepo struct {
+	db *sql.DB
+}
+
+// NewLabOrderRepo creates a new LabOrderRepo instance
+func NewLabOrderRepo(db *sql.DB) *LabOrderRepo {
+	return &LabOrderRepo{db: db}
+}
+
+// Create inserts the order with its tests
+func (r *LabOrderRepo) Create(ctx context.Context, order *models.LabOrder) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO lab_orders (number, access_code, consultation_id, doctor_id, patient_id, kind, priority,
+			clinical_indication, notes, status, ordered_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, order.Number, order.AccessCode, order.ConsultationID, order.DoctorID, order.PatientID, order.Kind,
+		order.Priority, order.ClinicalIndication, order.Notes, order.Status, order.OrderedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for i := range order.Tests {
+		test := &order.Tests[i]
+		result, err = tx.ExecContext(ctx, `
+			INSERT INTO lab_order_tests (order_id, code, name, specimen, instructions)
+			VALUES (?, ?, ?, ?, ?)
+		`, id, test.Code, test.Name, test.Specimen, test.Instructions)
+		if err != nil {
+			return err
+		}
+		testID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		test.ID = int(testID)
+		test.OrderID = int(id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	order.ID = int(id)
+	return nil
+}
+
+// GetByID retrieves an order with its tests, results and reports
+func (r *LabOrderRepo) GetByID(ctx context.Context, id int) (*models.LabOrder, error) {
+	return r.getOne(ctx, `SELECT `+labOrderColumns+` FROM lab_orders WHERE id = ?`, id)
+}
+
+// GetByNumber retrieves an order by the number printed on its requisition
+func (r *LabOrderRepo) GetByNumber(ctx context.Context, number string) (*models.LabOrder, error) {
+	return r.getOne(ctx, `SELECT `+labOrderColumns+` FROM lab_orders WHERE number = ?`, number)
+}
+
+// ListByConsultation retrieves a consultation's orders, oldest first
+func (r *LabOrderRepo) ListByConsultation(ctx context.Context, consultationID int) ([]*models.LabOrder, error) {
+	query := `SELECT ` + labOrderColumns + ` FROM lab_orders WHERE consultation_id = ? ORDER BY ordered_at, id`
+	return r.list(ctx, query, consultationID)
+}
+
+// ListByPatient retrieves a patient's orders, newest first
+func (r *LabOrderRepo) ListByPatient(ctx context.Context, patientID int) ([]*models.LabOrder, error) {
+	query := `SELECT ` + labOrderColumns + ` FROM lab_orders WHERE patient_id = ? ORDER BY ordered_at DESC, id DESC`
+	return r.list(ctx, query, patientID)
+}
+
+// AddResults stores a batch of results and the report they came with. The order keeps the time
+// of its first results.
+func (r *LabOrderRepo) AddResults(ctx context.Context, orderID int, results []models.LabResult, report *models.LabReport, at time.Time) (ok bool, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE lab_orders SET status = 'resulted', resulted_at = COALESCE(resulted_at, ?)
+		WHERE id = ? AND status <> 'cancelled'
+	`, at, orderID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		// MySQL does not count rows left unchanged, so tell a cancelled order from a repeat upload
+		var status string
+		if err = tx.QueryRowContext(ctx, `SELECT status FROM lab_orders WHERE id = ?`, orderID).Scan(&status); err != nil {
+			return false, err
+		}
+		if status == models.LabOrderCancelled {
+			tx.Rollback()
+			return false, nil
+		}
+	}
+
+	for i := range results {
+		res := &results[i]
+		result, err = tx.ExecContext(ctx, `
+			INSERT INTO lab_results (order_id, test_id, analyte, value, unit, reference_low, reference_high,
+				flag, comment, resulted_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, orderID, res.TestID, res.Analyte, res.Value, res.Unit, res.ReferenceLow, res.ReferenceHigh,
+			res.Flag, res.Comment, res.ResultedAt)
+		if err != nil {
+			return false, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return false, err
+		}
+		res.ID = int(id)
+		res.OrderID = orderID
+	}
+
+	if report != nil {
+		result, err = tx.ExecContext(ctx, `
+			INSERT INTO lab_reports (order_id, file_name, file_path, uploaded_by, uploaded_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, orderID, report.FileName, report.FilePath, report.UploadedBy, report.UploadedAt)
+		if err != nil {
+			return false, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return false, err
+		}
+		report.ID = int(id)
+		report.OrderID = orderID
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Cancel cancels an order that has not been resulted
+func (r *LabOrderRepo) Cancel(ctx context.Context, id int, reason string, at time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE lab_orders SET status = 'cancelled', cancelled_at = ?, cancel_reason = ?
+		WHERE id = ? AND status = 'ordered'
+	`, at, reason, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// GetReport retrieves one of an order's uploaded reports
+func (r *LabOrderRepo) GetReport(ctx context.Context, orderID, reportID int) (*models.LabReport, error) {
+	var report models.LabReport
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, order_id, file_name, file_path, uploaded_by, uploaded_at
+		FROM lab_reports WHERE id = ? AND order_id = ?
+	`, reportID, orderID).Scan(&report.ID, &report.OrderID, &report.FileName, &report.FilePath,
+		&report.UploadedBy, &report.UploadedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("lab report not found")
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *LabOrderRepo) list(ctx context.Context, query string, args ...interface{}) ([]*models.LabOrder, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []*models.LabOrder{}
+	for rows.Next() {
+		order, err := scanLabOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		if err := r.loadLines(ctx, order); err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+func (r *LabOrderRepo) getOne(ctx context.Context, query string, arg interface{}) (*models.LabOrder, error) {
+	order, err := scanLabOrder(r.db.QueryRowContext(ctx, query, arg))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("lab order not found")
+		}
+		return nil, err
+	}
+	if err := r.loadLines(ctx, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// loadLines reads the order's tests, results and reports
+func (r *LabOrderRepo) loadLines(ctx context.Context, order *models.LabOrder) error {
+	var err error
+	if order.Tests, err = r.listTests(ctx, order.ID); err != nil {
+		return err
+	}
+	if order.Results, err = r.listResults(ctx, order.ID); err != nil {
+		return err
+	}
+	if order.Reports, err = r.listReports(ctx, order.ID); err != nil {
+		return err
+	}
+	order.AbnormalCount = 0
+	for _, result := range order.Results {
+		if result.Abnormal() {
+			order.AbnormalCount++
+		}
+	}
+	return nil
+}
+
+func (r *LabOrderRepo) listTests(ctx context.Context, orderID int) ([]models.LabOrderTest, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, order_id, code, name, specimen, instructions
+		FROM lab_order_tests WHERE order_id = ? ORDER BY id
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tests := []models.LabOrderTest{}
+	for rows.Next() {
+		var test models.LabOrderTest
+		if err := rows.Scan(&test.ID, &test.OrderID, &test.Code, &test.Name, &test.Specimen, &test.Instructions); err != nil {
+			return nil, err
+		}
+		tests = append(tests, test)
+	}
+	return tests, rows.Err()
+}
+
+func (r *LabOrderRepo) listResults(ctx context.Context, orderID int) ([]models.LabResult, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, order_id, test_id, analyte, value, unit, reference_low, reference_high, flag, comment, resulted_at
+		FROM lab_results WHERE order_id = ? ORDER BY resulted_at, id
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.LabResult
+	for rows.Next() {
+		var result models.LabResult
+		if err := rows.Scan(&result.ID, &result.OrderID, &result.TestID, &result.Analyte, &result.Value,
+			&result.Unit, &result.ReferenceLow, &result.ReferenceHigh, &result.Flag, &result.Comment,
+			&result.ResultedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+func (r *LabOrderRepo) listReports(ctx context.Context, orderID int) ([]models.LabReport, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, order_id, file_name, file_path, uploaded_by, uploaded_at
+		FROM lab_reports WHERE order_id = ? ORDER BY uploaded_at, id
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []models.LabReport
+	for rows.Next() {
+		var report models.LabReport
+		if err := rows.Scan(&report.ID, &report.OrderID, &report.FileName, &report.FilePath,
+			&report.UploadedBy, &report.UploadedAt); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+func scanLabOrder(row rowScanner) (*models.LabOrder, error) {
+	var (
+		order        models.LabOrder
+		cancelReason sql.NullString
+	)
+	err := row.Scan(
+		&order.ID, &order.Number, &order.AccessCode, &order.ConsultationID, &order.DoctorID, &order.PatientID,
+		&order.Kind, &order.Priority, &order.ClinicalIndication, &order.Notes, &order.Status, &order.OrderedAt,
+		&order.ResultedAt, &order.CancelledAt, &cancelReason,
+	)
+	if err != nil {
+		return nil, err
+	}
+	order.CancelReason = cancelReason.String
+	return &order, nil
+}
@@ -21,12 +21,14 @@ func NewMedicalHistoryRepo(db *sql.DB) *MedicalHistoryRepo {
 // Create inserts a new medical history record into the database
 func (r *MedicalHistoryRepo) Create(ctx context.Context, history *models.MedicalHistory) error {
 	query := `
-		INSERT INTO medical_history (patient_id, condition_name, diagnosis_date, treatment, is_current)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO medical_history (patient_id, condition_name, diagnosis_date, treatment, is_current, allergen,
+			condition_code, consultation_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	
 	result, err := r.db.ExecContext(ctx, query, 
-		history.PatientID, history.ConditionName, history.DiagnosisDate, history.Treatment, history.IsCurrent)
+		history.PatientID, history.ConditionName, history.DiagnosisDate, history.Treatment, history.IsCurrent, history.Allergen,
+		history.ConditionCode, history.ConsultationID)
 	if err != nil {
 		return err
 	}
@@ -43,7 +45,7 @@ func (r *MedicalHistoryRepo) Create(ctx context.Context, history *models.Medical
 // GetByID retrieves a medical history record by its ID
 func (r *MedicalHistoryRepo) GetByID(ctx context.Context, id int) (*models.MedicalHistory, error) {
 	query := `
-		SELECT id, patient_id, condition_name, diagnosis_date, treatment, is_current
+		SELECT id, patient_id, condition_name, diagnosis_date, treatment, is_current, allergen, condition_code, consultation_id
 		FROM medical_history
 		WHERE id = ?
 	`
@@ -51,7 +53,8 @@ func (r *MedicalHistoryRepo) GetByID(ctx context.Context, id int) (*models.Medic
 	var history models.MedicalHistory
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&history.ID, &history.PatientID, &history.ConditionName,
-		&history.DiagnosisDate, &history.Treatment, &history.IsCurrent,
+		&history.DiagnosisDate, &history.Treatment, &history.IsCurrent, &history.Allergen,
+		&history.ConditionCode, &history.ConsultationID,
 	)
 
 	if err != nil {
@@ -68,17 +71,49 @@ func (r *MedicalHistoryRepo) GetByID(ctx context.Context, id int) (*models.Medic
 func (r *MedicalHistoryRepo) Update(ctx context.Context, history *models.MedicalHistory) error {
 	query := `
 		UPDATE medical_history
-		SET condition_name = ?, diagnosis_date = ?, treatment = ?, is_current = ?
+		SET condition_name = ?, diagnosis_date = ?, treatment = ?, is_current = ?, allergen = ?, condition_code = ?
 		WHERE id = ?
 	`
 
 	_, err := r.db.ExecContext(ctx, query,
 		history.ConditionName, history.DiagnosisDate, history.Treatment,
-		history.IsCurrent, history.ID)
+		history.IsCurrent, history.Allergen, history.ConditionCode, history.ID)
 
 	return err
 }
 
+// CreateIfAbsent inserts a coded entry unless the patient already has a current entry with
+// the same condition code, reporting whether it was inserted
+func (r *MedicalHistoryRepo) CreateIfAbsent(ctx context.Context, history *models.MedicalHistory) (bool, error) {
+	query := `
+		INSERT INTO medical_history (patient_id, condition_name, diagnosis_date, treatment, is_current, allergen,
+			condition_code, consultation_id)
+		SELECT ?, ?, ?, ?, ?, ?, ?, ?
+		FROM DUAL
+		WHERE NOT EXISTS (
+			SELECT 1 FROM medical_history WHERE patient_id = ? AND condition_code = ? AND is_current
+		)
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		history.PatientID, history.ConditionName, history.DiagnosisDate, history.Treatment, history.IsCurrent, history.Allergen,
+		history.ConditionCode, history.ConsultationID, history.PatientID, history.ConditionCode)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	history.ID = int(id)
+	return true, nil
+}
+
 // Delete removes a medical history record from the database
 func (r *MedicalHistoryRepo) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM medical_history WHERE id = ?`
@@ -89,7 +124,7 @@ func (r *MedicalHistoryRepo) Delete(ctx context.Context, id int) error {
 // Change this method name
 func (r *MedicalHistoryRepo) GetByPatientID(ctx context.Context, patientID int) ([]*models.MedicalHistory, error) {
     query := `
-        SELECT id, patient_id, condition_name, diagnosis_date, treatment, is_current
+        SELECT id, patient_id, condition_name, diagnosis_date, treatment, is_current, allergen, condition_code, consultation_id
         FROM medical_history
         WHERE patient_id = ?
         ORDER BY diagnosis_date DESC
@@ -106,7 +141,8 @@ func (r *MedicalHistoryRepo) GetByPatientID(ctx context.Context, patientID int)
         var history models.MedicalHistory
         err := rows.Scan(
             &history.ID, &history.PatientID, &history.ConditionName,
-            &history.DiagnosisDate, &history.Treatment, &history.IsCurrent,
+            &history.DiagnosisDate, &history.Treatment, &history.IsCurrent, &history.Allergen,
+            &history.ConditionCode, &history.ConsultationID,
         )
         if err != nil {
             return nil, err
@@ -0,0 +1,252 @@
+// File: internal/repository/mysql/no_show_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"shifa/internal/models"
+)
+
+// NoShowRepo represents the MySQL repository for no-show records, policy and patient restrictions
+type NoShowRepo struct {
+	db *sql.DB
+}
+
+// NewNoShowRepo creates a new NoShowRepo instance
+func NewNoShowRepo(db *sql.DB) *NoShowRepo {
+	return &NoShowRepo{db: db}
+}
+
+// GetPolicy retrieves the no-show policy, or nil when none has been saved
+func (r *NoShowRepo) GetPolicy(ctx context.Context) (*models.NoShowPolicy, error) {
+	query := `
+		SELECT grace_period_minutes, warning_threshold, prepayment_threshold, updated_at
+		FROM no_show_policy
+		WHERE id = 1
+	`
+
+	var policy models.NoShowPolicy
+	err := r.db.QueryRowContext(ctx, query).Scan(
+		&policy.GracePeriodMinutes, &policy.WarningThreshold, &policy.PrepaymentThreshold, &policy.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SavePolicy creates or replaces the single no-show policy row
+func (r *NoShowRepo) SavePolicy(ctx context.Context, policy *models.NoShowPolicy) error {
+	query := `
+		INSERT INTO no_show_policy (id, grace_period_minutes, warning_threshold, prepayment_threshold)
+		VALUES (1, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			grace_period_minutes = VALUES(grace_period_minutes),
+			warning_threshold = VALUES(warning_threshold),
+			prepayment_threshold = VALUES(prepayment_threshold)
+	`
+
+	_, err := r.db.ExecContext(ctx, query, policy.GracePeriodMinutes, policy.WarningThreshold, policy.PrepaymentThreshold)
+	if err != nil {
+		return err
+	}
+	policy.UpdatedAt = time.Now()
+	return nil
+}
+
+// ListCandidates retrieves scheduled appointments past their grace period with nothing started for them.
+// A consultation counts when it started from an hour before the appointment up to the end of the grace
+// period; a home care visit counts when it is in progress or completed within 12 hours of the start.
+// Times are compared as absolute instants, since the appointment date is in the provider's zone.
+func (r *NoShowRepo) ListCandidates(ctx context.Context, now time.Time, grace time.Duration) ([]*models.Appointment, error) {
+	query := `
+		SELECT a.id, a.patient_id, a.provider_type, a.doctor_id, a.home_care_provider_id,
+			a.appointment_date, TIME_FORMAT(a.start_time, '%H:%i:%s'), TIME_FORMAT(a.end_time, '%H:%i:%s'),
+			a.status, a.cancellation_reason, a.created_at, a.updated_at, NULL,
+			a.starts_at, a.ends_at, a.time_zone
+		FROM appointments a
+		WHERE a.status = 'scheduled'
+		AND COALESCE(a.starts_at, TIMESTAMP(a.appointment_date, a.start_time)) <= ?
+		AND NOT EXISTS (
+			SELECT 1 FROM consultations c
+			WHERE a.provider_type = 'doctor'
+			AND c.patient_id = a.patient_id
+			AND c.doctor_id = a.doctor_id
+			AND c.started_at >= COALESCE(a.starts_at, TIMESTAMP(a.appointment_date, a.start_time)) - INTERVAL 60 MINUTE
+			AND c.started_at <= COALESCE(a.starts_at, TIMESTAMP(a.appointment_date, a.start_time)) + INTERVAL ? MINUTE
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM home_care_visits v
+			WHERE a.provider_type = 'home_care_provider'
+			AND v.patient_id = a.patient_id
+			AND v.provider_id = a.home_care_provider_id
+			AND v.status IN ('in_progress', 'completed')
+			AND ABS(TIMESTAMPDIFF(MINUTE, v.scheduled_start,
+				COALESCE(a.starts_at, TIMESTAMP(a.appointment_date, a.start_time)))) <= 720
+		)
+		ORDER BY COALESCE(a.starts_at, TIMESTAMP(a.appointment_date, a.start_time))
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now.Add(-grace), int(grace/time.Minute))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query no-show candidates: %w", err)
+	}
+	defer rows.Close()
+
+	return scanProviderAppointments(rows)
+}
+
+// Record inserts a no-show record and flips its appointment to no_show
+func (r *NoShowRepo) Record(ctx context.Context, record *models.NoShowRecord) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE appointments SET status = 'no_show', updated_at = ?, sequence = sequence + 1 WHERE id = ? AND status = 'scheduled'`,
+		time.Now(), record.AppointmentID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		err = errors.New("appointment is no longer scheduled")
+		return err
+	}
+
+	result, err = tx.ExecContext(ctx, `
+		INSERT INTO no_show_records (appointment_id, patient_id, provider_id, provider_type, appointment_date)
+		VALUES (?, ?, ?, ?, ?)
+	`, record.AppointmentID, record.PatientID, record.ProviderID, record.ProviderType,
+		record.AppointmentDate.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	record.ID = int(id)
+	record.RecordedAt = time.Now()
+	return nil
+}
+
+// ListByPatient retrieves a patient's no-show records, newest first
+func (r *NoShowRepo) ListByPatient(ctx context.Context, patientID int) ([]*models.NoShowRecord, error) {
+	query := `
+		SELECT id, appointment_id, patient_id, provider_id, provider_type, appointment_date, recorded_at
+		FROM no_show_records
+		WHERE patient_id = ?
+		ORDER BY appointment_date DESC, id DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*models.NoShowRecord
+	for rows.Next() {
+		var record models.NoShowRecord
+		err := rows.Scan(
+			&record.ID, &record.AppointmentID, &record.PatientID, &record.ProviderID,
+			&record.ProviderType, &record.AppointmentDate, &record.RecordedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// PatientStats counts a patient's completed and missed appointments
+func (r *NoShowRepo) PatientStats(ctx context.Context, patientID int) (*models.NoShowStats, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(status = 'no_show'), 0)
+		FROM appointments
+		WHERE patient_id = ? AND status IN ('completed', 'no_show')
+	`
+
+	stats := &models.NoShowStats{SubjectType: "patient", SubjectID: patientID}
+	if err := r.db.QueryRowContext(ctx, query, patientID).Scan(&stats.Appointments, &stats.NoShows); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// ProviderStats counts a provider's completed and missed appointments
+func (r *NoShowRepo) ProviderStats(ctx context.Context, providerID int, providerType string) (*models.NoShowStats, error) {
+	var providerColumn string
+	switch providerType {
+	case "doctor":
+		providerColumn = "doctor_id"
+	case "home_care_provider":
+		providerColumn = "home_care_provider_id"
+	default:
+		return nil, fmt.Errorf("unknown provider type: %s", providerType)
+	}
+
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(status = 'no_show'), 0)
+		FROM appointments
+		WHERE provider_type = ? AND ` + providerColumn + ` = ? AND status IN ('completed', 'no_show')
+	`
+
+	stats := &models.NoShowStats{SubjectType: providerType, SubjectID: providerID}
+	if err := r.db.QueryRowContext(ctx, query, providerType, providerID).Scan(&stats.Appointments, &stats.NoShows); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// IsPrepaymentRequired reports whether the patient must prepay before booking
+func (r *NoShowRepo) IsPrepaymentRequired(ctx context.Context, patientID int) (bool, error) {
+	query := `SELECT prepayment_required FROM patient_booking_restrictions WHERE patient_id = ?`
+
+	var required bool
+	err := r.db.QueryRowContext(ctx, query, patientID).Scan(&required)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return required, nil
+}
+
+// SetPrepaymentRequired sets or clears the patient's prepayment restriction
+func (r *NoShowRepo) SetPrepaymentRequired(ctx context.Context, patientID int, required bool) error {
+	query := `
+		INSERT INTO patient_booking_restrictions (patient_id, prepayment_required)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE prepayment_required = VALUES(prepayment_required)
+	`
+	_, err := r.db.ExecContext(ctx, query, patientID, required)
+	return err
+}
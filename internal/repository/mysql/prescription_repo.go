@@ -0,0 +1,347 @@
+// File: internal/repository/mysql/prescription_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"shifa/internal/models"
+)
+
+const prescriptionColumns = `id, number, verification_code, consultation_id, doctor_id, patient_id,
+		prescriber_name, prescriber_license, patient_name, status, notes, issued_at,
+		dispensed_at, dispensed_by, fills_dispensed, voided_at, void_reason`
+
+// PrescriptionRepo represents the MySQL repository for e-prescriptions
+type PrescriptionRepo struct {
+	db *sql.DB
+}
+
+// NewPrescriptionRepo creates a new PrescriptionRepo instance
+func NewPrescriptionRepo(db *sql.DB) *PrescriptionRepo {
+	return &PrescriptionRepo{db: db}
+}
+
+// Create inserts the prescription with its medication lines and warning overrides together
+func (r *PrescriptionRepo) Create(ctx context.Context, p *models.Prescription) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO prescriptions (number, verification_code, consultation_id, doctor_id, patient_id,
+			prescriber_name, prescriber_license, patient_name, status, notes, issued_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.Number, p.VerificationCode, p.ConsultationID, p.DoctorID, p.PatientID,
+		p.PrescriberName, p.PrescriberLicense, p.PatientName, p.Status, p.Notes, p.IssuedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for i := range p.Items {
+		item := &p.Items[i]
+		result, err = tx.ExecContext(ctx, `
+			INSERT INTO prescription_items (prescription_id, drug_name, strength, form, dose, frequency,
+				duration_days, quantity, refills, instructions)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, id, item.DrugName, item.Strength, item.Form, item.Dose, item.Frequency,
+			item.DurationDays, item.Quantity, item.Refills, item.Instructions)
+		if err != nil {
+			return err
+		}
+		itemID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		item.ID = int(itemID)
+		item.PrescriptionID = int(id)
+	}
+
+	for i := range p.Overrides {
+		override := &p.Overrides[i]
+		result, err = tx.ExecContext(ctx, `
+			INSERT INTO prescription_warning_overrides (prescription_id, warning_key, warning_type, severity,
+				description, reason, doctor_id, overridden_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, id, override.WarningKey, override.WarningType, override.Severity,
+			override.Description, override.Reason, override.DoctorID, override.OverriddenAt)
+		if err != nil {
+			return err
+		}
+		overrideID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		override.ID = int(overrideID)
+		override.PrescriptionID = int(id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	p.ID = int(id)
+	return nil
+}
+
+// GetByID retrieves a prescription with its items by ID
+func (r *PrescriptionRepo) GetByID(ctx context.Context, id int) (*models.Prescription, error) {
+	query := `SELECT ` + prescriptionColumns + ` FROM prescriptions WHERE id = ?`
+	return r.getOne(ctx, query, id)
+}
+
+// GetByNumber retrieves a prescription with its items by its prescription number
+func (r *PrescriptionRepo) GetByNumber(ctx context.Context, number string) (*models.Prescription, error) {
+	query := `SELECT ` + prescriptionColumns + ` FROM prescriptions WHERE number = ?`
+	return r.getOne(ctx, query, number)
+}
+
+// ListByConsultation retrieves a consultation's prescriptions, oldest first
+func (r *PrescriptionRepo) ListByConsultation(ctx context.Context, consultationID int) ([]*models.Prescription, error) {
+	query := `SELECT ` + prescriptionColumns + ` FROM prescriptions WHERE consultation_id = ? ORDER BY issued_at, id`
+	return r.list(ctx, query, consultationID)
+}
+
+// ListByPatient retrieves the patient's prescriptions that were issued since the given time and not voided
+func (r *PrescriptionRepo) ListByPatient(ctx context.Context, patientID int, issuedSince time.Time) ([]*models.Prescription, error) {
+	query := `SELECT ` + prescriptionColumns + ` FROM prescriptions
+		WHERE patient_id = ? AND issued_at >= ? AND status <> 'void'
+		ORDER BY issued_at, id`
+	return r.list(ctx, query, patientID, issuedSince)
+}
+
+// Dispense records the next fill of an active prescription by the named pharmacy. The row is
+// locked while the fill is counted, so concurrent fills cannot exceed fillsAllowed.
+func (r *PrescriptionRepo) Dispense(ctx context.Context, id, fillsAllowed int, dispensedBy string, at time.Time) (fill *models.PrescriptionFill, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil || fill == nil {
+			tx.Rollback()
+		}
+	}()
+
+	var (
+		status string
+		fills  int
+	)
+	err = tx.QueryRowContext(ctx, `SELECT status, fills_dispensed FROM prescriptions WHERE id = ? FOR UPDATE`, id).
+		Scan(&status, &fills)
+	if err != nil {
+		return nil, err
+	}
+	if status != models.PrescriptionActive || fills >= fillsAllowed {
+		return nil, nil
+	}
+
+	fill = &models.PrescriptionFill{PrescriptionID: id, FillNumber: fills + 1, DispensedBy: dispensedBy, DispensedAt: at}
+	status = models.PrescriptionActive
+	if fill.FillNumber >= fillsAllowed {
+		status = models.PrescriptionDispensed
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE prescriptions SET status = ?, fills_dispensed = ?, dispensed_at = ?, dispensed_by = ?
+		WHERE id = ?
+	`, status, fill.FillNumber, at, dispensedBy, id)
+	if err != nil {
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO prescription_fills (prescription_id, fill_number, dispensed_by, dispensed_at)
+		VALUES (?, ?, ?, ?)
+	`, id, fill.FillNumber, dispensedBy, at)
+	if err != nil {
+		return nil, err
+	}
+	fillID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	fill.ID = int(fillID)
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return fill, nil
+}
+
+// Void cancels an active prescription
+func (r *PrescriptionRepo) Void(ctx context.Context, id int, reason string, at time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE prescriptions SET status = 'void', voided_at = ?, void_reason = ?
+		WHERE id = ? AND status = 'active'
+	`, at, reason, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (r *PrescriptionRepo) list(ctx context.Context, query string, args ...interface{}) ([]*models.Prescription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prescriptions []*models.Prescription
+	for rows.Next() {
+		p, err := scanPrescription(rows)
+		if err != nil {
+			return nil, err
+		}
+		prescriptions = append(prescriptions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, p := range prescriptions {
+		if err := r.loadLines(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+	return prescriptions, nil
+}
+
+func (r *PrescriptionRepo) getOne(ctx context.Context, query string, arg interface{}) (*models.Prescription, error) {
+	p, err := scanPrescription(r.db.QueryRowContext(ctx, query, arg))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("prescription not found")
+		}
+		return nil, err
+	}
+	if err := r.loadLines(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// loadLines reads the prescription's items, warning overrides and fills
+func (r *PrescriptionRepo) loadLines(ctx context.Context, p *models.Prescription) error {
+	var err error
+	if p.Items, err = r.listItems(ctx, p.ID); err != nil {
+		return err
+	}
+	if p.Overrides, err = r.listOverrides(ctx, p.ID); err != nil {
+		return err
+	}
+	p.Fills, err = r.listFills(ctx, p.ID)
+	return err
+}
+
+func (r *PrescriptionRepo) listItems(ctx context.Context, prescriptionID int) ([]models.PrescriptionItem, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, prescription_id, drug_name, strength, form, dose, frequency,
+			duration_days, quantity, refills, instructions
+		FROM prescription_items
+		WHERE prescription_id = ?
+		ORDER BY id
+	`, prescriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.PrescriptionItem{}
+	for rows.Next() {
+		var item models.PrescriptionItem
+		err := rows.Scan(
+			&item.ID, &item.PrescriptionID, &item.DrugName, &item.Strength, &item.Form, &item.Dose,
+			&item.Frequency, &item.DurationDays, &item.Quantity, &item.Refills, &item.Instructions,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *PrescriptionRepo) listOverrides(ctx context.Context, prescriptionID int) ([]models.WarningOverride, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, prescription_id, warning_key, warning_type, severity, description, reason, doctor_id, overridden_at
+		FROM prescription_warning_overrides
+		WHERE prescription_id = ?
+		ORDER BY id
+	`, prescriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []models.WarningOverride
+	for rows.Next() {
+		var o models.WarningOverride
+		err := rows.Scan(
+			&o.ID, &o.PrescriptionID, &o.WarningKey, &o.WarningType, &o.Severity,
+			&o.Description, &o.Reason, &o.DoctorID, &o.OverriddenAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+func (r *PrescriptionRepo) listFills(ctx context.Context, prescriptionID int) ([]models.PrescriptionFill, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, prescription_id, fill_number, dispensed_by, dispensed_at
+		FROM prescription_fills
+		WHERE prescription_id = ?
+		ORDER BY fill_number
+	`, prescriptionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fills []models.PrescriptionFill
+	for rows.Next() {
+		var f models.PrescriptionFill
+		if err := rows.Scan(&f.ID, &f.PrescriptionID, &f.FillNumber, &f.DispensedBy, &f.DispensedAt); err != nil {
+			return nil, err
+		}
+		fills = append(fills, f)
+	}
+	return fills, rows.Err()
+}
+
+func scanPrescription(row rowScanner) (*models.Prescription, error) {
+	var (
+		p           models.Prescription
+		dispensedBy sql.NullString
+		voidReason  sql.NullString
+	)
+	err := row.Scan(
+		&p.ID, &p.Number, &p.VerificationCode, &p.ConsultationID, &p.DoctorID, &p.PatientID,
+		&p.PrescriberName, &p.PrescriberLicense, &p.PatientName, &p.Status, &p.Notes, &p.IssuedAt,
+		&p.DispensedAt, &dispensedBy, &p.FillsDispensed, &p.VoidedAt, &voidReason,
+	)
+	if err != nil {
+		return nil, err
+	}
+	p.DispensedBy = dispensedBy.String
+	p.VoidReason = voidReason.String
+	return &p, nil
+}
@@ -0,0 +1,165 @@
+// File: internal/repository/mysql/referral_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"shifa/internal/models"
+)
+
+const referralColumns = `id, consultation_id, patient_id, referring_doctor_id, target_type, target_specialty,
+		target_service_type_id, recipient_id, reason, urgency, clinical_summary, status,
+		responder_id, response_note, responded_at, appointment_id, created_at, updated_at`
+
+// referralOrder puts the most urgent referrals first, oldest first within an urgency
+const referralOrder = ` ORDER BY FIELD(urgency, 'emergency', 'urgent', 'routine'), created_at, id`
+
+// ReferralRepo represents the MySQL repository for referrals
+type ReferralRepo struct {
+	db *sql.DB
+}
+
+// NewReferralRepo creates a new ReferralRepo instance
+func NewReferralRepo(db *sql.DB) *ReferralRepo {
+	return &ReferralRepo{db: db}
+}
+
+// Create inserts a new referral
+func (r *ReferralRepo) Create(ctx context.Context, referral *models.Referral) error {
+	now := time.Now().UTC().Truncate(time.Second)
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO referrals (consultation_id, patient_id, referring_doctor_id, target_type, target_specialty,
+			target_service_type_id, recipient_id, reason, urgency, clinical_summary, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, referral.ConsultationID, referral.PatientID, referral.ReferringDoctorID, referral.TargetType,
+		referral.TargetSpecialty, referral.TargetServiceTypeID, referral.RecipientID, referral.Reason,
+		referral.Urgency, referral.ClinicalSummary, referral.Status, now, now)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	referral.ID = int(id)
+	referral.CreatedAt = now
+	referral.UpdatedAt = now
+	return nil
+}
+
+// GetByID retrieves a referral by its ID
+func (r *ReferralRepo) GetByID(ctx context.Context, id int) (*models.Referral, error) {
+	referral, err := scanReferral(r.db.QueryRowContext(ctx, `SELECT `+referralColumns+` FROM referrals WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("referral not found")
+		}
+		return nil, err
+	}
+	return referral, nil
+}
+
+// ListSent retrieves the referrals written by a doctor, newest first
+func (r *ReferralRepo) ListSent(ctx context.Context, doctorID int, status string) ([]*models.Referral, error) {
+	query := `SELECT ` + referralColumns + ` FROM referrals
+		WHERE referring_doctor_id = ? AND (? = '' OR status = ?)
+		ORDER BY created_at DESC, id DESC`
+	return r.list(ctx, query, doctorID, status, status)
+}
+
+// ListByPatient retrieves a patient's referrals, newest first
+func (r *ReferralRepo) ListByPatient(ctx context.Context, patientID int) ([]*models.Referral, error) {
+	query := `SELECT ` + referralColumns + ` FROM referrals WHERE patient_id = ? ORDER BY created_at DESC, id DESC`
+	return r.list(ctx, query, patientID)
+}
+
+// ListInbox retrieves the referrals addressed to or answered by the receiver, plus the pending
+// referrals of their pool
+func (r *ReferralRepo) ListInbox(ctx context.Context, inbox models.ReferralInbox) ([]*models.Referral, error) {
+	query := `SELECT ` + referralColumns + ` FROM referrals
+		WHERE (recipient_id = ? OR responder_id = ?
+			OR (recipient_id IS NULL AND status = 'pending' AND target_type = ?
+				AND (target_specialty = ? AND target_type = 'doctor'
+					OR target_service_type_id = ? AND target_type = 'home_care_provider')))
+			AND (? = '' OR status = ?)` + referralOrder
+	return r.list(ctx, query, inbox.UserID, inbox.UserID, inbox.TargetType,
+		inbox.Specialty, inbox.ServiceTypeID, inbox.Status, inbox.Status)
+}
+
+// Respond records the receiver's answer to a pending referral. Referrals addressed to someone
+// else are left alone.
+func (r *ReferralRepo) Respond(ctx context.Context, id int, status string, receiverID int, note string, at time.Time) (bool, error) {
+	return r.update(ctx, `
+		UPDATE referrals SET status = ?, responder_id = ?, response_note = ?, responded_at = ?, updated_at = ?
+		WHERE id = ? AND status = 'pending' AND (recipient_id IS NULL OR recipient_id = ?)
+	`, status, receiverID, note, at, at, id, receiverID)
+}
+
+// Cancel withdraws a referral that has not been declined, booked or cancelled
+func (r *ReferralRepo) Cancel(ctx context.Context, id int, at time.Time) (bool, error) {
+	return r.update(ctx, `
+		UPDATE referrals SET status = 'cancelled', updated_at = ?
+		WHERE id = ? AND status IN ('pending', 'accepted')
+	`, at, id)
+}
+
+// MarkBooked links an accepted referral to the appointment booked for it
+func (r *ReferralRepo) MarkBooked(ctx context.Context, id, appointmentID int, at time.Time) (bool, error) {
+	return r.update(ctx, `
+		UPDATE referrals SET status = 'booked', appointment_id = ?, updated_at = ?
+		WHERE id = ? AND status = 'accepted'
+	`, appointmentID, at, id)
+}
+
+func (r *ReferralRepo) update(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (r *ReferralRepo) list(ctx context.Context, query string, args ...interface{}) ([]*models.Referral, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	referrals := []*models.Referral{}
+	for rows.Next() {
+		referral, err := scanReferral(rows)
+		if err != nil {
+			return nil, err
+		}
+		referrals = append(referrals, referral)
+	}
+	return referrals, rows.Err()
+}
+
+func scanReferral(row rowScanner) (*models.Referral, error) {
+	var (
+		referral     models.Referral
+		responseNote sql.NullString
+	)
+	err := row.Scan(
+		&referral.ID, &referral.ConsultationID, &referral.PatientID, &referral.ReferringDoctorID,
+		&referral.TargetType, &referral.TargetSpecialty, &referral.TargetServiceTypeID, &referral.RecipientID,
+		&referral.Reason, &referral.Urgency, &referral.ClinicalSummary, &referral.Status,
+		&referral.ResponderID, &responseNote, &referral.RespondedAt, &referral.AppointmentID,
+		&referral.CreatedAt, &referral.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	referral.ResponseNote = responseNote.String
+	return &referral, nil
+}
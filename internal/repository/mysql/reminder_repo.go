@@ -0,0 +1,96 @@
+// File: internal/repository/mysql/reminder_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"shifa/internal/models"
+)
+
+// ReminderRepo represents the MySQL repository for sent appointment reminders
+type ReminderRepo struct {
+	db *sql.DB
+}
+
+// NewReminderRepo creates a new ReminderRepo instance
+func NewReminderRepo(db *sql.DB) *ReminderRepo {
+	return &ReminderRepo{db: db}
+}
+
+// ListDue retrieves scheduled appointments starting in (from, to] for which the patient or
+// the provider has not received a reminder of this type
+func (r *ReminderRepo) ListDue(ctx context.Context, reminderType string, from, to time.Time) ([]*models.Appointment, error) {
+	query := `
+		SELECT a.id, a.patient_id, a.provider_type, a.doctor_id, a.home_care_provider_id,
+			a.appointment_date, TIME_FORMAT(a.start_time, '%H:%i:%s'), TIME_FORMAT(a.end_time, '%H:%i:%s'),
+			a.status, a.cancellation_reason, a.created_at, a.updated_at,
+			COALESCE(u.name, 'Unknown Patient'), a.starts_at, a.ends_at, a.time_zone
+		FROM appointments a
+		LEFT JOIN users u ON u.id = a.patient_id
+		WHERE a.status = 'scheduled'
+		AND COALESCE(a.starts_at, TIMESTAMP(a.appointment_date, a.start_time)) > ?
+		AND COALESCE(a.starts_at, TIMESTAMP(a.appointment_date, a.start_time)) <= ?
+		AND (
+			NOT EXISTS (
+				SELECT 1 FROM appointment_reminders ar
+				WHERE ar.appointment_id = a.id AND ar.reminder_type = ? AND ar.recipient_id = a.patient_id
+			)
+			OR (
+				COALESCE(a.doctor_id, a.home_care_provider_id) IS NOT NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM appointment_reminders ar
+					WHERE ar.appointment_id = a.id AND ar.reminder_type = ?
+					AND ar.recipient_id = COALESCE(a.doctor_id, a.home_care_provider_id)
+				)
+			)
+		)
+		ORDER BY COALESCE(a.starts_at, TIMESTAMP(a.appointment_date, a.start_time))
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, from, to, reminderType, reminderType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due reminders: %w", err)
+	}
+	defer rows.Close()
+
+	return scanProviderAppointments(rows)
+}
+
+// Claim inserts the reminder record; the unique key turns a duplicate into a no-op
+func (r *ReminderRepo) Claim(ctx context.Context, appointmentID int, reminderType string, recipientID int) (bool, error) {
+	query := `
+		INSERT IGNORE INTO appointment_reminders (appointment_id, reminder_type, recipient_id)
+		VALUES (?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, appointmentID, reminderType, recipientID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// Unclaim deletes a reminder record
+func (r *ReminderRepo) Unclaim(ctx context.Context, appointmentID int, reminderType string, recipientID int) error {
+	query := `
+		DELETE FROM appointment_reminders
+		WHERE appointment_id = ? AND reminder_type = ? AND recipient_id = ?
+	`
+	_, err := r.db.ExecContext(ctx, query, appointmentID, reminderType, recipientID)
+	return err
+}
+
+// DeleteByAppointment deletes every reminder record of the appointment
+func (r *ReminderRepo) DeleteByAppointment(ctx context.Context, appointmentID int) error {
+	query := `DELETE FROM appointment_reminders WHERE appointment_id = ?`
+	_, err := r.db.ExecContext(ctx, query, appointmentID)
+	return err
+}
@@ -27,11 +27,11 @@ func NewUserRepo(db *sql.DB) *UserRepo {
 // Create inserts a new user into the database
 func (r *UserRepo) Create(ctx context.Context, user *models.User) error {
 	query := `
-		INSERT INTO users (email, password_hash, name, role)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO users (email, password_hash, name, role, time_zone, language)
+		VALUES (?, ?, ?, ?, COALESCE(NULLIF(?, ''), 'UTC'), COALESCE(NULLIF(?, ''), 'en'))
 	`
 	
-	result, err := r.db.ExecContext(ctx, query, user.Email, user.PasswordHash, user.Name, user.Role)
+	result, err := r.db.ExecContext(ctx, query, user.Email, user.PasswordHash, user.Name, user.Role, user.TimeZone, user.Language)
 	if err != nil {
 		return err
 	}
@@ -48,7 +48,7 @@ func (r *UserRepo) Create(ctx context.Context, user *models.User) error {
 // GetByID retrieves a user by their ID
 func (r *UserRepo) GetByID(ctx context.Context, id int) (*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, name, role, created_at, updated_at
+		SELECT id, email, password_hash, name, role, time_zone, language, created_at, updated_at
 		FROM users
 		WHERE id = ?
 	`
@@ -60,6 +60,8 @@ func (r *UserRepo) GetByID(ctx context.Context, id int) (*models.User, error) {
 		&user.PasswordHash,
 		&user.Name,
 		&user.Role,
+		&user.TimeZone,
+		&user.Language,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -77,7 +79,7 @@ func (r *UserRepo) GetByID(ctx context.Context, id int) (*models.User, error) {
 // GetByEmail retrieves a user by their email address
 func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, name, role, created_at, updated_at
+		SELECT id, email, password_hash, name, role, time_zone, language, created_at, updated_at
 		FROM users
 		WHERE email = ?
 	`
@@ -89,6 +91,8 @@ func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*models.User,
 		&user.PasswordHash,
 		&user.Name,
 		&user.Role,
+		&user.TimeZone,
+		&user.Language,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -107,11 +111,13 @@ func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*models.User,
 func (r *UserRepo) Update(ctx context.Context, user *models.User) error {
 	query := `
 		UPDATE users
-		SET email = ?, password_hash = ?, name = ?, role = ?, updated_at = ?
+		SET email = ?, password_hash = ?, name = ?, role = ?,
+			time_zone = COALESCE(NULLIF(?, ''), time_zone),
+			language = COALESCE(NULLIF(?, ''), language), updated_at = ?
 		WHERE id = ?
 	`
 
-	_, err := r.db.ExecContext(ctx, query, user.Email, user.PasswordHash, user.Name, user.Role, time.Now(), user.ID)
+	_, err := r.db.ExecContext(ctx, query, user.Email, user.PasswordHash, user.Name, user.Role, user.TimeZone, user.Language, time.Now(), user.ID)
 	return err
 }
 
@@ -125,7 +131,7 @@ func (r *UserRepo) Delete(ctx context.Context, id int) error {
 // List retrieves a list of users with optional pagination
 func (r *UserRepo) List(ctx context.Context, offset, limit int) ([]*models.User, error) {
 	query := `
-		SELECT id, email, password_hash, name, role, created_at, updated_at
+		SELECT id, email, password_hash, name, role, time_zone, language, created_at, updated_at
 		FROM users
 		ORDER BY id
 		LIMIT ? OFFSET ?
@@ -146,6 +152,8 @@ func (r *UserRepo) List(ctx context.Context, offset, limit int) ([]*models.User,
 			&user.PasswordHash,
 			&user.Name,
 			&user.Role,
+			&user.TimeZone,
+			&user.Language,
 			&user.CreatedAt,
 			&user.UpdatedAt,
 		)
@@ -0,0 +1,85 @@
+// File: internal/repository/mysql/video_session_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"shifa/internal/models"
+)
+
+// VideoSessionRepo represents the MySQL repository for consultation video sessions
+type VideoSessionRepo struct {
+	db *sql.DB
+}
+
+// NewVideoSessionRepo creates a new VideoSessionRepo instance
+func NewVideoSessionRepo(db *sql.DB) *VideoSessionRepo {
+	return &VideoSessionRepo{db: db}
+}
+
+// GetOrCreate inserts a waiting session unless the consultation already has one, then reads it back
+func (r *VideoSessionRepo) GetOrCreate(ctx context.Context, consultationID int) (*models.VideoSession, error) {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT IGNORE INTO video_sessions (consultation_id, status) VALUES (?, ?)`,
+		consultationID, models.VideoSessionWaiting)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByConsultationID(ctx, consultationID)
+}
+
+// GetByConsultationID retrieves the video session of a consultation
+func (r *VideoSessionRepo) GetByConsultationID(ctx context.Context, consultationID int) (*models.VideoSession, error) {
+	query := `
+		SELECT id, consultation_id, status, connected_at, ended_at, duration_seconds, created_at
+		FROM video_sessions
+		WHERE consultation_id = ?
+	`
+
+	var session models.VideoSession
+	err := r.db.QueryRowContext(ctx, query, consultationID).Scan(
+		&session.ID, &session.ConsultationID, &session.Status, &session.ConnectedAt,
+		&session.EndedAt, &session.DurationSeconds, &session.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("video session not found")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Save updates the session and the consultation's video duration together
+func (r *VideoSessionRepo) Save(ctx context.Context, session *models.VideoSession) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE video_sessions
+		SET status = ?, connected_at = ?, ended_at = ?, duration_seconds = ?
+		WHERE id = ?
+	`, session.Status, session.ConnectedAt, session.EndedAt, session.DurationSeconds, session.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE consultations SET video_duration_seconds = ? WHERE id = ?`,
+		session.DurationSeconds, session.ConsultationID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,259 @@
+// File: internal/repository/mysql/waitlist_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"shifa/internal/models"
+)
+
+const waitlistEntryColumns = `id, patient_id, provider_id, provider_type, from_date, to_date, status, created_at`
+
+const waitlistOfferColumns = `id, entry_id, patient_id, provider_id, provider_type, appointment_date, start_time,
+		end_time, status, expires_at, appointment_id, created_at`
+
+// WaitlistRepo represents the MySQL repository for waitlist entries and slot offers
+type WaitlistRepo struct {
+	db *sql.DB
+}
+
+// NewWaitlistRepo creates a new WaitlistRepo instance
+func NewWaitlistRepo(db *sql.DB) *WaitlistRepo {
+	return &WaitlistRepo{db: db}
+}
+
+// CreateEntry inserts a new waitlist entry into the database
+func (r *WaitlistRepo) CreateEntry(ctx context.Context, entry *models.WaitlistEntry) error {
+	query := `
+		INSERT INTO waitlist_entries (patient_id, provider_id, provider_type, from_date, to_date, status)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		entry.PatientID, entry.ProviderID, entry.ProviderType,
+		entry.FromDate.Format("2006-01-02"), entry.ToDate.Format("2006-01-02"), entry.Status)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	entry.ID = int(id)
+	entry.CreatedAt = time.Now()
+	return nil
+}
+
+// GetEntryByID retrieves a waitlist entry by its ID
+func (r *WaitlistRepo) GetEntryByID(ctx context.Context, id int) (*models.WaitlistEntry, error) {
+	query := `SELECT ` + waitlistEntryColumns + ` FROM waitlist_entries WHERE id = ?`
+
+	entry, err := scanWaitlistEntry(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("waitlist entry not found")
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// UpdateEntryStatus changes the status of a waitlist entry
+func (r *WaitlistRepo) UpdateEntryStatus(ctx context.Context, id int, status string) error {
+	query := `UPDATE waitlist_entries SET status = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, status, id)
+	return err
+}
+
+// ListEntriesByPatient retrieves all waitlist entries of a patient, newest first
+func (r *WaitlistRepo) ListEntriesByPatient(ctx context.Context, patientID int) ([]*models.WaitlistEntry, error) {
+	query := `
+		SELECT ` + waitlistEntryColumns + `
+		FROM waitlist_entries
+		WHERE patient_id = ?
+		ORDER BY created_at DESC, id DESC
+	`
+	return r.queryEntries(ctx, query, patientID)
+}
+
+// ListWaitingForDate retrieves waiting entries for a provider whose date range covers date, in queue order
+func (r *WaitlistRepo) ListWaitingForDate(ctx context.Context, providerID int, providerType string, date time.Time) ([]*models.WaitlistEntry, error) {
+	query := `
+		SELECT ` + waitlistEntryColumns + `
+		FROM waitlist_entries
+		WHERE provider_id = ? AND provider_type = ? AND status = ?
+		AND from_date <= ? AND to_date >= ?
+		ORDER BY created_at, id
+	`
+	day := date.Format("2006-01-02")
+	return r.queryEntries(ctx, query, providerID, providerType, models.WaitlistStatusWaiting, day, day)
+}
+
+// CreateOffer inserts a new slot offer into the database
+func (r *WaitlistRepo) CreateOffer(ctx context.Context, offer *models.WaitlistOffer) error {
+	query := `
+		INSERT INTO waitlist_offers (entry_id, patient_id, provider_id, provider_type,
+			appointment_date, start_time, end_time, status, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		offer.EntryID, offer.PatientID, offer.ProviderID, offer.ProviderType,
+		offer.AppointmentDate.Format("2006-01-02"), offer.StartTime, offer.EndTime, offer.Status, offer.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	offer.ID = int(id)
+	offer.CreatedAt = time.Now()
+	return nil
+}
+
+// GetOfferByID retrieves a slot offer by its ID
+func (r *WaitlistRepo) GetOfferByID(ctx context.Context, id int) (*models.WaitlistOffer, error) {
+	query := `SELECT ` + waitlistOfferColumns + ` FROM waitlist_offers WHERE id = ?`
+
+	offer, err := scanWaitlistOffer(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("waitlist offer not found")
+		}
+		return nil, err
+	}
+	return offer, nil
+}
+
+// UpdateOffer saves the status and booked appointment of a slot offer
+func (r *WaitlistRepo) UpdateOffer(ctx context.Context, offer *models.WaitlistOffer) error {
+	query := `UPDATE waitlist_offers SET status = ?, appointment_id = ? WHERE id = ?`
+	_, err := r.db.ExecContext(ctx, query, offer.Status, offer.AppointmentID, offer.ID)
+	return err
+}
+
+// ListOffersByPatient retrieves all slot offers made to a patient, newest first
+func (r *WaitlistRepo) ListOffersByPatient(ctx context.Context, patientID int) ([]*models.WaitlistOffer, error) {
+	query := `
+		SELECT ` + waitlistOfferColumns + `
+		FROM waitlist_offers
+		WHERE patient_id = ?
+		ORDER BY created_at DESC, id DESC
+	`
+	return r.queryOffers(ctx, query, patientID)
+}
+
+// ListOffersForSlot retrieves every offer made for one slot
+func (r *WaitlistRepo) ListOffersForSlot(ctx context.Context, providerID int, providerType string, date, start time.Time) ([]*models.WaitlistOffer, error) {
+	query := `
+		SELECT ` + waitlistOfferColumns + `
+		FROM waitlist_offers
+		WHERE provider_id = ? AND provider_type = ? AND appointment_date = ? AND start_time = ?
+		ORDER BY created_at, id
+	`
+	return r.queryOffers(ctx, query, providerID, providerType, date.Format("2006-01-02"), start.Format("15:04:05"))
+}
+
+// ListActiveOffers retrieves pending offers on a date that are still within their hold
+func (r *WaitlistRepo) ListActiveOffers(ctx context.Context, providerID int, providerType string, date, now time.Time) ([]*models.WaitlistOffer, error) {
+	query := `
+		SELECT ` + waitlistOfferColumns + `
+		FROM waitlist_offers
+		WHERE provider_id = ? AND provider_type = ? AND appointment_date = ?
+		AND status = ? AND expires_at > ?
+		ORDER BY start_time
+	`
+	return r.queryOffers(ctx, query, providerID, providerType, date.Format("2006-01-02"), models.OfferStatusPending, now)
+}
+
+// ListExpiredOffers retrieves pending offers whose hold has run out
+func (r *WaitlistRepo) ListExpiredOffers(ctx context.Context, now time.Time) ([]*models.WaitlistOffer, error) {
+	query := `
+		SELECT ` + waitlistOfferColumns + `
+		FROM waitlist_offers
+		WHERE status = ? AND expires_at <= ?
+		ORDER BY expires_at
+	`
+	return r.queryOffers(ctx, query, models.OfferStatusPending, now)
+}
+
+func (r *WaitlistRepo) queryEntries(ctx context.Context, query string, args ...interface{}) ([]*models.WaitlistEntry, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.WaitlistEntry
+	for rows.Next() {
+		entry, err := scanWaitlistEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *WaitlistRepo) queryOffers(ctx context.Context, query string, args ...interface{}) ([]*models.WaitlistOffer, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var offers []*models.WaitlistOffer
+	for rows.Next() {
+		offer, err := scanWaitlistOffer(rows)
+		if err != nil {
+			return nil, err
+		}
+		offers = append(offers, offer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return offers, nil
+}
+
+func scanWaitlistEntry(row rowScanner) (*models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	err := row.Scan(
+		&entry.ID, &entry.PatientID, &entry.ProviderID, &entry.ProviderType,
+		&entry.FromDate, &entry.ToDate, &entry.Status, &entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func scanWaitlistOffer(row rowScanner) (*models.WaitlistOffer, error) {
+	var offer models.WaitlistOffer
+	var appointmentID sql.NullInt64
+	err := row.Scan(
+		&offer.ID, &offer.EntryID, &offer.PatientID, &offer.ProviderID, &offer.ProviderType,
+		&offer.AppointmentDate, &offer.StartTime, &offer.EndTime,
+		&offer.Status, &offer.ExpiresAt, &appointmentID, &offer.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if appointmentID.Valid {
+		id := int(appointmentID.Int64)
+		offer.AppointmentID = &id
+	}
+	return &offer, nil
+}
@@ -0,0 +1,244 @@
+// File: internal/repository/mysql/walk_in_queue_repo.go
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"shifa/internal/models"
+)
+
+const queueEntryColumns = `id, doctor_id, patient_id, queue_date, ticket_number, source, status,
+		joined_at, called_at, finished_at`
+
+// WalkInQueueRepo represents the MySQL repository for walk-in queue entries
+type WalkInQueueRepo struct {
+	db *sql.DB
+}
+
+// NewWalkInQueueRepo creates a new WalkInQueueRepo instance
+func NewWalkInQueueRepo(db *sql.DB) *WalkInQueueRepo {
+	return &WalkInQueueRepo{db: db}
+}
+
+// Join locks the day's queue while taking the next ticket number, so concurrent joins never share one
+func (r *WalkInQueueRepo) Join(ctx context.Context, entry *models.QueueEntry) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	date := entry.QueueDate.Format("2006-01-02")
+	var last int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(ticket_number), 0)
+		FROM walk_in_queue_entries
+		WHERE doctor_id = ? AND queue_date = ?
+		FOR UPDATE
+	`, entry.DoctorID, date).Scan(&last)
+	if err != nil {
+		return err
+	}
+
+	entry.TicketNumber = last + 1
+	entry.JoinedAt = time.Now()
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO walk_in_queue_entries (doctor_id, patient_id, queue_date, ticket_number, source, status, joined_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, entry.DoctorID, entry.PatientID, date, entry.TicketNumber, entry.Source, entry.Status, entry.JoinedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	entry.ID = int(id)
+	return tx.Commit()
+}
+
+// GetByID retrieves a queue entry by its ID
+func (r *WalkInQueueRepo) GetByID(ctx context.Context, id int) (*models.QueueEntry, error) {
+	query := `SELECT ` + queueEntryColumns + ` FROM walk_in_queue_entries WHERE id = ?`
+
+	entry, err := scanQueueEntry(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("queue entry not found")
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// GetActiveForPatient retrieves the patient's waiting or called entry in the day's queue, if any
+func (r *WalkInQueueRepo) GetActiveForPatient(ctx context.Context, doctorID int, date time.Time, patientID int) (*models.QueueEntry, error) {
+	query := `
+		SELECT ` + queueEntryColumns + `
+		FROM walk_in_queue_entries
+		WHERE doctor_id = ? AND queue_date = ? AND patient_id = ? AND status IN ('waiting', 'called')
+		LIMIT 1
+	`
+
+	entry, err := scanQueueEntry(r.db.QueryRowContext(ctx, query, doctorID, date.Format("2006-01-02"), patientID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ListActive retrieves the day's waiting and called entries in ticket order
+func (r *WalkInQueueRepo) ListActive(ctx context.Context, doctorID int, date time.Time) ([]*models.QueueEntry, error) {
+	query := `
+		SELECT ` + queueEntryColumns + `
+		FROM walk_in_queue_entries
+		WHERE doctor_id = ? AND queue_date = ? AND status IN ('waiting', 'called')
+		ORDER BY ticket_number
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, doctorID, date.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.QueueEntry
+	for rows.Next() {
+		entry, err := scanQueueEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// CallNext marks the patient being seen as served and calls the lowest waiting ticket in one transaction
+func (r *WalkInQueueRepo) CallNext(ctx context.Context, doctorID int, date time.Time) (entry *models.QueueEntry, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	day := date.Format("2006-01-02")
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `
+		UPDATE walk_in_queue_entries
+		SET status = 'served', finished_at = ?
+		WHERE doctor_id = ? AND queue_date = ? AND status = 'called'
+	`, now, doctorID, day)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err = scanQueueEntry(tx.QueryRowContext(ctx, `
+		SELECT `+queueEntryColumns+`
+		FROM walk_in_queue_entries
+		WHERE doctor_id = ? AND queue_date = ? AND status = 'waiting'
+		ORDER BY ticket_number
+		LIMIT 1
+		FOR UPDATE
+	`, doctorID, day))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, tx.Commit()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE walk_in_queue_entries SET status = 'called', called_at = ? WHERE id = ?`, now, entry.ID)
+	if err != nil {
+		return nil, err
+	}
+	entry.Status = models.QueueStatusCalled
+	entry.CalledAt = models.NullTime{Time: now, Valid: true}
+	return entry, tx.Commit()
+}
+
+// Finish closes an entry that is still waiting or being seen
+func (r *WalkInQueueRepo) Finish(ctx context.Context, id int, status string) error {
+	query := `
+		UPDATE walk_in_queue_entries
+		SET status = ?, finished_at = ?
+		WHERE id = ? AND status IN ('waiting', 'called')
+	`
+
+	result, err := r.db.ExecContext(ctx, query, status, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("queue entry is no longer active")
+	}
+	return nil
+}
+
+// MarkNextNotified sets next_notified_at once; only the first caller gets true
+func (r *WalkInQueueRepo) MarkNextNotified(ctx context.Context, id int) (bool, error) {
+	query := `UPDATE walk_in_queue_entries SET next_notified_at = ? WHERE id = ? AND next_notified_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// AverageConsultationMinutes averages started-to-completed durations of the doctor's consultations
+func (r *WalkInQueueRepo) AverageConsultationMinutes(ctx context.Context, doctorID int, from time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(AVG(TIMESTAMPDIFF(SECOND, started_at, completed_at)), 0) / 60
+		FROM consultations
+		WHERE doctor_id = ?
+		AND status = 'completed'
+		AND started_at >= ?
+		AND completed_at > started_at
+	`
+
+	var minutes float64
+	if err := r.db.QueryRowContext(ctx, query, doctorID, from).Scan(&minutes); err != nil {
+		return 0, err
+	}
+	return minutes, nil
+}
+
+func scanQueueEntry(row rowScanner) (*models.QueueEntry, error) {
+	var entry models.QueueEntry
+	err := row.Scan(
+		&entry.ID, &entry.DoctorID, &entry.PatientID, &entry.QueueDate, &entry.TicketNumber,
+		&entry.Source, &entry.Status, &entry.JoinedAt, &entry.CalledAt, &entry.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
@@ -64,19 +64,92 @@ type DoctorAvailabilityRepository interface {
 	ListAllAvailability(ctx context.Context) ([]*models.DoctorAvailability, error)
 }
 
+type AvailabilityExceptionRepository interface {
+	Create(ctx context.Context, exception *models.AvailabilityException) error
+	GetByID(ctx context.Context, id int) (*models.AvailabilityException, error)
+	Delete(ctx context.Context, id int) error
+	// ListByDoctorID returns the doctor's exceptions overlapping the [from, to) range
+	ListByDoctorID(ctx context.Context, doctorID int, from, to time.Time) ([]*models.AvailabilityException, error)
+}
+
+type HolidayRepository interface {
+	Create(ctx context.Context, holiday *models.Holiday) error
+	Delete(ctx context.Context, id int) error
+	// List returns holidays falling between from and to, inclusive
+	List(ctx context.Context, from, to time.Time) ([]*models.Holiday, error)
+}
+
+type WaitlistRepository interface {
+	CreateEntry(ctx context.Context, entry *models.WaitlistEntry) error
+	GetEntryByID(ctx context.Context, id int) (*models.WaitlistEntry, error)
+	UpdateEntryStatus(ctx context.Context, id int, status string) error
+	ListEntriesByPatient(ctx context.Context, patientID int) ([]*models.WaitlistEntry, error)
+	// ListWaitingForDate returns waiting entries whose range covers date, oldest first
+	ListWaitingForDate(ctx context.Context, providerID int, providerType string, date time.Time) ([]*models.WaitlistEntry, error)
+	CreateOffer(ctx context.Context, offer *models.WaitlistOffer) error
+	GetOfferByID(ctx context.Context, id int) (*models.WaitlistOffer, error)
+	UpdateOffer(ctx context.Context, offer *models.WaitlistOffer) error
+	ListOffersByPatient(ctx context.Context, patientID int) ([]*models.WaitlistOffer, error)
+	// ListOffersForSlot returns every offer ever made for the slot starting at start on date
+	ListOffersForSlot(ctx context.Context, providerID int, providerType string, date, start time.Time) ([]*models.WaitlistOffer, error)
+	// ListActiveOffers returns pending offers on date that have not yet expired at now
+	ListActiveOffers(ctx context.Context, providerID int, providerType string, date, now time.Time) ([]*models.WaitlistOffer, error)
+	// ListExpiredOffers returns pending offers whose hold ended at or before now
+	ListExpiredOffers(ctx context.Context, now time.Time) ([]*models.WaitlistOffer, error)
+}
+
+type NoShowRepository interface {
+	// GetPolicy returns nil without error when no policy has been saved
+	GetPolicy(ctx context.Context) (*models.NoShowPolicy, error)
+	SavePolicy(ctx context.Context, policy *models.NoShowPolicy) error
+	// ListCandidates returns scheduled appointments that started at least grace before now
+	// and have no consultation or home care visit started for them
+	ListCandidates(ctx context.Context, now time.Time, grace time.Duration) ([]*models.Appointment, error)
+	// Record stores the no-show and marks its appointment as no_show in one transaction
+	Record(ctx context.Context, record *models.NoShowRecord) error
+	ListByPatient(ctx context.Context, patientID int) ([]*models.NoShowRecord, error)
+	PatientStats(ctx context.Context, patientID int) (*models.NoShowStats, error)
+	ProviderStats(ctx context.Context, providerID int, providerType string) (*models.NoShowStats, error)
+	IsPrepaymentRequired(ctx context.Context, patientID int) (bool, error)
+	SetPrepaymentRequired(ctx context.Context, patientID int, required bool) error
+}
+
 type MedicalHistoryRepository interface {
 	Create(ctx context.Context, history *models.MedicalHistory) error
+	// CreateIfAbsent inserts a coded entry unless the patient already has a current one with its code
+	CreateIfAbsent(ctx context.Context, history *models.MedicalHistory) (bool, error)
 	GetByPatientID(ctx context.Context, patientID int) ([]*models.MedicalHistory, error)
 	Update(ctx context.Context, history *models.MedicalHistory) error
 	Delete(ctx context.Context, id int) error
 }
 
-// AppointmentFilter struct should be defined in the repository package
+// Sort fields accepted by AppointmentFilter.SortBy
+const (
+	AppointmentSortStartsAt  = "starts_at"
+	AppointmentSortCreatedAt = "created_at"
+	AppointmentSortUpdatedAt = "updated_at"
+)
+
+// AppointmentFilter struct should be defined in the repository package. ProviderID matches
+// either provider column; ServiceTypeID matches the provider's service type.
 type AppointmentFilter struct {
-	StartDate    *time.Time
-	EndDate      *time.Time
-	Status       string
-	ProviderType string
+	StartDate     *time.Time
+	EndDate       *time.Time
+	Status        string
+	ProviderType  string
+	PatientID     int
+	ProviderID    int
+	ServiceTypeID int
+	SortBy        string
+	Descending    bool
+	// After continues a listing from the last row of the previous page
+	After *AppointmentCursor
+}
+
+// AppointmentCursor is the position of a row in a sorted listing: its sort value, with the ID breaking ties
+type AppointmentCursor struct {
+	Value time.Time
+	ID    int
 }
 
 type AppointmentRepository interface {
@@ -84,9 +157,13 @@ type AppointmentRepository interface {
 	GetByID(ctx context.Context, id int) (*models.Appointment, error)
 	Update(ctx context.Context, appointment *models.Appointment) error
 	Delete(ctx context.Context, id int) error
-	List(ctx context.Context, filter AppointmentFilter, offset, limit int) ([]*models.Appointment, error)
+	// List returns up to limit appointments matching filter, after filter.After when set, and
+	// the total number of matches regardless of the cursor
+	List(ctx context.Context, filter AppointmentFilter, limit int) ([]*models.Appointment, int, error)
 	GetByProviderID(ctx context.Context, providerID int, providerType string) ([]*models.Appointment, error)
 	GetByPatientID(ctx context.Context, patientID, limit, offset int) ([]*models.Appointment, error)
+	// GetByProviderAndDateRange returns the provider's appointments dated between startDate and endDate, inclusive
+	GetByProviderAndDateRange(ctx context.Context, providerID int, providerType string, startDate, endDate time.Time) ([]*models.Appointment, error)
 }
 
 type ConsultationRepository interface {
@@ -99,6 +176,14 @@ type ConsultationRepository interface {
 	// GetByAppointmentID retrieves a consultation by its appointment ID
 	GetByAppointmentID(ctx context.Context, appointmentID int) (*models.Consultation, error)
 
+	// Start creates an in-progress consultation and moves its appointment, which must be
+	// scheduled between the same doctor and patient, to in_progress in one transaction
+	Start(ctx context.Context, consultation *models.Consultation) error
+
+	// Complete completes an in-progress consultation and its appointment and creates the
+	// consultation's payment in one transaction
+	Complete(ctx context.Context, consultation *models.Consultation, payment *Payment) error
+
 	// Update modifies an existing consultation
 	Update(ctx context.Context, consultation *models.Consultation) error
 
@@ -118,6 +203,26 @@ type HomeCareVisitRepository interface {
 	GetByPatientID(ctx context.Context, patientID int) ([]models.HomeCareVisit, error)
 	GetByProviderID(ctx context.Context, providerID int) ([]models.HomeCareVisit, error)
 	GetByDateRange(ctx context.Context, startDate, endDate time.Time) ([]models.HomeCareVisit, error)
+	// GetByProviderAndTimeRange returns the provider's visits whose scheduled start falls in [from, to)
+	GetByProviderAndTimeRange(ctx context.Context, providerID int, from, to time.Time) ([]models.HomeCareVisit, error)
+}
+
+type HomeCareAvailabilityRepository interface {
+	Create(ctx context.Context, availability *models.HomeCareAvailability) error
+	// Update and Delete only touch the provider's own windows
+	Update(ctx context.Context, availability *models.HomeCareAvailability) error
+	Delete(ctx context.Context, providerID, id int) error
+	ListByProviderID(ctx context.Context, providerID int) ([]*models.HomeCareAvailability, error)
+
+	CreateException(ctx context.Context, exception *models.HomeCareAvailabilityException) error
+	GetExceptionByID(ctx context.Context, id int) (*models.HomeCareAvailabilityException, error)
+	DeleteException(ctx context.Context, id int) error
+	// ListExceptions returns the provider's exceptions overlapping the [from, to) range
+	ListExceptions(ctx context.Context, providerID int, from, to time.Time) ([]*models.HomeCareAvailabilityException, error)
+
+	// GetServiceArea returns nil without error when the provider has not configured one
+	GetServiceArea(ctx context.Context, providerID int) (*models.HomeCareServiceArea, error)
+	UpsertServiceArea(ctx context.Context, area *models.HomeCareServiceArea) error
 }
 
 type PaymentRepository interface {
@@ -141,10 +246,42 @@ type ReviewRepository interface {
 }
 
 type ChatMessageRepository interface {
-	Create(ctx context.Context, message *ChatMessage) error
-	GetByConsultationID(ctx context.Context, consultationID int, limit, offset int) ([]*ChatMessage, error)
+	// Create inserts the message together with its attachments
+	Create(ctx context.Context, message *models.ChatMessage) error
+	GetByID(ctx context.Context, id int) (*models.ChatMessage, error)
+	// GetAttachment retrieves a chat attachment by ID
+	GetAttachment(ctx context.Context, id int) (*models.ChatAttachment, error)
+	GetByConsultationID(ctx context.Context, consultationID int, limit, offset int) ([]*models.ChatMessage, error)
+	// ListSince returns up to limit of the consultation's messages after afterID, oldest first
+	ListSince(ctx context.Context, consultationID, afterID, limit int) ([]*models.ChatMessage, error)
 	MarkAsRead(ctx context.Context, messageID int) error
+	// MarkReadUpTo marks the unread messages up to upToID that others sent in the consultation
+	// as read by readerID, returning how many were marked
+	MarkReadUpTo(ctx context.Context, consultationID, readerID, upToID int) (int, error)
+	// GetUnreadCount counts the unread messages sent to the user in their consultations
 	GetUnreadCount(ctx context.Context, userID int) (int, error)
+	// ListConversations returns the consultations the user takes part in with their chat
+	// state, most recently active first
+	ListConversations(ctx context.Context, userID, limit, offset int) ([]*models.ChatConversation, error)
+	// Edit replaces the message's text, recording the previous text in its edit history.
+	// It returns false when the text changed meanwhile or the message is deleted or archived.
+	Edit(ctx context.Context, edit *models.ChatMessageEdit, newMessage string) (bool, error)
+	// ListEdits returns the message's edits, oldest first
+	ListEdits(ctx context.Context, messageID int) ([]*models.ChatMessageEdit, error)
+	// SoftDelete marks the message deleted, keeping its content; it returns false when the
+	// message is already deleted or archived
+	SoftDelete(ctx context.Context, messageID, deletedBy int, at time.Time) (bool, error)
+	// ArchiveCompletedBefore archives the messages of consultations completed before cutoff,
+	// returning how many were archived
+	ArchiveCompletedBefore(ctx context.Context, cutoff, at time.Time) (int, error)
+	// GetRetentionPolicy retrieves the chat retention policy, or nil when none has been saved
+	GetRetentionPolicy(ctx context.Context) (*models.ChatRetentionPolicy, error)
+	// SaveRetentionPolicy creates or replaces the single chat retention policy row
+	SaveRetentionPolicy(ctx context.Context, policy *models.ChatRetentionPolicy) error
+	// Search returns the messages of the filter's user's consultations matching a boolean-mode
+	// full-text query, best matches first, with their Message and Position set. Deleted
+	// messages are left out.
+	Search(ctx context.Context, booleanQuery string, filter models.ChatSearchFilter) ([]*models.ChatSearchResult, error)
 }
 
 type NotificationRepository interface {
@@ -154,6 +291,80 @@ type NotificationRepository interface {
 	GetUnreadCount(ctx context.Context, userID int) (int, error)
 }
 
+// ReminderRepository tracks which appointment reminders have been sent to whom
+type ReminderRepository interface {
+	// ListDue returns scheduled appointments starting in (from, to] that still owe someone a reminder of this type
+	ListDue(ctx context.Context, reminderType string, from, to time.Time) ([]*models.Appointment, error)
+	// Claim records the reminder for the recipient, reporting false if it was already recorded
+	Claim(ctx context.Context, appointmentID int, reminderType string, recipientID int) (bool, error)
+	// Unclaim removes a claim whose notification could not be delivered, so it is retried
+	Unclaim(ctx context.Context, appointmentID int, reminderType string, recipientID int) error
+	// DeleteByAppointment removes all of the appointment's claims, so its reminders are sent again
+	DeleteByAppointment(ctx context.Context, appointmentID int) error
+}
+
+// JobLeaseRepository stores the leases that keep scheduled jobs to one instance at a time
+type JobLeaseRepository interface {
+	// TryAcquire takes or renews the job's lease for holder until the given time. It succeeds
+	// when the lease is free, expired at now, or already held by holder.
+	TryAcquire(ctx context.Context, jobName, holder string, now, until time.Time) (bool, error)
+}
+
+type CalendarRepository interface {
+	// GetToken returns nil without error when the user has no feed token
+	GetToken(ctx context.Context, userID int) (*models.CalendarFeedToken, error)
+	GetUserIDByToken(ctx context.Context, token string) (int, error)
+	// SaveToken creates the user's token or replaces the existing one
+	SaveToken(ctx context.Context, token *models.CalendarFeedToken) error
+	DeleteToken(ctx context.Context, userID int) error
+	GetAppointmentItem(ctx context.Context, appointmentID int) (*models.CalendarItem, error)
+	// ListItemsForUser returns appointments and scheduled visits from the given time onward
+	// in which the user takes part as patient or provider
+	ListItemsForUser(ctx context.Context, userID int, from time.Time) ([]*models.CalendarItem, error)
+}
+
+type WalkInQueueRepository interface {
+	// Join assigns the next ticket number of the doctor's queue for the day and inserts the entry
+	Join(ctx context.Context, entry *models.QueueEntry) error
+	GetByID(ctx context.Context, id int) (*models.QueueEntry, error)
+	// GetActiveForPatient returns nil without error when the patient is not waiting or being seen
+	GetActiveForPatient(ctx context.Context, doctorID int, date time.Time, patientID int) (*models.QueueEntry, error)
+	// ListActive returns the waiting and called entries of the day in ticket order
+	ListActive(ctx context.Context, doctorID int, date time.Time) ([]*models.QueueEntry, error)
+	// CallNext finishes any called entry and calls the lowest waiting ticket, returning nil
+	// when nobody is waiting
+	CallNext(ctx context.Context, doctorID int, date time.Time) (*models.QueueEntry, error)
+	// Finish moves a waiting or called entry to served or left
+	Finish(ctx context.Context, id int, status string) error
+	// MarkNextNotified records the "you're next" notification, reporting false when it was already sent
+	MarkNextNotified(ctx context.Context, id int) (bool, error)
+	// AverageConsultationMinutes averages the doctor's completed consultations started since from,
+	// returning 0 when there are none
+	AverageConsultationMinutes(ctx context.Context, doctorID int, from time.Time) (float64, error)
+}
+
+// ClinicalNoteRepository stores the versions of consultations' SOAP notes
+type ClinicalNoteRepository interface {
+	// Create inserts a note as the consultation's next version
+	Create(ctx context.Context, note *models.ClinicalNote) error
+	GetByID(ctx context.Context, id int) (*models.ClinicalNote, error)
+	// ListByConsultation returns every version of the consultation's note, oldest first
+	ListByConsultation(ctx context.Context, consultationID int) ([]*models.ClinicalNote, error)
+	// UpdateDraft saves the content of a note that is still a draft
+	UpdateDraft(ctx context.Context, note *models.ClinicalNote) error
+	// Sign signs a draft and, for an amendment, marks the note it amends as amended, in one transaction
+	Sign(ctx context.Context, note *models.ClinicalNote) error
+}
+
+// VideoSessionRepository stores the video rooms of consultations
+type VideoSessionRepository interface {
+	// GetOrCreate returns the consultation's session, creating a waiting one on first use
+	GetOrCreate(ctx context.Context, consultationID int) (*models.VideoSession, error)
+	GetByConsultationID(ctx context.Context, consultationID int) (*models.VideoSession, error)
+	// Save stores the session's state and copies its duration onto the consultation in one transaction
+	Save(ctx context.Context, session *models.VideoSession) error
+}
+
 type ConsultationDetailsRepository interface {
 	Create(ctx context.Context, details *models.ConsultationDetails) error
 	GetByID(ctx context.Context, id int) (*models.ConsultationDetails, error)
@@ -306,16 +517,6 @@ type Review struct {
 	CreatedAt          time.Time `json:"created_at" db:"created_at"`
 }
 
-type ChatMessage struct {
-	ID             int
-	ConsultationID int
-	SenderType     string
-	SenderID       int
-	Message        string
-	SentAt         time.Time
-	IsRead         bool
-}
-
 type Notification struct {
 	ID               int
 	UserID           int
@@ -324,3 +525,115 @@ type Notification struct {
 	IsRead           bool
 	CreatedAt        time.Time
 }
+
+// PrescriptionRepository stores e-prescriptions with their medication lines
+type PrescriptionRepository interface {
+	// Create inserts the prescription, its items and its warning overrides in one transaction
+	Create(ctx context.Context, prescription *models.Prescription) error
+	GetByID(ctx context.Context, id int) (*models.Prescription, error)
+	GetByNumber(ctx context.Context, number string) (*models.Prescription, error)
+	ListByConsultation(ctx context.Context, consultationID int) ([]*models.Prescription, error)
+	// ListByPatient returns the patient's prescriptions issued since the given time, void ones excluded
+	ListByPatient(ctx context.Context, patientID int, issuedSince time.Time) ([]*models.Prescription, error)
+	// Dispense records the next fill of an active prescription, marking it dispensed once
+	// fillsAllowed fills are recorded. It returns nil when the prescription is no longer active.
+	Dispense(ctx context.Context, id, fillsAllowed int, dispensedBy string, at time.Time) (*models.PrescriptionFill, error)
+	// Void cancels an active prescription, reporting false when it is no longer active
+	Void(ctx context.Context, id int, reason string, at time.Time) (bool, error)
+}
+
+// DrugDataRepository stores the locally imported drug interaction and drug class datasets.
+// Names are stored normalised to lower case.
+type DrugDataRepository interface {
+	// ImportInteractions upserts interaction rows in one transaction, returning how many were stored
+	ImportInteractions(ctx context.Context, interactions []models.DrugInteraction) (int, error)
+	// ImportClasses upserts drug class rows in one transaction, returning how many were stored
+	ImportClasses(ctx context.Context, classes []models.DrugClass) (int, error)
+	// FindInteractions returns the rows whose two sides are both among the given names
+	FindInteractions(ctx context.Context, names []string) ([]models.DrugInteraction, error)
+	// ListClasses returns the classes of the given drugs
+	ListClasses(ctx context.Context, drugNames []string) ([]models.DrugClass, error)
+}
+
+// DiagnosisCodeRepository stores the locally imported ICD-10 catalogue
+type DiagnosisCodeRepository interface {
+	// Import upserts catalogue entries in one transaction, returning how many were stored
+	Import(ctx context.Context, codes []models.DiagnosisCode) (int, error)
+	// Search matches codes by prefix and descriptions by substring, code matches first
+	Search(ctx context.Context, query string, limit int) ([]models.DiagnosisCode, error)
+	// GetByCodes returns the catalogue entries of the given codes; unknown codes are left out
+	GetByCodes(ctx context.Context, codes []string) ([]models.DiagnosisCode, error)
+}
+
+// ReferralRepository stores referrals and their responses
+type ReferralRepository interface {
+	Create(ctx context.Context, referral *models.Referral) error
+	GetByID(ctx context.Context, id int) (*models.Referral, error)
+	// ListSent returns the referrals a doctor wrote, newest first; an empty status matches all
+	ListSent(ctx context.Context, doctorID int, status string) ([]*models.Referral, error)
+	ListByPatient(ctx context.Context, patientID int) ([]*models.Referral, error)
+	// ListInbox returns the referrals a receiver can see, most urgent first
+	ListInbox(ctx context.Context, inbox models.ReferralInbox) ([]*models.Referral, error)
+	// Respond accepts or declines a pending referral as the receiver, reporting false when it is
+	// no longer pending
+	Respond(ctx context.Context, id int, status string, receiverID int, note string, at time.Time) (bool, error)
+	// Cancel withdraws a pending or accepted referral, reporting false when it is past that
+	Cancel(ctx context.Context, id int, at time.Time) (bool, error)
+	// MarkBooked links an accepted referral to its appointment, reporting false when it is not accepted
+	MarkBooked(ctx context.Context, id, appointmentID int, at time.Time) (bool, error)
+}
+
+// LabOrderRepository stores lab and imaging orders with their tests, results and reports.
+// Orders are returned with all three loaded.
+type LabOrderRepository interface {
+	// Create inserts the order and its tests in one transaction
+	Create(ctx context.Context, order *models.LabOrder) error
+	GetByID(ctx context.Context, id int) (*models.LabOrder, error)
+	GetByNumber(ctx context.Context, number string) (*models.LabOrder, error)
+	ListByConsultation(ctx context.Context, consultationID int) ([]*models.LabOrder, error)
+	// ListByPatient returns the patient's orders, newest first
+	ListByPatient(ctx context.Context, patientID int) ([]*models.LabOrder, error)
+	// AddResults stores results and an optional report and marks the order resulted, in one
+	// transaction, reporting false when the order was cancelled
+	AddResults(ctx context.Context, orderID int, results []models.LabResult, report *models.LabReport, at time.Time) (bool, error)
+	// Cancel cancels an order that has no results yet, reporting false when it has
+	Cancel(ctx context.Context, id int, reason string, at time.Time) (bool, error)
+	GetReport(ctx context.Context, orderID, reportID int) (*models.LabReport, error)
+}
+
+// FollowUpRepository stores follow-up recommendations made when consultations are completed
+type FollowUpRepository interface {
+	Create(ctx context.Context, followUp *models.FollowUp) error
+	GetByID(ctx context.Context, id int) (*models.FollowUp, error)
+	GetByConsultationID(ctx context.Context, consultationID int) (*models.FollowUp, error)
+	// ListByPatient returns the patient's follow-ups, pending ones first
+	ListByPatient(ctx context.Context, patientID int) ([]*models.FollowUp, error)
+	// ListOverdue returns the doctor's pending follow-ups due before the given date, oldest
+	// first, with the patient's name
+	ListOverdue(ctx context.Context, doctorID int, before time.Time) ([]*models.FollowUp, error)
+	// ListToRemind returns pending follow-ups due on or before dueBy that have had fewer than
+	// maxReminders reminders, none of them since remindedBefore
+	ListToRemind(ctx context.Context, dueBy, remindedBefore time.Time, maxReminders int) ([]*models.FollowUp, error)
+	// ClaimReminder counts a reminder, reporting false if another run already sent it
+	ClaimReminder(ctx context.Context, id, remindersSent int, at time.Time) (bool, error)
+	// MarkBooked links a pending follow-up to its appointment, reporting false when it is not pending
+	MarkBooked(ctx context.Context, id, appointmentID int, at time.Time) (bool, error)
+	// Dismiss closes a pending follow-up, reporting false when it is not pending
+	Dismiss(ctx context.Context, id int, at time.Time) (bool, error)
+}
+
+// ConsultationSummaryRepository stores the generated consultation summary documents
+type ConsultationSummaryRepository interface {
+	// Get returns the stored summary in the language and format, or nil when none exists
+	Get(ctx context.Context, consultationID int, language, format string) (*models.ConsultationSummary, error)
+	// Save stores the summary, replacing the one in the same language and format
+	Save(ctx context.Context, summary *models.ConsultationSummary) error
+}
+
+// ChatTranscriptRepository stores the signed chat transcripts of consultations
+type ChatTranscriptRepository interface {
+	// Create stores a consultation's transcript; a consultation has at most one
+	Create(ctx context.Context, transcript *models.ChatTranscript) error
+	// GetByConsultationID returns the consultation's transcript, or nil when none exists
+	GetByConsultationID(ctx context.Context, consultationID int) (*models.ChatTranscript, error)
+}
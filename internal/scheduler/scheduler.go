@@ -0,0 +1,114 @@
+// Package scheduler runs periodic jobs inside the server process. Each run is guarded by
+// a database lease so that, with several instances deployed, only one of them executes
+// a given job per interval.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"shifa/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Job is a named unit of work run every Interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type Scheduler struct {
+	leaseRepo repository.JobLeaseRepository
+	holder    string
+	jobs      []Job
+	logger    *logrus.Logger
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// New creates a scheduler whose leases are held under this process's host name and PID
+func New(leaseRepo repository.JobLeaseRepository, logger *logrus.Logger) *Scheduler {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return &Scheduler{
+		leaseRepo: leaseRepo,
+		holder:    fmt.Sprintf("%s-%d", host, os.Getpid()),
+		logger:    logger,
+	}
+}
+
+// Register adds a job; it must be called before Start
+func (s *Scheduler) Register(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Start launches one goroutine per registered job. Each job first runs after one interval.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+			s.loop(ctx, job)
+		}(job)
+	}
+
+	s.logger.WithField("holder", s.holder).Infof("Scheduler started with %d jobs", len(s.jobs))
+}
+
+// Stop cancels all jobs and waits for running ones to return
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+	s.logger.Info("Scheduler stopped")
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runOnce(ctx, job)
+		}
+	}
+}
+
+// runOnce executes the job if this instance wins the lease for the current interval.
+// The lease is kept until the interval ends rather than released, so instances whose
+// tickers fire a little later do not run the job again.
+func (s *Scheduler) runOnce(ctx context.Context, job Job) {
+	now := time.Now()
+	acquired, err := s.leaseRepo.TryAcquire(ctx, job.Name, s.holder, now, now.Add(job.Interval))
+	if err != nil {
+		s.logger.WithError(err).WithField("job", job.Name).Error("Failed to acquire job lease")
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, job.Interval)
+	defer cancel()
+
+	if err := job.Run(runCtx); err != nil {
+		s.logger.WithError(err).WithField("job", job.Name).Error("Scheduled job failed")
+		return
+	}
+	s.logger.WithField("job", job.Name).WithField("duration", time.Since(now).String()).Debug("Scheduled job finished")
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+)
+
+const (
+	defaultAppointmentPageSize = 20
+	maxAppointmentPageSize     = 100
+)
+
+var (
+	// ErrInvalidCursor is returned for cursors that are malformed or were issued for a different sort
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidSort is returned for sort fields the listing does not support
+	ErrInvalidSort = errors.New("invalid sort field")
+)
+
+// encodeAppointmentCursor builds the opaque cursor continuing a listing after appointment.
+// The sort is part of the cursor so it cannot be replayed against a different ordering.
+func encodeAppointmentCursor(appointment *models.Appointment, filter repository.AppointmentFilter) string {
+	raw := fmt.Sprintf("%s:%s:%d:%d", filter.SortBy, sortDirection(filter),
+		appointmentSortValue(appointment, filter.SortBy).UnixNano(), appointment.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeAppointmentCursor(cursor string, filter repository.AppointmentFilter) (*repository.AppointmentCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 4 || parts[0] != filter.SortBy || parts[1] != sortDirection(filter) {
+		return nil, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &repository.AppointmentCursor{Value: time.Unix(0, nanos).UTC(), ID: id}, nil
+}
+
+func sortDirection(filter repository.AppointmentFilter) string {
+	if filter.Descending {
+		return "desc"
+	}
+	return "asc"
+}
+
+// appointmentSortValue returns the value the repository orders appointment by. Rows saved
+// before starts_at existed sort by their wall-clock start read as UTC.
+func appointmentSortValue(appointment *models.Appointment, sortBy string) time.Time {
+	switch sortBy {
+	case repository.AppointmentSortCreatedAt:
+		return appointment.CreatedAt
+	case repository.AppointmentSortUpdatedAt:
+		return appointment.UpdatedAt
+	}
+	if appointment.StartsAt.Valid {
+		return appointment.StartsAt.Time
+	}
+	clock := appointment.StartTime.Time()
+	return time.Date(appointment.AppointmentDate.Year(), appointment.AppointmentDate.Month(), appointment.AppointmentDate.Day(),
+		clock.Hour(), clock.Minute(), clock.Second(), 0, time.UTC)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+)
+
+func TestAppointmentCursorRoundTrip(t *testing.T) {
+	riyadh := time.FixedZone("AST", 3*60*60)
+	appointment := &models.Appointment{
+		ID:        42,
+		StartsAt:  models.NullTime{Time: time.Date(2024, 3, 4, 9, 30, 0, 0, riyadh), Valid: true},
+		CreatedAt: time.Date(2024, 2, 1, 8, 0, 0, 123456789, time.UTC),
+		UpdatedAt: time.Date(2024, 2, 2, 10, 15, 0, 0, time.UTC),
+	}
+	legacy := &models.Appointment{
+		ID:              7,
+		AppointmentDate: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+		StartTime:       models.CustomTime(time.Date(0, 1, 1, 14, 0, 0, 0, time.UTC)),
+	}
+
+	tests := []struct {
+		name        string
+		appointment *models.Appointment
+		filter      repository.AppointmentFilter
+		want        time.Time
+	}{
+		{"starts at", appointment, repository.AppointmentFilter{SortBy: repository.AppointmentSortStartsAt}, appointment.StartsAt.Time},
+		{
+			"starts at descending",
+			appointment,
+			repository.AppointmentFilter{SortBy: repository.AppointmentSortStartsAt, Descending: true},
+			appointment.StartsAt.Time,
+		},
+		{"created at keeps nanoseconds", appointment, repository.AppointmentFilter{SortBy: repository.AppointmentSortCreatedAt}, appointment.CreatedAt},
+		{"updated at", appointment, repository.AppointmentFilter{SortBy: repository.AppointmentSortUpdatedAt}, appointment.UpdatedAt},
+		{
+			"wall clock start of rows without starts at",
+			legacy,
+			repository.AppointmentFilter{SortBy: repository.AppointmentSortStartsAt},
+			time.Date(2024, 3, 5, 14, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor := encodeAppointmentCursor(tt.appointment, tt.filter)
+			got, err := decodeAppointmentCursor(cursor, tt.filter)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if !got.Value.Equal(tt.want) {
+				t.Errorf("Value = %v, want %v", got.Value, tt.want)
+			}
+			if got.ID != tt.appointment.ID {
+				t.Errorf("ID = %d, want %d", got.ID, tt.appointment.ID)
+			}
+		})
+	}
+}
+
+func TestDecodeAppointmentCursorRejects(t *testing.T) {
+	startsAsc := repository.AppointmentFilter{SortBy: repository.AppointmentSortStartsAt}
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+	valid := encodeAppointmentCursor(&models.Appointment{
+		ID:       1,
+		StartsAt: models.NullTime{Time: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC), Valid: true},
+	}, startsAsc)
+
+	tests := []struct {
+		name   string
+		cursor string
+		filter repository.AppointmentFilter
+	}{
+		{"not base64", "not a cursor!", startsAsc},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("starts_at:asc:1:1")), startsAsc},
+		{"too few parts", encode("starts_at:asc:1"), startsAsc},
+		{"too many parts", encode("starts_at:asc:1:2:3"), startsAsc},
+		{"other sort", valid, repository.AppointmentFilter{SortBy: repository.AppointmentSortCreatedAt}},
+		{"other direction", valid, repository.AppointmentFilter{SortBy: repository.AppointmentSortStartsAt, Descending: true}},
+		{"bad time", encode("starts_at:asc:soon:1"), startsAsc},
+		{"bad id", encode("starts_at:asc:1:first"), startsAsc},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeAppointmentCursor(tt.cursor, tt.filter); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
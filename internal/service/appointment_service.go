@@ -15,6 +15,12 @@ type AppointmentService struct {
 	appointmentRepo      repository.AppointmentRepository
 	doctorRepo           repository.DoctorRepository
 	homeCareProviderRepo repository.HomeCareProviderRepository
+	availabilityService  *DoctorAvailabilityService
+	homeCareAvailability *HomeCareAvailabilityService
+	noShowService        *NoShowService
+	reminders            *ReminderService
+	timeZones            *TimeZoneService
+	waitlist             *WaitlistService
 	logger               *logrus.Logger
 }
 
@@ -22,16 +28,87 @@ func NewAppointmentService(
 	appointmentRepo repository.AppointmentRepository,
 	doctorRepo repository.DoctorRepository,
 	homeCareProviderRepo repository.HomeCareProviderRepository,
+	availabilityService *DoctorAvailabilityService,
+	homeCareAvailability *HomeCareAvailabilityService,
+	noShowService *NoShowService,
+	reminders *ReminderService,
+	timeZones *TimeZoneService,
 	logger *logrus.Logger,
 ) *AppointmentService {
 	return &AppointmentService{
 		appointmentRepo:      appointmentRepo,
 		doctorRepo:           doctorRepo,
 		homeCareProviderRepo: homeCareProviderRepo,
+		availabilityService:  availabilityService,
+		homeCareAvailability: homeCareAvailability,
+		noShowService:        noShowService,
+		reminders:            reminders,
+		timeZones:            timeZones,
 		logger:               logger,
 	}
 }
 
+// SetWaitlist attaches the waitlist, which is constructed after this service because it books through it
+func (s *AppointmentService) SetWaitlist(waitlist *WaitlistService) {
+	s.waitlist = waitlist
+}
+
+// releaseSlot hands a freed appointment slot to the waitlist. Failures are logged rather
+// than returned so they never undo the cancellation itself.
+func (s *AppointmentService) releaseSlot(ctx context.Context, appointment *models.Appointment) {
+	if s.waitlist == nil {
+		return
+	}
+	if err := s.waitlist.SlotReleased(ctx, appointment); err != nil {
+		s.logger.WithError(err).Errorf("Failed to offer freed slot of appointment ID: %d", appointment.ID)
+	}
+}
+
+// normalizeTimes anchors an appointment in its provider's time zone. Clients may send either
+// starts_at/ends_at instants or a date with wall-clock start and end times in the provider's
+// zone; the other form is derived so both are always stored.
+func (s *AppointmentService) normalizeTimes(ctx context.Context, appointment *models.Appointment) error {
+	providerID := appointmentProviderID(appointment)
+	if providerID == 0 {
+		return nil
+	}
+	loc, err := s.timeZones.Location(ctx, providerID)
+	if err != nil {
+		return err
+	}
+	appointment.TimeZone = loc.String()
+
+	if appointment.StartsAt.Valid && appointment.EndsAt.Valid {
+		start := appointment.StartsAt.Time.In(loc)
+		end := appointment.EndsAt.Time.In(loc)
+		if civilDate(start) != civilDate(end) {
+			return errors.New("appointment must start and end on the same day in the provider's time zone")
+		}
+		appointment.AppointmentDate = civilDate(start)
+		appointment.StartTime = models.CustomTime(start)
+		appointment.EndTime = models.CustomTime(end)
+		return nil
+	}
+
+	if appointment.AppointmentDate.IsZero() || appointment.StartTime.IsZero() || appointment.EndTime.IsZero() {
+		// Left for validateAppointment to report
+		return nil
+	}
+	day := dateIn(appointment.AppointmentDate, loc)
+	start, err := wallClockInstant(day, appointment.StartTime.Time(), loc)
+	if err != nil {
+		return err
+	}
+	end, err := wallClockInstant(day, appointment.EndTime.Time(), loc)
+	if err != nil {
+		return err
+	}
+	appointment.AppointmentDate = civilDate(day)
+	appointment.StartsAt = models.NullTime{Time: start, Valid: true}
+	appointment.EndsAt = models.NullTime{Time: end, Valid: true}
+	return nil
+}
+
 func (s *AppointmentService) validateAppointment(appointment *models.Appointment) error {
 	if appointment.PatientID == 0 {
 		return errors.New("patient ID is required")
@@ -61,11 +138,19 @@ func (s *AppointmentService) validateAppointment(appointment *models.Appointment
 }
 
 func (s *AppointmentService) CreateAppointment(ctx context.Context, appointment *models.Appointment) (*models.Appointment, error) {
+	if err := s.normalizeTimes(ctx, appointment); err != nil {
+		return nil, err
+	}
 	// Validate basic appointment data
 	if err := s.validateAppointment(appointment); err != nil {
 		return nil, err
 	}
 
+	// Patients with repeated no-shows must prepay before booking again
+	if err := s.noShowService.CheckBookingAllowed(ctx, appointment.PatientID); err != nil {
+		return nil, err
+	}
+
 	// Validate provider exists based on provider type
 	if appointment.ProviderType == "doctor" && appointment.DoctorID != nil {
 		// Check if doctor exists
@@ -86,6 +171,16 @@ func (s *AppointmentService) CreateAppointment(ctx context.Context, appointment
 			return nil, fmt.Errorf("home care provider is not available")
 		}
 	}
+	if err := s.checkSlot(ctx, appointment, 0); err != nil {
+		return nil, err
+	}
+
+	// Slots freed by cancellations are held for waitlisted patients until the offer lapses
+	if s.waitlist != nil {
+		if err := s.waitlist.CheckHold(ctx, appointment); err != nil {
+			return nil, err
+		}
+	}
 
 	err := s.appointmentRepo.Create(ctx, appointment)
 	if err != nil {
@@ -105,33 +200,126 @@ func (s *AppointmentService) GetAppointment(ctx context.Context, id int) (*model
 }
 
 func (s *AppointmentService) UpdateAppointment(ctx context.Context, appointment *models.Appointment) (*models.Appointment, error) {
+	if err := s.normalizeTimes(ctx, appointment); err != nil {
+		return nil, err
+	}
 	if err := s.validateAppointment(appointment); err != nil {
 		return nil, err
 	}
-	err := s.appointmentRepo.Update(ctx, appointment)
+	existing, err := s.appointmentRepo.GetByID(ctx, appointment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get appointment with ID %d: %w", appointment.ID, err)
+	}
+	// A rescheduled or reinstated appointment must fit the provider's calendar, leaving its
+	// own current booking out of the overlap check
+	rebooked := appointment.Status != "cancelled" && (appointmentMoved(existing, appointment) || existing.Status == "cancelled")
+	if rebooked {
+		if err := s.checkSlot(ctx, appointment, appointment.ID); err != nil {
+			return nil, err
+		}
+	}
+	err = s.appointmentRepo.Update(ctx, appointment)
 	if err != nil {
 		s.logger.WithError(err).Errorf("Failed to update appointment with ID: %d", appointment.ID)
 		return nil, fmt.Errorf("failed to update appointment with ID %d: %w", appointment.ID, err)
 	}
+	if existing.Status == "scheduled" && appointment.Status == "cancelled" {
+		s.releaseSlot(ctx, existing)
+	}
+	// Reminders sent for the old time say nothing about the new one; the update stands either way
+	if rebooked {
+		s.reminders.ResetReminders(ctx, appointment.ID)
+	}
 	return appointment, nil
 }
 
+// checkSlot honours the provider's weekly hours, holidays, exceptions and existing bookings
+// other than excludeAppointmentID
+func (s *AppointmentService) checkSlot(ctx context.Context, appointment *models.Appointment, excludeAppointmentID int) error {
+	if appointment.ProviderType == "doctor" && appointment.DoctorID != nil {
+		return s.availabilityService.CheckAvailability(ctx, *appointment.DoctorID,
+			appointment.StartsAt.Time, appointment.EndsAt.Time, excludeAppointmentID)
+	}
+	if appointment.ProviderType == "home_care_provider" && appointment.HomeCareProviderID != nil {
+		return s.homeCareAvailability.CheckAvailability(ctx, *appointment.HomeCareProviderID,
+			appointment.StartsAt.Time, appointment.EndsAt.Time, excludeAppointmentID)
+	}
+	return nil
+}
+
+// appointmentMoved reports whether an update changes the appointment's time or provider
+func appointmentMoved(existing, updated *models.Appointment) bool {
+	return !existing.StartsAt.Time.Equal(updated.StartsAt.Time) ||
+		!existing.EndsAt.Time.Equal(updated.EndsAt.Time) ||
+		existing.ProviderType != updated.ProviderType ||
+		!sameID(existing.DoctorID, updated.DoctorID) ||
+		!sameID(existing.HomeCareProviderID, updated.HomeCareProviderID)
+}
+
+func sameID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 func (s *AppointmentService) DeleteAppointment(ctx context.Context, id int) error {
-	err := s.appointmentRepo.Delete(ctx, id)
+	existing, err := s.appointmentRepo.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get appointment with ID %d: %w", id, err)
+	}
+	err = s.appointmentRepo.Delete(ctx, id)
 	if err != nil {
 		s.logger.WithError(err).Errorf("Failed to delete appointment with ID: %d", id)
 		return fmt.Errorf("failed to delete appointment with ID %d: %w", id, err)
 	}
+	if existing.Status == "scheduled" {
+		s.releaseSlot(ctx, existing)
+	}
 	return nil
 }
 
-func (s *AppointmentService) ListAppointments(ctx context.Context, filter repository.AppointmentFilter, offset, limit int) ([]*models.Appointment, error) {
-	appointments, err := s.appointmentRepo.List(ctx, filter, offset, limit)
+// ListAppointments returns one page of appointments matching filter. cursor is the NextCursor
+// of the previous page and must come from a listing with the same sort.
+func (s *AppointmentService) ListAppointments(ctx context.Context, filter repository.AppointmentFilter, cursor string, limit int) (*models.AppointmentPage, error) {
+	if filter.SortBy == "" {
+		filter.SortBy = repository.AppointmentSortStartsAt
+	}
+	switch filter.SortBy {
+	case repository.AppointmentSortStartsAt, repository.AppointmentSortCreatedAt, repository.AppointmentSortUpdatedAt:
+	default:
+		return nil, fmt.Errorf("%w %q", ErrInvalidSort, filter.SortBy)
+	}
+	if limit <= 0 {
+		limit = defaultAppointmentPageSize
+	}
+	if limit > maxAppointmentPageSize {
+		limit = maxAppointmentPageSize
+	}
+	if cursor != "" {
+		after, err := decodeAppointmentCursor(cursor, filter)
+		if err != nil {
+			return nil, err
+		}
+		filter.After = after
+	}
+
+	// Fetching one extra row tells whether another page follows
+	appointments, total, err := s.appointmentRepo.List(ctx, filter, limit+1)
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to list appointments")
 		return nil, fmt.Errorf("failed to list appointments: %w", err)
 	}
-	return appointments, nil
+
+	page := &models.AppointmentPage{Items: appointments, Total: total}
+	if len(appointments) > limit {
+		page.Items = appointments[:limit]
+		page.NextCursor = encodeAppointmentCursor(page.Items[limit-1], filter)
+	}
+	if page.Items == nil {
+		page.Items = []*models.Appointment{}
+	}
+	return page, nil
 }
 
 func (s *AppointmentService) ListAppointmentsByPatient(ctx context.Context, patientID, limit, offset int) ([]*models.Appointment, error) {
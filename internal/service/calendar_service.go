@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+	"shifa/pkg/ical"
+	"shifa/pkg/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// calendarFeedLookback keeps recently past and cancelled events in feeds so that
+// subscribed clients see the final state instead of the event silently vanishing
+const calendarFeedLookback = 30 * 24 * time.Hour
+
+const calendarUIDDomain = "shifa"
+
+var ErrNotAppointmentParticipant = errors.New("only the appointment's patient or provider can export it")
+
+type CalendarService struct {
+	calendarRepo repository.CalendarRepository
+	logger       *logrus.Logger
+}
+
+func NewCalendarService(calendarRepo repository.CalendarRepository, logger *logrus.Logger) *CalendarService {
+	return &CalendarService{
+		calendarRepo: calendarRepo,
+		logger:       logger,
+	}
+}
+
+// GetFeedToken returns the user's feed token, creating one on first use
+func (s *CalendarService) GetFeedToken(ctx context.Context, userID int) (*models.CalendarFeedToken, error) {
+	token, err := s.calendarRepo.GetToken(ctx, userID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get calendar token for user ID: %d", userID)
+		return nil, fmt.Errorf("failed to get calendar token: %w", err)
+	}
+	if token != nil {
+		return token, nil
+	}
+	return s.RotateFeedToken(ctx, userID)
+}
+
+// RotateFeedToken issues a new feed token, invalidating the previous feed URL
+func (s *CalendarService) RotateFeedToken(ctx context.Context, userID int) (*models.CalendarFeedToken, error) {
+	value, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate calendar token: %w", err)
+	}
+
+	token := &models.CalendarFeedToken{UserID: userID, Token: value}
+	if err := s.calendarRepo.SaveToken(ctx, token); err != nil {
+		s.logger.WithError(err).Errorf("Failed to save calendar token for user ID: %d", userID)
+		return nil, fmt.Errorf("failed to save calendar token: %w", err)
+	}
+	return token, nil
+}
+
+func (s *CalendarService) RevokeFeedToken(ctx context.Context, userID int) error {
+	if err := s.calendarRepo.DeleteToken(ctx, userID); err != nil {
+		s.logger.WithError(err).Errorf("Failed to delete calendar token for user ID: %d", userID)
+		return fmt.Errorf("failed to delete calendar token: %w", err)
+	}
+	return nil
+}
+
+// ExportAppointment renders a single appointment as an iCalendar document for its patient,
+// its provider or an admin
+func (s *CalendarService) ExportAppointment(ctx context.Context, appointmentID, userID int, admin bool) (string, error) {
+	item, err := s.calendarRepo.GetAppointmentItem(ctx, appointmentID)
+	if err != nil {
+		return "", err
+	}
+	if !admin && userID != item.PatientID && userID != item.ProviderID {
+		return "", ErrNotAppointmentParticipant
+	}
+
+	calendar := ical.Calendar{Events: []ical.Event{calendarEvent(item, 0)}}
+	return calendar.String(), nil
+}
+
+// Feed renders the calendar of the token's owner, covering everything from a month ago onward
+func (s *CalendarService) Feed(ctx context.Context, token string) (string, error) {
+	userID, err := s.calendarRepo.GetUserIDByToken(ctx, token)
+	if err != nil {
+		return "", err
+	}
+
+	items, err := s.calendarRepo.ListItemsForUser(ctx, userID, time.Now().Add(-calendarFeedLookback))
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load calendar items for user ID: %d", userID)
+		return "", fmt.Errorf("failed to load calendar: %w", err)
+	}
+
+	calendar := ical.Calendar{Name: "Shifa appointments"}
+	for _, item := range items {
+		calendar.Events = append(calendar.Events, calendarEvent(item, userID))
+	}
+	return calendar.String(), nil
+}
+
+// calendarEvent converts an item to an event titled from the viewer's point of view;
+// a viewerID of 0 names both participants
+func calendarEvent(item *models.CalendarItem, viewerID int) ical.Event {
+	kind := "Appointment"
+	if item.Kind == models.CalendarItemHomeCareVisit {
+		kind = "Home care visit"
+	}
+
+	var summary string
+	switch viewerID {
+	case item.PatientID:
+		summary = fmt.Sprintf("%s with %s", kind, item.ProviderName)
+	case item.ProviderID:
+		summary = fmt.Sprintf("%s with %s", kind, item.PatientName)
+	default:
+		summary = fmt.Sprintf("%s: %s with %s", kind, item.PatientName, item.ProviderName)
+	}
+
+	status := ical.StatusConfirmed
+	if item.Status == "cancelled" {
+		status = ical.StatusCancelled
+	}
+
+	return ical.Event{
+		UID:          fmt.Sprintf("%s-%d@%s", item.Kind, item.ID, calendarUIDDomain),
+		Sequence:     item.Sequence,
+		Start:        item.Start,
+		End:          item.End,
+		Summary:      summary,
+		Description:  fmt.Sprintf("Status: %s", item.Status),
+		Location:     item.Location,
+		Status:       status,
+		LastModified: item.UpdatedAt,
+	}
+}
@@ -2,40 +2,127 @@ package service
 
 import (
     "context"
+    "encoding/json"
     "errors"
+    "fmt"
+    "strconv"
+    "strings"
     "time"
+    "unicode"
+    "unicode/utf8"
     "github.com/sirupsen/logrus"
+    "shifa/internal/models"
+    "shifa/internal/pubsub"
     "shifa/internal/repository"
 )
 
+const (
+    maxChatMessageLength = 4000
+    maxChatAttachments   = 5
+
+    // DefaultChatPageSize is the page size of a consultation's message list when none is given
+    DefaultChatPageSize = 10
+    maxChatPageSize     = 100
+    // chatFragmentLength is the length in runes of the excerpt shown for a search result
+    chatFragmentLength = 200
+)
+
+var (
+    ErrNotChatParticipant   = errors.New("only the consultation's patient and doctor can use its chat")
+    ErrNotChatMessageSender = errors.New("only the sender can change a message")
+    ErrChatEditWindowClosed = errors.New("the time allowed to change this message has passed")
+    ErrChatMessageDeleted   = errors.New("message has been deleted")
+    ErrChatArchived         = errors.New("this chat has been archived and is read-only")
+    ErrChatTranscribed      = errors.New("this chat has a signed transcript and is read-only")
+)
+
+// defaultChatRetentionPolicy applies until an administrator saves a policy
+var defaultChatRetentionPolicy = models.ChatRetentionPolicy{
+    EditWindowMinutes:   15,
+    DeleteWindowMinutes: 60,
+    ArchiveAfterDays:    90,
+}
+
 // ChatService interface defines the contract for chat operations
 type ChatService interface {
-    SendMessage(ctx context.Context, message *repository.ChatMessage) error
-    GetMessagesByConsultationID(ctx context.Context, consultationID int, page, pageSize int) ([]*repository.ChatMessage, error)
-    MarkMessageAsRead(ctx context.Context, messageID int) error
+    SendMessage(ctx context.Context, message *models.ChatMessage) error
+    GetMessagesByConsultationID(ctx context.Context, consultationID int, page, pageSize int) ([]*models.ChatMessage, error)
+    // MarkMessageAsRead is MarkReadUpTo for the consultation of the given message
+    MarkMessageAsRead(ctx context.Context, messageID, readerID int) error
     GetUnreadMessageCount(ctx context.Context, userID int) (int, error)
+
+    // Participant returns the user's sender type in the consultation's chat, "doctor" or "patient"
+    Participant(ctx context.Context, consultationID, userID int) (string, error)
+    // Post stores a participant's message and pushes it to everyone connected to the chat
+    Post(ctx context.Context, consultationID, senderID int, text, clientID string) (*models.ChatMessage, error)
+    // PostWithAttachments is Post for a message carrying stored files; the text may then be empty
+    PostWithAttachments(ctx context.Context, consultationID, senderID int, text, clientID string, attachments []*models.ChatAttachment) (*models.ChatMessage, error)
+    // Attachment returns a chat attachment to one of its consultation's participants
+    Attachment(ctx context.Context, attachmentID, userID int) (*models.ChatAttachment, error)
+    // MessagesSince returns up to limit messages after afterID, oldest first, for catching up
+    MessagesSince(ctx context.Context, consultationID, afterID, limit int) ([]*models.ChatMessage, error)
+    // MarkReadUpTo marks the other side's messages up to messageID as read and pushes a receipt
+    MarkReadUpTo(ctx context.Context, consultationID, readerID, messageID int) error
+    // SetTyping pushes a typing indicator; it is not stored
+    SetTyping(ctx context.Context, consultationID, userID int, typing bool) error
+    // Edit replaces the text of the user's message within the edit window, keeping the
+    // previous text in its history
+    Edit(ctx context.Context, messageID, userID int, text string) (*models.ChatMessage, error)
+    // Delete deletes the user's message for everyone within the delete window. The content
+    // is kept for the medical record.
+    Delete(ctx context.Context, messageID, userID int) (*models.ChatMessage, error)
+    // History returns a message with its edits to the participants, or in full, deleted
+    // content included, to an administrator
+    History(ctx context.Context, messageID, userID int, admin bool) (*models.ChatMessageHistory, error)
+    // GetRetentionPolicy returns the saved retention policy, falling back to the defaults
+    GetRetentionPolicy(ctx context.Context) (*models.ChatRetentionPolicy, error)
+    UpdateRetentionPolicy(ctx context.Context, policy *models.ChatRetentionPolicy) error
+    // ArchiveExpiredChats archives the chats the retention policy has closed, returning the
+    // number of messages archived
+    ArchiveExpiredChats(ctx context.Context) (int, error)
+    // Search finds the messages of the user's consultations containing words that start with
+    // the query's words, best matches first
+    Search(ctx context.Context, filter models.ChatSearchFilter) ([]*models.ChatSearchResult, error)
+    // ListConversations returns the user's consultation chats, most recently active first
+    ListConversations(ctx context.Context, userID, limit, offset int) ([]*models.ChatConversation, error)
+    // MarkAllRead marks every message the other participant sent in the consultation as read,
+    // returning how many were unread
+    MarkAllRead(ctx context.Context, consultationID, userID int) (int, error)
+    // Subscribe calls handler with every event of the consultation's chat until the returned
+    // function is called. The handler must not block.
+    Subscribe(consultationID int, handler func(*models.ChatEvent)) (func(), error)
 }
 
 type chatService struct {
-    chatRepo repository.ChatMessageRepository
-    log      *logrus.Entry // Change this to *logrus.Entry
+    chatRepo         repository.ChatMessageRepository
+    consultationRepo repository.ConsultationRepository
+    transcriptRepo   repository.ChatTranscriptRepository
+    broker           pubsub.Broker
+    log              *logrus.Entry // Change this to *logrus.Entry
 }
 
-// NewChatService constructor
+// NewChatService constructor. Chat events go through broker, so that participants connected
+// to different server instances see each other's messages.
 func NewChatService(
     chatRepo repository.ChatMessageRepository, 
+    consultationRepo repository.ConsultationRepository,
+    transcriptRepo repository.ChatTranscriptRepository,
+    broker pubsub.Broker,
     log *logrus.Logger, // Accept *logrus.Logger
 ) ChatService {
     // Create a service-specific logger with additional context
     serviceLogger := log.WithField("service", "chat_service")
     
     return &chatService{
-        chatRepo: chatRepo,
-        log:      serviceLogger, // Use the *logrus.Entry
+        chatRepo:         chatRepo,
+        consultationRepo: consultationRepo,
+        transcriptRepo:   transcriptRepo,
+        broker:           broker,
+        log:              serviceLogger, // Use the *logrus.Entry
     }
 }
 
-func (s *chatService) SendMessage(ctx context.Context, message *repository.ChatMessage) error {
+func (s *chatService) SendMessage(ctx context.Context, message *models.ChatMessage) error {
     methodLogger := s.log.WithFields(logrus.Fields{
         "method":         "SendMessage",
         "senderID":      message.SenderID,
@@ -43,22 +130,20 @@ func (s *chatService) SendMessage(ctx context.Context, message *repository.ChatM
         "consultationID": message.ConsultationID,
     })
 
-    // Set message metadata
-    message.SentAt = time.Now()
-    message.IsRead = false
-
-    // Attempt to create message
-    err := s.chatRepo.Create(ctx, message)
+    // Sent like any other message, so the participant, text and archive checks apply; the
+    // sender type comes from the consultation rather than the request
+    sent, err := s.Post(ctx, message.ConsultationID, message.SenderID, message.Message, "")
     if err != nil {
-        methodLogger.Error("Failed to send message", "error", err.Error(), "details", "message creation failed")
-        return errors.New("failed to send message")
+        methodLogger.WithError(err).Warn("Failed to send message")
+        return err
     }
+    *message = *sent
 
     methodLogger.Info("Message sent successfully", "messageID", message.ID)
     return nil
 }
 
-func (s *chatService) GetMessagesByConsultationID(ctx context.Context, consultationID int, page, pageSize int) ([]*repository.ChatMessage, error) {
+func (s *chatService) GetMessagesByConsultationID(ctx context.Context, consultationID int, page, pageSize int) ([]*models.ChatMessage, error) {
     methodLogger := s.log.WithFields(logrus.Fields{
         "method":          "GetMessagesByConsultationID",
         "consultationID":  consultationID,
@@ -77,24 +162,25 @@ func (s *chatService) GetMessagesByConsultationID(ctx context.Context, consultat
     }
 
     methodLogger.Debug("Messages retrieved successfully", "messageCount", len(messages))
+    redactDeleted(messages...)
     return messages, nil
 }
 
-func (s *chatService) MarkMessageAsRead(ctx context.Context, messageID int) error {
+func (s *chatService) MarkMessageAsRead(ctx context.Context, messageID, readerID int) error {
     methodLogger := s.log.WithFields(logrus.Fields{
         "method":     "MarkMessageAsRead",
         "messageID":  messageID,
     })
 
-    // Attempt to mark message as read
-    err := s.chatRepo.MarkAsRead(ctx, messageID)
+    message, err := s.chatRepo.GetByID(ctx, messageID)
     if err != nil {
-        methodLogger.Error("Failed to mark message as read", "error", err.Error(), "details", "update operation failed")
-        return errors.New("failed to update message status")
+        methodLogger.Error("Failed to get message", "error", err.Error())
+        return fmt.Errorf("failed to get message: %w", err)
     }
 
-    methodLogger.Info("Message marked as read successfully")
-    return nil
+    // Only the consultation's participants can read its messages, and the receipt covers
+    // every earlier message of the other side, as on the socket
+    return s.MarkReadUpTo(ctx, message.ConsultationID, readerID, messageID)
 }
 
 func (s *chatService) GetUnreadMessageCount(ctx context.Context, userID int) (int, error) {
@@ -112,4 +198,503 @@ func (s *chatService) GetUnreadMessageCount(ctx context.Context, userID int) (in
 
     methodLogger.Debug("Unread message count retrieved", "unreadCount", count)
     return count, nil
-}
\ No newline at end of file
+}
+
+func (s *chatService) Participant(ctx context.Context, consultationID, userID int) (string, error) {
+    _, senderType, err := s.participant(ctx, consultationID, userID)
+    return senderType, err
+}
+
+func (s *chatService) participant(ctx context.Context, consultationID, userID int) (*models.Consultation, string, error) {
+    consultation, err := s.consultationRepo.GetByID(ctx, consultationID)
+    if err != nil {
+        s.log.WithError(err).Errorf("Failed to get consultation ID: %d", consultationID)
+        return nil, "", fmt.Errorf("failed to get consultation: %w", err)
+    }
+    switch userID {
+    case consultation.DoctorID:
+        return consultation, "doctor", nil
+    case consultation.PatientID:
+        return consultation, "patient", nil
+    }
+    return nil, "", ErrNotChatParticipant
+}
+
+func (s *chatService) Post(ctx context.Context, consultationID, senderID int, text, clientID string) (*models.ChatMessage, error) {
+    return s.PostWithAttachments(ctx, consultationID, senderID, text, clientID, nil)
+}
+
+func (s *chatService) PostWithAttachments(ctx context.Context, consultationID, senderID int, text, clientID string, attachments []*models.ChatAttachment) (*models.ChatMessage, error) {
+    consultation, senderType, err := s.participant(ctx, consultationID, senderID)
+    if err != nil {
+        return nil, err
+    }
+    text, err = validateChatText(text, len(attachments) > 0)
+    if err != nil {
+        return nil, err
+    }
+    if len(attachments) > maxChatAttachments {
+        return nil, fmt.Errorf("a message can have at most %d attachments", maxChatAttachments)
+    }
+    policy, err := s.GetRetentionPolicy(ctx)
+    if err != nil {
+        return nil, err
+    }
+    if chatArchived(consultation, policy, time.Now()) {
+        return nil, ErrChatArchived
+    }
+    if err := s.checkNotTranscribed(ctx, consultationID); err != nil {
+        return nil, err
+    }
+
+    message := &models.ChatMessage{
+        ConsultationID: consultationID,
+        SenderType:     senderType,
+        SenderID:       senderID,
+        Message:        text,
+        SentAt:         time.Now().UTC().Truncate(time.Second),
+        Attachments:    attachments,
+    }
+    if err := s.chatRepo.Create(ctx, message); err != nil {
+        s.log.WithError(err).Errorf("Failed to store chat message in consultation ID: %d", consultationID)
+        return nil, errors.New("failed to send message")
+    }
+
+    s.publish(ctx, &models.ChatEvent{Type: models.ChatEventMessage, ConsultationID: consultationID, Message: message, ClientID: clientID})
+    return message, nil
+}
+
+func (s *chatService) Attachment(ctx context.Context, attachmentID, userID int) (*models.ChatAttachment, error) {
+    attachment, err := s.chatRepo.GetAttachment(ctx, attachmentID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get attachment: %w", err)
+    }
+    if _, err := s.Participant(ctx, attachment.ConsultationID, userID); err != nil {
+        return nil, err
+    }
+    message, err := s.chatRepo.GetByID(ctx, attachment.MessageID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get message: %w", err)
+    }
+    if message.DeletedAt.Valid {
+        return nil, ErrChatMessageDeleted
+    }
+    return attachment, nil
+}
+
+func (s *chatService) MessagesSince(ctx context.Context, consultationID, afterID, limit int) ([]*models.ChatMessage, error) {
+    messages, err := s.chatRepo.ListSince(ctx, consultationID, afterID, limit)
+    if err != nil {
+        s.log.WithError(err).Errorf("Failed to list chat messages of consultation ID: %d", consultationID)
+        return nil, errors.New("failed to fetch messages")
+    }
+    redactDeleted(messages...)
+    return messages, nil
+}
+
+func (s *chatService) MarkReadUpTo(ctx context.Context, consultationID, readerID, messageID int) error {
+    if _, err := s.Participant(ctx, consultationID, readerID); err != nil {
+        return err
+    }
+    if _, err := s.chatRepo.MarkReadUpTo(ctx, consultationID, readerID, messageID); err != nil {
+        s.log.WithError(err).Errorf("Failed to mark chat messages read in consultation ID: %d", consultationID)
+        return errors.New("failed to update message status")
+    }
+
+    s.publish(ctx, &models.ChatEvent{Type: models.ChatEventRead, ConsultationID: consultationID, UserID: readerID, MessageID: messageID})
+    return nil
+}
+
+func (s *chatService) SetTyping(ctx context.Context, consultationID, userID int, typing bool) error {
+    if _, err := s.Participant(ctx, consultationID, userID); err != nil {
+        return err
+    }
+    s.publish(ctx, &models.ChatEvent{Type: models.ChatEventTyping, ConsultationID: consultationID, UserID: userID, Typing: typing})
+    return nil
+}
+
+func (s *chatService) Edit(ctx context.Context, messageID, userID int, text string) (*models.ChatMessage, error) {
+    message, err := s.changeableMessage(ctx, messageID, userID, func(p *models.ChatRetentionPolicy) int { return p.EditWindowMinutes })
+    if err != nil {
+        return nil, err
+    }
+    text, err = validateChatText(text, len(message.Attachments) > 0)
+    if err != nil {
+        return nil, err
+    }
+    if text == message.Message {
+        return message, nil
+    }
+
+    now := time.Now().UTC().Truncate(time.Second)
+    edit := &models.ChatMessageEdit{MessageID: messageID, PreviousMessage: message.Message, EditedBy: userID, EditedAt: now}
+    updated, err := s.chatRepo.Edit(ctx, edit, text)
+    if err != nil {
+        s.log.WithError(err).Errorf("Failed to edit chat message ID: %d", messageID)
+        return nil, fmt.Errorf("failed to edit message: %w", err)
+    }
+    if !updated {
+        return nil, errors.New("message was changed meanwhile, try again")
+    }
+
+    message.Message = text
+    message.EditedAt = models.NullTime{Time: now, Valid: true}
+    s.publish(ctx, &models.ChatEvent{Type: models.ChatEventEdited, ConsultationID: message.ConsultationID, Message: message})
+    return message, nil
+}
+
+func (s *chatService) Delete(ctx context.Context, messageID, userID int) (*models.ChatMessage, error) {
+    message, err := s.changeableMessage(ctx, messageID, userID, func(p *models.ChatRetentionPolicy) int { return p.DeleteWindowMinutes })
+    if err != nil {
+        return nil, err
+    }
+
+    now := time.Now().UTC().Truncate(time.Second)
+    deleted, err := s.chatRepo.SoftDelete(ctx, messageID, userID, now)
+    if err != nil {
+        s.log.WithError(err).Errorf("Failed to delete chat message ID: %d", messageID)
+        return nil, fmt.Errorf("failed to delete message: %w", err)
+    }
+    if !deleted {
+        return nil, ErrChatMessageDeleted
+    }
+
+    message.DeletedAt = models.NullTime{Time: now, Valid: true}
+    message.DeletedBy = &userID
+    redactDeleted(message)
+    s.publish(ctx, &models.ChatEvent{Type: models.ChatEventDeleted, ConsultationID: message.ConsultationID, Message: message, MessageID: messageID})
+    return message, nil
+}
+
+// changeableMessage loads a message its sender may still change: it is neither deleted nor
+// archived, its chat has no signed transcript, and it was sent less than the policy's window ago
+func (s *chatService) changeableMessage(ctx context.Context, messageID, userID int, window func(*models.ChatRetentionPolicy) int) (*models.ChatMessage, error) {
+    message, err := s.chatRepo.GetByID(ctx, messageID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get message: %w", err)
+    }
+    if message.SenderID != userID {
+        return nil, ErrNotChatMessageSender
+    }
+    switch {
+    case message.DeletedAt.Valid:
+        return nil, ErrChatMessageDeleted
+    case message.ArchivedAt.Valid:
+        return nil, ErrChatArchived
+    }
+    if err := s.checkNotTranscribed(ctx, message.ConsultationID); err != nil {
+        return nil, err
+    }
+
+    policy, err := s.GetRetentionPolicy(ctx)
+    if err != nil {
+        return nil, err
+    }
+    if time.Since(message.SentAt) > time.Duration(window(policy))*time.Minute {
+        return nil, ErrChatEditWindowClosed
+    }
+    return message, nil
+}
+
+// checkNotTranscribed fails once the consultation's chat has a signed transcript, so that the
+// transcript stays the complete record of the chat
+func (s *chatService) checkNotTranscribed(ctx context.Context, consultationID int) error {
+    transcript, err := s.transcriptRepo.GetByConsultationID(ctx, consultationID)
+    if err != nil {
+        s.log.WithError(err).Errorf("Failed to get chat transcript of consultation ID: %d", consultationID)
+        return fmt.Errorf("failed to get chat transcript: %w", err)
+    }
+    if transcript != nil {
+        return ErrChatTranscribed
+    }
+    return nil
+}
+
+func (s *chatService) History(ctx context.Context, messageID, userID int, admin bool) (*models.ChatMessageHistory, error) {
+    message, err := s.chatRepo.GetByID(ctx, messageID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to get message: %w", err)
+    }
+    if !admin {
+        if _, err := s.Participant(ctx, message.ConsultationID, userID); err != nil {
+            return nil, err
+        }
+    }
+
+    history := &models.ChatMessageHistory{Message: message, Edits: []*models.ChatMessageEdit{}}
+    if message.DeletedAt.Valid && !admin {
+        redactDeleted(message)
+        return history, nil
+    }
+    if history.Edits, err = s.chatRepo.ListEdits(ctx, messageID); err != nil {
+        s.log.WithError(err).Errorf("Failed to list edits of chat message ID: %d", messageID)
+        return nil, fmt.Errorf("failed to get edit history: %w", err)
+    }
+    return history, nil
+}
+
+func (s *chatService) GetRetentionPolicy(ctx context.Context) (*models.ChatRetentionPolicy, error) {
+    policy, err := s.chatRepo.GetRetentionPolicy(ctx)
+    if err != nil {
+        s.log.WithError(err).Error("Failed to get chat retention policy")
+        return nil, fmt.Errorf("failed to get chat retention policy: %w", err)
+    }
+    if policy == nil {
+        defaults := defaultChatRetentionPolicy
+        return &defaults, nil
+    }
+    return policy, nil
+}
+
+func (s *chatService) UpdateRetentionPolicy(ctx context.Context, policy *models.ChatRetentionPolicy) error {
+    if policy.EditWindowMinutes < 0 || policy.DeleteWindowMinutes < 0 {
+        return errors.New("edit and delete windows must not be negative")
+    }
+    if policy.ArchiveAfterDays < 0 {
+        return errors.New("archive period must not be negative")
+    }
+
+    if err := s.chatRepo.SaveRetentionPolicy(ctx, policy); err != nil {
+        s.log.WithError(err).Error("Failed to save chat retention policy")
+        return fmt.Errorf("failed to save chat retention policy: %w", err)
+    }
+    return nil
+}
+
+func (s *chatService) ArchiveExpiredChats(ctx context.Context) (int, error) {
+    policy, err := s.GetRetentionPolicy(ctx)
+    if err != nil {
+        return 0, err
+    }
+    if policy.ArchiveAfterDays == 0 {
+        return 0, nil
+    }
+
+    now := time.Now().UTC()
+    archived, err := s.chatRepo.ArchiveCompletedBefore(ctx, now.AddDate(0, 0, -policy.ArchiveAfterDays), now.Truncate(time.Second))
+    if err != nil {
+        s.log.WithError(err).Error("Failed to archive chats")
+        return 0, fmt.Errorf("failed to archive chats: %w", err)
+    }
+    if archived > 0 {
+        s.log.Infof("Archived %d chat messages", archived)
+    }
+    return archived, nil
+}
+
+func (s *chatService) Search(ctx context.Context, filter models.ChatSearchFilter) ([]*models.ChatSearchResult, error) {
+    terms := searchTerms(filter.Query)
+    if len(terms) == 0 {
+        return nil, errors.New("search query has no words")
+    }
+    if filter.SenderType != "" && filter.SenderType != "doctor" && filter.SenderType != "patient" {
+        return nil, errors.New("sender must be doctor or patient")
+    }
+    if filter.Limit <= 0 || filter.Limit > maxChatPageSize {
+        filter.Limit = 20
+    }
+    if filter.Offset < 0 {
+        filter.Offset = 0
+    }
+    if filter.ListPageSize <= 0 || filter.ListPageSize > maxChatPageSize {
+        filter.ListPageSize = DefaultChatPageSize
+    }
+
+    // Every word must occur, as a prefix of a word in the message
+    booleanQuery := make([]string, len(terms))
+    for i, term := range terms {
+        booleanQuery[i] = "+" + term + "*"
+    }
+
+    results, err := s.chatRepo.Search(ctx, strings.Join(booleanQuery, " "), filter)
+    if err != nil {
+        s.log.WithError(err).Errorf("Failed to search chat messages of user ID: %d", filter.UserID)
+        return nil, errors.New("failed to search messages")
+    }
+    for _, result := range results {
+        result.Fragment, result.Highlights = chatFragment(result.Message.Message, terms)
+        result.PageSize = filter.ListPageSize
+        result.Page = result.Position/filter.ListPageSize + 1
+    }
+    return results, nil
+}
+
+func (s *chatService) ListConversations(ctx context.Context, userID, limit, offset int) ([]*models.ChatConversation, error) {
+    if limit <= 0 || limit > maxChatPageSize {
+        limit = 20
+    }
+    if offset < 0 {
+        offset = 0
+    }
+
+    conversations, err := s.chatRepo.ListConversations(ctx, userID, limit, offset)
+    if err != nil {
+        s.log.WithError(err).Errorf("Failed to list conversations of user ID: %d", userID)
+        return nil, errors.New("failed to list conversations")
+    }
+    for _, conversation := range conversations {
+        if conversation.LastMessage != nil {
+            redactDeleted(conversation.LastMessage)
+        }
+    }
+    return conversations, nil
+}
+
+func (s *chatService) MarkAllRead(ctx context.Context, consultationID, userID int) (int, error) {
+    if _, err := s.Participant(ctx, consultationID, userID); err != nil {
+        return 0, err
+    }
+    latest, err := s.chatRepo.GetByConsultationID(ctx, consultationID, 1, 0)
+    if err != nil {
+        s.log.WithError(err).Errorf("Failed to get latest chat message of consultation ID: %d", consultationID)
+        return 0, errors.New("failed to update message status")
+    }
+    if len(latest) == 0 {
+        return 0, nil
+    }
+
+    marked, err := s.chatRepo.MarkReadUpTo(ctx, consultationID, userID, latest[0].ID)
+    if err != nil {
+        s.log.WithError(err).Errorf("Failed to mark chat messages read in consultation ID: %d", consultationID)
+        return 0, errors.New("failed to update message status")
+    }
+    if marked > 0 {
+        s.publish(ctx, &models.ChatEvent{Type: models.ChatEventRead, ConsultationID: consultationID, UserID: userID, MessageID: latest[0].ID})
+    }
+    return marked, nil
+}
+
+func (s *chatService) Subscribe(consultationID int, handler func(*models.ChatEvent)) (func(), error) {
+    return s.broker.Subscribe(chatTopic(consultationID), func(payload []byte) {
+        var event models.ChatEvent
+        if err := json.Unmarshal(payload, &event); err != nil {
+            s.log.WithError(err).Error("Failed to decode chat event")
+            return
+        }
+        handler(&event)
+    })
+}
+
+// publish pushes an event to the chat's subscribers. The change it reports is already stored,
+// so failures are only logged; clients catch up when they reconnect.
+func (s *chatService) publish(ctx context.Context, event *models.ChatEvent) {
+    payload, err := json.Marshal(event)
+    if err != nil {
+        s.log.WithError(err).Error("Failed to encode chat event")
+        return
+    }
+    if err := s.broker.Publish(ctx, chatTopic(event.ConsultationID), payload); err != nil {
+        s.log.WithError(err).Errorf("Failed to publish chat event to consultation ID: %d", event.ConsultationID)
+    }
+}
+
+func chatTopic(consultationID int) string {
+    return "chat.consultation." + strconv.Itoa(consultationID)
+}
+
+// validateChatText trims a message's text and checks its length; text may only be empty
+// when the message has attachments
+func validateChatText(text string, hasAttachments bool) (string, error) {
+    text = strings.TrimSpace(text)
+    if text == "" && !hasAttachments {
+        return "", errors.New("message is empty")
+    }
+    if utf8.RuneCountInString(text) > maxChatMessageLength {
+        return "", fmt.Errorf("message is longer than %d characters", maxChatMessageLength)
+    }
+    return text, nil
+}
+
+// chatArchived reports whether the retention policy has closed the consultation's chat, which
+// the archiving job may not have caught up with yet
+func chatArchived(consultation *models.Consultation, policy *models.ChatRetentionPolicy, now time.Time) bool {
+    if policy.ArchiveAfterDays == 0 || consultation.Status != "completed" || !consultation.CompletedAt.Valid {
+        return false
+    }
+    return consultation.CompletedAt.Time.Before(now.AddDate(0, 0, -policy.ArchiveAfterDays))
+}
+
+// redactDeleted hides the content of deleted messages from participants
+func redactDeleted(messages ...*models.ChatMessage) {
+    for _, message := range messages {
+        if message.DeletedAt.Valid {
+            message.Message = ""
+            message.Attachments = nil
+        }
+    }
+}
+
+// searchTerms splits a search query into lower-case words. Full-text operators are dropped,
+// so users cannot change the query's meaning by typing them.
+func searchTerms(query string) []string {
+    words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool { return !isWordRune(r) })
+    if len(words) > 10 {
+        words = words[:10]
+    }
+    return words
+}
+
+// chatFragment excerpts text around its first word starting with one of the terms and returns
+// the matching words in the excerpt
+func chatFragment(text string, terms []string) (string, []models.TextRange) {
+    runes := []rune(text)
+    matches := []models.TextRange{}
+    for i := 0; i < len(runes); {
+        if !isWordRune(runes[i]) {
+            i++
+            continue
+        }
+        j := i
+        for j < len(runes) && isWordRune(runes[j]) {
+            j++
+        }
+        word := strings.ToLower(string(runes[i:j]))
+        for _, term := range terms {
+            if strings.HasPrefix(word, term) {
+                matches = append(matches, models.TextRange{Start: i, End: j})
+                break
+            }
+        }
+        i = j
+    }
+
+    if len(runes) <= chatFragmentLength {
+        return text, matches
+    }
+
+    // Start a little before the first match, at a word boundary, and mark the cuts
+    start := 0
+    if len(matches) > 0 {
+        start = max(0, matches[0].Start-chatFragmentLength/4)
+        for start > 0 && isWordRune(runes[start-1]) {
+            start--
+        }
+    }
+    end := min(len(runes), start+chatFragmentLength)
+    for end < len(runes) && end > start && isWordRune(runes[end]) {
+        end--
+    }
+    if end == start {
+        end = min(len(runes), start+chatFragmentLength)
+    }
+
+    prefix, suffix := "", ""
+    if start > 0 {
+        prefix = "… "
+    }
+    if end < len(runes) {
+        suffix = " …"
+    }
+    shift := utf8.RuneCountInString(prefix) - start
+    highlights := []models.TextRange{}
+    for _, match := range matches {
+        if match.Start >= start && match.End <= end {
+            highlights = append(highlights, models.TextRange{Start: match.Start + shift, End: match.End + shift})
+        }
+    }
+    return prefix + string(runes[start:end]) + suffix, highlights
+}
+
+func isWordRune(r rune) bool {
+    return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"shifa/internal/models"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"words", "Fever and Cough", []string{"fever", "and", "cough"}},
+		{"full-text operators dropped", `+fever -"cough*" (rash) ~itch <pain> @2`, []string{"fever", "cough", "rash", "itch", "pain", "2"}},
+		{"punctuation splits words", "follow-up, 10mg", []string{"follow", "up", "10mg"}},
+		{"arabic", "حمى، سعال", []string{"حمى", "سعال"}},
+		{"arabic with diacritics", "دَوَاء", []string{"دَوَاء"}},
+		{"at most ten words", "a b c d e f g h i j k l", []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}},
+		{"nothing searchable", ` +-"*() `, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := searchTerms(tt.query)
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("searchTerms(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatFragmentShortText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  []models.TextRange
+	}{
+		{"prefix match", "I have a fever since Monday", []string{"fev"}, []models.TextRange{{Start: 9, End: 14}}},
+		{"case-insensitive", "Fever, then FEVERISH", []string{"fever"}, []models.TextRange{{Start: 0, End: 5}, {Start: 12, End: 20}}},
+		{"matches start at a word", "antifever", []string{"fever"}, []models.TextRange{}},
+		{"any term", "cough and rash", []string{"rash", "cou"}, []models.TextRange{{Start: 0, End: 5}, {Start: 10, End: 14}}},
+		{"positions count runes", "عندي حمى شديدة", []string{"حم"}, []models.TextRange{{Start: 5, End: 8}}},
+		{"no terms", "I have a fever", nil, []models.TextRange{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fragment, highlights := chatFragment(tt.text, tt.terms)
+			if fragment != tt.text {
+				t.Errorf("fragment = %q, want the whole text", fragment)
+			}
+			if !reflect.DeepEqual(highlights, tt.want) {
+				t.Errorf("highlights = %v, want %v", highlights, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatFragmentLongText(t *testing.T) {
+	filler := func(n int) string { return strings.Repeat("lorem ", n) }
+
+	tests := []struct {
+		name       string
+		text       string
+		term       string
+		wantPrefix bool
+		wantSuffix bool
+		wantWords  []string // highlighted words
+	}{
+		{"match in the middle", filler(100) + "fever " + filler(100), "fever", true, true, []string{"fever"}},
+		{"match near the start", "fever " + filler(100), "fever", false, true, []string{"fever"}},
+		{"match at the end", filler(100) + "fever", "fever", true, false, []string{"fever"}},
+		{"no match", filler(100), "fever", false, true, nil},
+		{"arabic", strings.Repeat("كلام ", 100) + "حمى " + strings.Repeat("كلام ", 100), "حم", true, true, []string{"حمى"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fragment, highlights := chatFragment(tt.text, []string{tt.term})
+
+			if got := strings.HasPrefix(fragment, "… "); got != tt.wantPrefix {
+				t.Errorf("prefix cut = %v, want %v: %q", got, tt.wantPrefix, fragment)
+			}
+			if got := strings.HasSuffix(fragment, " …"); got != tt.wantSuffix {
+				t.Errorf("suffix cut = %v, want %v: %q", got, tt.wantSuffix, fragment)
+			}
+			body := strings.TrimSuffix(strings.TrimPrefix(fragment, "… "), " …")
+			if n := utf8.RuneCountInString(body); n > chatFragmentLength {
+				t.Errorf("excerpt of %d runes, want at most %d", n, chatFragmentLength)
+			}
+			// The excerpt is a run of whole words from the text
+			if !strings.Contains(" "+tt.text+" ", " "+strings.TrimSpace(body)+" ") {
+				t.Errorf("excerpt is not whole words of the text: %q", body)
+			}
+
+			runes := []rune(fragment)
+			var got []string
+			for _, h := range highlights {
+				got = append(got, string(runes[h.Start:h.End]))
+			}
+			if !reflect.DeepEqual(got, tt.wantWords) {
+				t.Errorf("highlighted %q, want %q", got, tt.wantWords)
+			}
+		})
+	}
+}
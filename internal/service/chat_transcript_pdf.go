@@ -0,0 +1,91 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"shifa/internal/models"
+	"shifa/pkg/pdffonts"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// chatTranscriptTime is how the transcript PDF shows times; the JSON document keeps them exact
+const chatTranscriptTime = "2006-01-02 15:04:05 UTC"
+
+// renderChatTranscriptPDF renders the transcript document in English. Messages are set in the
+// direction of their own text, so Arabic messages read right to left. contentHash is printed so
+// a paper copy can be matched with the signed JSON document.
+func renderChatTranscriptPDF(d *models.ChatTranscriptDocument, contentHash string) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdffonts.Register(pdf)
+	pdf.SetTitle("Consultation chat transcript", true)
+	pdf.SetMargins(summaryMargin, summaryMargin, summaryMargin)
+	pdf.AddPage()
+	pageWidth, _ := pdf.GetPageSize()
+	w := &summaryPDF{pdf: pdf, align: "L", width: pageWidth - 2*summaryMargin}
+
+	w.write("Consultation chat transcript", "B", 18, 9)
+	w.write("Consultation no.: "+strconv.Itoa(d.ConsultationID), "", 10, 6)
+	if d.StartedAt.Valid {
+		w.write("Started: "+d.StartedAt.Time.UTC().Format(chatTranscriptTime), "", 10, 6)
+	}
+	if d.CompletedAt.Valid {
+		w.write("Completed: "+d.CompletedAt.Time.UTC().Format(chatTranscriptTime), "", 10, 6)
+	}
+	pdf.Ln(3)
+	w.write("Patient: "+d.Patient.Name, "", 11, 6)
+	w.write("Doctor: "+d.Doctor.Name, "", 11, 6)
+
+	w.heading("Messages")
+	if len(d.Messages) == 0 {
+		w.write("No messages were sent in this consultation.", "", 10, 5)
+	}
+	for _, entry := range d.Messages {
+		w.write(fmt.Sprintf("#%d  %s  %s", entry.MessageID, entry.SentAt.UTC().Format(chatTranscriptTime), chatTranscriptSender(d, entry)), "B", 9, 5)
+		if entry.Message != "" {
+			w.write(entry.Message, "", 10, 5)
+		}
+		for _, attachment := range entry.Attachments {
+			w.write(fmt.Sprintf("Attachment #%d: %s (%s, %d bytes), SHA-256 %s",
+				attachment.AttachmentID, attachment.FileName, attachment.ContentType, attachment.Size, attachment.SHA256), "", 8, 4)
+		}
+		for _, edit := range entry.Edits {
+			w.write("Text before the edit of "+edit.EditedAt.UTC().Format(chatTranscriptTime)+":", "", 8, 4)
+			w.write(edit.PreviousMessage, "", 8, 4)
+		}
+		if entry.DeletedAt.Valid {
+			deleted := "Deleted " + entry.DeletedAt.Time.UTC().Format(chatTranscriptTime)
+			if entry.DeletedBy != nil {
+				deleted += " by user " + strconv.Itoa(*entry.DeletedBy)
+			}
+			w.write(deleted+"; hidden from the participants", "", 8, 4)
+		}
+		pdf.Ln(2)
+	}
+
+	w.heading("Signature")
+	w.write(fmt.Sprintf("Signed by %s (user %d) on %s", d.SignerName, d.SignedBy, d.SignedAt.UTC().Format(chatTranscriptTime)), "", 10, 5)
+	w.write("SHA-256 of the signed JSON transcript:", "", 10, 5)
+	w.write(contentHash, "", 9, 5)
+	pdf.Ln(6)
+	w.write("This document renders the signed chat transcript of the consultation. The JSON transcript is the record; its hash above and the hashes of the attachments detect any later change.", "", 8, 4)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// chatTranscriptSender names who sent a message
+func chatTranscriptSender(d *models.ChatTranscriptDocument, entry models.ChatTranscriptEntry) string {
+	switch entry.SenderID {
+	case d.Doctor.UserID:
+		return d.Doctor.Name + " (doctor)"
+	case d.Patient.UserID:
+		return d.Patient.Name + " (patient)"
+	}
+	return fmt.Sprintf("%s %d", entry.SenderType, entry.SenderID)
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+	"shifa/pkg/fileutils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// chatTranscriptPage is how many messages are read at a time while exporting a chat
+const chatTranscriptPage = 200
+
+var (
+	ErrNotTranscriptSigner        = errors.New("only the consultation's doctor can sign its chat transcript")
+	ErrChatTranscriptAccessDenied = errors.New("only the consultation's patient and doctor can read its chat transcript")
+	ErrChatTranscriptNotCompleted = errors.New("the chat transcript can be exported once the consultation is completed")
+	ErrChatTranscriptExists       = errors.New("the consultation's chat transcript has already been signed")
+	ErrChatTranscriptNotFound     = errors.New("the consultation's chat transcript has not been exported")
+)
+
+// ChatTranscriptService exports the chat of a completed consultation into its medical record.
+// For async visits the chat is the clinical record, so the export is signed by the doctor and
+// stored once, as a JSON document and a PDF rendering of it, with hashes that reveal any later
+// change to the files or to the attachments they reference.
+type ChatTranscriptService struct {
+	transcriptRepo   repository.ChatTranscriptRepository
+	chatRepo         repository.ChatMessageRepository
+	consultationRepo repository.ConsultationRepository
+	userRepo         repository.UserRepository
+	logger           *logrus.Logger
+}
+
+func NewChatTranscriptService(
+	transcriptRepo repository.ChatTranscriptRepository,
+	chatRepo repository.ChatMessageRepository,
+	consultationRepo repository.ConsultationRepository,
+	userRepo repository.UserRepository,
+	logger *logrus.Logger,
+) *ChatTranscriptService {
+	return &ChatTranscriptService{
+		transcriptRepo:   transcriptRepo,
+		chatRepo:         chatRepo,
+		consultationRepo: consultationRepo,
+		userRepo:         userRepo,
+		logger:           logger,
+	}
+}
+
+// Export signs the chat of a completed consultation as its doctor and stores the transcript.
+// Every message is included as it stands, with the texts edits replaced, deleted messages and
+// the hashes of attached files. A consultation's transcript is exported once, and its chat is
+// read-only from then on.
+func (s *ChatTranscriptService) Export(ctx context.Context, consultationID, doctorID int, ipAddress string) (*models.ChatTranscript, error) {
+	consultation, err := s.consultationRepo.GetByID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to get consultation: %w", err)
+	}
+	if consultation.DoctorID != doctorID {
+		return nil, ErrNotTranscriptSigner
+	}
+	if consultation.Status != "completed" {
+		return nil, ErrChatTranscriptNotCompleted
+	}
+	existing, err := s.transcriptRepo.GetByConsultationID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get chat transcript of consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to get chat transcript: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrChatTranscriptExists
+	}
+
+	patient, err := s.userRepo.GetByID(ctx, consultation.PatientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+	doctor, err := s.userRepo.GetByID(ctx, consultation.DoctorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get doctor: %w", err)
+	}
+
+	document := &models.ChatTranscriptDocument{
+		ConsultationID: consultationID,
+		Patient:        models.ChatTranscriptParty{UserID: patient.ID, Name: patient.Name},
+		Doctor:         models.ChatTranscriptParty{UserID: doctor.ID, Name: doctor.Name},
+		StartedAt:      consultation.StartedAt,
+		CompletedAt:    consultation.CompletedAt,
+		SignedBy:       doctor.ID,
+		SignerName:     doctor.Name,
+		SignedAt:       time.Now().UTC().Truncate(time.Second),
+	}
+	if document.Messages, err = s.transcriptEntries(ctx, consultationID); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode chat transcript: %w", err)
+	}
+	contentHash := sha256Hex(data)
+	pdf, err := renderChatTranscriptPDF(document, contentHash)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to render chat transcript of consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to render chat transcript: %w", err)
+	}
+
+	transcript := &models.ChatTranscript{
+		ConsultationID: consultationID,
+		MessageCount:   len(document.Messages),
+		Signature: models.ClinicalSignature{
+			SignedBy:    doctor.ID,
+			SignerName:  doctor.Name,
+			SignedAt:    document.SignedAt,
+			ContentHash: contentHash,
+			IPAddress:   ipAddress,
+		},
+		PDFHash: sha256Hex(pdf),
+	}
+	baseName := fmt.Sprintf("consultation_%d_chat", consultationID)
+	if transcript.JSONPath, err = fileutils.SavePrivateFile(bytes.NewReader(data), baseName+".json", fileutils.ChatTranscripts); err != nil {
+		s.logger.WithError(err).Errorf("Failed to store chat transcript of consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to store chat transcript: %w", err)
+	}
+	if transcript.PDFPath, err = fileutils.SavePrivateFile(bytes.NewReader(pdf), baseName+".pdf", fileutils.ChatTranscripts); err != nil {
+		fileutils.DeletePrivateFile(transcript.JSONPath)
+		s.logger.WithError(err).Errorf("Failed to store chat transcript of consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to store chat transcript: %w", err)
+	}
+	if err := s.transcriptRepo.Create(ctx, transcript); err != nil {
+		fileutils.DeletePrivateFile(transcript.JSONPath)
+		fileutils.DeletePrivateFile(transcript.PDFPath)
+		s.logger.WithError(err).Errorf("Failed to save chat transcript of consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to save chat transcript: %w", err)
+	}
+
+	s.logger.Infof("Chat transcript of consultation ID: %d signed by user ID: %d with %d messages",
+		consultationID, doctorID, transcript.MessageCount)
+	return transcript, nil
+}
+
+// Get returns the consultation's transcript to its patient and doctor, or to an admin
+func (s *ChatTranscriptService) Get(ctx context.Context, consultationID, userID int, admin bool) (*models.ChatTranscript, error) {
+	consultation, err := s.consultationRepo.GetByID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to get consultation: %w", err)
+	}
+	if !admin && userID != consultation.PatientID && userID != consultation.DoctorID {
+		return nil, ErrChatTranscriptAccessDenied
+	}
+	transcript, err := s.transcriptRepo.GetByConsultationID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get chat transcript of consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to get chat transcript: %w", err)
+	}
+	if transcript == nil {
+		return nil, ErrChatTranscriptNotFound
+	}
+	return transcript, nil
+}
+
+// File returns the stored path of the transcript in the given format, pdf or json
+func (s *ChatTranscriptService) File(ctx context.Context, consultationID, userID int, admin bool, format string) (string, error) {
+	if format != models.ChatTranscriptFormatPDF && format != models.ChatTranscriptFormatJSON {
+		return "", fmt.Errorf("unsupported format %q, expected %q or %q", format, models.ChatTranscriptFormatPDF, models.ChatTranscriptFormatJSON)
+	}
+	transcript, err := s.Get(ctx, consultationID, userID, admin)
+	if err != nil {
+		return "", err
+	}
+	if format == models.ChatTranscriptFormatJSON {
+		return transcript.JSONPath, nil
+	}
+	return transcript.PDFPath, nil
+}
+
+// Verify hashes the stored transcript files and the attachments the transcript references
+// again and compares them with the hashes recorded when it was signed. Files that are missing
+// or cannot be read count as changed.
+func (s *ChatTranscriptService) Verify(ctx context.Context, consultationID, userID int, admin bool) (*models.ChatTranscriptVerification, error) {
+	transcript, err := s.Get(ctx, consultationID, userID, admin)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &models.ChatTranscriptVerification{
+		ConsultationID: consultationID,
+		ContentHash:    transcript.Signature.ContentHash,
+		VerifiedAt:     time.Now().UTC().Truncate(time.Second),
+	}
+	data, err := os.ReadFile(fileutils.GetPrivatePath(transcript.JSONPath))
+	if err != nil {
+		s.logger.WithError(err).Warnf("Failed to read chat transcript %s", transcript.JSONPath)
+	}
+	verification.JSONIntact = err == nil && sha256Hex(data) == transcript.Signature.ContentHash
+	verification.PDFIntact = s.fileMatches(transcript.PDFPath, transcript.PDFHash)
+
+	var document models.ChatTranscriptDocument
+	if verification.JSONIntact && json.Unmarshal(data, &document) == nil {
+		verification.AttachmentsIntact, err = s.attachmentsMatch(ctx, document.Messages)
+		if err != nil {
+			return nil, err
+		}
+	}
+	verification.Valid = verification.JSONIntact && verification.PDFIntact && verification.AttachmentsIntact
+
+	if !verification.Valid {
+		s.logger.Warnf("Chat transcript of consultation ID: %d failed verification: json=%t pdf=%t attachments=%t",
+			consultationID, verification.JSONIntact, verification.PDFIntact, verification.AttachmentsIntact)
+	}
+	return verification, nil
+}
+
+// transcriptEntries reads all of the consultation's messages, oldest first, with their edits
+// and the hashes of their attachments
+func (s *ChatTranscriptService) transcriptEntries(ctx context.Context, consultationID int) ([]models.ChatTranscriptEntry, error) {
+	entries := []models.ChatTranscriptEntry{}
+	afterID := 0
+	for {
+		messages, err := s.chatRepo.ListSince(ctx, consultationID, afterID, chatTranscriptPage)
+		if err != nil {
+			s.logger.WithError(err).Errorf("Failed to list chat messages of consultation ID: %d", consultationID)
+			return nil, fmt.Errorf("failed to list chat messages: %w", err)
+		}
+		for _, message := range messages {
+			entry := models.ChatTranscriptEntry{
+				MessageID:  message.ID,
+				SenderType: message.SenderType,
+				SenderID:   message.SenderID,
+				SentAt:     message.SentAt.UTC(),
+				Message:    message.Message,
+				EditedAt:   message.EditedAt,
+				DeletedAt:  message.DeletedAt,
+				DeletedBy:  message.DeletedBy,
+			}
+			if message.EditedAt.Valid {
+				if entry.Edits, err = s.chatRepo.ListEdits(ctx, message.ID); err != nil {
+					s.logger.WithError(err).Errorf("Failed to list edits of chat message ID: %d", message.ID)
+					return nil, fmt.Errorf("failed to list chat message edits: %w", err)
+				}
+			}
+			for _, attachment := range message.Attachments {
+				hash, err := fileSHA256(fileutils.GetPrivatePath(attachment.FilePath))
+				if err != nil {
+					s.logger.WithError(err).Errorf("Failed to hash chat attachment ID: %d", attachment.ID)
+					return nil, fmt.Errorf("failed to read attachment %q: %w", attachment.FileName, err)
+				}
+				entry.Attachments = append(entry.Attachments, models.ChatTranscriptAttachment{
+					AttachmentID: attachment.ID,
+					FileName:     attachment.FileName,
+					ContentType:  attachment.ContentType,
+					Size:         attachment.Size,
+					SHA256:       hash,
+				})
+			}
+			entries = append(entries, entry)
+			afterID = message.ID
+		}
+		if len(messages) < chatTranscriptPage {
+			return entries, nil
+		}
+	}
+}
+
+// attachmentsMatch reports whether the referenced attachments still hash as recorded
+func (s *ChatTranscriptService) attachmentsMatch(ctx context.Context, entries []models.ChatTranscriptEntry) (bool, error) {
+	for _, entry := range entries {
+		for _, recorded := range entry.Attachments {
+			attachment, err := s.chatRepo.GetAttachment(ctx, recorded.AttachmentID)
+			if err != nil {
+				s.logger.WithError(err).Errorf("Failed to get chat attachment ID: %d", recorded.AttachmentID)
+				return false, fmt.Errorf("failed to get attachment: %w", err)
+			}
+			if !s.fileMatches(attachment.FilePath, recorded.SHA256) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+func (s *ChatTranscriptService) fileMatches(relativePath, hash string) bool {
+	actual, err := fileSHA256(fileutils.GetPrivatePath(relativePath))
+	if err != nil {
+		s.logger.WithError(err).Warnf("Failed to hash %s", relativePath)
+		return false
+	}
+	return actual == hash
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,391 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrNotConsultationDoctor = errors.New("only the consultation's doctor can write its clinical note")
+	ErrNoteAccessDenied      = errors.New("only the consultation's patient and doctor can read its clinical note")
+	ErrNoteNotDraft          = errors.New("signed clinical notes cannot be changed; amend them instead")
+)
+
+// ClinicalNoteService manages the SOAP notes of consultations: drafting, signing and amending
+type ClinicalNoteService struct {
+	noteRepo         repository.ClinicalNoteRepository
+	consultationRepo repository.ConsultationRepository
+	userRepo         repository.UserRepository
+	codes            *DiagnosisCodeService
+	history          *MedicalHistoryService
+	logger           *logrus.Logger
+}
+
+func NewClinicalNoteService(
+	noteRepo repository.ClinicalNoteRepository,
+	consultationRepo repository.ConsultationRepository,
+	userRepo repository.UserRepository,
+	codes *DiagnosisCodeService,
+	history *MedicalHistoryService,
+	logger *logrus.Logger,
+) *ClinicalNoteService {
+	return &ClinicalNoteService{
+		noteRepo:         noteRepo,
+		consultationRepo: consultationRepo,
+		userRepo:         userRepo,
+		codes:            codes,
+		history:          history,
+		logger:           logger,
+	}
+}
+
+// CreateDraft starts the consultation's note. Once a note has been signed, changes go through
+// Amend so that the signed version is kept.
+func (s *ClinicalNoteService) CreateDraft(ctx context.Context, consultationID, authorID int, note *models.ClinicalNote) error {
+	if _, err := s.consultationForDoctor(ctx, consultationID, authorID); err != nil {
+		return err
+	}
+	if err := s.validate(ctx, note); err != nil {
+		return err
+	}
+
+	notes, err := s.noteRepo.ListByConsultation(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list clinical notes for consultation ID: %d", consultationID)
+		return fmt.Errorf("failed to list clinical notes: %w", err)
+	}
+	if len(notes) > 0 {
+		return errors.New("the consultation already has a clinical note; edit the draft or amend the signed note")
+	}
+
+	note.ConsultationID = consultationID
+	note.AuthorID = authorID
+	note.Status = models.ClinicalNoteDraft
+	note.AmendsNoteID = nil
+	note.AmendmentReason = ""
+	note.Signature = nil
+	if err := s.noteRepo.Create(ctx, note); err != nil {
+		s.logger.WithError(err).Errorf("Failed to create clinical note for consultation ID: %d", consultationID)
+		return fmt.Errorf("failed to create clinical note: %w", err)
+	}
+	return nil
+}
+
+// UpdateDraft replaces the content of a draft note
+func (s *ClinicalNoteService) UpdateDraft(ctx context.Context, id, authorID int, content *models.ClinicalNote) (*models.ClinicalNote, error) {
+	note, err := s.getNote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.consultationForDoctor(ctx, note.ConsultationID, authorID); err != nil {
+		return nil, err
+	}
+	if note.Status != models.ClinicalNoteDraft {
+		return nil, ErrNoteNotDraft
+	}
+	if err := s.validate(ctx, content); err != nil {
+		return nil, err
+	}
+
+	note.Subjective = content.Subjective
+	note.Objective = content.Objective
+	note.Assessment = content.Assessment
+	note.Plan = content.Plan
+	if note.AmendsNoteID != nil && content.AmendmentReason != "" {
+		note.AmendmentReason = content.AmendmentReason
+	}
+	if err := s.noteRepo.UpdateDraft(ctx, note); err != nil {
+		s.logger.WithError(err).Errorf("Failed to update clinical note ID: %d", id)
+		return nil, fmt.Errorf("failed to update clinical note: %w", err)
+	}
+	return note, nil
+}
+
+// Sign e-signs a draft as the consultation's doctor. Signing an amendment supersedes the
+// note it amends.
+func (s *ClinicalNoteService) Sign(ctx context.Context, id, signerID int, ipAddress string) (*models.ClinicalNote, error) {
+	note, err := s.getNote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	consultation, err := s.consultationForDoctor(ctx, note.ConsultationID, signerID)
+	if err != nil {
+		return nil, err
+	}
+	if note.Status != models.ClinicalNoteDraft {
+		return nil, errors.New("clinical note is already signed")
+	}
+	if note.Subjective.ChiefComplaint == "" || len(note.Assessment.Diagnoses) == 0 {
+		return nil, errors.New("a chief complaint and at least one diagnosis are required before signing")
+	}
+
+	signer, err := s.userRepo.GetByID(ctx, signerID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get signer user ID: %d", signerID)
+		return nil, fmt.Errorf("failed to get signer: %w", err)
+	}
+
+	signedAt := time.Now().UTC().Truncate(time.Second)
+	hash, err := clinicalNoteHash(note, signerID, signedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash clinical note: %w", err)
+	}
+	note.Signature = &models.ClinicalSignature{
+		SignedBy:    signerID,
+		SignerName:  signer.Name,
+		SignedAt:    signedAt,
+		ContentHash: hash,
+		IPAddress:   ipAddress,
+	}
+
+	if err := s.noteRepo.Sign(ctx, note); err != nil {
+		s.logger.WithError(err).Errorf("Failed to sign clinical note ID: %d", id)
+		return nil, fmt.Errorf("failed to sign clinical note: %w", err)
+	}
+	s.logger.Infof("Clinical note signed: ID=%d, version=%d", note.ID, note.Version)
+
+	// The note is signed either way; a failed propagation is logged for follow-up
+	_, err = s.history.RecordConsultationDiagnoses(ctx, consultation.PatientID, consultation.ID,
+		signedAt, note.Plan.Treatment, note.Assessment.Diagnoses)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to record diagnoses of clinical note ID: %d in medical history", note.ID)
+	}
+	return note, nil
+}
+
+// Amend opens a draft amendment of the current signed note; the signed note stays as it was
+func (s *ClinicalNoteService) Amend(ctx context.Context, id, authorID int, reason string, content *models.ClinicalNote) (*models.ClinicalNote, error) {
+	original, err := s.getNote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.consultationForDoctor(ctx, original.ConsultationID, authorID); err != nil {
+		return nil, err
+	}
+	if original.Status != models.ClinicalNoteSigned {
+		return nil, errors.New("only the current signed version of a note can be amended")
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, errors.New("validation error: an amendment reason is required")
+	}
+	if err := s.validate(ctx, content); err != nil {
+		return nil, err
+	}
+
+	notes, err := s.noteRepo.ListByConsultation(ctx, original.ConsultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list clinical notes for consultation ID: %d", original.ConsultationID)
+		return nil, fmt.Errorf("failed to list clinical notes: %w", err)
+	}
+	for _, note := range notes {
+		if note.Status == models.ClinicalNoteDraft {
+			return nil, fmt.Errorf("amendment %d is already in progress", note.ID)
+		}
+	}
+
+	amendment := &models.ClinicalNote{
+		ConsultationID:  original.ConsultationID,
+		AuthorID:        authorID,
+		Status:          models.ClinicalNoteDraft,
+		AmendsNoteID:    &original.ID,
+		AmendmentReason: reason,
+		Subjective:      content.Subjective,
+		Objective:       content.Objective,
+		Assessment:      content.Assessment,
+		Plan:            content.Plan,
+	}
+	if err := s.noteRepo.Create(ctx, amendment); err != nil {
+		s.logger.WithError(err).Errorf("Failed to create amendment of clinical note ID: %d", id)
+		return nil, fmt.Errorf("failed to create amendment: %w", err)
+	}
+	return amendment, nil
+}
+
+// GetNote returns a note version to the consultation's patient or doctor
+func (s *ClinicalNoteService) GetNote(ctx context.Context, id, userID int) (*models.ClinicalNote, error) {
+	note, err := s.getNote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkReader(ctx, note.ConsultationID, userID); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// ListVersions returns every version of the consultation's note, oldest first
+func (s *ClinicalNoteService) ListVersions(ctx context.Context, consultationID, userID int) ([]*models.ClinicalNote, error) {
+	if err := s.checkReader(ctx, consultationID, userID); err != nil {
+		return nil, err
+	}
+	notes, err := s.noteRepo.ListByConsultation(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list clinical notes for consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to list clinical notes: %w", err)
+	}
+	if notes == nil {
+		notes = []*models.ClinicalNote{}
+	}
+	return notes, nil
+}
+
+// GetCurrent returns the consultation's current note to its patient or doctor
+func (s *ClinicalNoteService) GetCurrent(ctx context.Context, consultationID, userID int) (*models.ClinicalNote, error) {
+	if err := s.checkReader(ctx, consultationID, userID); err != nil {
+		return nil, err
+	}
+	return s.CurrentNote(ctx, consultationID)
+}
+
+// CurrentNote returns the consultation's signed note in force, or its draft when nothing has
+// been signed yet; nil when there is no note
+func (s *ClinicalNoteService) CurrentNote(ctx context.Context, consultationID int) (*models.ClinicalNote, error) {
+	notes, err := s.noteRepo.ListByConsultation(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list clinical notes for consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to list clinical notes: %w", err)
+	}
+	var draft *models.ClinicalNote
+	for _, note := range notes {
+		switch note.Status {
+		case models.ClinicalNoteSigned:
+			return note, nil
+		case models.ClinicalNoteDraft:
+			draft = note
+		}
+	}
+	return draft, nil
+}
+
+func (s *ClinicalNoteService) getNote(ctx context.Context, id int) (*models.ClinicalNote, error) {
+	note, err := s.noteRepo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get clinical note ID: %d", id)
+		return nil, fmt.Errorf("failed to get clinical note: %w", err)
+	}
+	return note, nil
+}
+
+func (s *ClinicalNoteService) consultationForDoctor(ctx context.Context, consultationID, doctorID int) (*models.Consultation, error) {
+	consultation, err := s.consultationRepo.GetByID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to get consultation: %w", err)
+	}
+	if consultation.DoctorID != doctorID {
+		return nil, ErrNotConsultationDoctor
+	}
+	return consultation, nil
+}
+
+func (s *ClinicalNoteService) checkReader(ctx context.Context, consultationID, userID int) error {
+	consultation, err := s.consultationRepo.GetByID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get consultation ID: %d", consultationID)
+		return fmt.Errorf("failed to get consultation: %w", err)
+	}
+	if userID != consultation.DoctorID && userID != consultation.PatientID {
+		return ErrNoteAccessDenied
+	}
+	return nil
+}
+
+// clinicalNoteHash hashes what the signature covers: the SOAP content, its version and the signer
+func clinicalNoteHash(note *models.ClinicalNote, signerID int, signedAt time.Time) (string, error) {
+	data, err := json.Marshal(struct {
+		ConsultationID int                      `json:"consultation_id"`
+		Version        int                      `json:"version"`
+		AmendsNoteID   *int                     `json:"amends_note_id"`
+		Subjective     models.SubjectiveSection `json:"subjective"`
+		Objective      models.ObjectiveSection  `json:"objective"`
+		Assessment     models.AssessmentSection `json:"assessment"`
+		Plan           models.PlanSection       `json:"plan"`
+		SignedBy       int                      `json:"signed_by"`
+		SignedAt       string                   `json:"signed_at"`
+	}{
+		note.ConsultationID, note.Version, note.AmendsNoteID,
+		note.Subjective, note.Objective, note.Assessment, note.Plan,
+		signerID, signedAt.Format(time.RFC3339),
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// validate checks the note and resolves its ICD-10 codes against the catalogue
+func (s *ClinicalNoteService) validate(ctx context.Context, note *models.ClinicalNote) error {
+	if err := validateClinicalNote(note); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	if err := s.codes.ResolveDiagnoses(ctx, note.Assessment.Diagnoses); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+	return nil
+}
+
+// validateClinicalNote checks diagnoses and that recorded vitals are physiologically plausible
+func validateClinicalNote(note *models.ClinicalNote) error {
+	primaries := 0
+	for i := range note.Assessment.Diagnoses {
+		diagnosis := &note.Assessment.Diagnoses[i]
+		diagnosis.Code = strings.ToUpper(strings.TrimSpace(diagnosis.Code))
+		if diagnosis.Code == "" {
+			return errors.New("diagnosis code is required")
+		}
+		if diagnosis.System == "" {
+			diagnosis.System = models.DiagnosisCodeSystemICD10
+		}
+		if diagnosis.Primary {
+			primaries++
+		}
+	}
+	if primaries > 1 {
+		return errors.New("only one diagnosis can be primary")
+	}
+
+	v := note.Objective.Vitals
+	checks := []struct {
+		name     string
+		value    *float64
+		min, max float64
+	}{
+		{"temperature_c", v.TemperatureC, 25, 45},
+		{"heart_rate", intAsFloat(v.HeartRate), 20, 250},
+		{"respiratory_rate", intAsFloat(v.RespiratoryRate), 4, 80},
+		{"systolic_bp", intAsFloat(v.SystolicBP), 50, 300},
+		{"diastolic_bp", intAsFloat(v.DiastolicBP), 20, 200},
+		{"oxygen_saturation", intAsFloat(v.OxygenSaturation), 50, 100},
+		{"weight_kg", v.WeightKg, 0.3, 500},
+		{"height_cm", v.HeightCm, 20, 280},
+	}
+	for _, check := range checks {
+		if check.value != nil && (*check.value < check.min || *check.value > check.max) {
+			return fmt.Errorf("%s must be between %g and %g", check.name, check.min, check.max)
+		}
+	}
+	if v.SystolicBP != nil && v.DiastolicBP != nil && *v.DiastolicBP >= *v.SystolicBP {
+		return errors.New("diastolic_bp must be lower than systolic_bp")
+	}
+	return nil
+}
+
+func intAsFloat(v *int) *float64 {
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
@@ -6,35 +6,56 @@ import (
 	"fmt"
 	"shifa/internal/models"
 	"shifa/internal/repository"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 // ConsultationDetailsService manages consultation details business logic
 type ConsultationDetailsService struct {
-	detailsRepo repository.ConsultationDetailsRepository
-	logger      *logrus.Logger
+	detailsRepo      repository.ConsultationDetailsRepository
+	consultationRepo repository.ConsultationRepository
+	notes            *ClinicalNoteService
+	codes            *DiagnosisCodeService
+	history          *MedicalHistoryService
+	logger           *logrus.Logger
 }
 
 // NewConsultationDetailsService creates a new ConsultationDetailsService
-func NewConsultationDetailsService(detailsRepo repository.ConsultationDetailsRepository, logger *logrus.Logger) *ConsultationDetailsService {
+func NewConsultationDetailsService(
+	detailsRepo repository.ConsultationDetailsRepository,
+	consultationRepo repository.ConsultationRepository,
+	notes *ClinicalNoteService,
+	codes *DiagnosisCodeService,
+	history *MedicalHistoryService,
+	logger *logrus.Logger,
+) *ConsultationDetailsService {
 	return &ConsultationDetailsService{
-		detailsRepo: detailsRepo,
-		logger:      logger,
+		detailsRepo:      detailsRepo,
+		consultationRepo: consultationRepo,
+		notes:            notes,
+		codes:            codes,
+		history:          history,
+		logger:           logger,
 	}
 }
 
-// CreateDetails creates new consultation details
-func (s *ConsultationDetailsService) CreateDetails(ctx context.Context, details models.ConsultationDetails) error {
+// CreateDetails creates new consultation details; authorID is the authenticated caller
+func (s *ConsultationDetailsService) CreateDetails(ctx context.Context, details models.ConsultationDetails, authorID int) error {
 	if err := s.validateDetails(details); err != nil {
 		s.logger.WithError(err).Error("Invalid consultation details data")
 		return fmt.Errorf("validation error: %w", err)
 	}
+	if err := s.resolveDiagnosisCode(ctx, &details); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
 
 	if err := s.detailsRepo.Create(ctx, &details); err != nil {
 		s.logger.WithError(err).Error("Failed to create consultation details")
 		return fmt.Errorf("failed to create consultation details: %w", err)
 	}
+	s.recordDiagnosis(ctx, details, authorID)
 
 	s.logger.Infof("Consultation details created successfully: ID=%d", details.ID)
 	return nil
@@ -50,27 +71,76 @@ func (s *ConsultationDetailsService) GetDetailsByID(ctx context.Context, id int)
 	return details, nil
 }
 
-// GetDetailsByConsultationID retrieves consultation details by consultation ID
-func (s *ConsultationDetailsService) GetDetailsByConsultationID(ctx context.Context, consultationID int) (*models.ConsultationDetails, error) {
+// GetDetailsByConsultationID retrieves consultation details by consultation ID, falling back to
+// the signed clinical note for the consultation's patient or doctor
+func (s *ConsultationDetailsService) GetDetailsByConsultationID(ctx context.Context, consultationID, userID int) (*models.ConsultationDetails, error) {
 	details, err := s.detailsRepo.GetByConsultationID(ctx, consultationID)
 	if err != nil {
+		// Consultations documented with a SOAP note have no free-text details row; drafts are
+		// not part of the record yet. The note is only shown to the consultation's participants.
+		if readErr := s.notes.checkReader(ctx, consultationID, userID); readErr != nil {
+			return nil, readErr
+		}
+		if note, noteErr := s.notes.CurrentNote(ctx, consultationID); noteErr == nil && note != nil && note.Status == models.ClinicalNoteSigned {
+			return detailsFromNote(note), nil
+		}
 		s.logger.WithError(err).Error("Failed to get consultation details by consultation ID")
 		return nil, fmt.Errorf("failed to get consultation details by consultation ID: %w", err)
 	}
 	return details, nil
 }
 
-// UpdateDetails updates existing consultation details
-func (s *ConsultationDetailsService) UpdateDetails(ctx context.Context, details models.ConsultationDetails) error {
+// detailsFromNote presents a clinical note in the free-text details shape for older clients
+func detailsFromNote(note *models.ClinicalNote) *models.ConsultationDetails {
+	diagnoses := make([]string, 0, len(note.Assessment.Diagnoses))
+	code := ""
+	for i, diagnosis := range note.Assessment.Diagnoses {
+		if diagnosis.System == models.DiagnosisCodeSystemICD10 && (diagnosis.Primary || i == 0) {
+			code = diagnosis.Code
+		}
+		text := diagnosis.Code
+		if diagnosis.Description != "" {
+			text += " " + diagnosis.Description
+		}
+		diagnoses = append(diagnoses, text)
+	}
+	symptoms := note.Subjective.ChiefComplaint
+	if note.Subjective.Symptoms != "" {
+		symptoms += "\n" + note.Subjective.Symptoms
+	}
+	notes := note.Assessment.Summary
+	for _, part := range []string{note.Plan.Treatment, note.Plan.FollowUp} {
+		if part != "" {
+			notes = strings.TrimPrefix(notes+"\n"+part, "\n")
+		}
+	}
+
+	return &models.ConsultationDetails{
+		ConsultationID: note.ConsultationID,
+		RequestDetails: note.Subjective.History,
+		Symptoms:       symptoms,
+		Diagnosis:      strings.Join(diagnoses, "; "),
+		DiagnosisCode:  code,
+		Prescription:   note.Plan.Medications,
+		Notes:          notes,
+	}
+}
+
+// UpdateDetails updates existing consultation details; authorID is the authenticated caller
+func (s *ConsultationDetailsService) UpdateDetails(ctx context.Context, details models.ConsultationDetails, authorID int) error {
 	if err := s.validateDetails(details); err != nil {
 		s.logger.WithError(err).Error("Invalid consultation details data")
 		return fmt.Errorf("validation error: %w", err)
 	}
+	if err := s.resolveDiagnosisCode(ctx, &details); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
 
 	if err := s.detailsRepo.Update(ctx, &details); err != nil {
 		s.logger.WithError(err).Error("Failed to update consultation details")
 		return fmt.Errorf("failed to update consultation details: %w", err)
 	}
+	s.recordDiagnosis(ctx, details, authorID)
 
 	s.logger.Infof("Consultation details updated successfully: ID=%d", details.ID)
 	return nil
@@ -87,6 +157,57 @@ func (s *ConsultationDetailsService) DeleteDetails(ctx context.Context, id int)
 	return nil
 }
 
+// resolveDiagnosisCode checks the diagnosis code against the catalogue; the free-text
+// diagnosis defaults to the code's description
+func (s *ConsultationDetailsService) resolveDiagnosisCode(ctx context.Context, details *models.ConsultationDetails) error {
+	if details.DiagnosisCode == "" {
+		return nil
+	}
+	entry, err := s.codes.Get(ctx, details.DiagnosisCode)
+	if err != nil {
+		return err
+	}
+	details.DiagnosisCode = entry.Code
+	if details.Diagnosis == "" {
+		details.Diagnosis = entry.Description
+	}
+	return nil
+}
+
+// recordDiagnosis adds the coded diagnosis to the patient's medical history when the author is
+// the consultation's doctor. The details are saved either way, so failures are only logged.
+func (s *ConsultationDetailsService) recordDiagnosis(ctx context.Context, details models.ConsultationDetails, authorID int) {
+	if details.DiagnosisCode == "" {
+		return
+	}
+	consultation, err := s.consultationRepo.GetByID(ctx, details.ConsultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get consultation ID: %d", details.ConsultationID)
+		return
+	}
+	if consultation.DoctorID != authorID {
+		s.logger.Warnf("Diagnosis of consultation ID: %d not added to medical history: written by user ID: %d, not its doctor",
+			consultation.ID, authorID)
+		return
+	}
+
+	diagnosedAt := time.Now()
+	if consultation.CompletedAt.Valid {
+		diagnosedAt = consultation.CompletedAt.Time
+	}
+	diagnosis := models.CodedDiagnosis{
+		System:      models.DiagnosisCodeSystemICD10,
+		Code:        details.DiagnosisCode,
+		Description: details.Diagnosis,
+		Primary:     true,
+	}
+	_, err = s.history.RecordConsultationDiagnoses(ctx, consultation.PatientID, consultation.ID,
+		diagnosedAt, details.Prescription, []models.CodedDiagnosis{diagnosis})
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to record diagnosis of consultation ID: %d in medical history", consultation.ID)
+	}
+}
+
 // validateDetails validates consultation details
 func (s *ConsultationDetailsService) validateDetails(details models.ConsultationDetails) error {
 	if details.ConsultationID == 0 {
@@ -22,29 +22,69 @@ type ConsultationRepository interface {
 
 type ConsultationService struct {
 	consultationRepo repository.ConsultationRepository
+	appointmentRepo  repository.AppointmentRepository
+	doctorRepo       repository.DoctorRepository
+	followUps        *FollowUpService
 	logger           *logrus.Logger
 }
 
-func NewConsultationService(consultationRepo repository.ConsultationRepository, logger *logrus.Logger) *ConsultationService {
+func NewConsultationService(
+	consultationRepo repository.ConsultationRepository,
+	appointmentRepo repository.AppointmentRepository,
+	doctorRepo repository.DoctorRepository,
+	followUps *FollowUpService,
+	logger *logrus.Logger,
+) *ConsultationService {
 	return &ConsultationService{
 		consultationRepo: consultationRepo,
+		appointmentRepo:  appointmentRepo,
+		doctorRepo:       doctorRepo,
+		followUps:        followUps,
 		logger:           logger,
 	}
 }
 
-func (s *ConsultationService) StartConsultation(ctx context.Context, consultation models.Consultation) error {
-	if err := s.validateConsultation(consultation); err != nil {
-		s.logger.WithError(err).Error("Invalid consultation data")
-		return fmt.Errorf("validation error: %w", err)
+// StartConsultation opens the consultation for a scheduled appointment with the doctor and
+// moves the appointment to in_progress. The patient and doctor default to the appointment's.
+func (s *ConsultationService) StartConsultation(ctx context.Context, consultation *models.Consultation) error {
+	if consultation.AppointmentID == nil {
+		return errors.New("validation error: appointment ID is required")
+	}
+
+	appointment, err := s.appointmentRepo.GetByID(ctx, *consultation.AppointmentID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get appointment ID: %d", *consultation.AppointmentID)
+		return fmt.Errorf("failed to get appointment: %w", err)
+	}
+	if appointment.ProviderType != "doctor" || appointment.DoctorID == nil {
+		return errors.New("consultations can only be started for doctor appointments")
+	}
+	if consultation.PatientID == 0 {
+		consultation.PatientID = appointment.PatientID
+	}
+	if consultation.DoctorID == 0 {
+		consultation.DoctorID = *appointment.DoctorID
+	}
+	if consultation.PatientID != appointment.PatientID || consultation.DoctorID != *appointment.DoctorID {
+		return errors.New("appointment is not between this doctor and patient")
+	}
+	if appointment.Status != "scheduled" {
+		return fmt.Errorf("appointment is %s, only scheduled appointments can be started", appointment.Status)
+	}
+
+	doctor, err := s.doctorRepo.GetByID(ctx, consultation.DoctorID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get doctor ID: %d", consultation.DoctorID)
+		return fmt.Errorf("failed to get doctor: %w", err)
 	}
 
 	consultation.Status = "in_progress"
-	now := time.Now()
-	consultation.StartedAt = models.NullTime{Time: now, Valid: true}
+	consultation.StartedAt = models.NullTime{Time: time.Now(), Valid: true}
+	consultation.CompletedAt = models.NullTime{}
+	consultation.Fee = doctor.ConsultationFee
 
-	// Directly use the models.Consultation
-	if err := s.consultationRepo.Create(ctx, &consultation); err != nil {
-		s.logger.WithError(err).Error("Failed to start consultation")
+	if err := s.consultationRepo.Start(ctx, consultation); err != nil {
+		s.logger.WithError(err).Errorf("Failed to start consultation for appointment ID: %d", *consultation.AppointmentID)
 		return fmt.Errorf("failed to start consultation: %w", err)
 	}
 
@@ -52,29 +92,56 @@ func (s *ConsultationService) StartConsultation(ctx context.Context, consultatio
 	return nil
 }
 
-func (s *ConsultationService) CompleteConsultation(ctx context.Context, consultation models.Consultation) error {
-	existing, err := s.consultationRepo.GetByID(ctx, consultation.ID)
+// CompleteConsultation completes an in-progress consultation together with its appointment
+// and bills the doctor's consultation fee as a pending payment. Once completed, the patient
+// can review the consultation. With a follow-up plan it also recommends a follow-up visit,
+// which is returned; the consultation stays completed if recording the follow-up fails.
+func (s *ConsultationService) CompleteConsultation(ctx context.Context, id int, plan *models.FollowUpPlan) (*models.Consultation, *models.FollowUp, error) {
+	if plan != nil {
+		if err := validateFollowUpPlan(plan); err != nil {
+			return nil, nil, fmt.Errorf("validation error: %w", err)
+		}
+	}
+
+	consultation, err := s.consultationRepo.GetByID(ctx, id)
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to fetch consultation")
-		return fmt.Errorf("failed to fetch consultation: %w", err)
+		return nil, nil, fmt.Errorf("failed to fetch consultation: %w", err)
 	}
 
-	if existing.Status != "in_progress" {
-		return errors.New("consultation must be in progress to complete")
+	if consultation.Status != "in_progress" {
+		return nil, nil, errors.New("consultation must be in progress to complete")
+	}
+
+	doctor, err := s.doctorRepo.GetByID(ctx, consultation.DoctorID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get doctor ID: %d", consultation.DoctorID)
+		return nil, nil, fmt.Errorf("failed to get doctor: %w", err)
 	}
 
 	consultation.Status = "completed"
-	now := time.Now()
-	consultation.CompletedAt = models.NullTime{Time: now, Valid: true}
+	consultation.CompletedAt = models.NullTime{Time: time.Now(), Valid: true}
+	consultation.Fee = doctor.ConsultationFee
+	payment := &repository.Payment{
+		Amount:         doctor.ConsultationFee,
+		Status:         "pending",
+		ConsultationID: consultation.ID,
+	}
 
-	// Directly use the models.Consultation
-	if err := s.consultationRepo.Update(ctx, &consultation); err != nil {
-		s.logger.WithError(err).Error("Failed to complete consultation")
-		return fmt.Errorf("failed to complete consultation: %w", err)
+	if err := s.consultationRepo.Complete(ctx, consultation, payment); err != nil {
+		s.logger.WithError(err).Errorf("Failed to complete consultation ID: %d", id)
+		return nil, nil, fmt.Errorf("failed to complete consultation: %w", err)
 	}
 
-	s.logger.Infof("Consultation completed successfully: ID=%d", consultation.ID)
-	return nil
+	s.logger.Infof("Consultation completed successfully: ID=%d, payment ID=%d", consultation.ID, payment.ID)
+	if plan == nil {
+		return consultation, nil, nil
+	}
+	followUp, err := s.followUps.Recommend(ctx, consultation, plan)
+	if err != nil {
+		return consultation, nil, fmt.Errorf("consultation completed but the follow-up was not recorded: %w", err)
+	}
+	return consultation, followUp, nil
 }
 
 func (s *ConsultationService) GetByID(ctx context.Context, id int) (*models.Consultation, error) {
@@ -108,6 +175,17 @@ func (s *ConsultationService) Update(ctx context.Context, consultation models.Co
 		return fmt.Errorf("validation error: %w", err)
 	}
 
+	if consultation.Status == "in_progress" || consultation.Status == "completed" {
+		existing, err := s.consultationRepo.GetByID(ctx, consultation.ID)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to fetch consultation")
+			return fmt.Errorf("failed to fetch consultation: %w", err)
+		}
+		if existing.Status != consultation.Status {
+			return errors.New("consultations are started and completed through their own endpoints")
+		}
+	}
+
 	// Directly use the models.Consultation
 	if err := s.consultationRepo.Update(ctx, &consultation); err != nil {
 		s.logger.WithError(err).Error("Failed to update consultation")
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"bytes"
+	"html/template"
+
+	"shifa/internal/models"
+)
+
+// summaryTemplate lays out a summary for the browser, which handles Arabic shaping and
+// bidirectional text itself; entered text is isolated with dir="auto" and <bdi>
+var summaryTemplate = template.Must(template.New("summary").Funcs(template.FuncMap{
+	"drugName": summaryDrugName,
+	"itemLine": prescriptionItemLine,
+}).Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}" dir="{{.Dir}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Text.Title}}</title>
+<style>
+body { font-family: "DejaVu Sans", Tahoma, Arial, sans-serif; max-width: 46rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; color: #222; }
+h1 { font-size: 1.6rem; margin-bottom: 0.25rem; }
+h2 { font-size: 1.1rem; border-bottom: 1px solid #999; padding-bottom: 0.2rem; margin-top: 1.5rem; }
+dl { display: grid; grid-template-columns: max-content 1fr; gap: 0.25rem 1rem; }
+dt { font-weight: bold; }
+dd { margin: 0; }
+ul { padding-inline-start: 1.2rem; }
+li { margin-bottom: 0.6rem; }
+.text { white-space: pre-line; }
+.muted { color: #666; font-size: 0.85rem; }
+</style>
+</head>
+<body>
+<h1>{{.Text.Title}}</h1>
+<dl>
+<dt>{{.Text.ConsultationNo}}</dt><dd><bdi>{{.C.ConsultationID}}</bdi></dd>
+<dt>{{.Text.Date}}</dt><dd><bdi>{{.C.Date}}</bdi></dd>
+<dt>{{.Text.Patient}}</dt><dd><bdi>{{.C.PatientName}}</bdi></dd>
+<dt>{{.Text.Doctor}}</dt><dd><bdi>{{.C.DoctorName}}</bdi></dd>
+{{- if .C.Specialty}}
+<dt>{{.Text.Specialty}}</dt><dd><bdi>{{.C.Specialty}}</bdi></dd>
+{{- end}}
+</dl>
+{{- range .Sections}}
+<h2>{{.Title}}</h2>
+<p class="text" dir="auto">{{.Body}}</p>
+{{- end}}
+{{- if .C.Prescriptions}}
+<h2>{{.Text.Medications}}</h2>
+{{- range .C.Prescriptions}}
+<p class="muted">{{printf $.Text.Prescription .Number}}</p>
+<ul>
+{{- range .Items}}
+<li><strong dir="auto">{{drugName .}}</strong><br>{{itemLine $.Text .}}
+{{- if .Instructions}}<br>{{$.Text.Instructions}}: <bdi>{{.Instructions}}</bdi>{{end}}</li>
+{{- end}}
+</ul>
+{{- if .Notes}}
+<p class="text" dir="auto">{{.Notes}}</p>
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .FollowUp}}
+<h2>{{.Text.FollowUp}}</h2>
+<p>{{.FollowUp}}</p>
+{{- if .C.FollowUpNote}}
+<p class="text" dir="auto">{{.C.FollowUpNote}}</p>
+{{- end}}
+{{- end}}
+<p class="muted">{{.Text.Footer}}</p>
+</body>
+</html>
+`))
+
+func renderSummaryHTML(c *summaryContent, language string) ([]byte, error) {
+	text := summaryTexts[language]
+	dir := "ltr"
+	if language == models.LanguageArabic {
+		dir = "rtl"
+	}
+
+	var buf bytes.Buffer
+	err := summaryTemplate.Execute(&buf, map[string]interface{}{
+		"Lang":     language,
+		"Dir":      dir,
+		"Text":     text,
+		"C":        c,
+		"Sections": c.sections(text),
+		"FollowUp": c.followUpText(text),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
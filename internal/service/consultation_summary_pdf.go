@@ -0,0 +1,159 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"shifa/internal/models"
+	"shifa/pkg/arabic"
+	"shifa/pkg/pdffonts"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const summaryMargin = 15 // mm
+
+// summaryPDF writes a summary, or a chat transcript, page by page. gofpdf draws text left to right without shaping,
+// so lines are shaped, wrapped and put in visual order here, and right-aligned in Arabic.
+type summaryPDF struct {
+	pdf   *gofpdf.Fpdf
+	rtl   bool
+	align string
+	width float64
+}
+
+func renderSummaryPDF(c *summaryContent, language string) ([]byte, error) {
+	text := summaryTexts[language]
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdffonts.Register(pdf)
+	pdf.SetTitle(text.Title, true)
+	pdf.SetMargins(summaryMargin, summaryMargin, summaryMargin)
+	pdf.AddPage()
+	pageWidth, _ := pdf.GetPageSize()
+
+	w := &summaryPDF{pdf: pdf, rtl: language == models.LanguageArabic, align: "L", width: pageWidth - 2*summaryMargin}
+	if w.rtl {
+		w.align = "R"
+	}
+
+	w.write(text.Title, "B", 18, 9)
+	w.write(text.ConsultationNo+": "+strconv.Itoa(c.ConsultationID), "", 10, 6)
+	w.write(text.Date+": "+c.Date, "", 10, 6)
+	pdf.Ln(3)
+	w.write(text.Patient+": "+c.PatientName, "", 11, 6)
+	w.write(text.Doctor+": "+c.DoctorName, "", 11, 6)
+	if c.Specialty != "" {
+		w.write(text.Specialty+": "+c.Specialty, "", 11, 6)
+	}
+
+	for _, section := range c.sections(text) {
+		w.heading(section.Title)
+		w.write(section.Body, "", 10, 5)
+	}
+
+	if len(c.Prescriptions) > 0 {
+		w.heading(text.Medications)
+		for _, p := range c.Prescriptions {
+			w.write(fmt.Sprintf(text.Prescription, p.Number), "", 9, 5)
+			for _, item := range p.Items {
+				w.write(summaryDrugName(item), "B", 10, 5)
+				w.write(prescriptionItemLine(text, item), "", 10, 5)
+				if item.Instructions != "" {
+					w.write(text.Instructions+": "+item.Instructions, "", 10, 5)
+				}
+				pdf.Ln(2)
+			}
+			if p.Notes != "" {
+				w.write(p.Notes, "", 10, 5)
+			}
+		}
+	}
+
+	if followUp := c.followUpText(text); followUp != "" {
+		w.heading(text.FollowUp)
+		w.write(followUp, "", 10, 5)
+		if c.FollowUpNote != "" {
+			w.write(c.FollowUpNote, "", 10, 5)
+		}
+	}
+
+	pdf.Ln(6)
+	w.write(text.Footer, "", 8, 4)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// summaryDrugName names a medication with its strength and form
+func summaryDrugName(item models.PrescriptionItem) string {
+	return strings.Join(strings.Fields(item.DrugName+" "+item.Strength+" "+item.Form), " ")
+}
+
+// prescriptionItemLine describes how to take a medication
+func prescriptionItemLine(text summaryText, item models.PrescriptionItem) string {
+	parts := []string{text.Dose + ": " + item.Dose, text.Frequency + ": " + item.Frequency}
+	if item.DurationDays > 0 {
+		parts = append(parts, text.DurationDays+": "+strconv.Itoa(item.DurationDays))
+	}
+	if item.Refills > 0 {
+		parts = append(parts, text.Refills+": "+strconv.Itoa(item.Refills))
+	}
+	return strings.Join(parts, " - ")
+}
+
+func (w *summaryPDF) heading(title string) {
+	w.pdf.Ln(3)
+	w.pdf.SetFont(pdffonts.Family, "B", 12)
+	w.pdf.CellFormat(0, 7, arabic.Visual(arabic.Shape(title), w.rtl), "B", 1, w.align, false, 0, "")
+	w.pdf.Ln(1)
+}
+
+// write sets text in paragraphs, one per line of the text. Each paragraph reads in the
+// direction of its first letter, so English entered in an Arabic summary keeps its order.
+func (w *summaryPDF) write(text, style string, size, lineHeight float64) {
+	w.pdf.SetFont(pdffonts.Family, style, size)
+	for _, paragraph := range strings.Split(text, "\n") {
+		shaped := arabic.Shape(strings.TrimSpace(paragraph))
+		rtl := arabic.StartsRTL(shaped, w.rtl)
+		for _, line := range w.wrap(shaped) {
+			w.pdf.CellFormat(0, lineHeight, arabic.Visual(line, rtl), "", 1, w.align, false, 0, "")
+		}
+	}
+}
+
+// wrap breaks shaped text into lines that fit the page, between words where possible
+func (w *summaryPDF) wrap(text string) []string {
+	if text == "" {
+		return []string{""}
+	}
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if w.pdf.GetStringWidth(candidate) <= w.width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		// A word wider than the page is broken between letters
+		line = ""
+		for _, r := range word {
+			if line != "" && w.pdf.GetStringWidth(line+string(r)) > w.width {
+				lines = append(lines, line)
+				line = ""
+			}
+			line += string(r)
+		}
+	}
+	return append(lines, line)
+}
@@ -0,0 +1,367 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+	"shifa/pkg/fileutils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// summaryLayoutVersion is part of the content hash; bump it when the documents' layout
+// changes so stored summaries are rendered again
+const summaryLayoutVersion = "1"
+
+var (
+	ErrSummaryAccessDenied      = errors.New("only the consultation's patient and doctor can download its summary")
+	ErrConsultationNotCompleted = errors.New("the summary is available once the consultation is completed")
+)
+
+// ConsultationSummaryService produces the take-home summary patients get after a visit, as a
+// PDF or HTML document in English or Arabic. Documents are stored and only rendered again
+// when the consultation's record changes.
+type ConsultationSummaryService struct {
+	summaryRepo      repository.ConsultationSummaryRepository
+	consultationRepo repository.ConsultationRepository
+	detailsRepo      repository.ConsultationDetailsRepository
+	notes            *ClinicalNoteService
+	prescriptionRepo repository.PrescriptionRepository
+	followUpRepo     repository.FollowUpRepository
+	doctorRepo       repository.DoctorRepository
+	userRepo         repository.UserRepository
+	timeZones        *TimeZoneService
+	logger           *logrus.Logger
+}
+
+func NewConsultationSummaryService(
+	summaryRepo repository.ConsultationSummaryRepository,
+	consultationRepo repository.ConsultationRepository,
+	detailsRepo repository.ConsultationDetailsRepository,
+	notes *ClinicalNoteService,
+	prescriptionRepo repository.PrescriptionRepository,
+	followUpRepo repository.FollowUpRepository,
+	doctorRepo repository.DoctorRepository,
+	userRepo repository.UserRepository,
+	timeZones *TimeZoneService,
+	logger *logrus.Logger,
+) *ConsultationSummaryService {
+	return &ConsultationSummaryService{
+		summaryRepo:      summaryRepo,
+		consultationRepo: consultationRepo,
+		detailsRepo:      detailsRepo,
+		notes:            notes,
+		prescriptionRepo: prescriptionRepo,
+		followUpRepo:     followUpRepo,
+		doctorRepo:       doctorRepo,
+		userRepo:         userRepo,
+		timeZones:        timeZones,
+		logger:           logger,
+	}
+}
+
+// summaryContent is everything a summary shows, in the patient's time zone
+type summaryContent struct {
+	ConsultationID int
+	PatientName    string
+	DoctorName     string
+	Specialty      string
+	Date           string
+	Complaint      string
+	Symptoms       string
+	Diagnosis      string
+	Treatment      string
+	Notes          string
+	Prescriptions  []summaryPrescription
+	FollowUpDue    string // due date of a pending follow-up
+	FollowUpBooked bool
+	FollowUpNote   string
+}
+
+type summaryPrescription struct {
+	Number string
+	Items  []models.PrescriptionItem
+	Notes  string
+}
+
+// summaryText holds a summary's fixed wording in one language
+type summaryText struct {
+	Title           string
+	ConsultationNo  string
+	Patient         string
+	Doctor          string
+	Specialty       string
+	Date            string
+	Complaint       string
+	Symptoms        string
+	Diagnosis       string
+	Treatment       string
+	Notes           string
+	Medications     string
+	Prescription    string
+	Dose            string
+	Frequency       string
+	DurationDays    string
+	Refills         string
+	Instructions    string
+	FollowUp        string
+	FollowUpPending string
+	FollowUpBooked  string
+	Footer          string
+}
+
+var summaryTexts = map[string]summaryText{
+	models.LanguageEnglish: {
+		Title:           "Consultation summary",
+		ConsultationNo:  "Consultation no.",
+		Patient:         "Patient",
+		Doctor:          "Doctor",
+		Specialty:       "Specialty",
+		Date:            "Date",
+		Complaint:       "Reason for visit",
+		Symptoms:        "Symptoms",
+		Diagnosis:       "Diagnosis",
+		Treatment:       "Treatment plan",
+		Notes:           "Doctor's notes",
+		Medications:     "Prescribed medications",
+		Prescription:    "Prescription %s",
+		Dose:            "Dose",
+		Frequency:       "Frequency",
+		DurationDays:    "Duration (days)",
+		Refills:         "Refills",
+		Instructions:    "Instructions",
+		FollowUp:        "Follow-up",
+		FollowUpPending: "Please book a follow-up visit with your doctor by %s.",
+		FollowUpBooked:  "Your follow-up visit has been booked.",
+		Footer:          "This summary is a copy of your consultation record for your own reference. Contact your doctor if your symptoms get worse.",
+	},
+	models.LanguageArabic: {
+		Title:           "ملخص الاستشارة",
+		ConsultationNo:  "رقم الاستشارة",
+		Patient:         "المريض",
+		Doctor:          "الطبيب",
+		Specialty:       "التخصص",
+		Date:            "التاريخ",
+		Complaint:       "سبب الزيارة",
+		Symptoms:        "الأعراض",
+		Diagnosis:       "التشخيص",
+		Treatment:       "الخطة العلاجية",
+		Notes:           "ملاحظات الطبيب",
+		Medications:     "الأدوية الموصوفة",
+		Prescription:    "الوصفة رقم %s",
+		Dose:            "الجرعة",
+		Frequency:       "عدد المرات",
+		DurationDays:    "المدة (أيام)",
+		Refills:         "مرات إعادة الصرف",
+		Instructions:    "التعليمات",
+		FollowUp:        "المتابعة",
+		FollowUpPending: "يرجى حجز موعد متابعة مع طبيبك في موعد أقصاه %s.",
+		FollowUpBooked:  "تم حجز موعد المتابعة.",
+		Footer:          "هذا الملخص نسخة من سجل استشارتك للرجوع إليها. تواصل مع طبيبك إذا ساءت الأعراض.",
+	},
+}
+
+// Summary returns the stored summary of a completed consultation to its patient or doctor,
+// rendering it first when there is none or the record has changed since. An empty language
+// uses the patient's preference.
+func (s *ConsultationSummaryService) Summary(ctx context.Context, consultationID, userID int, language, format string) (*models.ConsultationSummary, error) {
+	if format != models.SummaryFormatPDF && format != models.SummaryFormatHTML {
+		return nil, fmt.Errorf("unsupported format %q, expected %q or %q", format, models.SummaryFormatPDF, models.SummaryFormatHTML)
+	}
+	if err := validateLanguage(language); err != nil {
+		return nil, err
+	}
+
+	consultation, err := s.consultationRepo.GetByID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to get consultation: %w", err)
+	}
+	if userID != consultation.PatientID && userID != consultation.DoctorID {
+		return nil, ErrSummaryAccessDenied
+	}
+	if consultation.Status != "completed" {
+		return nil, ErrConsultationNotCompleted
+	}
+
+	patient, err := s.userRepo.GetByID(ctx, consultation.PatientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+	if language == "" {
+		language = patient.Language
+	}
+	if _, ok := summaryTexts[language]; !ok {
+		language = models.LanguageEnglish
+	}
+
+	content, err := s.content(ctx, consultation, patient)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := summaryHash(content, language, format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash summary: %w", err)
+	}
+
+	stored, err := s.summaryRepo.Get(ctx, consultationID, language, format)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get summary of consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to get summary: %w", err)
+	}
+	if stored != nil && stored.ContentHash == hash {
+		return stored, nil
+	}
+
+	var data []byte
+	if format == models.SummaryFormatPDF {
+		data, err = renderSummaryPDF(content, language)
+	} else {
+		data, err = renderSummaryHTML(content, language)
+	}
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to render summary of consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to render summary: %w", err)
+	}
+
+	fileName := fmt.Sprintf("consultation_%d_%s.%s", consultationID, language, format)
+	path, err := fileutils.SavePrivateFile(bytes.NewReader(data), fileName, fileutils.ConsultationSummaries)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to store summary of consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to store summary: %w", err)
+	}
+	summary := &models.ConsultationSummary{
+		ConsultationID: consultationID,
+		Language:       language,
+		Format:         format,
+		FilePath:       path,
+		ContentHash:    hash,
+		GeneratedAt:    time.Now().UTC().Truncate(time.Second),
+	}
+	if err := s.summaryRepo.Save(ctx, summary); err != nil {
+		fileutils.DeletePrivateFile(path)
+		s.logger.WithError(err).Errorf("Failed to save summary of consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to save summary: %w", err)
+	}
+	if stored != nil && stored.FilePath != path {
+		if err := fileutils.DeletePrivateFile(stored.FilePath); err != nil {
+			s.logger.WithError(err).Warnf("Failed to delete outdated summary %s", stored.FilePath)
+		}
+	}
+
+	s.logger.Infof("Summary of consultation ID: %d rendered as %s in %s", consultationID, format, language)
+	return summary, nil
+}
+
+// content gathers the consultation's record. Consultations documented with a SOAP note have no
+// details row and use the signed note instead. Missing details or follow-up leave their
+// sections out; void prescriptions are left out.
+func (s *ConsultationSummaryService) content(ctx context.Context, consultation *models.Consultation, patient *models.User) (*summaryContent, error) {
+	loc, err := s.timeZones.Location(ctx, patient.ID)
+	if err != nil {
+		return nil, err
+	}
+	content := &summaryContent{
+		ConsultationID: consultation.ID,
+		PatientName:    patient.Name,
+		Date:           consultation.CompletedAt.Time.In(loc).Format("2006-01-02"),
+	}
+
+	doctor, err := s.userRepo.GetByID(ctx, consultation.DoctorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get doctor: %w", err)
+	}
+	content.DoctorName = doctor.Name
+	if profile, err := s.doctorRepo.GetByID(ctx, consultation.DoctorID); err == nil {
+		content.Specialty = profile.Specialty
+	}
+
+	details, err := s.detailsRepo.GetByConsultationID(ctx, consultation.ID)
+	if err != nil {
+		if note, noteErr := s.notes.CurrentNote(ctx, consultation.ID); noteErr == nil && note != nil && note.Status == models.ClinicalNoteSigned {
+			details, err = detailsFromNote(note), nil
+		}
+	}
+	if err == nil {
+		content.Complaint = strings.TrimSpace(details.RequestDetails)
+		content.Symptoms = strings.TrimSpace(details.Symptoms)
+		content.Diagnosis = strings.TrimSpace(details.Diagnosis)
+		if details.DiagnosisCode != "" {
+			content.Diagnosis = strings.TrimSpace(content.Diagnosis + " (" + details.DiagnosisCode + ")")
+		}
+		content.Treatment = strings.TrimSpace(details.Prescription)
+		content.Notes = strings.TrimSpace(details.Notes)
+	}
+
+	prescriptions, err := s.prescriptionRepo.ListByConsultation(ctx, consultation.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prescriptions: %w", err)
+	}
+	for _, p := range prescriptions {
+		if p.Status == models.PrescriptionVoid {
+			continue
+		}
+		content.Prescriptions = append(content.Prescriptions, summaryPrescription{Number: p.Number, Items: p.Items, Notes: p.Notes})
+	}
+
+	if followUp, err := s.followUpRepo.GetByConsultationID(ctx, consultation.ID); err == nil && followUp.Status != models.FollowUpDismissed {
+		content.FollowUpBooked = followUp.Status == models.FollowUpBooked
+		if !content.FollowUpBooked {
+			content.FollowUpDue = followUp.DueDate.Format("2006-01-02")
+		}
+		content.FollowUpNote = followUp.Note
+	}
+	return content, nil
+}
+
+// summarySection is a titled part of the consultation details
+type summarySection struct {
+	Title string
+	Body  string
+}
+
+// sections returns the consultation details that were filled in
+func (c *summaryContent) sections(text summaryText) []summarySection {
+	var sections []summarySection
+	for _, section := range []summarySection{
+		{text.Complaint, c.Complaint},
+		{text.Symptoms, c.Symptoms},
+		{text.Diagnosis, c.Diagnosis},
+		{text.Treatment, c.Treatment},
+		{text.Notes, c.Notes},
+	} {
+		if section.Body != "" {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+// followUpText is the follow-up instruction in the summary's language, or "" for none
+func (c *summaryContent) followUpText(text summaryText) string {
+	switch {
+	case c.FollowUpBooked:
+		return text.FollowUpBooked
+	case c.FollowUpDue != "":
+		return fmt.Sprintf(text.FollowUpPending, c.FollowUpDue)
+	}
+	return ""
+}
+
+func summaryHash(content *summaryContent, language, format string) (string, error) {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(summaryLayoutVersion + "\n" + language + "\n" + format + "\n" + string(data)))
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultCodeSearchLimit = 10
+	maxCodeSearchLimit     = 50
+)
+
+var (
+	ErrUnknownDiagnosisCode = errors.New("unknown ICD-10 code")
+
+	icd10Pattern = regexp.MustCompile(`^[A-Z][0-9][0-9A-Z](\.[0-9A-Z]{1,4})?$`)
+)
+
+// DiagnosisCodeService manages the ICD-10 catalogue and checks coded diagnoses against it
+type DiagnosisCodeService struct {
+	repo   repository.DiagnosisCodeRepository
+	logger *logrus.Logger
+}
+
+func NewDiagnosisCodeService(repo repository.DiagnosisCodeRepository, logger *logrus.Logger) *DiagnosisCodeService {
+	return &DiagnosisCodeService{repo: repo, logger: logger}
+}
+
+// Import loads catalogue entries from CSV (code, description, with an optional header) or
+// from a JSON array
+func (s *DiagnosisCodeService) Import(ctx context.Context, format string, r io.Reader) (int, error) {
+	var codes []models.DiagnosisCode
+	switch format {
+	case "csv":
+		records, err := readDatasetCSV(r, 2, "code")
+		if err != nil {
+			return 0, err
+		}
+		for _, record := range records {
+			codes = append(codes, models.DiagnosisCode{Code: record[0], Description: record[1]})
+		}
+	case "json":
+		if err := json.NewDecoder(r).Decode(&codes); err != nil {
+			return 0, fmt.Errorf("invalid JSON: %w", err)
+		}
+	default:
+		return 0, fmt.Errorf("unsupported format %q", format)
+	}
+
+	for i := range codes {
+		code := &codes[i]
+		code.Code = NormalizeICD10Code(code.Code)
+		code.Description = strings.TrimSpace(code.Description)
+		if !icd10Pattern.MatchString(code.Code) {
+			return 0, fmt.Errorf("row %d: %q is not an ICD-10 code", i+1, code.Code)
+		}
+		if code.Description == "" {
+			return 0, fmt.Errorf("row %d: description is required", i+1)
+		}
+		code.Category = code.Code[:3]
+	}
+
+	n, err := s.repo.Import(ctx, codes)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to import ICD-10 codes")
+		return 0, fmt.Errorf("failed to import ICD-10 codes: %w", err)
+	}
+	s.logger.Infof("Imported %d ICD-10 codes", n)
+	return n, nil
+}
+
+// Search serves code autocomplete; a limit of 0 uses the default
+func (s *DiagnosisCodeService) Search(ctx context.Context, query string, limit int) ([]models.DiagnosisCode, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("a search query is required")
+	}
+	if limit <= 0 {
+		limit = defaultCodeSearchLimit
+	}
+	if limit > maxCodeSearchLimit {
+		limit = maxCodeSearchLimit
+	}
+
+	codes, err := s.repo.Search(ctx, query, limit)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to search ICD-10 codes for %q", query)
+		return nil, fmt.Errorf("failed to search ICD-10 codes: %w", err)
+	}
+	return codes, nil
+}
+
+// Get returns one catalogue entry
+func (s *DiagnosisCodeService) Get(ctx context.Context, code string) (*models.DiagnosisCode, error) {
+	entries, err := s.lookup(ctx, []string{code})
+	if err != nil {
+		return nil, err
+	}
+	entry := entries[NormalizeICD10Code(code)]
+	return &entry, nil
+}
+
+// ResolveDiagnoses normalises the ICD-10 codes of the diagnoses, checks them against the
+// catalogue and fills in missing descriptions. Diagnoses in other code systems are left alone.
+func (s *DiagnosisCodeService) ResolveDiagnoses(ctx context.Context, diagnoses []models.CodedDiagnosis) error {
+	var codes []string
+	for i := range diagnoses {
+		if diagnoses[i].System == models.DiagnosisCodeSystemICD10 {
+			diagnoses[i].Code = NormalizeICD10Code(diagnoses[i].Code)
+			codes = append(codes, diagnoses[i].Code)
+		}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+
+	entries, err := s.lookup(ctx, codes)
+	if err != nil {
+		return err
+	}
+	for i := range diagnoses {
+		if diagnoses[i].System == models.DiagnosisCodeSystemICD10 && diagnoses[i].Description == "" {
+			diagnoses[i].Description = entries[diagnoses[i].Code].Description
+		}
+	}
+	return nil
+}
+
+// lookup returns the catalogue entries of the codes, keyed by normalised code, failing with
+// ErrUnknownDiagnosisCode when any of them is not in the catalogue
+func (s *DiagnosisCodeService) lookup(ctx context.Context, codes []string) (map[string]models.DiagnosisCode, error) {
+	wanted := map[string]bool{}
+	for _, code := range codes {
+		wanted[NormalizeICD10Code(code)] = true
+	}
+
+	found, err := s.repo.GetByCodes(ctx, setKeys(wanted))
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to look up ICD-10 codes")
+		return nil, fmt.Errorf("failed to look up ICD-10 codes: %w", err)
+	}
+	entries := make(map[string]models.DiagnosisCode, len(found))
+	for _, entry := range found {
+		entries[entry.Code] = entry
+	}
+
+	var unknown []string
+	for _, code := range setKeys(wanted) {
+		if _, ok := entries[code]; !ok {
+			unknown = append(unknown, code)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDiagnosisCode, strings.Join(unknown, ", "))
+	}
+	return entries, nil
+}
+
+// NormalizeICD10Code upper-cases a code and restores the dot after the category, so "j069"
+// and "J06.9" are the same code
+func NormalizeICD10Code(code string) string {
+	code = strings.ToUpper(strings.Join(strings.Fields(code), ""))
+	if len(code) > 3 && !strings.Contains(code, ".") {
+		code = code[:3] + "." + code[3:]
+	}
+	return code
+}
@@ -0,0 +1,29 @@
+package service
+
+import "testing"
+
+func TestNormalizeICD10Code(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"J06.9", "J06.9"},
+		{"j06.9", "J06.9"},
+		{"j069", "J06.9"},
+		{" J06 .9 ", "J06.9"},
+		{"E11", "E11"},
+		{"e11", "E11"},
+		{"S72001A", "S72.001A"},
+		{"s72.001a", "S72.001A"},
+		{"J0", "J0"},
+		{"", ""},
+		{"   ", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := NormalizeICD10Code(tt.code); got != tt.want {
+				t.Errorf("NormalizeICD10Code(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
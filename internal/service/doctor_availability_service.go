@@ -6,18 +6,40 @@ import (
 	"fmt"
 	"shifa/internal/models"
 	"shifa/internal/repository"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// ErrSlotUnavailable is returned when a requested time falls outside the provider's bookable hours
+var ErrSlotUnavailable = errors.New("provider is not available at the selected time")
+
 type DoctorAvailabilityService struct {
 	doctorAvailabilityRepo repository.DoctorAvailabilityRepository
+	exceptionRepo          repository.AvailabilityExceptionRepository
+	holidayRepo            repository.HolidayRepository
+	appointmentRepo        repository.AppointmentRepository
+	notificationService    NotificationService
+	timeZones              *TimeZoneService
 	logger                 *logrus.Logger
 }
 
-func NewDoctorAvailabilityService(doctorAvailabilityRepo repository.DoctorAvailabilityRepository, logger *logrus.Logger) *DoctorAvailabilityService {
+func NewDoctorAvailabilityService(
+	doctorAvailabilityRepo repository.DoctorAvailabilityRepository,
+	exceptionRepo repository.AvailabilityExceptionRepository,
+	holidayRepo repository.HolidayRepository,
+	appointmentRepo repository.AppointmentRepository,
+	notificationService NotificationService,
+	timeZones *TimeZoneService,
+	logger *logrus.Logger,
+) *DoctorAvailabilityService {
 	return &DoctorAvailabilityService{
 		doctorAvailabilityRepo: doctorAvailabilityRepo,
+		exceptionRepo:          exceptionRepo,
+		holidayRepo:            holidayRepo,
+		appointmentRepo:        appointmentRepo,
+		notificationService:    notificationService,
+		timeZones:              timeZones,
 		logger:                 logger,
 	}
 }
@@ -87,6 +109,256 @@ func (s *DoctorAvailabilityService) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
+// AddException records a blocked or extra-hours range for a doctor
+func (s *DoctorAvailabilityService) AddException(ctx context.Context, exception *models.AvailabilityException) error {
+	if err := s.validateException(exception); err != nil {
+		s.logger.WithError(err).Error("Invalid availability exception data")
+		return err
+	}
+
+	if err := s.exceptionRepo.Create(ctx, exception); err != nil {
+		s.logger.WithError(err).Error("Failed to create availability exception")
+		return fmt.Errorf("failed to create availability exception: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"doctor_id":      exception.DoctorID,
+		"exception_id":   exception.ID,
+		"exception_type": exception.ExceptionType,
+	}).Info("Availability exception created")
+	return nil
+}
+
+// ListExceptions retrieves a doctor's exceptions overlapping the [from, to) range
+func (s *DoctorAvailabilityService) ListExceptions(ctx context.Context, doctorID int, from, to time.Time) ([]*models.AvailabilityException, error) {
+	exceptions, err := s.exceptionRepo.ListByDoctorID(ctx, doctorID, from, to)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list availability exceptions for doctor ID: %d", doctorID)
+		return nil, fmt.Errorf("failed to list availability exceptions: %w", err)
+	}
+	return exceptions, nil
+}
+
+// DeleteException removes one of a doctor's availability exceptions
+func (s *DoctorAvailabilityService) DeleteException(ctx context.Context, doctorID, exceptionID int) error {
+	exception, err := s.getDoctorException(ctx, doctorID, exceptionID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.exceptionRepo.Delete(ctx, exception.ID); err != nil {
+		s.logger.WithError(err).Errorf("Failed to delete availability exception with ID: %d", exceptionID)
+		return fmt.Errorf("failed to delete availability exception: %w", err)
+	}
+	return nil
+}
+
+// GetAvailableSlots returns the free slots of the given length for a doctor on a date in
+// the doctor's time zone. Weekly hours are dropped on holidays, extra ranges are added,
+// blocked ranges and already scheduled appointments are removed.
+func (s *DoctorAvailabilityService) GetAvailableSlots(ctx context.Context, doctorID int, date time.Time, slotLength time.Duration) ([]models.TimeSlot, error) {
+	if slotLength <= 0 {
+		return nil, errors.New("slot length must be positive")
+	}
+
+	loc, err := s.timeZones.Location(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	day := dateIn(date, loc)
+
+	open, err := s.openWindows(ctx, doctorID, day)
+	if err != nil {
+		return nil, err
+	}
+	booked, err := s.bookedRanges(ctx, doctorID, day, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := []models.TimeSlot{}
+	for _, slot := range splitIntoSlots(open, slotLength) {
+		if overlapsAny(slot, booked) {
+			continue
+		}
+		slots = append(slots, models.TimeSlot{
+			Date:      civilDate(day),
+			StartTime: models.CustomTime(slot.Start),
+			EndTime:   models.CustomTime(slot.End),
+			StartsAt:  slot.Start,
+			EndsAt:    slot.End,
+		})
+	}
+	return slots, nil
+}
+
+// CheckAvailability verifies that a doctor can take an appointment between the start and end
+// instants. excludeAppointmentID skips one existing appointment, for rescheduling; pass 0 for
+// new bookings.
+func (s *DoctorAvailabilityService) CheckAvailability(ctx context.Context, doctorID int, start, end time.Time, excludeAppointmentID int) error {
+	loc, err := s.timeZones.Location(ctx, doctorID)
+	if err != nil {
+		return err
+	}
+	requested := timeRange{Start: start, End: end}
+	date := dateIn(start.In(loc), loc)
+
+	open, err := s.openWindows(ctx, doctorID, date)
+	if err != nil {
+		return err
+	}
+	if !withinAny(requested, open) {
+		return ErrSlotUnavailable
+	}
+
+	booked, err := s.bookedRanges(ctx, doctorID, date, excludeAppointmentID)
+	if err != nil {
+		return err
+	}
+	if overlapsAny(requested, booked) {
+		return errors.New("there is a conflicting appointment at the selected time")
+	}
+	return nil
+}
+
+// CancelAppointmentsInException cancels every scheduled appointment inside a blocked
+// exception and notifies the affected patients. It returns the cancelled appointments.
+func (s *DoctorAvailabilityService) CancelAppointmentsInException(ctx context.Context, doctorID, exceptionID int, reason string) ([]*models.Appointment, error) {
+	exception, err := s.getDoctorException(ctx, doctorID, exceptionID)
+	if err != nil {
+		return nil, err
+	}
+	if exception.ExceptionType != models.ExceptionTypeBlocked {
+		return nil, errors.New("only blocked exceptions can cancel appointments")
+	}
+	if reason == "" {
+		reason = exception.Reason
+	}
+	if reason == "" {
+		reason = "Provider unavailable"
+	}
+
+	// Appointment dates are in the doctor's zone, so widen the date range by a day either side
+	appointments, err := s.appointmentRepo.GetByProviderAndDateRange(ctx, doctorID, "doctor",
+		exception.StartAt.AddDate(0, 0, -1), exception.EndAt.AddDate(0, 0, 1))
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load appointments for doctor ID: %d", doctorID)
+		return nil, fmt.Errorf("failed to load appointments: %w", err)
+	}
+
+	blocked := timeRange{Start: exception.StartAt, End: exception.EndAt}
+	cancelled := []*models.Appointment{}
+	for _, appointment := range appointments {
+		if appointment.Status != "scheduled" || !blocked.overlaps(appointmentRange(appointment)) {
+			continue
+		}
+
+		appointment.Status = "cancelled"
+		appointment.CancellationReason = &reason
+		if err := s.appointmentRepo.Update(ctx, appointment); err != nil {
+			s.logger.WithError(err).Errorf("Failed to cancel appointment with ID: %d", appointment.ID)
+			return cancelled, fmt.Errorf("failed to cancel appointment with ID %d: %w", appointment.ID, err)
+		}
+		cancelled = append(cancelled, appointment)
+
+		notification := &models.Notification{
+			UserID:           appointment.PatientID,
+			NotificationType: "appointment_cancelled",
+			Message: fmt.Sprintf("Your appointment on %s was cancelled: %s",
+				s.timeZones.LocalTime(ctx, appointment.PatientID, appointmentRange(appointment).Start).Format(notificationTimeLayout), reason),
+		}
+		if err := s.notificationService.CreateNotification(ctx, notification); err != nil {
+			// The cancellation stands even if the patient could not be notified
+			s.logger.WithError(err).Warnf("Failed to notify patient %d about cancelled appointment %d", appointment.PatientID, appointment.ID)
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"doctor_id":    doctorID,
+		"exception_id": exceptionID,
+		"cancelled":    len(cancelled),
+	}).Info("Cancelled appointments inside blocked range")
+	return cancelled, nil
+}
+
+// openWindows computes the doctor's bookable ranges on a date given as midnight in the doctor's zone
+func (s *DoctorAvailabilityService) openWindows(ctx context.Context, doctorID int, date time.Time) ([]timeRange, error) {
+	day := dayRange(date)
+
+	holidays, err := s.holidayRepo.List(ctx, day.Start, day.Start)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load holidays")
+		return nil, fmt.Errorf("failed to load holidays: %w", err)
+	}
+
+	availabilities, err := s.doctorAvailabilityRepo.ListByDoctorID(ctx, doctorID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load weekly availability for doctor ID: %d", doctorID)
+		return nil, fmt.Errorf("failed to load doctor availability: %w", err)
+	}
+	weekly := make([]weeklyHours, 0, len(availabilities))
+	for _, availability := range availabilities {
+		weekly = append(weekly, weeklyHours{DayOfWeek: availability.DayOfWeek, Start: availability.StartTime, End: availability.EndTime})
+	}
+
+	exceptions, err := s.exceptionRepo.ListByDoctorID(ctx, doctorID, day.Start, day.End)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load availability exceptions for doctor ID: %d", doctorID)
+		return nil, fmt.Errorf("failed to load availability exceptions: %w", err)
+	}
+	overrides := make([]dateException, 0, len(exceptions))
+	for _, exception := range exceptions {
+		overrides = append(overrides, dateException{Type: exception.ExceptionType, Start: exception.StartAt, End: exception.EndAt})
+	}
+
+	return openWindowsOn(date, len(holidays) > 0, weekly, overrides), nil
+}
+
+// bookedRanges returns the time ranges taken by the doctor's scheduled appointments on a date
+func (s *DoctorAvailabilityService) bookedRanges(ctx context.Context, doctorID int, date time.Time, excludeAppointmentID int) ([]timeRange, error) {
+	appointments, err := s.appointmentRepo.GetByProviderAndDateRange(ctx, doctorID, "doctor", date, date)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load appointments for doctor ID: %d", doctorID)
+		return nil, fmt.Errorf("failed to load appointments: %w", err)
+	}
+
+	var booked []timeRange
+	for _, appointment := range appointments {
+		if !holdsSlot(appointment) || appointment.ID == excludeAppointmentID {
+			continue
+		}
+		booked = append(booked, appointmentRange(appointment))
+	}
+	return booked, nil
+}
+
+func (s *DoctorAvailabilityService) getDoctorException(ctx context.Context, doctorID, exceptionID int) (*models.AvailabilityException, error) {
+	exception, err := s.exceptionRepo.GetByID(ctx, exceptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get availability exception: %w", err)
+	}
+	if exception.DoctorID != doctorID {
+		return nil, errors.New("availability exception not found")
+	}
+	return exception, nil
+}
+
+func (s *DoctorAvailabilityService) validateException(exception *models.AvailabilityException) error {
+	if exception.DoctorID == 0 {
+		return errors.New("doctor ID is required")
+	}
+	if exception.ExceptionType != models.ExceptionTypeBlocked && exception.ExceptionType != models.ExceptionTypeExtra {
+		return fmt.Errorf("exception type must be %q or %q", models.ExceptionTypeBlocked, models.ExceptionTypeExtra)
+	}
+	if exception.StartAt.IsZero() || exception.EndAt.IsZero() {
+		return errors.New("start and end are required")
+	}
+	if !exception.StartAt.Before(exception.EndAt) {
+		return errors.New("start must be before end")
+	}
+	return nil
+}
+
 // validateDoctorAvailability validates the doctor availability data
 func (s *DoctorAvailabilityService) validateDoctorAvailability(availability models.DoctorAvailability) error {
 	if availability.DoctorID == 0 {
@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// medicationLookbackDays bounds how far back prescriptions are searched for current medications
+const medicationLookbackDays = 365
+
+var severityRank = map[string]int{
+	models.SeverityMinor:           1,
+	models.SeverityModerate:        2,
+	models.SeverityMajor:           3,
+	models.SeverityContraindicated: 4,
+}
+
+// DrugSafetyService checks prescriptions against the patient's current medications and
+// documented allergies, using the locally imported interaction and drug class datasets
+type DrugSafetyService struct {
+	drugRepo         repository.DrugDataRepository
+	prescriptionRepo repository.PrescriptionRepository
+	historyRepo      repository.MedicalHistoryRepository
+	logger           *logrus.Logger
+}
+
+func NewDrugSafetyService(
+	drugRepo repository.DrugDataRepository,
+	prescriptionRepo repository.PrescriptionRepository,
+	historyRepo repository.MedicalHistoryRepository,
+	logger *logrus.Logger,
+) *DrugSafetyService {
+	return &DrugSafetyService{
+		drugRepo:         drugRepo,
+		prescriptionRepo: prescriptionRepo,
+		historyRepo:      historyRepo,
+		logger:           logger,
+	}
+}
+
+// ImportInteractions loads interaction rows from CSV (drug_a, drug_b, severity, description,
+// with an optional header) or from a JSON array
+func (s *DrugSafetyService) ImportInteractions(ctx context.Context, format string, r io.Reader) (int, error) {
+	var interactions []models.DrugInteraction
+	switch format {
+	case "csv":
+		records, err := readDatasetCSV(r, 4, "drug_a")
+		if err != nil {
+			return 0, err
+		}
+		for _, record := range records {
+			interactions = append(interactions, models.DrugInteraction{
+				DrugA: record[0], DrugB: record[1], Severity: record[2], Description: record[3],
+			})
+		}
+	case "json":
+		if err := json.NewDecoder(r).Decode(&interactions); err != nil {
+			return 0, fmt.Errorf("invalid JSON: %w", err)
+		}
+	default:
+		return 0, fmt.Errorf("unsupported format %q", format)
+	}
+
+	for i := range interactions {
+		interaction := &interactions[i]
+		interaction.DrugA = normalizeDrugName(interaction.DrugA)
+		interaction.DrugB = normalizeDrugName(interaction.DrugB)
+		interaction.Severity = strings.ToLower(strings.TrimSpace(interaction.Severity))
+		interaction.Description = strings.TrimSpace(interaction.Description)
+		if interaction.DrugA == "" || interaction.DrugB == "" || interaction.DrugA == interaction.DrugB {
+			return 0, fmt.Errorf("row %d: two different drugs are required", i+1)
+		}
+		if _, ok := severityRank[interaction.Severity]; !ok {
+			return 0, fmt.Errorf("row %d: unknown severity %q", i+1, interaction.Severity)
+		}
+		// Pairs are stored in name order so each pair has a single row
+		if interaction.DrugB < interaction.DrugA {
+			interaction.DrugA, interaction.DrugB = interaction.DrugB, interaction.DrugA
+		}
+	}
+
+	n, err := s.drugRepo.ImportInteractions(ctx, interactions)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to import drug interactions")
+		return 0, fmt.Errorf("failed to import drug interactions: %w", err)
+	}
+	s.logger.Infof("Imported %d drug interactions", n)
+	return n, nil
+}
+
+// ImportClasses loads drug class rows from CSV (drug_name, class_name, with an optional header)
+// or from a JSON array
+func (s *DrugSafetyService) ImportClasses(ctx context.Context, format string, r io.Reader) (int, error) {
+	var classes []models.DrugClass
+	switch format {
+	case "csv":
+		records, err := readDatasetCSV(r, 2, "drug_name")
+		if err != nil {
+			return 0, err
+		}
+		for _, record := range records {
+			classes = append(classes, models.DrugClass{DrugName: record[0], ClassName: record[1]})
+		}
+	case "json":
+		if err := json.NewDecoder(r).Decode(&classes); err != nil {
+			return 0, fmt.Errorf("invalid JSON: %w", err)
+		}
+	default:
+		return 0, fmt.Errorf("unsupported format %q", format)
+	}
+
+	for i := range classes {
+		classes[i].DrugName = normalizeDrugName(classes[i].DrugName)
+		classes[i].ClassName = normalizeDrugName(classes[i].ClassName)
+		if classes[i].DrugName == "" || classes[i].ClassName == "" {
+			return 0, fmt.Errorf("row %d: drug and class names are required", i+1)
+		}
+	}
+
+	n, err := s.drugRepo.ImportClasses(ctx, classes)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to import drug classes")
+		return 0, fmt.Errorf("failed to import drug classes: %w", err)
+	}
+	s.logger.Infof("Imported %d drug classes", n)
+	return n, nil
+}
+
+// medication is a drug taking part in a check: a line of the new prescription or one the
+// patient is currently taking
+type medication struct {
+	name    string // normalised
+	display string
+	current bool
+}
+
+// Check returns the warnings raised by prescribing the items to the patient, most severe first
+func (s *DrugSafetyService) Check(ctx context.Context, patientID int, items []models.PrescriptionItem) ([]models.PrescriptionWarning, error) {
+	current, err := s.currentMedications(ctx, patientID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	histories, err := s.historyRepo.GetByPatientID(ctx, patientID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get medical history for patient ID: %d", patientID)
+		return nil, fmt.Errorf("failed to get medical history: %w", err)
+	}
+
+	var prescribed []medication
+	for _, item := range items {
+		prescribed = append(prescribed, medication{name: normalizeDrugName(item.DrugName), display: item.DrugName})
+	}
+
+	names := map[string]bool{}
+	for _, m := range append(append([]medication{}, prescribed...), current...) {
+		names[m.name] = true
+	}
+	classes, err := s.drugRepo.ListClasses(ctx, setKeys(names))
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list drug classes")
+		return nil, fmt.Errorf("failed to list drug classes: %w", err)
+	}
+	classesOf := map[string][]string{}
+	terms := map[string]bool{}
+	for name := range names {
+		terms[name] = true
+	}
+	for _, class := range classes {
+		classesOf[class.DrugName] = append(classesOf[class.DrugName], class.ClassName)
+		terms[class.ClassName] = true
+	}
+
+	rows, err := s.drugRepo.FindInteractions(ctx, setKeys(terms))
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to find drug interactions")
+		return nil, fmt.Errorf("failed to find drug interactions: %w", err)
+	}
+	interactions := map[[2]string]models.DrugInteraction{}
+	for _, row := range rows {
+		interactions[orderedPair(row.DrugA, row.DrugB)] = row
+	}
+
+	warnings := map[string]models.PrescriptionWarning{}
+	add := func(w models.PrescriptionWarning) {
+		if existing, ok := warnings[w.Key]; !ok || severityRank[w.Severity] > severityRank[existing.Severity] {
+			warnings[w.Key] = w
+		}
+	}
+
+	for i, m := range prescribed {
+		others := append(append([]medication{}, prescribed[i+1:]...), current...)
+		for _, other := range others {
+			pair := orderedPair(m.name, other.name)
+			if w, ok := duplicateTherapy(m, other, classesOf); ok {
+				w.Key = models.WarningDuplicateTherapy + ":" + pair[0] + ":" + pair[1]
+				add(w)
+			}
+			if m.name == other.name {
+				continue
+			}
+			if interaction, ok := worstInteraction(m, other, classesOf, interactions); ok {
+				add(models.PrescriptionWarning{
+					Key:         models.WarningInteraction + ":" + pair[0] + ":" + pair[1],
+					Type:        models.WarningInteraction,
+					Severity:    interaction.Severity,
+					Drug:        m.display,
+					Conflict:    other.display,
+					Description: interaction.Description,
+				})
+			}
+		}
+
+		for _, history := range histories {
+			allergen := normalizeDrugName(history.Allergen)
+			if allergen == "" || !history.IsCurrent {
+				continue
+			}
+			w := models.PrescriptionWarning{
+				Key:      models.WarningAllergy + ":" + m.name + ":" + allergen,
+				Type:     models.WarningAllergy,
+				Drug:     m.display,
+				Conflict: history.Allergen,
+			}
+			if allergen == m.name {
+				w.Severity = models.SeverityContraindicated
+				w.Description = "The patient has a documented allergy to " + history.Allergen
+				add(w)
+			} else if containsString(classesOf[m.name], allergen) {
+				w.Severity = models.SeverityMajor
+				w.Description = fmt.Sprintf("%s belongs to the class %s; the patient has a documented allergy to %s", m.display, allergen, history.Allergen)
+				add(w)
+			}
+		}
+	}
+
+	result := make([]models.PrescriptionWarning, 0, len(warnings))
+	for _, w := range warnings {
+		result = append(result, w)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if severityRank[result[i].Severity] != severityRank[result[j].Severity] {
+			return severityRank[result[i].Severity] > severityRank[result[j].Severity]
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+// currentMedications lists the drugs of the patient's prescriptions whose course, refills
+// included, has not yet run out
+func (s *DrugSafetyService) currentMedications(ctx context.Context, patientID int, now time.Time) ([]medication, error) {
+	prescriptions, err := s.prescriptionRepo.ListByPatient(ctx, patientID, now.AddDate(0, 0, -medicationLookbackDays))
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list prescriptions for patient ID: %d", patientID)
+		return nil, fmt.Errorf("failed to list prescriptions: %w", err)
+	}
+
+	var current []medication
+	for _, p := range prescriptions {
+		for _, item := range p.Items {
+			if p.IssuedAt.AddDate(0, 0, item.DurationDays*(item.Refills+1)).After(now) {
+				current = append(current, medication{name: normalizeDrugName(item.DrugName), display: item.DrugName, current: true})
+			}
+		}
+	}
+	return current, nil
+}
+
+// RequiresOverride reports whether a warning blocks the prescription until the doctor overrides it
+func RequiresOverride(w models.PrescriptionWarning) bool {
+	return severityRank[w.Severity] >= severityRank[models.SeverityModerate]
+}
+
+func duplicateTherapy(m, other medication, classesOf map[string][]string) (models.PrescriptionWarning, bool) {
+	w := models.PrescriptionWarning{
+		Type:     models.WarningDuplicateTherapy,
+		Severity: models.SeverityModerate,
+		Drug:     m.display,
+		Conflict: other.display,
+	}
+	if m.name == other.name {
+		if other.current {
+			w.Description = "The patient is already taking " + other.display
+		} else {
+			w.Description = m.display + " is prescribed more than once"
+		}
+		return w, true
+	}
+	for _, class := range classesOf[m.name] {
+		if containsString(classesOf[other.name], class) {
+			w.Description = fmt.Sprintf("%s and %s are both %s", m.display, other.display, class)
+			return w, true
+		}
+	}
+	return w, false
+}
+
+// worstInteraction looks the two drugs up by name and by class, returning the most severe match
+func worstInteraction(m, other medication, classesOf map[string][]string, interactions map[[2]string]models.DrugInteraction) (models.DrugInteraction, bool) {
+	var (
+		worst models.DrugInteraction
+		found bool
+	)
+	for _, a := range append([]string{m.name}, classesOf[m.name]...) {
+		for _, b := range append([]string{other.name}, classesOf[other.name]...) {
+			interaction, ok := interactions[orderedPair(a, b)]
+			if ok && (!found || severityRank[interaction.Severity] > severityRank[worst.Severity]) {
+				worst, found = interaction, true
+			}
+		}
+	}
+	return worst, found
+}
+
+// readDatasetCSV reads rows of the given width, skipping a header whose first column is header
+func readDatasetCSV(r io.Reader, width int, header string) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = width
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) > 0 && strings.EqualFold(strings.TrimSpace(records[0][0]), header) {
+		records = records[1:]
+	}
+	if len(records) == 0 {
+		return nil, errors.New("the file has no rows")
+	}
+	return records, nil
+}
+
+func normalizeDrugName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func orderedPair(a, b string) [2]string {
+	if b < a {
+		return [2]string{b, a}
+	}
+	return [2]string{a, b}
+}
+
+func setKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeDrugData serves the interaction and class datasets from memory
+type fakeDrugData struct {
+	repository.DrugDataRepository
+	interactions []models.DrugInteraction
+	classes      []models.DrugClass
+}
+
+func (f *fakeDrugData) FindInteractions(ctx context.Context, names []string) ([]models.DrugInteraction, error) {
+	var found []models.DrugInteraction
+	for _, interaction := range f.interactions {
+		if containsString(names, interaction.DrugA) && containsString(names, interaction.DrugB) {
+			found = append(found, interaction)
+		}
+	}
+	return found, nil
+}
+
+func (f *fakeDrugData) ListClasses(ctx context.Context, drugNames []string) ([]models.DrugClass, error) {
+	var found []models.DrugClass
+	for _, class := range f.classes {
+		if containsString(drugNames, class.DrugName) {
+			found = append(found, class)
+		}
+	}
+	return found, nil
+}
+
+type fakePatientPrescriptions struct {
+	repository.PrescriptionRepository
+	prescriptions []*models.Prescription
+}
+
+func (f *fakePatientPrescriptions) ListByPatient(ctx context.Context, patientID int, issuedSince time.Time) ([]*models.Prescription, error) {
+	return f.prescriptions, nil
+}
+
+type fakeHistory struct {
+	repository.MedicalHistoryRepository
+	entries []*models.MedicalHistory
+}
+
+func (f *fakeHistory) GetByPatientID(ctx context.Context, patientID int) ([]*models.MedicalHistory, error) {
+	return f.entries, nil
+}
+
+func TestDrugSafetyCheck(t *testing.T) {
+	drugData := &fakeDrugData{
+		interactions: []models.DrugInteraction{
+			{DrugA: "aspirin", DrugB: "warfarin", Severity: models.SeverityMajor, Description: "bleeding"},
+			{DrugA: "nsaid", DrugB: "warfarin", Severity: models.SeverityModerate, Description: "bleeding"},
+		},
+		classes: []models.DrugClass{
+			{DrugName: "aspirin", ClassName: "nsaid"},
+			{DrugName: "ibuprofen", ClassName: "nsaid"},
+			{DrugName: "amoxicillin", ClassName: "penicillin"},
+		},
+	}
+	taking := func(drug string, issuedDaysAgo, durationDays, refills int) *models.Prescription {
+		return &models.Prescription{
+			IssuedAt: time.Now().AddDate(0, 0, -issuedDaysAgo),
+			Items:    []models.PrescriptionItem{{DrugName: drug, DurationDays: durationDays, Refills: refills}},
+		}
+	}
+	allergy := func(allergen string, current bool) *models.MedicalHistory {
+		return &models.MedicalHistory{Allergen: allergen, IsCurrent: current}
+	}
+
+	tests := []struct {
+		name    string
+		items   []string
+		current []*models.Prescription
+		history []*models.MedicalHistory
+		want    []string // key and severity, in order
+	}{
+		{"no findings", []string{"Paracetamol"}, nil, nil, []string{}},
+		{
+			"interaction between new lines",
+			[]string{" Warfarin ", "ASPIRIN"}, nil, nil,
+			[]string{"interaction:aspirin:warfarin major"},
+		},
+		{
+			"interaction through a class with a current medication",
+			[]string{"Ibuprofen"}, []*models.Prescription{taking("Warfarin", 5, 30, 0)}, nil,
+			[]string{"interaction:ibuprofen:warfarin moderate"},
+		},
+		{
+			"duplicate therapy within a class",
+			[]string{"Aspirin", "Ibuprofen"}, nil, nil,
+			[]string{"duplicate_therapy:aspirin:ibuprofen moderate"},
+		},
+		{
+			"already taking the drug",
+			[]string{"Warfarin"}, []*models.Prescription{taking("warfarin", 5, 30, 0)}, nil,
+			[]string{"duplicate_therapy:warfarin:warfarin moderate"},
+		},
+		{
+			"finished course is not current",
+			[]string{"Warfarin"}, []*models.Prescription{taking("Warfarin", 45, 30, 0)}, nil,
+			[]string{},
+		},
+		{
+			"refills extend the course",
+			[]string{"Warfarin"}, []*models.Prescription{taking("Warfarin", 45, 30, 1)}, nil,
+			[]string{"duplicate_therapy:warfarin:warfarin moderate"},
+		},
+		{
+			"allergy to the drug",
+			[]string{"Amoxicillin"}, nil, []*models.MedicalHistory{allergy("Amoxicillin", true)},
+			[]string{"allergy:amoxicillin:amoxicillin contraindicated"},
+		},
+		{
+			"allergy to the drug's class",
+			[]string{"Amoxicillin"}, nil, []*models.MedicalHistory{allergy("Penicillin", true)},
+			[]string{"allergy:amoxicillin:penicillin major"},
+		},
+		{
+			"past allergy is ignored",
+			[]string{"Amoxicillin"}, nil, []*models.MedicalHistory{allergy("Penicillin", false)},
+			[]string{},
+		},
+		{
+			"most severe first, then by key",
+			[]string{"Ibuprofen", "Amoxicillin", "Warfarin"}, nil,
+			[]*models.MedicalHistory{allergy("amoxicillin", true)},
+			[]string{
+				"allergy:amoxicillin:amoxicillin contraindicated",
+				"interaction:ibuprofen:warfarin moderate",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			s := NewDrugSafetyService(drugData, &fakePatientPrescriptions{prescriptions: tt.current}, &fakeHistory{entries: tt.history}, logger)
+
+			var items []models.PrescriptionItem
+			for _, drug := range tt.items {
+				items = append(items, models.PrescriptionItem{DrugName: drug})
+			}
+			warnings, err := s.Check(context.Background(), 1, items)
+			if err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+
+			got := []string{}
+			for _, w := range warnings {
+				got = append(got, w.Key+" "+w.Severity)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("warnings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	maxFollowUpIntervalDays = 365
+	// Patients are reminded from a week before the due date, at most weekly and at most
+	// maxFollowUpReminders times
+	followUpReminderLead     = 7 * 24 * time.Hour
+	followUpReminderInterval = 7 * 24 * time.Hour
+	maxFollowUpReminders     = 3
+	// The slot picker shows a week from the due date by default and at most two
+	defaultFollowUpSlotDays = 7
+	maxFollowUpSlotDays     = 14
+)
+
+var (
+	ErrFollowUpAccessDenied = errors.New("only the follow-up's patient and doctor can view it")
+	ErrNotFollowUpDoctor    = errors.New("only the doctor who recommended the follow-up can dismiss it")
+	ErrFollowUpNotPending   = errors.New("the follow-up has already been booked or dismissed")
+)
+
+// FollowUpService manages the follow-ups doctors recommend when completing a consultation:
+// booking them with the same doctor, reminding patients who have not booked, and listing the
+// overdue ones for the doctor
+type FollowUpService struct {
+	followUpRepo        repository.FollowUpRepository
+	doctorRepo          repository.DoctorRepository
+	userRepo            repository.UserRepository
+	availability        *DoctorAvailabilityService
+	appointmentService  *AppointmentService
+	notificationService NotificationService
+	timeZones           *TimeZoneService
+	logger              *logrus.Logger
+}
+
+func NewFollowUpService(
+	followUpRepo repository.FollowUpRepository,
+	doctorRepo repository.DoctorRepository,
+	userRepo repository.UserRepository,
+	availability *DoctorAvailabilityService,
+	appointmentService *AppointmentService,
+	notificationService NotificationService,
+	timeZones *TimeZoneService,
+	logger *logrus.Logger,
+) *FollowUpService {
+	return &FollowUpService{
+		followUpRepo:        followUpRepo,
+		doctorRepo:          doctorRepo,
+		userRepo:            userRepo,
+		availability:        availability,
+		appointmentService:  appointmentService,
+		notificationService: notificationService,
+		timeZones:           timeZones,
+		logger:              logger,
+	}
+}
+
+// validateFollowUpPlan checks a follow-up plan before the consultation is completed with it
+func validateFollowUpPlan(plan *models.FollowUpPlan) error {
+	if plan.IntervalDays < 1 || plan.IntervalDays > maxFollowUpIntervalDays {
+		return fmt.Errorf("follow-up interval must be between 1 and %d days", maxFollowUpIntervalDays)
+	}
+	return nil
+}
+
+// Recommend records a follow-up for a completed consultation. The due date counts the
+// interval from the completion date in the doctor's time zone.
+func (s *FollowUpService) Recommend(ctx context.Context, consultation *models.Consultation, plan *models.FollowUpPlan) (*models.FollowUp, error) {
+	if err := validateFollowUpPlan(plan); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+	loc, err := s.timeZones.Location(ctx, consultation.DoctorID)
+	if err != nil {
+		return nil, err
+	}
+
+	followUp := &models.FollowUp{
+		ConsultationID: consultation.ID,
+		PatientID:      consultation.PatientID,
+		DoctorID:       consultation.DoctorID,
+		IntervalDays:   plan.IntervalDays,
+		DueDate:        civilDate(consultation.CompletedAt.Time.In(loc)).AddDate(0, 0, plan.IntervalDays),
+		Note:           strings.TrimSpace(plan.Note),
+		Status:         models.FollowUpPending,
+	}
+	if err := s.followUpRepo.Create(ctx, followUp); err != nil {
+		s.logger.WithError(err).Errorf("Failed to create follow-up for consultation ID: %d", consultation.ID)
+		return nil, fmt.Errorf("failed to create follow-up: %w", err)
+	}
+
+	s.notify(ctx, followUp.PatientID, fmt.Sprintf("Your doctor recommends a follow-up visit by %s. Book it from your follow-ups.",
+		followUp.DueDate.Format("2006-01-02")))
+	s.logger.Infof("Follow-up %d recommended for consultation ID: %d, due %s", followUp.ID, consultation.ID, followUp.DueDate.Format("2006-01-02"))
+	return followUp, nil
+}
+
+// GetFollowUp returns a follow-up to its patient or doctor
+func (s *FollowUpService) GetFollowUp(ctx context.Context, id, userID int) (*models.FollowUp, error) {
+	followUp, err := s.getFollowUp(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if userID != followUp.PatientID && userID != followUp.DoctorID {
+		return nil, ErrFollowUpAccessDenied
+	}
+	return followUp, nil
+}
+
+// ListForPatient returns a patient's follow-ups, pending ones first
+func (s *FollowUpService) ListForPatient(ctx context.Context, patientID int) ([]*models.FollowUp, error) {
+	followUps, err := s.followUpRepo.ListByPatient(ctx, patientID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list follow-ups of patient ID: %d", patientID)
+		return nil, fmt.Errorf("failed to list follow-ups: %w", err)
+	}
+	return followUps, nil
+}
+
+// ListOverdue returns the doctor's follow-ups that are past their due date in the doctor's
+// time zone and have not been booked
+func (s *FollowUpService) ListOverdue(ctx context.Context, doctorID int) ([]*models.FollowUp, error) {
+	loc, err := s.timeZones.Location(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	followUps, err := s.followUpRepo.ListOverdue(ctx, doctorID, civilDate(time.Now().In(loc)))
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list overdue follow-ups of doctor ID: %d", doctorID)
+		return nil, fmt.Errorf("failed to list overdue follow-ups: %w", err)
+	}
+	return followUps, nil
+}
+
+// AvailableSlots lists the doctor's free slots for a pending follow-up over days days from
+// from, a date in the doctor's time zone. A zero from starts at the due date, or today once
+// that has passed.
+func (s *FollowUpService) AvailableSlots(ctx context.Context, id, userID int, from time.Time, days int, slotLength time.Duration) ([]models.TimeSlot, error) {
+	followUp, err := s.GetFollowUp(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if followUp.Status != models.FollowUpPending {
+		return nil, ErrFollowUpNotPending
+	}
+	if days <= 0 {
+		days = defaultFollowUpSlotDays
+	}
+	if days > maxFollowUpSlotDays {
+		days = maxFollowUpSlotDays
+	}
+	if from.IsZero() {
+		loc, err := s.timeZones.Location(ctx, followUp.DoctorID)
+		if err != nil {
+			return nil, err
+		}
+		from = followUp.DueDate
+		if today := civilDate(time.Now().In(loc)); from.Before(today) {
+			from = today
+		}
+	}
+
+	slots := []models.TimeSlot{}
+	for i := 0; i < days; i++ {
+		daySlots, err := s.availability.GetAvailableSlots(ctx, followUp.DoctorID, from.AddDate(0, 0, i), slotLength)
+		if err != nil {
+			return nil, err
+		}
+		slots = append(slots, daySlots...)
+	}
+	return slots, nil
+}
+
+// Book books a pending follow-up with its doctor. The slot comes from the appointment's
+// times; doctor, patient and type come from the follow-up.
+func (s *FollowUpService) Book(ctx context.Context, id, userID int, slot *models.Appointment) (*models.Appointment, error) {
+	followUp, err := s.GetFollowUp(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if followUp.Status != models.FollowUpPending {
+		return nil, ErrFollowUpNotPending
+	}
+	doctor, err := s.doctorRepo.GetByID(ctx, followUp.DoctorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get doctor: %w", err)
+	}
+
+	doctorID := followUp.DoctorID
+	appointment := &models.Appointment{
+		PatientID:       followUp.PatientID,
+		DoctorID:        &doctorID,
+		ServiceTypeID:   doctor.ServiceTypeID,
+		ProviderType:    "doctor",
+		AppointmentDate: slot.AppointmentDate,
+		StartTime:       slot.StartTime,
+		EndTime:         slot.EndTime,
+		StartsAt:        slot.StartsAt,
+		EndsAt:          slot.EndsAt,
+		Status:          "scheduled",
+	}
+	created, err := s.appointmentService.CreateAppointment(ctx, appointment)
+	if err != nil {
+		return nil, err
+	}
+	booked, err := s.followUpRepo.MarkBooked(ctx, id, created.ID, time.Now().UTC().Truncate(time.Second))
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to mark follow-up %d as booked", id)
+		return nil, fmt.Errorf("failed to update follow-up: %w", err)
+	}
+	if !booked {
+		// Dismissed while the appointment was being made; the appointment stands on its own
+		s.logger.Warnf("Follow-up %d changed before appointment %d could be linked", id, created.ID)
+	}
+
+	s.logger.Infof("Follow-up %d booked as appointment %d", id, created.ID)
+	return created, nil
+}
+
+// Dismiss lets the doctor close a follow-up that is no longer needed
+func (s *FollowUpService) Dismiss(ctx context.Context, id, doctorID int) (*models.FollowUp, error) {
+	followUp, err := s.getFollowUp(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if followUp.DoctorID != doctorID {
+		return nil, ErrNotFollowUpDoctor
+	}
+
+	at := time.Now().UTC().Truncate(time.Second)
+	dismissed, err := s.followUpRepo.Dismiss(ctx, id, at)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to dismiss follow-up ID: %d", id)
+		return nil, fmt.Errorf("failed to dismiss follow-up: %w", err)
+	}
+	if !dismissed {
+		return nil, ErrFollowUpNotPending
+	}
+
+	followUp.Status = models.FollowUpDismissed
+	followUp.UpdatedAt = at
+	return followUp, nil
+}
+
+// SendReminders reminds patients of pending follow-ups that are due within a week or overdue.
+// Each reminder is claimed before it is sent, so overlapping runs never send it twice. It
+// returns the number of reminders sent.
+func (s *FollowUpService) SendReminders(ctx context.Context) (int, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	followUps, err := s.followUpRepo.ListToRemind(ctx, civilDate(now.Add(followUpReminderLead)),
+		now.Add(-followUpReminderInterval), maxFollowUpReminders)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list follow-ups to remind")
+		return 0, fmt.Errorf("failed to list follow-ups to remind: %w", err)
+	}
+
+	sent := 0
+	for _, followUp := range followUps {
+		claimed, err := s.followUpRepo.ClaimReminder(ctx, followUp.ID, followUp.RemindersSent, now)
+		if err != nil {
+			s.logger.WithError(err).Errorf("Failed to claim reminder for follow-up ID: %d", followUp.ID)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		doctorName := "your doctor"
+		if doctor, err := s.userRepo.GetByID(ctx, followUp.DoctorID); err == nil {
+			doctorName = doctor.Name
+		}
+		due := followUp.DueDate.Format("2006-01-02")
+		message := fmt.Sprintf("Reminder: your follow-up visit with %s is due by %s and has not been booked yet.", doctorName, due)
+		if followUp.DueDate.Before(civilDate(now)) {
+			message = fmt.Sprintf("Your follow-up visit with %s was due on %s. Please book it as soon as you can.", doctorName, due)
+		}
+		s.notify(ctx, followUp.PatientID, message)
+		sent++
+	}
+	return sent, nil
+}
+
+func (s *FollowUpService) getFollowUp(ctx context.Context, id int) (*models.FollowUp, error) {
+	followUp, err := s.followUpRepo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get follow-up ID: %d", id)
+		return nil, fmt.Errorf("failed to get follow-up: %w", err)
+	}
+	return followUp, nil
+}
+
+// notify sends an in-app notification; failures are logged so they never undo the follow-up change
+func (s *FollowUpService) notify(ctx context.Context, userID int, message string) {
+	notification := &models.Notification{UserID: userID, NotificationType: "follow_up", Message: message}
+	if err := s.notificationService.CreateNotification(ctx, notification); err != nil {
+		s.logger.WithError(err).Errorf("Failed to notify user %d of follow-up", userID)
+	}
+}
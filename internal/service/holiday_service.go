@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"shifa/internal/models"
+	"shifa/internal/repository"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type HolidayService struct {
+	holidayRepo repository.HolidayRepository
+	logger      *logrus.Logger
+}
+
+func NewHolidayService(holidayRepo repository.HolidayRepository, logger *logrus.Logger) *HolidayService {
+	return &HolidayService{
+		holidayRepo: holidayRepo,
+		logger:      logger,
+	}
+}
+
+// CreateHoliday adds a clinic-wide closed day to the holiday calendar
+func (s *HolidayService) CreateHoliday(ctx context.Context, holiday *models.Holiday) error {
+	if holiday.HolidayDate.IsZero() {
+		return errors.New("holiday date is required")
+	}
+	if holiday.Name == "" {
+		return errors.New("holiday name is required")
+	}
+
+	if err := s.holidayRepo.Create(ctx, holiday); err != nil {
+		s.logger.WithError(err).Error("Failed to create holiday")
+		return fmt.Errorf("failed to create holiday: %w", err)
+	}
+
+	s.logger.Infof("Holiday created successfully: %s on %s", holiday.Name, holiday.HolidayDate.Format("2006-01-02"))
+	return nil
+}
+
+// ListHolidays retrieves holidays falling between from and to, inclusive
+func (s *HolidayService) ListHolidays(ctx context.Context, from, to time.Time) ([]*models.Holiday, error) {
+	holidays, err := s.holidayRepo.List(ctx, from, to)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list holidays")
+		return nil, fmt.Errorf("failed to list holidays: %w", err)
+	}
+	return holidays, nil
+}
+
+// DeleteHoliday removes a holiday from the calendar
+func (s *HolidayService) DeleteHoliday(ctx context.Context, id int) error {
+	if err := s.holidayRepo.Delete(ctx, id); err != nil {
+		s.logger.WithError(err).Errorf("Failed to delete holiday with ID: %d", id)
+		return fmt.Errorf("failed to delete holiday: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,458 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"shifa/internal/models"
+	"shifa/internal/repository"
+	"shifa/pkg/utils"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// averageTravelSpeedKmh is used to estimate driving time between two consecutive visits
+const averageTravelSpeedKmh = 30.0
+
+// HomeCareAvailabilityService manages when and where home care providers take visits
+type HomeCareAvailabilityService struct {
+	availabilityRepo     repository.HomeCareAvailabilityRepository
+	holidayRepo          repository.HolidayRepository
+	visitRepo            repository.HomeCareVisitRepository
+	appointmentRepo      repository.AppointmentRepository
+	homeCareProviderRepo repository.HomeCareProviderRepository
+	timeZones            *TimeZoneService
+	logger               *logrus.Logger
+}
+
+func NewHomeCareAvailabilityService(
+	availabilityRepo repository.HomeCareAvailabilityRepository,
+	holidayRepo repository.HolidayRepository,
+	visitRepo repository.HomeCareVisitRepository,
+	appointmentRepo repository.AppointmentRepository,
+	homeCareProviderRepo repository.HomeCareProviderRepository,
+	timeZones *TimeZoneService,
+	logger *logrus.Logger,
+) *HomeCareAvailabilityService {
+	return &HomeCareAvailabilityService{
+		availabilityRepo:     availabilityRepo,
+		holidayRepo:          holidayRepo,
+		visitRepo:            visitRepo,
+		appointmentRepo:      appointmentRepo,
+		homeCareProviderRepo: homeCareProviderRepo,
+		timeZones:            timeZones,
+		logger:               logger,
+	}
+}
+
+// SetAvailability creates a new weekly availability window
+func (s *HomeCareAvailabilityService) SetAvailability(ctx context.Context, availability *models.HomeCareAvailability) error {
+	if err := s.validateAvailability(availability); err != nil {
+		s.logger.WithError(err).Error("Invalid home care availability data")
+		return err
+	}
+
+	if err := s.availabilityRepo.Create(ctx, availability); err != nil {
+		s.logger.WithError(err).Error("Failed to set home care availability")
+		return fmt.Errorf("failed to set home care availability: %w", err)
+	}
+	return nil
+}
+
+// ListAvailability retrieves the provider's weekly availability windows
+func (s *HomeCareAvailabilityService) ListAvailability(ctx context.Context, providerID int) ([]*models.HomeCareAvailability, error) {
+	availabilities, err := s.availabilityRepo.ListByProviderID(ctx, providerID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list home care availability for provider ID: %d", providerID)
+		return nil, fmt.Errorf("failed to list home care availability: %w", err)
+	}
+	return availabilities, nil
+}
+
+// UpdateAvailability updates a weekly availability window
+func (s *HomeCareAvailabilityService) UpdateAvailability(ctx context.Context, availability *models.HomeCareAvailability) error {
+	if err := s.validateAvailability(availability); err != nil {
+		s.logger.WithError(err).Error("Invalid home care availability data")
+		return err
+	}
+
+	if err := s.availabilityRepo.Update(ctx, availability); err != nil {
+		s.logger.WithError(err).Error("Failed to update home care availability")
+		return fmt.Errorf("failed to update home care availability: %w", err)
+	}
+	return nil
+}
+
+// DeleteAvailability removes one of the provider's weekly availability windows
+func (s *HomeCareAvailabilityService) DeleteAvailability(ctx context.Context, providerID, id int) error {
+	if err := s.availabilityRepo.Delete(ctx, providerID, id); err != nil {
+		s.logger.WithError(err).Errorf("Failed to delete home care availability with ID: %d", id)
+		return fmt.Errorf("failed to delete home care availability: %w", err)
+	}
+	return nil
+}
+
+// AddException records a blocked or extra-hours range for a provider
+func (s *HomeCareAvailabilityService) AddException(ctx context.Context, exception *models.HomeCareAvailabilityException) error {
+	if exception.ProviderID == 0 {
+		return errors.New("provider ID is required")
+	}
+	if exception.ExceptionType != models.ExceptionTypeBlocked && exception.ExceptionType != models.ExceptionTypeExtra {
+		return fmt.Errorf("exception type must be %q or %q", models.ExceptionTypeBlocked, models.ExceptionTypeExtra)
+	}
+	if exception.StartAt.IsZero() || exception.EndAt.IsZero() {
+		return errors.New("start and end are required")
+	}
+	if !exception.StartAt.Before(exception.EndAt) {
+		return errors.New("start must be before end")
+	}
+
+	if err := s.availabilityRepo.CreateException(ctx, exception); err != nil {
+		s.logger.WithError(err).Error("Failed to create home care availability exception")
+		return fmt.Errorf("failed to create availability exception: %w", err)
+	}
+	return nil
+}
+
+// ListExceptions retrieves a provider's exceptions overlapping the [from, to) range
+func (s *HomeCareAvailabilityService) ListExceptions(ctx context.Context, providerID int, from, to time.Time) ([]*models.HomeCareAvailabilityException, error) {
+	exceptions, err := s.availabilityRepo.ListExceptions(ctx, providerID, from, to)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list availability exceptions for provider ID: %d", providerID)
+		return nil, fmt.Errorf("failed to list availability exceptions: %w", err)
+	}
+	return exceptions, nil
+}
+
+// DeleteException removes one of a provider's availability exceptions
+func (s *HomeCareAvailabilityService) DeleteException(ctx context.Context, providerID, exceptionID int) error {
+	exception, err := s.availabilityRepo.GetExceptionByID(ctx, exceptionID)
+	if err != nil {
+		return fmt.Errorf("failed to get availability exception: %w", err)
+	}
+	if exception.ProviderID != providerID {
+		return errors.New("availability exception not found")
+	}
+
+	if err := s.availabilityRepo.DeleteException(ctx, exceptionID); err != nil {
+		s.logger.WithError(err).Errorf("Failed to delete availability exception with ID: %d", exceptionID)
+		return fmt.Errorf("failed to delete availability exception: %w", err)
+	}
+	return nil
+}
+
+// GetServiceArea retrieves the provider's service area; nil means the provider travels anywhere
+func (s *HomeCareAvailabilityService) GetServiceArea(ctx context.Context, providerID int) (*models.HomeCareServiceArea, error) {
+	area, err := s.availabilityRepo.GetServiceArea(ctx, providerID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get service area for provider ID: %d", providerID)
+		return nil, fmt.Errorf("failed to get service area: %w", err)
+	}
+	return area, nil
+}
+
+// SetServiceArea creates or replaces the provider's service area
+func (s *HomeCareAvailabilityService) SetServiceArea(ctx context.Context, area *models.HomeCareServiceArea) error {
+	if area.ProviderID == 0 {
+		return errors.New("provider ID is required")
+	}
+	if area.RadiusKm <= 0 {
+		return errors.New("radius must be positive")
+	}
+	if area.TravelBufferMinutes < 0 {
+		return errors.New("travel buffer cannot be negative")
+	}
+
+	if err := s.availabilityRepo.UpsertServiceArea(ctx, area); err != nil {
+		s.logger.WithError(err).Error("Failed to set service area")
+		return fmt.Errorf("failed to set service area: %w", err)
+	}
+	return nil
+}
+
+// CheckVisit verifies that a visit lies inside the provider's service area and bookable
+// hours and leaves enough travel time around the provider's other visits that day.
+func (s *HomeCareAvailabilityService) CheckVisit(ctx context.Context, visit *models.HomeCareVisit) error {
+	if !visit.ScheduledStart.Valid {
+		return errors.New("scheduled start is required")
+	}
+	if visit.DurationHours <= 0 {
+		return errors.New("duration must be positive")
+	}
+
+	area, err := s.GetServiceArea(ctx, visit.ProviderID)
+	if err != nil {
+		return err
+	}
+	if area != nil {
+		provider, err := s.homeCareProviderRepo.GetByID(ctx, visit.ProviderID)
+		if err != nil {
+			return fmt.Errorf("invalid provider_id: %w", err)
+		}
+		distance := utils.DistanceKm(provider.Latitude, provider.Longitude, visit.Latitude, visit.Longitude)
+		if distance > area.RadiusKm {
+			return fmt.Errorf("visit address is %.1f km away, outside the provider's %.1f km service area", distance, area.RadiusKm)
+		}
+	}
+
+	loc, err := s.timeZones.Location(ctx, visit.ProviderID)
+	if err != nil {
+		return err
+	}
+	requested := timeRange{Start: visit.ScheduledStart.Time, End: visit.ScheduledEnd()}
+	day := dateIn(visit.ScheduledStart.Time.In(loc), loc)
+
+	open, err := s.openWindows(ctx, visit.ProviderID, day)
+	if err != nil {
+		return err
+	}
+	if !withinAny(requested, open) {
+		return ErrSlotUnavailable
+	}
+
+	busy, err := s.busyState(ctx, visit.ProviderID, day)
+	if err != nil {
+		return err
+	}
+	if overlapsAny(requested, busy.appointments) {
+		return errors.New("there is a conflicting appointment at the selected time")
+	}
+	if conflict := travelConflict(*visit, busy.visits, area); conflict != nil {
+		return fmt.Errorf("not enough travel time around visit %d", conflict.ID)
+	}
+	return nil
+}
+
+// CheckAvailability verifies that a provider can take an appointment between the start and end
+// instants. excludeAppointmentID skips one existing appointment, for rescheduling; pass 0 for
+// new bookings.
+func (s *HomeCareAvailabilityService) CheckAvailability(ctx context.Context, providerID int, start, end time.Time, excludeAppointmentID int) error {
+	loc, err := s.timeZones.Location(ctx, providerID)
+	if err != nil {
+		return err
+	}
+	requested := timeRange{Start: start, End: end}
+	date := dateIn(start.In(loc), loc)
+
+	open, err := s.openWindows(ctx, providerID, date)
+	if err != nil {
+		return err
+	}
+	if !withinAny(requested, open) {
+		return ErrSlotUnavailable
+	}
+
+	busy, err := s.busyState(ctx, providerID, date)
+	if err != nil {
+		return err
+	}
+	for _, appointment := range busy.appointmentList {
+		if appointment.ID != excludeAppointmentID && requested.overlaps(appointmentRange(appointment)) {
+			return errors.New("there is a conflicting appointment at the selected time")
+		}
+	}
+	for _, visit := range busy.visits {
+		if requested.overlaps(timeRange{Start: visit.ScheduledStart.Time, End: visit.ScheduledEnd()}) {
+			return fmt.Errorf("the provider has home care visit %d at the selected time", visit.ID)
+		}
+	}
+	return nil
+}
+
+// GetAvailableSlots returns visit slots of the given length on a date in the provider's
+// time zone. When lat/lng are
+// given, travel time from the provider's other visits is taken into account and slots
+// outside the service area are not offered.
+func (s *HomeCareAvailabilityService) GetAvailableSlots(ctx context.Context, providerID int, date time.Time, slotLength time.Duration, lat, lng *float64) ([]models.TimeSlot, error) {
+	if slotLength <= 0 {
+		return nil, errors.New("slot length must be positive")
+	}
+
+	area, err := s.GetServiceArea(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+	if area != nil && lat != nil && lng != nil {
+		provider, err := s.homeCareProviderRepo.GetByID(ctx, providerID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid provider_id: %w", err)
+		}
+		if utils.DistanceKm(provider.Latitude, provider.Longitude, *lat, *lng) > area.RadiusKm {
+			return []models.TimeSlot{}, nil
+		}
+	}
+
+	loc, err := s.timeZones.Location(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+	day := dateIn(date, loc)
+
+	open, err := s.openWindows(ctx, providerID, day)
+	if err != nil {
+		return nil, err
+	}
+	busy, err := s.busyState(ctx, providerID, day)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := []models.TimeSlot{}
+	for _, slot := range splitIntoSlots(open, slotLength) {
+		if overlapsAny(slot, busy.appointments) {
+			continue
+		}
+
+		candidate := models.HomeCareVisit{
+			ProviderID:     providerID,
+			ScheduledStart: models.NullTime{Time: slot.Start, Valid: true},
+			DurationHours:  slotLength.Hours(),
+		}
+		if lat != nil && lng != nil {
+			candidate.Latitude, candidate.Longitude = *lat, *lng
+		} else {
+			// Without a destination only the fixed buffer can be applied
+			candidate.Latitude, candidate.Longitude = math.NaN(), math.NaN()
+		}
+		if travelConflict(candidate, busy.visits, area) != nil {
+			continue
+		}
+
+		slots = append(slots, models.TimeSlot{
+			Date:      civilDate(day),
+			StartTime: models.CustomTime(slot.Start),
+			EndTime:   models.CustomTime(slot.End),
+			StartsAt:  slot.Start,
+			EndsAt:    slot.End,
+		})
+	}
+	return slots, nil
+}
+
+// providerBusyState holds everything already booked for a provider on one day
+type providerBusyState struct {
+	appointmentList []*models.Appointment
+	appointments    []timeRange
+	visits          []models.HomeCareVisit
+}
+
+func (s *HomeCareAvailabilityService) busyState(ctx context.Context, providerID int, date time.Time) (*providerBusyState, error) {
+	day := dayRange(date)
+	state := &providerBusyState{}
+
+	appointments, err := s.appointmentRepo.GetByProviderAndDateRange(ctx, providerID, "home_care_provider", date, date)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load appointments for provider ID: %d", providerID)
+		return nil, fmt.Errorf("failed to load appointments: %w", err)
+	}
+	for _, appointment := range appointments {
+		if !holdsSlot(appointment) {
+			continue
+		}
+		state.appointmentList = append(state.appointmentList, appointment)
+		state.appointments = append(state.appointments, appointmentRange(appointment))
+	}
+
+	visits, err := s.visitRepo.GetByProviderAndTimeRange(ctx, providerID, day.Start, day.End)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load home care visits for provider ID: %d", providerID)
+		return nil, fmt.Errorf("failed to load home care visits: %w", err)
+	}
+	for _, visit := range visits {
+		if visit.Status == "cancelled" || !visit.ScheduledStart.Valid {
+			continue
+		}
+		state.visits = append(state.visits, visit)
+	}
+	return state, nil
+}
+
+// openWindows computes the provider's bookable ranges on a date given as midnight in the provider's zone
+func (s *HomeCareAvailabilityService) openWindows(ctx context.Context, providerID int, date time.Time) ([]timeRange, error) {
+	day := dayRange(date)
+
+	holidays, err := s.holidayRepo.List(ctx, day.Start, day.Start)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load holidays")
+		return nil, fmt.Errorf("failed to load holidays: %w", err)
+	}
+
+	availabilities, err := s.availabilityRepo.ListByProviderID(ctx, providerID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load weekly availability for provider ID: %d", providerID)
+		return nil, fmt.Errorf("failed to load home care availability: %w", err)
+	}
+	weekly := make([]weeklyHours, 0, len(availabilities))
+	for _, availability := range availabilities {
+		weekly = append(weekly, weeklyHours{DayOfWeek: availability.DayOfWeek, Start: availability.StartTime, End: availability.EndTime})
+	}
+
+	exceptions, err := s.availabilityRepo.ListExceptions(ctx, providerID, day.Start, day.End)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load availability exceptions for provider ID: %d", providerID)
+		return nil, fmt.Errorf("failed to load availability exceptions: %w", err)
+	}
+	overrides := make([]dateException, 0, len(exceptions))
+	for _, exception := range exceptions {
+		overrides = append(overrides, dateException{Type: exception.ExceptionType, Start: exception.StartAt, End: exception.EndAt})
+	}
+
+	return openWindowsOn(date, len(holidays) > 0, weekly, overrides), nil
+}
+
+func (s *HomeCareAvailabilityService) validateAvailability(availability *models.HomeCareAvailability) error {
+	if availability.ProviderID == 0 {
+		return errors.New("provider ID is required")
+	}
+	if availability.StartTime.IsZero() || availability.EndTime.IsZero() {
+		return errors.New("start time and end time are required")
+	}
+	if !availability.StartTime.Before(availability.EndTime) {
+		return errors.New("start time must be before end time")
+	}
+	if availability.DayOfWeek < 0 || availability.DayOfWeek > 6 {
+		return errors.New("day of week must be between 0 and 6")
+	}
+	return nil
+}
+
+// travelConflict returns the first existing visit that leaves too little travel time
+// before or after the candidate, or nil when the candidate fits
+func travelConflict(candidate models.HomeCareVisit, visits []models.HomeCareVisit, area *models.HomeCareServiceArea) *models.HomeCareVisit {
+	start := candidate.ScheduledStart.Time
+	end := candidate.ScheduledEnd()
+
+	for i := range visits {
+		other := visits[i]
+		if other.ID == candidate.ID && candidate.ID != 0 {
+			continue
+		}
+		gap := travelBuffer(candidate, other, area)
+		otherStart := other.ScheduledStart.Time
+		otherEnd := other.ScheduledEnd()
+		if start.Before(otherEnd.Add(gap)) && otherStart.Before(end.Add(gap)) {
+			return &other
+		}
+	}
+	return nil
+}
+
+// travelBuffer is the minimum gap between two visits: the provider's configured buffer or
+// the estimated driving time between the two addresses, whichever is longer
+func travelBuffer(a, b models.HomeCareVisit, area *models.HomeCareServiceArea) time.Duration {
+	var buffer time.Duration
+	if area != nil {
+		buffer = time.Duration(area.TravelBufferMinutes) * time.Minute
+	}
+	distance := utils.DistanceKm(a.Latitude, a.Longitude, b.Latitude, b.Longitude)
+	if math.IsNaN(distance) {
+		return buffer
+	}
+	travel := time.Duration(distance / averageTravelSpeedKmh * float64(time.Hour)).Round(time.Minute)
+	if travel > buffer {
+		return travel
+	}
+	return buffer
+}
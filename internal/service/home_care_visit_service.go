@@ -11,14 +11,16 @@ import (
 )
 
 type HomeCareVisitService struct {
-    Repo   repository.HomeCareVisitRepository
-    Logger *logrus.Logger // Using Logrus directly
+    Repo         repository.HomeCareVisitRepository
+    Availability *HomeCareAvailabilityService
+    Logger       *logrus.Logger // Using Logrus directly
 }
 
-func NewHomeCareVisitService(repo repository.HomeCareVisitRepository, logger *logrus.Logger) *HomeCareVisitService {
+func NewHomeCareVisitService(repo repository.HomeCareVisitRepository, availability *HomeCareAvailabilityService, logger *logrus.Logger) *HomeCareVisitService {
     return &HomeCareVisitService{
-        Repo:   repo,
-        Logger: logger,
+        Repo:         repo,
+        Availability: availability,
+        Logger:       logger,
     }
 }
 
@@ -34,6 +36,12 @@ func (s *HomeCareVisitService) ScheduleHomeCareVisit(ctx context.Context, visit
         visit.Status = "scheduled"
     }
 
+    // Enforce service area, availability and travel buffers
+    if err := s.Availability.CheckVisit(ctx, visit); err != nil {
+        s.Logger.Warn("Home care visit rejected", logrus.Fields{"error": err, "providerID": visit.ProviderID})
+        return err
+    }
+
     err := s.Repo.Create(ctx, visit)
     if err != nil {
         s.Logger.Error("Failed to schedule home care visit", logrus.Fields{"error": err})
@@ -61,6 +69,14 @@ func (s *HomeCareVisitService) UpdateHomeCareVisit(ctx context.Context, visit *m
         return errors.New("invalid visit ID")
     }
 
+    // Rescheduled visits must still fit the provider's schedule
+    if visit.Status == "scheduled" {
+        if err := s.Availability.CheckVisit(ctx, visit); err != nil {
+            s.Logger.Warn("Home care visit update rejected", logrus.Fields{"error": err, "visitID": visit.ID})
+            return err
+        }
+    }
+
     err := s.Repo.Update(ctx, visit)
     if err != nil {
         s.Logger.Error("Failed to update home care visit", logrus.Fields{"error": err, "visitID": visit.ID})
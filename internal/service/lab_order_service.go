@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrNotOrderingDoctor     = errors.New("only the consultation's doctor can order tests")
+	ErrLabOrderAccessDenied  = errors.New("only the order's patient and ordering doctor can view it")
+	ErrLabOrderCancelled     = errors.New("the lab order has been cancelled")
+	ErrLabOrderNotCancelable = errors.New("only an order without results can be cancelled")
+	// ErrLabOrderNotVerified hides whether the number or the access code was wrong
+	ErrLabOrderNotVerified = errors.New("no lab order matches this number and access code")
+)
+
+// LabOrderService orders lab tests and imaging during a consultation and takes their results
+// back, from the performing lab with the requisition's access code or from the ordering doctor
+type LabOrderService struct {
+	labOrderRepo        repository.LabOrderRepository
+	consultationRepo    repository.ConsultationRepository
+	userRepo            repository.UserRepository
+	notificationService NotificationService
+	logger              *logrus.Logger
+}
+
+func NewLabOrderService(
+	labOrderRepo repository.LabOrderRepository,
+	consultationRepo repository.ConsultationRepository,
+	userRepo repository.UserRepository,
+	notificationService NotificationService,
+	logger *logrus.Logger,
+) *LabOrderService {
+	return &LabOrderService{
+		labOrderRepo:        labOrderRepo,
+		consultationRepo:    consultationRepo,
+		userRepo:            userRepo,
+		notificationService: notificationService,
+		logger:              logger,
+	}
+}
+
+// Order writes a lab or imaging order for a consultation that is in progress or completed
+func (s *LabOrderService) Order(ctx context.Context, consultationID, doctorID int, order *models.LabOrder) error {
+	consultation, err := s.consultationRepo.GetByID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get consultation ID: %d", consultationID)
+		return fmt.Errorf("failed to get consultation: %w", err)
+	}
+	if consultation.DoctorID != doctorID {
+		return ErrNotOrderingDoctor
+	}
+	if consultation.Status != "in_progress" && consultation.Status != "completed" {
+		return errors.New("tests can only be ordered once the consultation has started")
+	}
+
+	if order.Kind == "" {
+		order.Kind = models.LabOrderLab
+	}
+	if order.Priority == "" {
+		order.Priority = models.LabPriorityRoutine
+	}
+	if err := validateLabOrder(order); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+
+	orderedAt := time.Now().UTC().Truncate(time.Second)
+	number, err := randomPrintedCode(5)
+	if err != nil {
+		return fmt.Errorf("failed to generate order number: %w", err)
+	}
+	code, err := randomPrintedCode(5)
+	if err != nil {
+		return fmt.Errorf("failed to generate access code: %w", err)
+	}
+
+	order.Number = "LAB-" + orderedAt.Format("20060102") + "-" + number
+	order.AccessCode = code
+	order.ConsultationID = consultationID
+	order.DoctorID = doctorID
+	order.PatientID = consultation.PatientID
+	order.Status = models.LabOrderOrdered
+	order.OrderedAt = orderedAt
+	order.Results = nil
+	order.Reports = nil
+	order.AbnormalCount = 0
+	order.ResultedAt = models.NullTime{}
+	order.CancelledAt = models.NullTime{}
+	order.CancelReason = ""
+	if err := s.labOrderRepo.Create(ctx, order); err != nil {
+		s.logger.WithError(err).Errorf("Failed to create lab order for consultation ID: %d", consultationID)
+		return fmt.Errorf("failed to create lab order: %w", err)
+	}
+
+	s.logger.Infof("Lab order %s placed for consultation ID: %d", order.Number, consultationID)
+	return nil
+}
+
+// GetOrder returns an order to its patient or ordering doctor
+func (s *LabOrderService) GetOrder(ctx context.Context, id, userID int) (*models.LabOrder, error) {
+	order, err := s.getOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if userID != order.PatientID && userID != order.DoctorID {
+		return nil, ErrLabOrderAccessDenied
+	}
+	return order, nil
+}
+
+// ListByConsultation returns a consultation's orders to its patient or doctor
+func (s *LabOrderService) ListByConsultation(ctx context.Context, consultationID, userID int) ([]*models.LabOrder, error) {
+	consultation, err := s.consultationRepo.GetByID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to get consultation: %w", err)
+	}
+	if userID != consultation.PatientID && userID != consultation.DoctorID {
+		return nil, ErrLabOrderAccessDenied
+	}
+
+	orders, err := s.labOrderRepo.ListByConsultation(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list lab orders for consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to list lab orders: %w", err)
+	}
+	return orders, nil
+}
+
+// ListForPatient returns the patient's orders: all of them to the patient, and to a doctor
+// the ones they placed
+func (s *LabOrderService) ListForPatient(ctx context.Context, patientID, userID int) ([]*models.LabOrder, error) {
+	orders, err := s.labOrderRepo.ListByPatient(ctx, patientID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list lab orders of patient ID: %d", patientID)
+		return nil, fmt.Errorf("failed to list lab orders: %w", err)
+	}
+	if userID == patientID {
+		return orders, nil
+	}
+
+	visible := []*models.LabOrder{}
+	for _, order := range orders {
+		if order.DoctorID == userID {
+			visible = append(visible, order)
+		}
+	}
+	return visible, nil
+}
+
+// Cancel cancels an order that has no results yet; only its ordering doctor can cancel it
+func (s *LabOrderService) Cancel(ctx context.Context, id, doctorID int, reason string) (*models.LabOrder, error) {
+	order, err := s.getOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.DoctorID != doctorID {
+		return nil, ErrNotOrderingDoctor
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a reason is required to cancel a lab order")
+	}
+
+	at := time.Now().UTC().Truncate(time.Second)
+	cancelled, err := s.labOrderRepo.Cancel(ctx, id, reason, at)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to cancel lab order ID: %d", id)
+		return nil, fmt.Errorf("failed to cancel lab order: %w", err)
+	}
+	if !cancelled {
+		return nil, ErrLabOrderNotCancelable
+	}
+
+	order.Status = models.LabOrderCancelled
+	order.CancelledAt = models.NullTime{Time: at, Valid: true}
+	order.CancelReason = reason
+	s.logger.Infof("Lab order %s cancelled", order.Number)
+	return order, nil
+}
+
+// RecordResults lets the ordering doctor enter results received outside the platform. report
+// is the uploaded result document, already stored, or nil.
+func (s *LabOrderService) RecordResults(ctx context.Context, id, doctorID int, results []models.LabResult, report *models.LabReport) (*models.LabOrder, error) {
+	order, err := s.getOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.DoctorID != doctorID {
+		return nil, ErrNotOrderingDoctor
+	}
+	doctor, err := s.userRepo.GetByID(ctx, doctorID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get user ID: %d", doctorID)
+		return nil, fmt.Errorf("failed to get doctor: %w", err)
+	}
+	return s.addResults(ctx, order, results, report, doctor.Name)
+}
+
+// IngestResults takes results from the performing lab, which proves it holds the requisition
+// with the order number and access code printed on it
+func (s *LabOrderService) IngestResults(ctx context.Context, number, code, lab string, results []models.LabResult, report *models.LabReport) (*models.LabOrder, error) {
+	lab = strings.TrimSpace(lab)
+	if lab == "" {
+		return nil, errors.New("the reporting lab is required")
+	}
+	order, err := s.labOrderRepo.GetByNumber(ctx, strings.ToUpper(strings.TrimSpace(number)))
+	if err != nil {
+		return nil, ErrLabOrderNotVerified
+	}
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if subtle.ConstantTimeCompare([]byte(code), []byte(order.AccessCode)) != 1 {
+		return nil, ErrLabOrderNotVerified
+	}
+
+	order, err = s.addResults(ctx, order, results, report, lab)
+	if err != nil {
+		return nil, err
+	}
+	// The lab sees what it submitted, not the codes to resubmit as someone else
+	order.AccessCode = ""
+	return order, nil
+}
+
+// ReportFile returns an uploaded result document's record to the order's patient or doctor
+func (s *LabOrderService) ReportFile(ctx context.Context, id, reportID, userID int) (*models.LabReport, error) {
+	if _, err := s.GetOrder(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	report, err := s.labOrderRepo.GetReport(ctx, id, reportID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get lab report ID: %d", reportID)
+		return nil, fmt.Errorf("failed to get lab report: %w", err)
+	}
+	return report, nil
+}
+
+// addResults flags and stores a batch of results and tells the doctor and patient they arrived
+func (s *LabOrderService) addResults(ctx context.Context, order *models.LabOrder, results []models.LabResult, report *models.LabReport, uploadedBy string) (*models.LabOrder, error) {
+	if order.Status == models.LabOrderCancelled {
+		return nil, ErrLabOrderCancelled
+	}
+	if len(results) == 0 && report == nil {
+		return nil, errors.New("results or a report are required")
+	}
+	if err := validateLabResults(order, results); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	at := time.Now().UTC().Truncate(time.Second)
+	abnormal := 0
+	for i := range results {
+		results[i].Analyte = strings.TrimSpace(results[i].Analyte)
+		results[i].Unit = strings.TrimSpace(results[i].Unit)
+		results[i].Flag = FlagLabResult(results[i])
+		results[i].ResultedAt = at
+		if results[i].Abnormal() {
+			abnormal++
+		}
+	}
+	if report != nil {
+		report.UploadedBy = uploadedBy
+		report.UploadedAt = at
+	}
+
+	stored, err := s.labOrderRepo.AddResults(ctx, order.ID, results, report, at)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to store results for lab order ID: %d", order.ID)
+		return nil, fmt.Errorf("failed to store lab results: %w", err)
+	}
+	if !stored {
+		return nil, ErrLabOrderCancelled
+	}
+
+	message := fmt.Sprintf("Results are available for %s order %s", order.Kind, order.Number)
+	if abnormal > 0 {
+		message += fmt.Sprintf(", %d outside the reference range", abnormal)
+	}
+	s.notify(ctx, order.DoctorID, message)
+	s.notify(ctx, order.PatientID, message)
+
+	s.logger.Infof("%d results for lab order %s received from %s", len(results), order.Number, uploadedBy)
+	return s.getOrder(ctx, order.ID)
+}
+
+func (s *LabOrderService) getOrder(ctx context.Context, id int) (*models.LabOrder, error) {
+	order, err := s.labOrderRepo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get lab order ID: %d", id)
+		return nil, fmt.Errorf("failed to get lab order: %w", err)
+	}
+	return order, nil
+}
+
+// notify sends an in-app notification; failures are logged so they never undo the results
+func (s *LabOrderService) notify(ctx context.Context, userID int, message string) {
+	notification := &models.Notification{UserID: userID, NotificationType: "lab_results", Message: message}
+	if err := s.notificationService.CreateNotification(ctx, notification); err != nil {
+		s.logger.WithError(err).Errorf("Failed to notify user %d of lab results", userID)
+	}
+}
+
+// FlagLabResult compares a result with its reference range. Either bound may be missing; a
+// result with neither has no flag.
+func FlagLabResult(result models.LabResult) string {
+	switch {
+	case result.ReferenceLow != nil && result.Value < *result.ReferenceLow:
+		return models.ResultFlagLow
+	case result.ReferenceHigh != nil && result.Value > *result.ReferenceHigh:
+		return models.ResultFlagHigh
+	case result.ReferenceLow != nil || result.ReferenceHigh != nil:
+		return models.ResultFlagNormal
+	}
+	return ""
+}
+
+func validateLabOrder(order *models.LabOrder) error {
+	switch order.Kind {
+	case models.LabOrderLab, models.LabOrderImaging:
+	default:
+		return fmt.Errorf("kind must be %q or %q", models.LabOrderLab, models.LabOrderImaging)
+	}
+	switch order.Priority {
+	case models.LabPriorityRoutine, models.LabPriorityUrgent, models.LabPriorityStat:
+	default:
+		return fmt.Errorf("unknown priority %q", order.Priority)
+	}
+	order.ClinicalIndication = strings.TrimSpace(order.ClinicalIndication)
+	if order.ClinicalIndication == "" {
+		return errors.New("a clinical indication is required")
+	}
+	if len(order.Tests) == 0 {
+		return errors.New("at least one test is required")
+	}
+	for i := range order.Tests {
+		order.Tests[i].Name = strings.TrimSpace(order.Tests[i].Name)
+		if order.Tests[i].Name == "" {
+			return fmt.Errorf("test %d: a name is required", i+1)
+		}
+	}
+	return nil
+}
+
+func validateLabResults(order *models.LabOrder, results []models.LabResult) error {
+	tests := map[int]bool{}
+	for _, test := range order.Tests {
+		tests[test.ID] = true
+	}
+	for i, result := range results {
+		if strings.TrimSpace(result.Analyte) == "" {
+			return fmt.Errorf("result %d: an analyte is required", i+1)
+		}
+		if result.TestID != nil && !tests[*result.TestID] {
+			return fmt.Errorf("result %d: test %d is not on this order", i+1, *result.TestID)
+		}
+		if result.ReferenceLow != nil && result.ReferenceHigh != nil && *result.ReferenceLow > *result.ReferenceHigh {
+			return fmt.Errorf("result %d: the reference range is inverted", i+1)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"shifa/internal/models"
+)
+
+func TestFlagLabResult(t *testing.T) {
+	bound := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name  string
+		value float64
+		low   *float64
+		high  *float64
+		want  string
+	}{
+		{"no range", 5, nil, nil, ""},
+		{"within range", 5, bound(3.5), bound(5.5), models.ResultFlagNormal},
+		{"at the low bound", 3.5, bound(3.5), bound(5.5), models.ResultFlagNormal},
+		{"at the high bound", 5.5, bound(3.5), bound(5.5), models.ResultFlagNormal},
+		{"below range", 3.4, bound(3.5), bound(5.5), models.ResultFlagLow},
+		{"above range", 5.6, bound(3.5), bound(5.5), models.ResultFlagHigh},
+		{"only a low bound, above it", 100, bound(40), nil, models.ResultFlagNormal},
+		{"only a low bound, below it", 39, bound(40), nil, models.ResultFlagLow},
+		{"only a high bound, below it", 150, nil, bound(200), models.ResultFlagNormal},
+		{"only a high bound, above it", 240, nil, bound(200), models.ResultFlagHigh},
+		{"zero bound is a bound", -1, bound(0), nil, models.ResultFlagLow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := models.LabResult{Value: tt.value, ReferenceLow: tt.low, ReferenceHigh: tt.high}
+			if got := FlagLabResult(result); got != tt.want {
+				t.Errorf("FlagLabResult = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"shifa/internal/models"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// LabResultsURL is where the performing lab uploads the order's results. ingestBaseURL is the
+// public ingestion endpoint, e.g. https://host/api/lab-orders/results.
+func LabResultsURL(ingestBaseURL string, order *models.LabOrder) string {
+	return ingestBaseURL + "/" + url.PathEscape(order.Number)
+}
+
+// RenderRequisition renders a printable A4 requisition for the order's patient or doctor, to be
+// taken to the lab. It carries the access code the lab needs to upload results.
+func (s *LabOrderService) RenderRequisition(ctx context.Context, id, userID int, ingestBaseURL string) ([]byte, *models.LabOrder, error) {
+	order, err := s.GetOrder(ctx, id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	doctor, err := s.userRepo.GetByID(ctx, order.DoctorID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get user ID: %d", order.DoctorID)
+		return nil, nil, fmt.Errorf("failed to get ordering doctor: %w", err)
+	}
+	patient, err := s.userRepo.GetByID(ctx, order.PatientID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get user ID: %d", order.PatientID)
+		return nil, nil, fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	data, err := renderLabRequisitionPDF(order, doctor.Name, patient.Name, LabResultsURL(ingestBaseURL, order))
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to render requisition for lab order ID: %d", id)
+		return nil, nil, fmt.Errorf("failed to render requisition: %w", err)
+	}
+	return data, order, nil
+}
+
+func renderLabRequisitionPDF(order *models.LabOrder, doctorName, patientName, resultsURL string) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	title := "Laboratory requisition"
+	if order.Kind == models.LabOrderImaging {
+		title = "Imaging requisition"
+	}
+	pdf.SetTitle(title+" "+order.Number, true)
+	pdf.SetMargins(15, 15, 15)
+	pdf.AddPage()
+	pageWidth, _ := pdf.GetPageSize()
+	contentWidth := pageWidth - 30
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 9, title, "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, "No. "+order.Number, "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Access code: "+order.AccessCode, "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Ordered: "+order.OrderedAt.Format("2006-01-02 15:04 MST"), "", 1, "L", false, 0, "")
+	if order.Priority != models.LabPriorityRoutine {
+		pdf.SetTextColor(200, 0, 0)
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(0, 7, "Priority: "+strings.ToUpper(order.Priority), "", 1, "L", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+	if order.Status == models.LabOrderCancelled {
+		pdf.SetTextColor(200, 0, 0)
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(0, 7, "CANCELLED - "+tr(order.CancelReason), "", 1, "L", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(contentWidth/2, 6, "Ordering doctor", "", 0, "L", false, 0, "")
+	pdf.CellFormat(contentWidth/2, 6, "Patient", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(contentWidth/2, 6, tr(doctorName), "", 0, "L", false, 0, "")
+	pdf.CellFormat(contentWidth/2, 6, tr(patientName), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(0, 7, "Clinical indication", "B", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.MultiCell(0, 5, tr(order.ClinicalIndication), "", "L", false)
+	pdf.Ln(3)
+
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(0, 7, "Requested", "B", 1, "L", false, 0, "")
+	pdf.Ln(2)
+	for i, test := range order.Tests {
+		name := tr(test.Name)
+		if test.Code != "" {
+			name += " [" + tr(test.Code) + "]"
+		}
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.MultiCell(0, 5, fmt.Sprintf("%d. %s", i+1, name), "", "L", false)
+		pdf.SetFont("Helvetica", "", 10)
+		if test.Specimen != "" {
+			pdf.MultiCell(0, 5, "Specimen: "+tr(test.Specimen), "", "L", false)
+		}
+		if test.Instructions != "" {
+			pdf.MultiCell(0, 5, tr(test.Instructions), "", "L", false)
+		}
+		pdf.Ln(2)
+	}
+
+	if order.Notes != "" {
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(0, 7, "Notes", "B", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(0, 5, tr(order.Notes), "", "L", false)
+		pdf.Ln(3)
+	}
+
+	pdf.SetFont("Helvetica", "I", 8)
+	pdf.MultiCell(0, 4, "Performing lab: upload results to "+resultsURL+
+		" with the access code above. Results are sent to the ordering doctor and the patient.", "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
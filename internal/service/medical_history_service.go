@@ -6,20 +6,24 @@ import (
     "fmt"
     "shifa/internal/models"
     "shifa/internal/repository"
+    "time"
     "github.com/sirupsen/logrus"
 )
 
 type MedicalHistoryService struct {
     medicalHistoryRepo repository.MedicalHistoryRepository
+    codes              *DiagnosisCodeService
     logger             *logrus.Logger
 }
 
 func NewMedicalHistoryService(
     medicalHistoryRepo repository.MedicalHistoryRepository, 
+    codes *DiagnosisCodeService,
     logger *logrus.Logger,
 ) *MedicalHistoryService {
     return &MedicalHistoryService{
         medicalHistoryRepo: medicalHistoryRepo,
+        codes:              codes,
         logger:             logger,
     }
 }
@@ -28,6 +32,9 @@ func (s *MedicalHistoryService) CreateMedicalHistory(
     ctx context.Context, 
     history *models.MedicalHistory,
 ) (*models.MedicalHistory, error) {
+    if err := s.resolveCondition(ctx, history); err != nil {
+        return nil, err
+    }
     if err := s.validateMedicalHistory(*history); err != nil {
         s.logger.WithError(err).Error("Invalid medical history data")
         return nil, err
@@ -64,6 +71,9 @@ func (s *MedicalHistoryService) UpdateMedicalHistory(
     ctx context.Context, 
     history *models.MedicalHistory,
 ) (*models.MedicalHistory, error) {
+    if err := s.resolveCondition(ctx, history); err != nil {
+        return nil, err
+    }
     if err := s.validateMedicalHistory(*history); err != nil {
         s.logger.WithError(err).Error("Invalid medical history data")
         return nil, err
@@ -88,6 +98,63 @@ func (s *MedicalHistoryService) DeleteMedicalHistory(
     return nil
 }
 
+// RecordConsultationDiagnoses adds a consultation's coded diagnoses to the patient's history.
+// Conditions the patient already has as current entries are skipped; it returns how many
+// entries were added.
+func (s *MedicalHistoryService) RecordConsultationDiagnoses(
+    ctx context.Context,
+    patientID, consultationID int,
+    diagnosedAt time.Time,
+    treatment string,
+    diagnoses []models.CodedDiagnosis,
+) (int, error) {
+    added := 0
+    for _, diagnosis := range diagnoses {
+        if diagnosis.System != models.DiagnosisCodeSystemICD10 || diagnosis.Code == "" {
+            continue
+        }
+        history := &models.MedicalHistory{
+            PatientID:      patientID,
+            ConditionName:  diagnosis.Description,
+            ConditionCode:  diagnosis.Code,
+            DiagnosisDate:  diagnosedAt,
+            Treatment:      treatment,
+            IsCurrent:      true,
+            ConsultationID: &consultationID,
+        }
+        if history.ConditionName == "" {
+            history.ConditionName = diagnosis.Code
+        }
+
+        inserted, err := s.medicalHistoryRepo.CreateIfAbsent(ctx, history)
+        if err != nil {
+            s.logger.WithError(err).Errorf("Failed to record diagnosis %s for patient ID: %d", diagnosis.Code, patientID)
+            return added, fmt.Errorf("failed to record diagnosis: %w", err)
+        }
+        if inserted {
+            added++
+        }
+    }
+    return added, nil
+}
+
+// resolveCondition checks a coded entry against the catalogue and names the condition after
+// the code when no name was given
+func (s *MedicalHistoryService) resolveCondition(ctx context.Context, history *models.MedicalHistory) error {
+    if history.ConditionCode == "" {
+        return nil
+    }
+    entry, err := s.codes.Get(ctx, history.ConditionCode)
+    if err != nil {
+        return err
+    }
+    history.ConditionCode = entry.Code
+    if history.ConditionName == "" {
+        history.ConditionName = entry.Description
+    }
+    return nil
+}
+
 func (s *MedicalHistoryService) validateMedicalHistory(history models.MedicalHistory) error {
     if history.PatientID == 0 {
         return errors.New("patient ID is required")
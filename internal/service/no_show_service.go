@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ErrPrepaymentRequired = errors.New("prepayment is required to book after repeated no-shows")
+
+// defaultNoShowPolicy applies until an administrator saves a policy
+var defaultNoShowPolicy = models.NoShowPolicy{
+	GracePeriodMinutes:  15,
+	WarningThreshold:    1,
+	PrepaymentThreshold: 3,
+}
+
+type NoShowService struct {
+	noShowRepo          repository.NoShowRepository
+	notificationService NotificationService
+	logger              *logrus.Logger
+}
+
+func NewNoShowService(noShowRepo repository.NoShowRepository, notificationService NotificationService, logger *logrus.Logger) *NoShowService {
+	return &NoShowService{
+		noShowRepo:          noShowRepo,
+		notificationService: notificationService,
+		logger:              logger,
+	}
+}
+
+// GetPolicy returns the saved no-show policy, falling back to the defaults
+func (s *NoShowService) GetPolicy(ctx context.Context) (*models.NoShowPolicy, error) {
+	policy, err := s.noShowRepo.GetPolicy(ctx)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get no-show policy")
+		return nil, fmt.Errorf("failed to get no-show policy: %w", err)
+	}
+	if policy == nil {
+		defaults := defaultNoShowPolicy
+		return &defaults, nil
+	}
+	return policy, nil
+}
+
+func (s *NoShowService) UpdatePolicy(ctx context.Context, policy *models.NoShowPolicy) error {
+	if policy.GracePeriodMinutes <= 0 {
+		return errors.New("grace period must be positive")
+	}
+	if policy.WarningThreshold < 0 || policy.PrepaymentThreshold < 0 {
+		return errors.New("thresholds must not be negative")
+	}
+
+	if err := s.noShowRepo.SavePolicy(ctx, policy); err != nil {
+		s.logger.WithError(err).Error("Failed to save no-show policy")
+		return fmt.Errorf("failed to save no-show policy: %w", err)
+	}
+	return nil
+}
+
+// DetectNoShows flags every scheduled appointment that passed its grace period without a
+// consultation or visit starting, then applies the policy consequences to each patient.
+// It returns the number of appointments flagged.
+func (s *NoShowService) DetectNoShows(ctx context.Context) (int, error) {
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	grace := time.Duration(policy.GracePeriodMinutes) * time.Minute
+	candidates, err := s.noShowRepo.ListCandidates(ctx, time.Now(), grace)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list no-show candidates")
+		return 0, fmt.Errorf("failed to list no-show candidates: %w", err)
+	}
+
+	flagged := 0
+	for _, appointment := range candidates {
+		record := &models.NoShowRecord{
+			AppointmentID:   appointment.ID,
+			PatientID:       appointment.PatientID,
+			ProviderID:      appointmentProviderID(appointment),
+			ProviderType:    appointment.ProviderType,
+			AppointmentDate: appointment.AppointmentDate,
+		}
+		if err := s.noShowRepo.Record(ctx, record); err != nil {
+			s.logger.WithError(err).Errorf("Failed to record no-show for appointment ID: %d", appointment.ID)
+			continue
+		}
+		flagged++
+
+		if err := s.applyPolicy(ctx, policy, appointment); err != nil {
+			s.logger.WithError(err).Errorf("Failed to apply no-show policy for patient ID: %d", appointment.PatientID)
+		}
+	}
+
+	if flagged > 0 {
+		s.logger.Infof("Flagged %d appointments as no-shows", flagged)
+	}
+	return flagged, nil
+}
+
+// applyPolicy warns the patient once they reach the warning threshold and requires
+// prepayment once they reach the prepayment threshold
+func (s *NoShowService) applyPolicy(ctx context.Context, policy *models.NoShowPolicy, appointment *models.Appointment) error {
+	stats, err := s.noShowRepo.PatientStats(ctx, appointment.PatientID)
+	if err != nil {
+		return fmt.Errorf("failed to count no-shows: %w", err)
+	}
+
+	if policy.PrepaymentThreshold > 0 && stats.NoShows >= policy.PrepaymentThreshold {
+		required, err := s.noShowRepo.IsPrepaymentRequired(ctx, appointment.PatientID)
+		if err != nil {
+			return err
+		}
+		if required {
+			return nil
+		}
+		if err := s.noShowRepo.SetPrepaymentRequired(ctx, appointment.PatientID, true); err != nil {
+			return err
+		}
+		return s.notificationService.CreateNotification(ctx, &models.Notification{
+			UserID:           appointment.PatientID,
+			NotificationType: "no_show_warning",
+			Message: fmt.Sprintf("You missed your appointment on %s. After %d missed appointments, future bookings require prepayment.",
+				appointment.AppointmentDate.Format("2006-01-02"), stats.NoShows),
+		})
+	}
+
+	if policy.WarningThreshold > 0 && stats.NoShows >= policy.WarningThreshold {
+		return s.notificationService.CreateNotification(ctx, &models.Notification{
+			UserID:           appointment.PatientID,
+			NotificationType: "no_show_warning",
+			Message: fmt.Sprintf("You missed your appointment on %s. Please cancel in advance if you cannot attend.",
+				appointment.AppointmentDate.Format("2006-01-02")),
+		})
+	}
+	return nil
+}
+
+func (s *NoShowService) ListPatientNoShows(ctx context.Context, patientID int) ([]*models.NoShowRecord, error) {
+	records, err := s.noShowRepo.ListByPatient(ctx, patientID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list no-shows for patient ID: %d", patientID)
+		return nil, fmt.Errorf("failed to list no-shows: %w", err)
+	}
+	return records, nil
+}
+
+// GetPatientStats returns the patient's no-show rate and whether prepayment is required
+func (s *NoShowService) GetPatientStats(ctx context.Context, patientID int) (*models.NoShowStats, error) {
+	stats, err := s.noShowRepo.PatientStats(ctx, patientID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get no-show stats for patient ID: %d", patientID)
+		return nil, fmt.Errorf("failed to get no-show stats: %w", err)
+	}
+	stats.Rate = noShowRate(stats)
+
+	stats.PrepaymentRequired, err = s.noShowRepo.IsPrepaymentRequired(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prepayment status: %w", err)
+	}
+	return stats, nil
+}
+
+func (s *NoShowService) GetProviderStats(ctx context.Context, providerID int, providerType string) (*models.NoShowStats, error) {
+	stats, err := s.noShowRepo.ProviderStats(ctx, providerID, providerType)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get no-show stats for %s ID: %d", providerType, providerID)
+		return nil, fmt.Errorf("failed to get no-show stats: %w", err)
+	}
+	stats.Rate = noShowRate(stats)
+	return stats, nil
+}
+
+// CheckBookingAllowed rejects bookings from patients who must prepay after repeated no-shows
+func (s *NoShowService) CheckBookingAllowed(ctx context.Context, patientID int) error {
+	required, err := s.noShowRepo.IsPrepaymentRequired(ctx, patientID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get prepayment status for patient ID: %d", patientID)
+		return fmt.Errorf("failed to get prepayment status: %w", err)
+	}
+	if required {
+		return ErrPrepaymentRequired
+	}
+	return nil
+}
+
+// ClearPrepaymentRequirement lifts the restriction, e.g. once a prepayment has been collected
+func (s *NoShowService) ClearPrepaymentRequirement(ctx context.Context, patientID int) error {
+	if err := s.noShowRepo.SetPrepaymentRequired(ctx, patientID, false); err != nil {
+		s.logger.WithError(err).Errorf("Failed to clear prepayment requirement for patient ID: %d", patientID)
+		return fmt.Errorf("failed to clear prepayment requirement: %w", err)
+	}
+	return nil
+}
+
+func noShowRate(stats *models.NoShowStats) float64 {
+	if stats.Appointments == 0 {
+		return 0
+	}
+	return float64(stats.NoShows) / float64(stats.Appointments)
+}
@@ -4,6 +4,7 @@ package service
 import (
     "context"
     "errors"
+    "fmt"
     "time"
     
     "github.com/sirupsen/logrus" // Import logrus
@@ -21,13 +22,17 @@ type NotificationService interface {
 
 type notificationService struct {
     notificationRepo repository.NotificationRepository
+    appointmentRepo  repository.AppointmentRepository
+    timeZones        *TimeZoneService
     logger           *logrus.Logger // Use *logrus.Logger
 }
 
 // Constructor for NotificationService
-func NewNotificationService(notificationRepo repository.NotificationRepository, logger *logrus.Logger) NotificationService {
+func NewNotificationService(notificationRepo repository.NotificationRepository, appointmentRepo repository.AppointmentRepository, timeZones *TimeZoneService, logger *logrus.Logger) NotificationService {
     return &notificationService{
         notificationRepo: notificationRepo,
+        appointmentRepo:  appointmentRepo,
+        timeZones:        timeZones,
         logger:           logger,
     }
 }
@@ -89,13 +94,34 @@ func (s *notificationService) GetUnreadNotificationCount(ctx context.Context, us
     return count, nil
 }
 
-// SendAppointmentReminder sends an appointment reminder notification
+// SendAppointmentReminder sends an immediate reminder to the appointment's patient and provider
 func (s *notificationService) SendAppointmentReminder(ctx context.Context, appointmentID int) error {
-    // Logic to fetch appointment details and create a reminder notification
-    notification := &models.Notification{ // Use models.Notification
-        UserID:           0, // Set the actual user ID
-        NotificationType: "appointment_reminder",
-        Message:          "You have an upcoming appointment.",
+    appointment, err := s.appointmentRepo.GetByID(ctx, appointmentID)
+    if err != nil {
+        s.logger.WithFields(logrus.Fields{
+            "error":         err,
+            "appointmentID": appointmentID,
+        }).Error("Failed to load appointment for reminder")
+        return fmt.Errorf("failed to load appointment: %w", err)
+    }
+
+    recipients := []int{appointment.PatientID}
+    if appointment.DoctorID != nil {
+        recipients = append(recipients, *appointment.DoctorID)
+    } else if appointment.HomeCareProviderID != nil {
+        recipients = append(recipients, *appointment.HomeCareProviderID)
     }
-    return s.CreateNotification(ctx, notification)
-}
\ No newline at end of file
+
+    for _, userID := range recipients {
+        notification := &models.Notification{
+            UserID:           userID,
+            NotificationType: "appointment_reminder",
+            Message: fmt.Sprintf("Reminder: you have an appointment on %s.",
+                s.timeZones.LocalTime(ctx, userID, appointmentRange(appointment).Start).Format(notificationTimeLayout)),
+        }
+        if err := s.CreateNotification(ctx, notification); err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"shifa/internal/models"
+
+	"github.com/jung-kurt/gofpdf"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const prescriptionQRSize = 35 // mm
+
+// PrescriptionVerificationURL is the link encoded in the prescription's QR code. verifyBaseURL
+// is the public verification endpoint, e.g. https://host/api/prescriptions/verify.
+func PrescriptionVerificationURL(verifyBaseURL string, p *models.Prescription) string {
+	return verifyBaseURL + "/" + url.PathEscape(p.Number) + "?code=" + url.QueryEscape(p.VerificationCode)
+}
+
+// RenderPDF renders a printable A4 prescription for its patient or prescriber, with a QR code
+// linking to the public verification endpoint
+func (s *PrescriptionService) RenderPDF(ctx context.Context, id, userID int, verifyBaseURL string) ([]byte, *models.Prescription, error) {
+	p, err := s.GetPrescription(ctx, id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	qr, err := qrcode.Encode(PrescriptionVerificationURL(verifyBaseURL, p), qrcode.Medium, 512)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode verification QR code: %w", err)
+	}
+	data, err := renderPrescriptionPDF(p, qr)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to render prescription ID: %d", id)
+		return nil, nil, fmt.Errorf("failed to render prescription: %w", err)
+	}
+	return data, p, nil
+}
+
+func renderPrescriptionPDF(p *models.Prescription, qr []byte) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle("Prescription "+p.Number, true)
+	pdf.SetMargins(15, 15, 15)
+	pdf.AddPage()
+	pageWidth, _ := pdf.GetPageSize()
+	contentWidth := pageWidth - 30
+
+	pdf.RegisterImageOptionsReader("qr", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qr))
+	pdf.ImageOptions("qr", pageWidth-15-prescriptionQRSize, 15, prescriptionQRSize, prescriptionQRSize,
+		false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(0, 9, "Prescription", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, "No. "+p.Number, "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Verification code: "+p.VerificationCode, "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Issued: "+p.IssuedAt.Format("2006-01-02 15:04 MST"), "", 1, "L", false, 0, "")
+	if p.Status == models.PrescriptionVoid {
+		pdf.SetTextColor(200, 0, 0)
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(0, 7, "VOID - "+tr(p.VoidReason), "", 1, "L", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+
+	pdf.SetY(15 + prescriptionQRSize + 5)
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(contentWidth/2, 6, "Prescriber", "", 0, "L", false, 0, "")
+	pdf.CellFormat(contentWidth/2, 6, "Patient", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(contentWidth/2, 6, tr(p.PrescriberName), "", 0, "L", false, 0, "")
+	pdf.CellFormat(contentWidth/2, 6, tr(p.PatientName), "", 1, "L", false, 0, "")
+	pdf.CellFormat(contentWidth/2, 6, "License: "+tr(p.PrescriberLicense), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(0, 7, "Medications", "B", 1, "L", false, 0, "")
+	pdf.Ln(2)
+	for i, item := range p.Items {
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.MultiCell(0, 5, fmt.Sprintf("%d. %s %s (%s)", i+1, tr(item.DrugName), tr(item.Strength), tr(item.Form)), "", "L", false)
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(0, 5, fmt.Sprintf("%s, %s for %d days", tr(item.Dose), tr(item.Frequency), item.DurationDays), "", "L", false)
+		pdf.MultiCell(0, 5, "Quantity: "+strconv.Itoa(item.Quantity)+"    Refills: "+strconv.Itoa(item.Refills), "", "L", false)
+		if item.Instructions != "" {
+			pdf.MultiCell(0, 5, tr(item.Instructions), "", "L", false)
+		}
+		pdf.Ln(3)
+	}
+
+	if p.Notes != "" {
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(0, 7, "Notes", "B", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.MultiCell(0, 5, tr(p.Notes), "", "L", false)
+		pdf.Ln(3)
+	}
+
+	pdf.SetFont("Helvetica", "I", 8)
+	pdf.MultiCell(0, 4, "Scan the QR code, or enter the prescription number and verification code, "+
+		"to verify this prescription before dispensing.", "", "L", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
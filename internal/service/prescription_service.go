@@ -0,0 +1,411 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxPrescriptionRefills caps the refills of one line; longer therapy needs a new prescription
+const maxPrescriptionRefills = 5
+
+var (
+	ErrNotPrescriber            = errors.New("only the consultation's doctor can prescribe")
+	ErrPrescriptionAccessDenied = errors.New("only the prescription's patient and prescriber can view it")
+	ErrPrescriptionNotActive    = errors.New("the prescription has no fills left or has been voided")
+	ErrUnresolvedWarnings       = errors.New("the prescription has safety warnings that must be overridden with a reason")
+	// ErrPrescriptionNotVerified hides whether the number or the code was wrong
+	ErrPrescriptionNotVerified = errors.New("no prescription matches this number and verification code")
+)
+
+var printedCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// PrescriptionService issues e-prescriptions and tracks them until they are dispensed or voided
+type PrescriptionService struct {
+	prescriptionRepo repository.PrescriptionRepository
+	consultationRepo repository.ConsultationRepository
+	doctorRepo       repository.DoctorRepository
+	userRepo         repository.UserRepository
+	safety           *DrugSafetyService
+	logger           *logrus.Logger
+}
+
+func NewPrescriptionService(
+	prescriptionRepo repository.PrescriptionRepository,
+	consultationRepo repository.ConsultationRepository,
+	doctorRepo repository.DoctorRepository,
+	userRepo repository.UserRepository,
+	safety *DrugSafetyService,
+	logger *logrus.Logger,
+) *PrescriptionService {
+	return &PrescriptionService{
+		prescriptionRepo: prescriptionRepo,
+		consultationRepo: consultationRepo,
+		doctorRepo:       doctorRepo,
+		userRepo:         userRepo,
+		safety:           safety,
+		logger:           logger,
+	}
+}
+
+// Check runs the safety checks on draft items without issuing anything
+func (s *PrescriptionService) Check(ctx context.Context, consultationID, doctorID int, items []models.PrescriptionItem) ([]models.PrescriptionWarning, error) {
+	consultation, err := s.consultationForPrescriber(ctx, consultationID, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePrescriptionItems(items); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+	return s.checkSafety(ctx, consultation.PatientID, items)
+}
+
+// Issue writes a prescription for a consultation that is in progress or completed. The
+// prescriber's license and both names are taken from their profiles at this moment.
+// Warnings of moderate or higher severity need an override with a reason in p.Overrides;
+// without one Issue returns ErrUnresolvedWarnings and leaves the findings in p.Warnings.
+func (s *PrescriptionService) Issue(ctx context.Context, consultationID, doctorID int, p *models.Prescription) error {
+	consultation, err := s.consultationForPrescriber(ctx, consultationID, doctorID)
+	if err != nil {
+		return err
+	}
+	if err := validatePrescriptionItems(p.Items); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+
+	issuedAt := time.Now().UTC().Truncate(time.Second)
+	warnings, err := s.checkSafety(ctx, consultation.PatientID, p.Items)
+	if err != nil {
+		return err
+	}
+	overrides, resolved := resolveWarnings(warnings, p.Overrides, doctorID, issuedAt)
+	p.Warnings = warnings
+	if !resolved {
+		return ErrUnresolvedWarnings
+	}
+	p.Overrides = overrides
+
+	doctor, err := s.doctorRepo.GetByID(ctx, doctorID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get doctor ID: %d", doctorID)
+		return fmt.Errorf("failed to get doctor: %w", err)
+	}
+	if strings.TrimSpace(doctor.LicenseNumber) == "" {
+		return errors.New("a license number is required on the doctor's profile to prescribe")
+	}
+	prescriber, err := s.userRepo.GetByID(ctx, doctorID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get prescriber user ID: %d", doctorID)
+		return fmt.Errorf("failed to get prescriber: %w", err)
+	}
+	patient, err := s.userRepo.GetByID(ctx, consultation.PatientID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get patient user ID: %d", consultation.PatientID)
+		return fmt.Errorf("failed to get patient: %w", err)
+	}
+
+	number, err := randomPrintedCode(5)
+	if err != nil {
+		return fmt.Errorf("failed to generate prescription number: %w", err)
+	}
+	code, err := randomPrintedCode(5)
+	if err != nil {
+		return fmt.Errorf("failed to generate verification code: %w", err)
+	}
+
+	p.Number = "RX-" + issuedAt.Format("20060102") + "-" + number
+	p.VerificationCode = code
+	p.ConsultationID = consultationID
+	p.DoctorID = doctorID
+	p.PatientID = consultation.PatientID
+	p.PrescriberName = prescriber.Name
+	p.PrescriberLicense = doctor.LicenseNumber
+	p.PatientName = patient.Name
+	p.Status = models.PrescriptionActive
+	p.IssuedAt = issuedAt
+	p.DispensedAt = models.NullTime{}
+	p.DispensedBy = ""
+	p.FillsDispensed = 0
+	p.Fills = nil
+	p.VoidedAt = models.NullTime{}
+	p.VoidReason = ""
+	if err := s.prescriptionRepo.Create(ctx, p); err != nil {
+		s.logger.WithError(err).Errorf("Failed to create prescription for consultation ID: %d", consultationID)
+		return fmt.Errorf("failed to create prescription: %w", err)
+	}
+
+	s.logger.Infof("Prescription %s issued for consultation ID: %d", p.Number, consultationID)
+	return nil
+}
+
+// GetPrescription returns a prescription to its patient or prescriber
+func (s *PrescriptionService) GetPrescription(ctx context.Context, id, userID int) (*models.Prescription, error) {
+	p, err := s.getPrescription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if userID != p.PatientID && userID != p.DoctorID {
+		return nil, ErrPrescriptionAccessDenied
+	}
+	return p, nil
+}
+
+// ListByConsultation returns a consultation's prescriptions to its patient or doctor
+func (s *PrescriptionService) ListByConsultation(ctx context.Context, consultationID, userID int) ([]*models.Prescription, error) {
+	consultation, err := s.consultationRepo.GetByID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to get consultation: %w", err)
+	}
+	if userID != consultation.PatientID && userID != consultation.DoctorID {
+		return nil, ErrPrescriptionAccessDenied
+	}
+
+	prescriptions, err := s.prescriptionRepo.ListByConsultation(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list prescriptions for consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to list prescriptions: %w", err)
+	}
+	return prescriptions, nil
+}
+
+// Void cancels an active prescription; only its prescriber can void it
+func (s *PrescriptionService) Void(ctx context.Context, id, doctorID int, reason string) (*models.Prescription, error) {
+	p, err := s.getPrescription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.DoctorID != doctorID {
+		return nil, ErrNotPrescriber
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a reason is required to void a prescription")
+	}
+
+	at := time.Now().UTC().Truncate(time.Second)
+	voided, err := s.prescriptionRepo.Void(ctx, id, reason, at)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to void prescription ID: %d", id)
+		return nil, fmt.Errorf("failed to void prescription: %w", err)
+	}
+	if !voided {
+		return nil, ErrPrescriptionNotActive
+	}
+
+	p.Status = models.PrescriptionVoid
+	p.VoidedAt = models.NullTime{Time: at, Valid: true}
+	p.VoidReason = reason
+	s.logger.Infof("Prescription %s voided", p.Number)
+	return p, nil
+}
+
+// Verify lets a pharmacy check a printed prescription against the record
+func (s *PrescriptionService) Verify(ctx context.Context, number, code string) (*models.PrescriptionVerification, error) {
+	p, err := s.verifiedPrescription(ctx, number, code)
+	if err != nil {
+		return nil, err
+	}
+	return prescriptionVerification(p), nil
+}
+
+// Dispense records that the named pharmacy has filled an active prescription. The prescription
+// stays active for refills until every line's refills are used.
+func (s *PrescriptionService) Dispense(ctx context.Context, number, code, pharmacy string) (*models.PrescriptionVerification, error) {
+	pharmacy = strings.TrimSpace(pharmacy)
+	if pharmacy == "" {
+		return nil, errors.New("the dispensing pharmacy is required")
+	}
+	p, err := s.verifiedPrescription(ctx, number, code)
+	if err != nil {
+		return nil, err
+	}
+
+	at := time.Now().UTC().Truncate(time.Second)
+	allowed := prescriptionFillsAllowed(p.Items)
+	fill, err := s.prescriptionRepo.Dispense(ctx, p.ID, allowed, pharmacy, at)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to dispense prescription ID: %d", p.ID)
+		return nil, fmt.Errorf("failed to dispense prescription: %w", err)
+	}
+	if fill == nil {
+		return nil, ErrPrescriptionNotActive
+	}
+
+	if fill.FillNumber >= allowed {
+		p.Status = models.PrescriptionDispensed
+	}
+	p.DispensedAt = models.NullTime{Time: at, Valid: true}
+	p.DispensedBy = pharmacy
+	p.FillsDispensed = fill.FillNumber
+	p.Fills = append(p.Fills, *fill)
+	s.logger.Infof("Prescription %s fill %d of %d dispensed by %s", p.Number, fill.FillNumber, allowed, pharmacy)
+	return prescriptionVerification(p), nil
+}
+
+// prescriptionFillsAllowed is the original fill plus the refills of the line with the most
+func prescriptionFillsAllowed(items []models.PrescriptionItem) int {
+	refills := 0
+	for _, item := range items {
+		if item.Refills > refills {
+			refills = item.Refills
+		}
+	}
+	return 1 + refills
+}
+
+func (s *PrescriptionService) consultationForPrescriber(ctx context.Context, consultationID, doctorID int) (*models.Consultation, error) {
+	consultation, err := s.consultationRepo.GetByID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to get consultation: %w", err)
+	}
+	if consultation.DoctorID != doctorID {
+		return nil, ErrNotPrescriber
+	}
+	if consultation.Status != "in_progress" && consultation.Status != "completed" {
+		return nil, errors.New("prescriptions can only be written once the consultation has started")
+	}
+	return consultation, nil
+}
+
+func (s *PrescriptionService) checkSafety(ctx context.Context, patientID int, items []models.PrescriptionItem) ([]models.PrescriptionWarning, error) {
+	warnings, err := s.safety.Check(ctx, patientID, items)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to check prescription safety for patient ID: %d", patientID)
+		return nil, fmt.Errorf("failed to check prescription safety: %w", err)
+	}
+	return warnings, nil
+}
+
+// resolveWarnings attaches the doctor's overrides to the warnings they name, reporting false
+// when a warning that needs an override has none. Overrides of unknown keys are dropped.
+func resolveWarnings(warnings []models.PrescriptionWarning, requested []models.WarningOverride, doctorID int, at time.Time) ([]models.WarningOverride, bool) {
+	reasons := map[string]string{}
+	for _, o := range requested {
+		if reason := strings.TrimSpace(o.Reason); reason != "" {
+			reasons[o.WarningKey] = reason
+		}
+	}
+
+	// Sized up front so the warnings can point into the slice that is stored
+	overrides := make([]models.WarningOverride, 0, len(warnings))
+	resolved := true
+	for i := range warnings {
+		w := &warnings[i]
+		reason, ok := reasons[w.Key]
+		if !ok {
+			if RequiresOverride(*w) {
+				resolved = false
+			}
+			continue
+		}
+		overrides = append(overrides, models.WarningOverride{
+			WarningKey:   w.Key,
+			WarningType:  w.Type,
+			Severity:     w.Severity,
+			Description:  w.Description,
+			Reason:       reason,
+			DoctorID:     doctorID,
+			OverriddenAt: at,
+		})
+		w.Override = &overrides[len(overrides)-1]
+	}
+	return overrides, resolved
+}
+
+func (s *PrescriptionService) getPrescription(ctx context.Context, id int) (*models.Prescription, error) {
+	p, err := s.prescriptionRepo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get prescription ID: %d", id)
+		return nil, fmt.Errorf("failed to get prescription: %w", err)
+	}
+	return p, nil
+}
+
+// verifiedPrescription looks a prescription up by number and checks the printed code
+func (s *PrescriptionService) verifiedPrescription(ctx context.Context, number, code string) (*models.Prescription, error) {
+	p, err := s.prescriptionRepo.GetByNumber(ctx, strings.ToUpper(strings.TrimSpace(number)))
+	if err != nil {
+		return nil, ErrPrescriptionNotVerified
+	}
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if subtle.ConstantTimeCompare([]byte(code), []byte(p.VerificationCode)) != 1 {
+		return nil, ErrPrescriptionNotVerified
+	}
+	return p, nil
+}
+
+func prescriptionVerification(p *models.Prescription) *models.PrescriptionVerification {
+	return &models.PrescriptionVerification{
+		Number:            p.Number,
+		Status:            p.Status,
+		PrescriberName:    p.PrescriberName,
+		PrescriberLicense: p.PrescriberLicense,
+		PatientInitials:   initials(p.PatientName),
+		Items:             p.Items,
+		IssuedAt:          p.IssuedAt,
+		DispensedAt:       p.DispensedAt,
+		DispensedBy:       p.DispensedBy,
+		FillsDispensed:    p.FillsDispensed,
+		FillsAllowed:      prescriptionFillsAllowed(p.Items),
+		VoidedAt:          p.VoidedAt,
+	}
+}
+
+func initials(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Fields(name) {
+		r := []rune(part)[0]
+		b.WriteRune(unicode.ToUpper(r))
+		b.WriteRune('.')
+	}
+	return b.String()
+}
+
+// randomPrintedCode returns n random bytes as upper-case base32, easy to read off paper
+func randomPrintedCode(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return printedCodeEncoding.EncodeToString(b), nil
+}
+
+func validatePrescriptionItems(items []models.PrescriptionItem) error {
+	if len(items) == 0 {
+		return errors.New("at least one medication is required")
+	}
+	for i := range items {
+		item := &items[i]
+		for _, field := range []*string{&item.DrugName, &item.Strength, &item.Form, &item.Dose, &item.Frequency, &item.Instructions} {
+			*field = strings.TrimSpace(*field)
+		}
+		switch {
+		case item.DrugName == "":
+			return fmt.Errorf("medication %d: drug name is required", i+1)
+		case item.Strength == "" || item.Form == "":
+			return fmt.Errorf("medication %d: strength and form are required", i+1)
+		case item.Dose == "" || item.Frequency == "":
+			return fmt.Errorf("medication %d: dose and frequency are required", i+1)
+		case item.DurationDays <= 0:
+			return fmt.Errorf("medication %d: duration must be at least one day", i+1)
+		case item.Quantity <= 0:
+			return fmt.Errorf("medication %d: quantity must be positive", i+1)
+		case item.Refills < 0 || item.Refills > maxPrescriptionRefills:
+			return fmt.Errorf("medication %d: refills must be between 0 and %d", i+1, maxPrescriptionRefills)
+		}
+	}
+	return nil
+}
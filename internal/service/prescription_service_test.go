@@ -0,0 +1,102 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"shifa/internal/models"
+)
+
+func TestResolveWarnings(t *testing.T) {
+	minor := models.PrescriptionWarning{Key: "interaction:a:b", Type: models.WarningInteraction, Severity: models.SeverityMinor}
+	moderate := models.PrescriptionWarning{Key: "duplicate_therapy:a:c", Type: models.WarningDuplicateTherapy, Severity: models.SeverityModerate}
+	major := models.PrescriptionWarning{Key: "allergy:a:penicillin", Type: models.WarningAllergy, Severity: models.SeverityMajor, Description: "class allergy"}
+	override := func(key, reason string) models.WarningOverride {
+		return models.WarningOverride{WarningKey: key, Reason: reason}
+	}
+
+	tests := []struct {
+		name         string
+		warnings     []models.PrescriptionWarning
+		requested    []models.WarningOverride
+		wantResolved bool
+		wantKeys     []string // keys of the stored overrides, in warning order
+	}{
+		{"no warnings", nil, nil, true, nil},
+		{"minor warnings need no override", []models.PrescriptionWarning{minor}, nil, true, nil},
+		{"moderate warning without override", []models.PrescriptionWarning{moderate}, nil, false, nil},
+		{
+			"blank reason does not count",
+			[]models.PrescriptionWarning{moderate},
+			[]models.WarningOverride{override(moderate.Key, "   ")},
+			false, nil,
+		},
+		{
+			"overridden with a reason",
+			[]models.PrescriptionWarning{moderate},
+			[]models.WarningOverride{override(moderate.Key, "monitored closely")},
+			true, []string{moderate.Key},
+		},
+		{
+			"minor warning may be overridden too",
+			[]models.PrescriptionWarning{minor},
+			[]models.WarningOverride{override(minor.Key, "noted")},
+			true, []string{minor.Key},
+		},
+		{
+			"unknown keys are dropped",
+			[]models.PrescriptionWarning{moderate},
+			[]models.WarningOverride{override("interaction:x:y", "stale"), override(moderate.Key, "ok")},
+			true, []string{moderate.Key},
+		},
+		{
+			"one of two blocking warnings left",
+			[]models.PrescriptionWarning{major, moderate},
+			[]models.WarningOverride{override(moderate.Key, "ok")},
+			false, []string{moderate.Key},
+		},
+		{
+			"all blocking warnings overridden",
+			[]models.PrescriptionWarning{major, minor, moderate},
+			[]models.WarningOverride{override(moderate.Key, "ok"), override(major.Key, "tolerated before")},
+			true, []string{major.Key, moderate.Key},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+			warnings := append([]models.PrescriptionWarning(nil), tt.warnings...)
+
+			overrides, resolved := resolveWarnings(warnings, tt.requested, 7, at)
+			if resolved != tt.wantResolved {
+				t.Errorf("resolved = %v, want %v", resolved, tt.wantResolved)
+			}
+			if len(overrides) != len(tt.wantKeys) {
+				t.Fatalf("got %d overrides, want %d", len(overrides), len(tt.wantKeys))
+			}
+			for i, o := range overrides {
+				if o.WarningKey != tt.wantKeys[i] || o.DoctorID != 7 || !o.OverriddenAt.Equal(at) || o.Reason == "" {
+					t.Errorf("override %d = %+v", i, o)
+				}
+			}
+
+			// Each overridden warning points at its stored override and carries its details
+			for _, w := range warnings {
+				if w.Override == nil {
+					continue
+				}
+				if w.Override.WarningKey != w.Key || w.Override.Severity != w.Severity ||
+					w.Override.WarningType != w.Type || w.Override.Description != w.Description {
+					t.Errorf("warning %s has override %+v", w.Key, *w.Override)
+				}
+				found := false
+				for i := range overrides {
+					found = found || w.Override == &overrides[i]
+				}
+				if !found {
+					t.Errorf("warning %s points outside the stored overrides", w.Key)
+				}
+			}
+		})
+	}
+}
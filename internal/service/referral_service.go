@@ -0,0 +1,466 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrNotReferrer           = errors.New("only the consultation's doctor can refer the patient")
+	ErrReferralAccessDenied  = errors.New("only the patient, the referring doctor and the receivers can view this referral")
+	ErrNotReferralReceiver   = errors.New("this referral is not addressed to you")
+	ErrReferralNotPending    = errors.New("the referral has already been answered or withdrawn")
+	ErrReferralNotBookable   = errors.New("only an accepted referral can be booked")
+	ErrReferralNotCancelable = errors.New("the referral has already been declined, booked or cancelled")
+)
+
+// ReferralService refers patients from a consultation to other doctors and to home care
+// providers, and tracks each referral from the receiver's answer to the booked appointment
+type ReferralService struct {
+	referralRepo        repository.ReferralRepository
+	consultationRepo    repository.ConsultationRepository
+	detailsRepo         repository.ConsultationDetailsRepository
+	doctorRepo          repository.DoctorRepository
+	providerRepo        repository.HomeCareProviderRepository
+	appointmentService  *AppointmentService
+	notificationService NotificationService
+	logger              *logrus.Logger
+}
+
+func NewReferralService(
+	referralRepo repository.ReferralRepository,
+	consultationRepo repository.ConsultationRepository,
+	detailsRepo repository.ConsultationDetailsRepository,
+	doctorRepo repository.DoctorRepository,
+	providerRepo repository.HomeCareProviderRepository,
+	appointmentService *AppointmentService,
+	notificationService NotificationService,
+	logger *logrus.Logger,
+) *ReferralService {
+	return &ReferralService{
+		referralRepo:        referralRepo,
+		consultationRepo:    consultationRepo,
+		detailsRepo:         detailsRepo,
+		doctorRepo:          doctorRepo,
+		providerRepo:        providerRepo,
+		appointmentService:  appointmentService,
+		notificationService: notificationService,
+		logger:              logger,
+	}
+}
+
+// Create refers the consultation's patient. A referral to one receiver takes its specialty or
+// service type from their profile; a pool referral names the specialty or service type. Without
+// a clinical summary, one is built from the consultation details.
+func (s *ReferralService) Create(ctx context.Context, consultationID, doctorID int, referral *models.Referral) error {
+	consultation, err := s.consultationRepo.GetByID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get consultation ID: %d", consultationID)
+		return fmt.Errorf("failed to get consultation: %w", err)
+	}
+	if consultation.DoctorID != doctorID {
+		return ErrNotReferrer
+	}
+	if consultation.Status != "in_progress" && consultation.Status != "completed" {
+		return errors.New("referrals can only be made once the consultation has started")
+	}
+
+	referral.Reason = strings.TrimSpace(referral.Reason)
+	referral.TargetSpecialty = strings.TrimSpace(referral.TargetSpecialty)
+	referral.ClinicalSummary = strings.TrimSpace(referral.ClinicalSummary)
+	if referral.Urgency == "" {
+		referral.Urgency = models.ReferralRoutine
+	}
+	if err := s.resolveTarget(ctx, referral, doctorID); err != nil {
+		return err
+	}
+	if referral.ClinicalSummary == "" {
+		referral.ClinicalSummary = s.consultationSummary(ctx, consultationID)
+	}
+	if err := validateReferral(referral); err != nil {
+		return fmt.Errorf("validation error: %w", err)
+	}
+
+	referral.ConsultationID = consultationID
+	referral.PatientID = consultation.PatientID
+	referral.ReferringDoctorID = doctorID
+	referral.Status = models.ReferralPending
+	referral.ResponderID = nil
+	referral.ResponseNote = ""
+	referral.RespondedAt = models.NullTime{}
+	referral.AppointmentID = nil
+	if err := s.referralRepo.Create(ctx, referral); err != nil {
+		s.logger.WithError(err).Errorf("Failed to create referral for consultation ID: %d", consultationID)
+		return fmt.Errorf("failed to create referral: %w", err)
+	}
+
+	if referral.RecipientID != nil {
+		s.notify(ctx, *referral.RecipientID, "referral_received",
+			fmt.Sprintf("You have a new %s referral: %s", referral.Urgency, referral.Reason))
+	}
+	s.logger.Infof("Referral %d created for consultation ID: %d", referral.ID, consultationID)
+	return nil
+}
+
+// GetReferral returns a referral to its patient, its referring doctor or a receiver who can answer it
+func (s *ReferralService) GetReferral(ctx context.Context, id, userID int) (*models.Referral, error) {
+	referral, err := s.getReferral(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if userID == referral.PatientID || userID == referral.ReferringDoctorID {
+		return referral, nil
+	}
+	if referral.ResponderID != nil && *referral.ResponderID == userID {
+		return referral, nil
+	}
+	if referral.Status == models.ReferralPending && s.canReceive(ctx, referral, userID) {
+		return referral, nil
+	}
+	return nil, ErrReferralAccessDenied
+}
+
+// ListSent returns the referrals a doctor wrote with their current status
+func (s *ReferralService) ListSent(ctx context.Context, doctorID int, status string) ([]*models.Referral, error) {
+	referrals, err := s.referralRepo.ListSent(ctx, doctorID, status)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list referrals sent by doctor ID: %d", doctorID)
+		return nil, fmt.Errorf("failed to list referrals: %w", err)
+	}
+	return referrals, nil
+}
+
+// ListForPatient returns a patient's referrals
+func (s *ReferralService) ListForPatient(ctx context.Context, patientID int) ([]*models.Referral, error) {
+	referrals, err := s.referralRepo.ListByPatient(ctx, patientID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list referrals of patient ID: %d", patientID)
+		return nil, fmt.Errorf("failed to list referrals: %w", err)
+	}
+	return referrals, nil
+}
+
+// ListInbox returns the referrals a doctor or home care provider has received, including the
+// pending referrals sent to their specialty or service type
+func (s *ReferralService) ListInbox(ctx context.Context, userID int, role models.Role, status string) ([]*models.Referral, error) {
+	inbox := models.ReferralInbox{UserID: userID, Status: status}
+	switch role {
+	case models.RoleDoctor:
+		doctor, err := s.doctorRepo.GetByID(ctx, userID)
+		if err != nil {
+			s.logger.WithError(err).Errorf("Failed to get doctor ID: %d", userID)
+			return nil, fmt.Errorf("failed to get doctor: %w", err)
+		}
+		inbox.TargetType = models.ReferralToDoctor
+		inbox.Specialty = doctor.Specialty
+	case models.RoleHomeCareProvider:
+		provider, err := s.providerRepo.GetByID(ctx, userID)
+		if err != nil {
+			s.logger.WithError(err).Errorf("Failed to get home care provider ID: %d", userID)
+			return nil, fmt.Errorf("failed to get home care provider: %w", err)
+		}
+		inbox.TargetType = models.ReferralToHomeCare
+		inbox.ServiceTypeID = provider.ServiceTypeID
+	default:
+		return nil, errors.New("only doctors and home care providers receive referrals")
+	}
+
+	referrals, err := s.referralRepo.ListInbox(ctx, inbox)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list referral inbox of user ID: %d", userID)
+		return nil, fmt.Errorf("failed to list referrals: %w", err)
+	}
+	return referrals, nil
+}
+
+// Accept takes on a pending referral; the patient can then book with the receiver
+func (s *ReferralService) Accept(ctx context.Context, id, userID int, note string) (*models.Referral, error) {
+	referral, err := s.respond(ctx, id, userID, models.ReferralAccepted, strings.TrimSpace(note))
+	if err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf("Your referral for %q was accepted", referral.Reason)
+	s.notify(ctx, referral.ReferringDoctorID, "referral_accepted", message)
+	s.notify(ctx, referral.PatientID, "referral_accepted", message+"; you can now book an appointment")
+	return referral, nil
+}
+
+// Decline turns down a pending referral; the reason goes back to the referring doctor
+func (s *ReferralService) Decline(ctx context.Context, id, userID int, reason string) (*models.Referral, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a reason is required to decline a referral")
+	}
+	referral, err := s.respond(ctx, id, userID, models.ReferralDeclined, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, referral.ReferringDoctorID, "referral_declined",
+		fmt.Sprintf("Your referral for %q was declined: %s", referral.Reason, reason))
+	return referral, nil
+}
+
+// Cancel lets the referring doctor withdraw a referral that has not been booked yet
+func (s *ReferralService) Cancel(ctx context.Context, id, doctorID int) (*models.Referral, error) {
+	referral, err := s.getReferral(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if referral.ReferringDoctorID != doctorID {
+		return nil, ErrNotReferrer
+	}
+
+	at := time.Now().UTC().Truncate(time.Second)
+	cancelled, err := s.referralRepo.Cancel(ctx, id, at)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to cancel referral ID: %d", id)
+		return nil, fmt.Errorf("failed to cancel referral: %w", err)
+	}
+	if !cancelled {
+		return nil, ErrReferralNotCancelable
+	}
+
+	if referral.ResponderID != nil {
+		s.notify(ctx, *referral.ResponderID, "referral_cancelled",
+			fmt.Sprintf("The referral for %q was withdrawn by the referring doctor", referral.Reason))
+	}
+	referral.Status = models.ReferralCancelled
+	referral.UpdatedAt = at
+	return referral, nil
+}
+
+// Book books the patient of an accepted referral with the receiver who accepted it. The slot
+// comes from the appointment's times; provider, patient and type come from the referral.
+func (s *ReferralService) Book(ctx context.Context, id, userID int, slot *models.Appointment) (*models.Appointment, error) {
+	referral, err := s.getReferral(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if userID != referral.PatientID && userID != referral.ReferringDoctorID {
+		return nil, ErrReferralAccessDenied
+	}
+	if referral.Status != models.ReferralAccepted || referral.ResponderID == nil {
+		return nil, ErrReferralNotBookable
+	}
+
+	providerID := *referral.ResponderID
+	appointment := &models.Appointment{
+		PatientID:       referral.PatientID,
+		ProviderType:    referral.TargetType,
+		AppointmentDate: slot.AppointmentDate,
+		StartTime:       slot.StartTime,
+		EndTime:         slot.EndTime,
+		StartsAt:        slot.StartsAt,
+		EndsAt:          slot.EndsAt,
+		Status:          "scheduled",
+	}
+	if referral.TargetType == models.ReferralToDoctor {
+		doctor, err := s.doctorRepo.GetByID(ctx, providerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get doctor: %w", err)
+		}
+		appointment.DoctorID = &providerID
+		appointment.ServiceTypeID = doctor.ServiceTypeID
+	} else {
+		provider, err := s.providerRepo.GetByID(ctx, providerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home care provider: %w", err)
+		}
+		appointment.HomeCareProviderID = &providerID
+		appointment.ServiceTypeID = provider.ServiceTypeID
+	}
+
+	created, err := s.appointmentService.CreateAppointment(ctx, appointment)
+	if err != nil {
+		return nil, err
+	}
+	booked, err := s.referralRepo.MarkBooked(ctx, id, created.ID, time.Now().UTC().Truncate(time.Second))
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to mark referral %d as booked", id)
+		return nil, fmt.Errorf("failed to update referral: %w", err)
+	}
+	if !booked {
+		// Cancelled while the appointment was being made; the appointment stands on its own
+		s.logger.Warnf("Referral %d changed before appointment %d could be linked", id, created.ID)
+	}
+
+	s.notify(ctx, referral.ReferringDoctorID, "referral_booked",
+		fmt.Sprintf("Your referral for %q has been booked", referral.Reason))
+	s.logger.Infof("Referral %d booked as appointment %d", id, created.ID)
+	return created, nil
+}
+
+// ReferralBookingURL returns where the patient finds the accepting receiver's free slots
+func ReferralBookingURL(base string, referral *models.Referral) string {
+	if referral.Status != models.ReferralAccepted || referral.ResponderID == nil {
+		return ""
+	}
+	path := "/doctors/"
+	if referral.TargetType == models.ReferralToHomeCare {
+		path = "/providers/"
+	}
+	return fmt.Sprintf("%s%s%d/slots?referral_id=%d", strings.TrimRight(base, "/"), path, *referral.ResponderID, referral.ID)
+}
+
+func (s *ReferralService) respond(ctx context.Context, id, userID int, status, note string) (*models.Referral, error) {
+	referral, err := s.getReferral(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if referral.Status != models.ReferralPending {
+		return nil, ErrReferralNotPending
+	}
+	if !s.canReceive(ctx, referral, userID) {
+		return nil, ErrNotReferralReceiver
+	}
+
+	at := time.Now().UTC().Truncate(time.Second)
+	answered, err := s.referralRepo.Respond(ctx, id, status, userID, note, at)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to answer referral ID: %d", id)
+		return nil, fmt.Errorf("failed to answer referral: %w", err)
+	}
+	if !answered {
+		// Another receiver in the pool answered first
+		return nil, ErrReferralNotPending
+	}
+
+	referral.Status = status
+	referral.ResponderID = &userID
+	referral.ResponseNote = note
+	referral.RespondedAt = models.NullTime{Time: at, Valid: true}
+	referral.UpdatedAt = at
+	s.logger.Infof("Referral %d %s by user ID: %d", id, status, userID)
+	return referral, nil
+}
+
+// canReceive reports whether the user may answer the referral: its recipient or, for a pool
+// referral, a doctor of the specialty or a home care provider of the service type
+func (s *ReferralService) canReceive(ctx context.Context, referral *models.Referral, userID int) bool {
+	if referral.RecipientID != nil {
+		return *referral.RecipientID == userID
+	}
+	if userID == referral.ReferringDoctorID {
+		return false
+	}
+	if referral.TargetType == models.ReferralToDoctor {
+		doctor, err := s.doctorRepo.GetByID(ctx, userID)
+		return err == nil && strings.EqualFold(doctor.Specialty, referral.TargetSpecialty)
+	}
+	provider, err := s.providerRepo.GetByID(ctx, userID)
+	return err == nil && provider.ServiceTypeID == referral.TargetServiceTypeID
+}
+
+// resolveTarget checks the receiver named by the referral and copies their specialty or
+// service type onto it
+func (s *ReferralService) resolveTarget(ctx context.Context, referral *models.Referral, doctorID int) error {
+	if referral.RecipientID == nil {
+		return nil
+	}
+	if *referral.RecipientID == doctorID {
+		return errors.New("a doctor cannot refer a patient to themselves")
+	}
+
+	switch referral.TargetType {
+	case models.ReferralToDoctor:
+		doctor, err := s.doctorRepo.GetByID(ctx, *referral.RecipientID)
+		if err != nil {
+			return fmt.Errorf("invalid recipient_id: %w", err)
+		}
+		if doctor.Status != "active" {
+			return errors.New("the receiving doctor is not active")
+		}
+		referral.TargetSpecialty = doctor.Specialty
+	case models.ReferralToHomeCare:
+		provider, err := s.providerRepo.GetByID(ctx, *referral.RecipientID)
+		if err != nil {
+			return fmt.Errorf("invalid recipient_id: %w", err)
+		}
+		if provider.Status != "active" {
+			return errors.New("the receiving home care provider is not active")
+		}
+		referral.TargetServiceTypeID = provider.ServiceTypeID
+	}
+	return nil
+}
+
+// consultationSummary builds a clinical summary from the consultation details, or returns ""
+// when there are none
+func (s *ReferralService) consultationSummary(ctx context.Context, consultationID int) string {
+	details, err := s.detailsRepo.GetByConsultationID(ctx, consultationID)
+	if err != nil {
+		return ""
+	}
+
+	diagnosis := details.Diagnosis
+	if details.DiagnosisCode != "" {
+		diagnosis = strings.TrimSpace(details.DiagnosisCode + " " + diagnosis)
+	}
+	var lines []string
+	for _, field := range []struct{ label, value string }{
+		{"Presenting complaint", details.RequestDetails},
+		{"Symptoms", details.Symptoms},
+		{"Diagnosis", diagnosis},
+		{"Treatment", details.Prescription},
+		{"Notes", details.Notes},
+	} {
+		if value := strings.TrimSpace(field.value); value != "" {
+			lines = append(lines, field.label+": "+value)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s *ReferralService) getReferral(ctx context.Context, id int) (*models.Referral, error) {
+	referral, err := s.referralRepo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get referral ID: %d", id)
+		return nil, fmt.Errorf("failed to get referral: %w", err)
+	}
+	return referral, nil
+}
+
+// notify sends an in-app notification; failures are logged so they never undo the referral change
+func (s *ReferralService) notify(ctx context.Context, userID int, kind, message string) {
+	notification := &models.Notification{UserID: userID, NotificationType: kind, Message: message}
+	if err := s.notificationService.CreateNotification(ctx, notification); err != nil {
+		s.logger.WithError(err).Errorf("Failed to notify user %d of %s", userID, kind)
+	}
+}
+
+func validateReferral(referral *models.Referral) error {
+	switch referral.TargetType {
+	case models.ReferralToDoctor:
+		if referral.TargetSpecialty == "" {
+			return errors.New("a target specialty or recipient doctor is required")
+		}
+	case models.ReferralToHomeCare:
+		if referral.TargetServiceTypeID == 0 {
+			return errors.New("a target service type or recipient home care provider is required")
+		}
+	default:
+		return fmt.Errorf("target type must be %q or %q", models.ReferralToDoctor, models.ReferralToHomeCare)
+	}
+	switch referral.Urgency {
+	case models.ReferralRoutine, models.ReferralUrgent, models.ReferralEmergency:
+	default:
+		return fmt.Errorf("unknown urgency %q", referral.Urgency)
+	}
+	if referral.Reason == "" {
+		return errors.New("a reason is required")
+	}
+	if referral.ClinicalSummary == "" {
+		return errors.New("a clinical summary is required when the consultation has no details")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// reminderWindow sends a reminder of Type to appointments starting within Lead of now,
+// excluding those already inside the next shorter window
+type reminderWindow struct {
+	Type  string
+	Lead  time.Duration
+	Label string
+}
+
+// reminderWindows is ordered from the shortest lead to the longest
+var reminderWindows = []reminderWindow{
+	{Type: "1h", Lead: time.Hour, Label: "in about an hour"},
+	{Type: "24h", Lead: 24 * time.Hour, Label: "within the next 24 hours"},
+}
+
+type ReminderService struct {
+	reminderRepo        repository.ReminderRepository
+	notificationService NotificationService
+	timeZones           *TimeZoneService
+	logger              *logrus.Logger
+}
+
+func NewReminderService(reminderRepo repository.ReminderRepository, notificationService NotificationService, timeZones *TimeZoneService, logger *logrus.Logger) *ReminderService {
+	return &ReminderService{
+		reminderRepo:        reminderRepo,
+		notificationService: notificationService,
+		timeZones:           timeZones,
+		logger:              logger,
+	}
+}
+
+// SendDueReminders notifies the patient and the provider of every appointment entering a
+// reminder window. Each reminder is claimed before it is sent, so overlapping runs and
+// restarts never send the same one twice. It returns the number of notifications sent.
+func (s *ReminderService) SendDueReminders(ctx context.Context) (int, error) {
+	now := time.Now()
+	sent := 0
+
+	from := now
+	for _, window := range reminderWindows {
+		to := now.Add(window.Lead)
+		appointments, err := s.reminderRepo.ListDue(ctx, window.Type, from, to)
+		if err != nil {
+			s.logger.WithError(err).Errorf("Failed to list due %s reminders", window.Type)
+			return sent, fmt.Errorf("failed to list due reminders: %w", err)
+		}
+
+		for _, appointment := range appointments {
+			sent += s.remind(ctx, window, appointment)
+		}
+		from = to
+	}
+
+	if sent > 0 {
+		s.logger.Infof("Sent %d appointment reminders", sent)
+	}
+	return sent, nil
+}
+
+// remind sends the window's reminder to each participant who has not had it yet
+func (s *ReminderService) remind(ctx context.Context, window reminderWindow, appointment *models.Appointment) int {
+	recipients := []int{appointment.PatientID}
+	if providerID := appointmentProviderID(appointment); providerID != 0 {
+		recipients = append(recipients, providerID)
+	}
+
+	sent := 0
+	for _, recipientID := range recipients {
+		claimed, err := s.reminderRepo.Claim(ctx, appointment.ID, window.Type, recipientID)
+		if err != nil {
+			s.logger.WithError(err).Errorf("Failed to claim %s reminder for appointment ID: %d", window.Type, appointment.ID)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		notification := &models.Notification{
+			UserID:           recipientID,
+			NotificationType: "appointment_reminder",
+			Message:          appointmentReminderMessage(appointment, window.Label, s.timeZones.LocalTime(ctx, recipientID, appointmentRange(appointment).Start)),
+		}
+		if err := s.notificationService.CreateNotification(ctx, notification); err != nil {
+			s.logger.WithError(err).Errorf("Failed to send %s reminder for appointment ID: %d", window.Type, appointment.ID)
+			if err := s.reminderRepo.Unclaim(ctx, appointment.ID, window.Type, recipientID); err != nil {
+				s.logger.WithError(err).Errorf("Failed to release %s reminder claim for appointment ID: %d", window.Type, appointment.ID)
+			}
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+// ResetReminders forgets the reminders already sent for an appointment that was moved or
+// reinstated, so that its participants are reminded of the new time
+func (s *ReminderService) ResetReminders(ctx context.Context, appointmentID int) error {
+	if err := s.reminderRepo.DeleteByAppointment(ctx, appointmentID); err != nil {
+		s.logger.WithError(err).Errorf("Failed to reset reminders for appointment ID: %d", appointmentID)
+		return fmt.Errorf("failed to reset reminders: %w", err)
+	}
+	return nil
+}
+
+// appointmentReminderMessage words a reminder; startsAt is the appointment start in the recipient's zone
+func appointmentReminderMessage(appointment *models.Appointment, when string, startsAt time.Time) string {
+	return fmt.Sprintf("Reminder: you have an appointment %s, on %s.", when, startsAt.Format(notificationTimeLayout))
+}
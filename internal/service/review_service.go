@@ -20,14 +20,16 @@ type ReviewService interface {
 }
 
 type reviewService struct {
-    reviewRepo repository.ReviewRepository
-    logger     *logrus.Logger // Change to *logrus.Logger
+    reviewRepo       repository.ReviewRepository
+    consultationRepo repository.ConsultationRepository
+    logger           *logrus.Logger // Change to *logrus.Logger
 }
 
-func NewReviewService(repo repository.ReviewRepository, log *logrus.Logger) ReviewService {
+func NewReviewService(repo repository.ReviewRepository, consultationRepo repository.ConsultationRepository, log *logrus.Logger) ReviewService {
     return &reviewService{
-        reviewRepo: repo,
-        logger:     log,
+        reviewRepo:       repo,
+        consultationRepo: consultationRepo,
+        logger:           log,
     }
 }
 
@@ -35,6 +37,12 @@ func (s *reviewService) CreateReview(ctx context.Context, review *models.Review)
     if review.Rating < 1 || review.Rating > 5 {
         return errors.New("invalid rating")
     }
+    // Any review naming a consultation is checked against it, whatever its type
+    if review.ReviewType == "consultation" || review.ConsultationID != nil {
+        if err := s.checkConsultationReviewable(ctx, review); err != nil {
+            return err
+        }
+    }
 
     repoReview := convertToRepoReview(review)
     err := s.reviewRepo.Create(ctx, repoReview)
@@ -103,6 +111,27 @@ func (s *reviewService) CalculateAverageRating(ctx context.Context, doctorID int
     return 4.5, nil
 }
 
+// checkConsultationReviewable only lets the patient review a consultation once it is completed,
+// and attributes the review to the consultation's doctor
+func (s *reviewService) checkConsultationReviewable(ctx context.Context, review *models.Review) error {
+    if review.ConsultationID == nil {
+        return errors.New("consultation ID is required")
+    }
+    consultation, err := s.consultationRepo.GetByID(ctx, *review.ConsultationID)
+    if err != nil {
+        s.logger.Error("Failed to get consultation for review", "error", err, "consultationID", *review.ConsultationID)
+        return errors.New("consultation not found")
+    }
+    if consultation.PatientID != review.PatientID {
+        return errors.New("only the consultation's patient can review it")
+    }
+    if consultation.Status != "completed" {
+        return errors.New("consultation can be reviewed once it is completed")
+    }
+    review.DoctorID = &consultation.DoctorID
+    return nil
+}
+
 // Conversion functions
 func convertToRepoReview(modelReview *models.Review) *repository.Review {
     return &repository.Review{
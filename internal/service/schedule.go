@@ -0,0 +1,185 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"shifa/internal/models"
+)
+
+// timeRange is a half-open [Start, End) interval used when computing bookable windows
+type timeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (r timeRange) overlaps(other timeRange) bool {
+	return r.Start.Before(other.End) && other.Start.Before(r.End)
+}
+
+func (r timeRange) contains(other timeRange) bool {
+	return !other.Start.Before(r.Start) && !other.End.After(r.End)
+}
+
+// clip limits the range to bounds, reporting false when nothing is left
+func (r timeRange) clip(bounds timeRange) (timeRange, bool) {
+	if r.Start.Before(bounds.Start) {
+		r.Start = bounds.Start
+	}
+	if r.End.After(bounds.End) {
+		r.End = bounds.End
+	}
+	return r, r.Start.Before(r.End)
+}
+
+// dayRange returns the midnight-to-midnight range of the given date
+func dayRange(day time.Time) timeRange {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	return timeRange{Start: start, End: start.AddDate(0, 0, 1)}
+}
+
+// onDate combines the calendar date of day with the clock time of timeOfDay
+func onDate(day, timeOfDay time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(),
+		timeOfDay.Hour(), timeOfDay.Minute(), timeOfDay.Second(), 0, day.Location())
+}
+
+// mergeRanges sorts the ranges and joins any that overlap or touch
+func mergeRanges(ranges []timeRange) []timeRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sorted := make([]timeRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	merged := []timeRange{sorted[0]}
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if !r.Start.After(last.End) {
+			if r.End.After(last.End) {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// subtractRanges removes every blocked range from the open ranges
+func subtractRanges(open, blocked []timeRange) []timeRange {
+	result := mergeRanges(open)
+	for _, b := range blocked {
+		var next []timeRange
+		for _, r := range result {
+			if !r.overlaps(b) {
+				next = append(next, r)
+				continue
+			}
+			if r.Start.Before(b.Start) {
+				next = append(next, timeRange{Start: r.Start, End: b.Start})
+			}
+			if b.End.Before(r.End) {
+				next = append(next, timeRange{Start: b.End, End: r.End})
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+// splitIntoSlots cuts each range into consecutive slots of the given length, dropping any remainder
+func splitIntoSlots(ranges []timeRange, length time.Duration) []timeRange {
+	var slots []timeRange
+	for _, r := range ranges {
+		for start := r.Start; !start.Add(length).After(r.End); start = start.Add(length) {
+			slots = append(slots, timeRange{Start: start, End: start.Add(length)})
+		}
+	}
+	return slots
+}
+
+// weeklyHours is one recurring weekly window, with clock times carried in Start and End
+type weeklyHours struct {
+	DayOfWeek int
+	Start     time.Time
+	End       time.Time
+}
+
+// dateException is a date-specific blocked or extra range
+type dateException struct {
+	Type  string
+	Start time.Time
+	End   time.Time
+}
+
+// openWindowsOn computes the bookable ranges on a date. Weekly hours are ignored on
+// holidays, extra exceptions are added and blocked exceptions are removed last, so a
+// block always wins over extra hours.
+func openWindowsOn(date time.Time, isHoliday bool, weekly []weeklyHours, exceptions []dateException) []timeRange {
+	day := dayRange(date)
+
+	var open []timeRange
+	if !isHoliday {
+		for _, hours := range weekly {
+			if hours.DayOfWeek != int(date.Weekday()) {
+				continue
+			}
+			open = append(open, timeRange{Start: onDate(date, hours.Start), End: onDate(date, hours.End)})
+		}
+	}
+
+	var blocked []timeRange
+	for _, exception := range exceptions {
+		r, ok := timeRange{Start: exception.Start, End: exception.End}.clip(day)
+		if !ok {
+			continue
+		}
+		if exception.Type == models.ExceptionTypeExtra {
+			open = append(open, r)
+		} else {
+			blocked = append(blocked, r)
+		}
+	}
+
+	return subtractRanges(open, blocked)
+}
+
+// appointmentRange returns the absolute time range covered by an appointment, falling
+// back to its wall-clock date and times in its own zone when no instants are stored
+func appointmentRange(appointment *models.Appointment) timeRange {
+	if appointment.StartsAt.Valid && appointment.EndsAt.Valid {
+		return timeRange{Start: appointment.StartsAt.Time, End: appointment.EndsAt.Time}
+	}
+	date := dateIn(appointment.AppointmentDate, loadLocation(appointment.TimeZone))
+	return timeRange{
+		Start: onDate(date, appointment.StartTime.Time()),
+		End:   onDate(date, appointment.EndTime.Time()),
+	}
+}
+
+// holdsSlot reports whether an appointment still occupies its time: booked, or with its
+// consultation under way
+func holdsSlot(appointment *models.Appointment) bool {
+	return appointment.Status == "scheduled" || appointment.Status == "in_progress"
+}
+
+func overlapsAny(r timeRange, others []timeRange) bool {
+	for _, other := range others {
+		if r.overlaps(other) {
+			return true
+		}
+	}
+	return false
+}
+
+// withinAny reports whether r lies entirely inside one of the windows
+func withinAny(r timeRange, windows []timeRange) bool {
+	for _, window := range windows {
+		if window.contains(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"shifa/internal/models"
+)
+
+// scheduleDay is a Monday, so weekly hours with DayOfWeek 1 apply to it
+var scheduleDay = time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+// clockAt returns the time of day on the test day
+func clockAt(hour, minute int) time.Time {
+	return scheduleDay.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+func span(startHour, startMinute, endHour, endMinute int) timeRange {
+	return timeRange{Start: clockAt(startHour, startMinute), End: clockAt(endHour, endMinute)}
+}
+
+func TestTimeRangeOverlaps(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b timeRange
+		want bool
+	}{
+		{"same range", span(9, 0, 10, 0), span(9, 0, 10, 0), true},
+		{"partial overlap", span(9, 0, 10, 0), span(9, 30, 10, 30), true},
+		{"contained", span(9, 0, 12, 0), span(10, 0, 11, 0), true},
+		{"touching ends", span(9, 0, 10, 0), span(10, 0, 11, 0), false},
+		{"disjoint", span(9, 0, 10, 0), span(11, 0, 12, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.overlaps(tt.b); got != tt.want {
+				t.Errorf("overlaps = %v, want %v", got, tt.want)
+			}
+			if got := tt.b.overlaps(tt.a); got != tt.want {
+				t.Errorf("reversed overlaps = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeRangeContains(t *testing.T) {
+	tests := []struct {
+		name  string
+		outer timeRange
+		inner timeRange
+		want  bool
+	}{
+		{"same range", span(9, 0, 10, 0), span(9, 0, 10, 0), true},
+		{"inside", span(9, 0, 12, 0), span(10, 0, 11, 0), true},
+		{"starts before", span(9, 0, 12, 0), span(8, 30, 10, 0), false},
+		{"ends after", span(9, 0, 12, 0), span(11, 0, 12, 30), false},
+		{"disjoint", span(9, 0, 10, 0), span(11, 0, 12, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.outer.contains(tt.inner); got != tt.want {
+				t.Errorf("contains = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeRangeClip(t *testing.T) {
+	bounds := span(9, 0, 17, 0)
+	tests := []struct {
+		name   string
+		r      timeRange
+		want   timeRange
+		wantOK bool
+	}{
+		{"inside", span(10, 0, 11, 0), span(10, 0, 11, 0), true},
+		{"starts before", span(8, 0, 10, 0), span(9, 0, 10, 0), true},
+		{"ends after", span(16, 0, 18, 0), span(16, 0, 17, 0), true},
+		{"covers bounds", span(8, 0, 18, 0), bounds, true},
+		{"ends at bounds start", span(8, 0, 9, 0), span(9, 0, 9, 0), false},
+		{"after bounds", span(18, 0, 19, 0), span(18, 0, 17, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.r.clip(bounds)
+			if ok != tt.wantOK {
+				t.Fatalf("clip ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("clip = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeRanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges []timeRange
+		want   []timeRange
+	}{
+		{"empty", nil, nil},
+		{"single", []timeRange{span(9, 0, 10, 0)}, []timeRange{span(9, 0, 10, 0)}},
+		{
+			"unsorted disjoint",
+			[]timeRange{span(13, 0, 14, 0), span(9, 0, 10, 0)},
+			[]timeRange{span(9, 0, 10, 0), span(13, 0, 14, 0)},
+		},
+		{
+			"overlapping",
+			[]timeRange{span(9, 0, 11, 0), span(10, 0, 12, 0)},
+			[]timeRange{span(9, 0, 12, 0)},
+		},
+		{
+			"touching",
+			[]timeRange{span(9, 0, 10, 0), span(10, 0, 11, 0)},
+			[]timeRange{span(9, 0, 11, 0)},
+		},
+		{
+			"contained",
+			[]timeRange{span(9, 0, 17, 0), span(10, 0, 11, 0)},
+			[]timeRange{span(9, 0, 17, 0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeRanges(tt.ranges); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeRanges = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeRangesLeavesInputUnchanged(t *testing.T) {
+	ranges := []timeRange{span(13, 0, 14, 0), span(9, 0, 10, 0)}
+	mergeRanges(ranges)
+	if ranges[0] != span(13, 0, 14, 0) {
+		t.Errorf("input reordered to %v", ranges)
+	}
+}
+
+func TestSubtractRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		open    []timeRange
+		blocked []timeRange
+		want    []timeRange
+	}{
+		{"nothing blocked", []timeRange{span(9, 0, 12, 0)}, nil, []timeRange{span(9, 0, 12, 0)}},
+		{
+			"block in the middle",
+			[]timeRange{span(9, 0, 12, 0)},
+			[]timeRange{span(10, 0, 11, 0)},
+			[]timeRange{span(9, 0, 10, 0), span(11, 0, 12, 0)},
+		},
+		{
+			"block at the start",
+			[]timeRange{span(9, 0, 12, 0)},
+			[]timeRange{span(8, 0, 10, 0)},
+			[]timeRange{span(10, 0, 12, 0)},
+		},
+		{
+			"block at the end",
+			[]timeRange{span(9, 0, 12, 0)},
+			[]timeRange{span(11, 0, 13, 0)},
+			[]timeRange{span(9, 0, 11, 0)},
+		},
+		{"block covers everything", []timeRange{span(9, 0, 12, 0)}, []timeRange{span(8, 0, 13, 0)}, nil},
+		{
+			"block touching the range",
+			[]timeRange{span(9, 0, 12, 0)},
+			[]timeRange{span(12, 0, 13, 0)},
+			[]timeRange{span(9, 0, 12, 0)},
+		},
+		{
+			"open ranges are merged first",
+			[]timeRange{span(11, 0, 13, 0), span(9, 0, 11, 0)},
+			[]timeRange{span(10, 0, 12, 0)},
+			[]timeRange{span(9, 0, 10, 0), span(12, 0, 13, 0)},
+		},
+		{
+			"several blocks",
+			[]timeRange{span(9, 0, 17, 0)},
+			[]timeRange{span(10, 0, 11, 0), span(13, 0, 14, 0)},
+			[]timeRange{span(9, 0, 10, 0), span(11, 0, 13, 0), span(14, 0, 17, 0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := subtractRanges(tt.open, tt.blocked); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("subtractRanges = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitIntoSlots(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges []timeRange
+		length time.Duration
+		want   []timeRange
+	}{
+		{
+			"exact fit",
+			[]timeRange{span(9, 0, 10, 0)},
+			30 * time.Minute,
+			[]timeRange{span(9, 0, 9, 30), span(9, 30, 10, 0)},
+		},
+		{
+			"remainder dropped",
+			[]timeRange{span(9, 0, 10, 15)},
+			30 * time.Minute,
+			[]timeRange{span(9, 0, 9, 30), span(9, 30, 10, 0)},
+		},
+		{"range shorter than a slot", []timeRange{span(9, 0, 9, 20)}, 30 * time.Minute, nil},
+		{
+			"several ranges",
+			[]timeRange{span(9, 0, 9, 30), span(14, 0, 15, 0)},
+			30 * time.Minute,
+			[]timeRange{span(9, 0, 9, 30), span(14, 0, 14, 30), span(14, 30, 15, 0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitIntoSlots(tt.ranges, tt.length); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitIntoSlots = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenWindowsOn(t *testing.T) {
+	clock := func(hour int) time.Time { return time.Date(0, 1, 1, hour, 0, 0, 0, time.UTC) }
+	monday := []weeklyHours{
+		{DayOfWeek: 1, Start: clock(9), End: clock(12)},
+		{DayOfWeek: 1, Start: clock(14), End: clock(17)},
+		{DayOfWeek: 2, Start: clock(8), End: clock(18)},
+	}
+
+	tests := []struct {
+		name       string
+		holiday    bool
+		weekly     []weeklyHours
+		exceptions []dateException
+		want       []timeRange
+	}{
+		{"weekly hours of the day", false, monday, nil, []timeRange{span(9, 0, 12, 0), span(14, 0, 17, 0)}},
+		{"no hours that day", false, monday[2:], nil, nil},
+		{"holiday", true, monday, nil, nil},
+		{
+			"blocked exception",
+			false, monday,
+			[]dateException{{Type: models.ExceptionTypeBlocked, Start: clockAt(10, 0), End: clockAt(15, 0)}},
+			[]timeRange{span(9, 0, 10, 0), span(15, 0, 17, 0)},
+		},
+		{
+			"extra exception joins the weekly hours",
+			false, monday,
+			[]dateException{{Type: models.ExceptionTypeExtra, Start: clockAt(12, 0), End: clockAt(14, 0)}},
+			[]timeRange{span(9, 0, 17, 0)},
+		},
+		{
+			"extra hours on a holiday",
+			true, monday,
+			[]dateException{{Type: models.ExceptionTypeExtra, Start: clockAt(10, 0), End: clockAt(11, 0)}},
+			[]timeRange{span(10, 0, 11, 0)},
+		},
+		{
+			"block wins over extra hours",
+			false, nil,
+			[]dateException{
+				{Type: models.ExceptionTypeExtra, Start: clockAt(10, 0), End: clockAt(12, 0)},
+				{Type: models.ExceptionTypeBlocked, Start: clockAt(11, 0), End: clockAt(13, 0)},
+			},
+			[]timeRange{span(10, 0, 11, 0)},
+		},
+		{
+			"exceptions are clipped to the day",
+			false, nil,
+			[]dateException{{Type: models.ExceptionTypeExtra, Start: clockAt(-2, 0), End: clockAt(2, 0)}},
+			[]timeRange{span(0, 0, 2, 0)},
+		},
+		{
+			"exceptions on other days are ignored",
+			false, monday,
+			[]dateException{{Type: models.ExceptionTypeBlocked, Start: clockAt(24, 0), End: clockAt(48, 0)}},
+			[]timeRange{span(9, 0, 12, 0), span(14, 0, 17, 0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := openWindowsOn(scheduleDay, tt.holiday, tt.weekly, tt.exceptions)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("openWindowsOn = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
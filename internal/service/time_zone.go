@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"shifa/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// notificationTimeLayout renders times in messages with the zone abbreviation, so the reader
+// knows which zone a time is in
+const notificationTimeLayout = "2006-01-02 at 15:04 MST"
+
+// ErrNonexistentLocalTime is returned for wall-clock times skipped by a daylight saving transition
+var ErrNonexistentLocalTime = errors.New("the selected time does not exist in the provider's time zone")
+
+// TimeZoneService resolves the IANA time zone users have chosen; users without one are in UTC
+type TimeZoneService struct {
+	userRepo repository.UserRepository
+	logger   *logrus.Logger
+}
+
+func NewTimeZoneService(userRepo repository.UserRepository, logger *logrus.Logger) *TimeZoneService {
+	return &TimeZoneService{
+		userRepo: userRepo,
+		logger:   logger,
+	}
+}
+
+// Location returns the time zone of a user
+func (s *TimeZoneService) Location(ctx context.Context, userID int) (*time.Location, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get time zone for user ID: %d", userID)
+		return nil, fmt.Errorf("failed to get time zone: %w", err)
+	}
+	return loadLocation(user.TimeZone), nil
+}
+
+// LocalTime converts t to the user's zone for display. Lookup failures are logged and fall
+// back to UTC so that notifications still go out.
+func (s *TimeZoneService) LocalTime(ctx context.Context, userID int, t time.Time) time.Time {
+	loc, err := s.Location(ctx, userID)
+	if err != nil {
+		return t.UTC()
+	}
+	return t.In(loc)
+}
+
+// validateTimeZone accepts an empty name, which keeps the current zone or defaults to UTC
+func validateTimeZone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("invalid time zone %q", name)
+	}
+	return nil
+}
+
+// loadLocation loads a stored zone name, falling back to UTC for empty or unknown names
+func loadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// dateIn returns midnight in loc of the calendar date of day
+func dateIn(day time.Time, loc *time.Location) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+}
+
+// civilDate returns the calendar date of t as UTC midnight, the form DATE columns and
+// date query parameters are exchanged in
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// wallClockInstant resolves the clock time on date in loc to an instant. Times skipped by
+// a spring-forward transition are rejected; times repeated by a fall-back transition
+// resolve to their first occurrence.
+func wallClockInstant(date, clock time.Time, loc *time.Location) (time.Time, error) {
+	instant := time.Date(date.Year(), date.Month(), date.Day(),
+		clock.Hour(), clock.Minute(), clock.Second(), 0, loc)
+	if instant.Hour() != clock.Hour() || instant.Minute() != clock.Minute() {
+		return time.Time{}, ErrNonexistentLocalTime
+	}
+
+	// time.Date may pick either occurrence of a repeated time, so step back to the
+	// earlier offset when it shows the same wall clock
+	_, offset := instant.Zone()
+	_, earlierOffset := instant.Add(-12 * time.Hour).Zone()
+	if earlierOffset > offset {
+		earlier := instant.Add(-time.Duration(earlierOffset-offset) * time.Second)
+		if earlier.Hour() == instant.Hour() && earlier.Minute() == instant.Minute() {
+			return earlier, nil
+		}
+	}
+	return instant, nil
+}
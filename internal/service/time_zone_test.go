@@ -0,0 +1,57 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestWallClockInstant(t *testing.T) {
+	tests := []struct {
+		name    string
+		zone    string
+		date    string
+		clock   string
+		want    string
+		wantErr error
+	}{
+		{"utc", "UTC", "2024-03-04", "09:00", "2024-03-04T09:00:00Z", nil},
+		{"standard time", "America/New_York", "2024-01-15", "09:30", "2024-01-15T14:30:00Z", nil},
+		{"daylight time", "America/New_York", "2024-07-01", "09:30", "2024-07-01T13:30:00Z", nil},
+		{"before spring forward", "America/New_York", "2024-03-10", "01:59", "2024-03-10T06:59:00Z", nil},
+		{"skipped by spring forward", "America/New_York", "2024-03-10", "02:30", "", ErrNonexistentLocalTime},
+		{"after spring forward", "America/New_York", "2024-03-10", "03:00", "2024-03-10T07:00:00Z", nil},
+		{"repeated by fall back", "America/New_York", "2024-11-03", "01:30", "2024-11-03T05:30:00Z", nil},
+		{"after fall back", "America/New_York", "2024-11-03", "02:00", "2024-11-03T07:00:00Z", nil},
+		{"skipped in london", "Europe/London", "2024-03-31", "01:15", "", ErrNonexistentLocalTime},
+		{"repeated in london", "Europe/London", "2024-10-27", "01:15", "2024-10-27T00:15:00Z", nil},
+		{"skipped in sydney", "Australia/Sydney", "2024-10-06", "02:30", "", ErrNonexistentLocalTime},
+		{"repeated in sydney", "Australia/Sydney", "2024-04-07", "02:30", "2024-04-06T15:30:00Z", nil},
+		{"no transitions", "Asia/Riyadh", "2024-03-10", "02:30", "2024-03-09T23:30:00Z", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.zone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			date, _ := time.Parse("2006-01-02", tt.date)
+			clock, _ := time.Parse("15:04", tt.clock)
+
+			got, err := wallClockInstant(date, clock, loc)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := got.UTC().Format(time.RFC3339); got != tt.want {
+				t.Errorf("wallClockInstant = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -33,6 +33,12 @@ func (s *UserService) CreateUser(user *models.User) (*models.User, error) {
     if !isValidPassword(user.Password) {
         return nil, errors.New("invalid password")
     }
+    if err := validateTimeZone(user.TimeZone); err != nil {
+        return nil, err
+    }
+    if err := validateLanguage(user.Language); err != nil {
+        return nil, err
+    }
 
     hashedPassword, err := hashPassword(user.Password)
     if err != nil {
@@ -66,6 +72,13 @@ func (s *UserService) UpdateUser(user *models.User) (*models.User, error) {
         return nil, err
     }
 
+    if err := validateTimeZone(user.TimeZone); err != nil {
+        return nil, err
+    }
+    if err := validateLanguage(user.Language); err != nil {
+        return nil, err
+    }
+
     // If password is provided, validate and hash it
     if user.Password != "" {
         if !isValidPassword(user.Password) {
@@ -80,6 +93,12 @@ func (s *UserService) UpdateUser(user *models.User) (*models.User, error) {
         // Keep existing password hash if no new password provided
         user.PasswordHash = existing.PasswordHash
     }
+    if user.TimeZone == "" {
+        user.TimeZone = existing.TimeZone
+    }
+    if user.Language == "" {
+        user.Language = existing.Language
+    }
 
     user.UpdatedAt = time.Now()
     
@@ -105,6 +124,12 @@ func (s *UserService) Register(ctx context.Context, user models.User) error {
     if !isValidPassword(user.Password) {
         return errors.New("password must be at least 8 characters long and contain at least one uppercase letter, one lowercase letter, one number, and one special character")
     }
+    if err := validateTimeZone(user.TimeZone); err != nil {
+        return err
+    }
+    if err := validateLanguage(user.Language); err != nil {
+        return err
+    }
     
     hashedPassword, err := hashPassword(user.Password)
     if err != nil {
@@ -189,4 +214,12 @@ func verifyPassword(hash, password string) bool {
 func isValidEmail(email string) bool {
     emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
     return emailRegex.MatchString(email)
-}
\ No newline at end of file
+}
+
+// validateLanguage accepts the languages documents are produced in; empty keeps the default
+func validateLanguage(language string) error {
+    if language != "" && language != models.LanguageEnglish && language != models.LanguageArabic {
+        return fmt.Errorf("unsupported language %q, expected %q or %q", language, models.LanguageEnglish, models.LanguageArabic)
+    }
+    return nil
+}
@@ -0,0 +1,391 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// videoJoinTokenTTL bounds how long a join token can be used to connect; an open
+// connection is not cut off when its token expires
+const videoJoinTokenTTL = 10 * time.Minute
+
+const videoJoinPurpose = "video_join"
+
+var (
+	ErrNotConsultationParticipant = errors.New("only the consultation's patient and doctor can join its video session")
+	ErrVideoSessionEnded          = errors.New("the video session has ended")
+	ErrInvalidJoinToken           = errors.New("invalid or expired join token")
+)
+
+// VideoPeer is a participant's signaling connection
+type VideoPeer interface {
+	Send(msg *models.SignalMessage) error
+	Close()
+}
+
+// VideoSessionService runs WebRTC signaling for consultations: it admits the patient and the
+// doctor with join tokens, relays offers, answers and ICE candidates between them, and tracks
+// the room's state. Rooms live in this process, so both participants must reach the same instance.
+type VideoSessionService struct {
+	sessionRepo      repository.VideoSessionRepository
+	consultationRepo repository.ConsultationRepository
+	jwtSecret        []byte
+	logger           *logrus.Logger
+
+	mu    sync.Mutex
+	rooms map[int]*videoRoom
+}
+
+// videoRoom is the live state of a session; connectedSince is set while both participants are in
+type videoRoom struct {
+	mu             sync.Mutex
+	session        *models.VideoSession
+	participants   map[int]*videoParticipant
+	connectedSince time.Time
+	closed         bool
+}
+
+type videoParticipant struct {
+	role string
+	peer VideoPeer
+}
+
+func NewVideoSessionService(
+	sessionRepo repository.VideoSessionRepository,
+	consultationRepo repository.ConsultationRepository,
+	jwtSecret string,
+	logger *logrus.Logger,
+) *VideoSessionService {
+	return &VideoSessionService{
+		sessionRepo:      sessionRepo,
+		consultationRepo: consultationRepo,
+		jwtSecret:        []byte(jwtSecret),
+		logger:           logger,
+		rooms:            make(map[int]*videoRoom),
+	}
+}
+
+// IssueJoinToken gives the consultation's patient or doctor a short-lived token for its video room
+func (s *VideoSessionService) IssueJoinToken(ctx context.Context, consultationID, userID int) (*models.VideoJoinToken, error) {
+	consultation, err := s.consultationRepo.GetByID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to get consultation: %w", err)
+	}
+	role, err := participantRole(consultation, userID)
+	if err != nil {
+		return nil, err
+	}
+	if consultation.Status != "in_progress" {
+		return nil, errors.New("video sessions are only available while the consultation is in progress")
+	}
+
+	session, err := s.sessionRepo.GetOrCreate(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get video session for consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to get video session: %w", err)
+	}
+	if session.Status == models.VideoSessionEnded {
+		return nil, ErrVideoSessionEnded
+	}
+
+	expiresAt := time.Now().Add(videoJoinTokenTTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"purpose":         videoJoinPurpose,
+		"consultation_id": consultationID,
+		"user_id":         userID,
+		"role":            role,
+		"exp":             expiresAt.Unix(),
+	})
+	signed, err := token.SignedString(s.jwtSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign join token: %w", err)
+	}
+	return &models.VideoJoinToken{Token: signed, Role: role, ExpiresAt: expiresAt}, nil
+}
+
+// VerifyJoinToken returns the user and role a join token admits to the consultation's room
+func (s *VideoSessionService) VerifyJoinToken(tokenString string, consultationID int) (int, string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidJoinToken
+		}
+		return s.jwtSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return 0, "", ErrInvalidJoinToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, "", ErrInvalidJoinToken
+	}
+	purpose, _ := claims["purpose"].(string)
+	tokenConsultationID, _ := claims["consultation_id"].(float64)
+	userID, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	if purpose != videoJoinPurpose || int(tokenConsultationID) != consultationID || userID == 0 || role == "" {
+		return 0, "", ErrInvalidJoinToken
+	}
+	return int(userID), role, nil
+}
+
+// Join puts a participant's connection in the room. A second connection from the same user
+// replaces the first, so a reloaded page takes over the call.
+func (s *VideoSessionService) Join(ctx context.Context, consultationID, userID int, role string, peer VideoPeer) error {
+	room, err := s.openRoom(ctx, consultationID)
+	if err != nil {
+		return err
+	}
+	defer room.mu.Unlock()
+
+	if previous, ok := room.participants[userID]; ok {
+		previous.peer.Close()
+	}
+	room.participants[userID] = &videoParticipant{role: role, peer: peer}
+	if len(room.participants) == 2 && room.connectedSince.IsZero() {
+		now := time.Now()
+		room.connectedSince = now
+		room.session.Status = models.VideoSessionConnected
+		if !room.session.ConnectedAt.Valid {
+			room.session.ConnectedAt = models.NullTime{Time: now, Valid: true}
+		}
+		s.save(ctx, room)
+	}
+	s.broadcastState(room)
+	return nil
+}
+
+// Relay forwards an offer, answer or ICE candidate to the other participant
+func (s *VideoSessionService) Relay(consultationID, userID int, msg *models.SignalMessage) error {
+	switch msg.Type {
+	case models.SignalOffer, models.SignalAnswer, models.SignalICECandidate:
+	default:
+		return fmt.Errorf("unsupported signal type %q", msg.Type)
+	}
+
+	s.mu.Lock()
+	room, ok := s.rooms[consultationID]
+	s.mu.Unlock()
+	if !ok {
+		return errors.New("not in the video session")
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	sender, ok := room.participants[userID]
+	if !ok {
+		return errors.New("not in the video session")
+	}
+	relayed := &models.SignalMessage{Type: msg.Type, From: sender.role, Payload: msg.Payload}
+	for id, participant := range room.participants {
+		if id == userID {
+			continue
+		}
+		if err := participant.peer.Send(relayed); err != nil {
+			s.logger.WithError(err).Warnf("Failed to relay %s in consultation ID: %d", msg.Type, consultationID)
+		}
+	}
+	return nil
+}
+
+// Leave removes a connection from the room. Connections already replaced by a newer one
+// from the same user are ignored.
+func (s *VideoSessionService) Leave(ctx context.Context, consultationID, userID int, peer VideoPeer) {
+	s.mu.Lock()
+	room, ok := s.rooms[consultationID]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	participant, ok := room.participants[userID]
+	if !ok || participant.peer != peer {
+		return
+	}
+	delete(room.participants, userID)
+
+	if !room.connectedSince.IsZero() {
+		s.addConnectedTime(room, time.Now())
+		room.session.Status = models.VideoSessionWaiting
+		s.save(ctx, room)
+	}
+	if len(room.participants) == 0 {
+		s.closeRoom(consultationID, room)
+		return
+	}
+	s.broadcastState(room)
+}
+
+// End closes the consultation's video session for good and records its final duration
+func (s *VideoSessionService) End(ctx context.Context, consultationID, userID int) (*models.VideoSession, error) {
+	consultation, err := s.consultationRepo.GetByID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to get consultation: %w", err)
+	}
+	if _, err := participantRole(consultation, userID); err != nil {
+		return nil, err
+	}
+
+	room, err := s.openRoom(ctx, consultationID)
+	if err != nil {
+		return nil, err
+	}
+	defer room.mu.Unlock()
+
+	now := time.Now()
+	s.addConnectedTime(room, now)
+	room.session.Status = models.VideoSessionEnded
+	room.session.EndedAt = models.NullTime{Time: now, Valid: true}
+	if err := s.sessionRepo.Save(ctx, room.session); err != nil {
+		s.logger.WithError(err).Errorf("Failed to end video session for consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to end video session: %w", err)
+	}
+
+	s.broadcastState(room)
+	for _, participant := range room.participants {
+		participant.peer.Close()
+	}
+	room.participants = map[int]*videoParticipant{}
+	session := *room.session
+	s.closeRoom(consultationID, room)
+	return &session, nil
+}
+
+// GetSession returns the consultation's video session with the roles currently connected
+func (s *VideoSessionService) GetSession(ctx context.Context, consultationID, userID int) (*models.VideoSession, error) {
+	consultation, err := s.consultationRepo.GetByID(ctx, consultationID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to get consultation ID: %d", consultationID)
+		return nil, fmt.Errorf("failed to get consultation: %w", err)
+	}
+	if _, err := participantRole(consultation, userID); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	room, ok := s.rooms[consultationID]
+	s.mu.Unlock()
+	if ok {
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		if !room.closed {
+			return s.snapshot(room), nil
+		}
+	}
+
+	session, err := s.sessionRepo.GetByConsultationID(ctx, consultationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get video session: %w", err)
+	}
+	session.Participants = []string{}
+	return session, nil
+}
+
+// openRoom returns the consultation's live room, locked, loading the session on first use
+func (s *VideoSessionService) openRoom(ctx context.Context, consultationID int) (*videoRoom, error) {
+	for {
+		s.mu.Lock()
+		room, ok := s.rooms[consultationID]
+		s.mu.Unlock()
+
+		if !ok {
+			session, err := s.sessionRepo.GetOrCreate(ctx, consultationID)
+			if err != nil {
+				s.logger.WithError(err).Errorf("Failed to get video session for consultation ID: %d", consultationID)
+				return nil, fmt.Errorf("failed to get video session: %w", err)
+			}
+			if session.Status == models.VideoSessionEnded {
+				return nil, ErrVideoSessionEnded
+			}
+			// A session left connected by a previous process has nobody in it now
+			session.Status = models.VideoSessionWaiting
+
+			s.mu.Lock()
+			if room, ok = s.rooms[consultationID]; !ok {
+				room = &videoRoom{session: session, participants: make(map[int]*videoParticipant)}
+				s.rooms[consultationID] = room
+			}
+			s.mu.Unlock()
+		}
+
+		room.mu.Lock()
+		if !room.closed {
+			return room, nil
+		}
+		// The room was closed while we waited for it; start over with a fresh one
+		room.mu.Unlock()
+	}
+}
+
+// closeRoom drops a room whose lock the caller holds
+func (s *VideoSessionService) closeRoom(consultationID int, room *videoRoom) {
+	room.closed = true
+	s.mu.Lock()
+	if s.rooms[consultationID] == room {
+		delete(s.rooms, consultationID)
+	}
+	s.mu.Unlock()
+}
+
+func (s *VideoSessionService) addConnectedTime(room *videoRoom, now time.Time) {
+	if room.connectedSince.IsZero() {
+		return
+	}
+	room.session.DurationSeconds += int(now.Sub(room.connectedSince) / time.Second)
+	room.connectedSince = time.Time{}
+}
+
+// save persists the room's state; failures are logged so that the call itself goes on
+func (s *VideoSessionService) save(ctx context.Context, room *videoRoom) {
+	if err := s.sessionRepo.Save(ctx, room.session); err != nil {
+		s.logger.WithError(err).Errorf("Failed to save video session for consultation ID: %d", room.session.ConsultationID)
+	}
+}
+
+func (s *VideoSessionService) broadcastState(room *videoRoom) {
+	msg := &models.SignalMessage{Type: models.SignalState, Session: s.snapshot(room)}
+	for _, participant := range room.participants {
+		if err := participant.peer.Send(msg); err != nil {
+			s.logger.WithError(err).Warnf("Failed to send video state for consultation ID: %d", room.session.ConsultationID)
+		}
+	}
+}
+
+// snapshot copies the session with its live participants and the duration up to now
+func (s *VideoSessionService) snapshot(room *videoRoom) *models.VideoSession {
+	session := *room.session
+	if !room.connectedSince.IsZero() {
+		session.DurationSeconds += int(time.Since(room.connectedSince) / time.Second)
+	}
+	session.Participants = make([]string, 0, len(room.participants))
+	for _, participant := range room.participants {
+		session.Participants = append(session.Participants, participant.role)
+	}
+	sort.Strings(session.Participants)
+	return &session
+}
+
+// participantRole returns "patient" or "doctor" for the consultation's participants
+func participantRole(consultation *models.Consultation, userID int) (string, error) {
+	switch userID {
+	case consultation.PatientID:
+		return string(models.RolePatient), nil
+	case consultation.DoctorID:
+		return string(models.RoleDoctor), nil
+	}
+	return "", ErrNotConsultationParticipant
+}
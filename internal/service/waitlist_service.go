@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// waitlistHoldDuration is how long a freed slot is held for the offered patient
+const waitlistHoldDuration = 30 * time.Minute
+
+var (
+	ErrSlotHeld      = errors.New("the selected time is held for a waitlisted patient")
+	ErrOfferExpired  = errors.New("waitlist offer has expired")
+	ErrNotOfferee    = errors.New("only the offered patient can accept or decline a waitlist offer")
+	ErrNotWaitlisted = errors.New("only the waitlisted patient can leave the waitlist")
+)
+
+type WaitlistService struct {
+	waitlistRepo        repository.WaitlistRepository
+	appointmentService  *AppointmentService
+	notificationService NotificationService
+	timeZones           *TimeZoneService
+	logger              *logrus.Logger
+}
+
+// NewWaitlistService creates the waitlist service and registers it with the appointment
+// service, so cancellations offer the freed slot and bookings respect held slots.
+func NewWaitlistService(
+	waitlistRepo repository.WaitlistRepository,
+	appointmentService *AppointmentService,
+	notificationService NotificationService,
+	timeZones *TimeZoneService,
+	logger *logrus.Logger,
+) *WaitlistService {
+	s := &WaitlistService{
+		waitlistRepo:        waitlistRepo,
+		appointmentService:  appointmentService,
+		notificationService: notificationService,
+		timeZones:           timeZones,
+		logger:              logger,
+	}
+	appointmentService.SetWaitlist(s)
+	return s
+}
+
+// JoinWaitlist queues a patient for any slot with the provider that frees up between FromDate and ToDate
+func (s *WaitlistService) JoinWaitlist(ctx context.Context, entry *models.WaitlistEntry) error {
+	if entry.PatientID == 0 {
+		return errors.New("patient ID is required")
+	}
+	if entry.ProviderID == 0 {
+		return errors.New("provider ID is required")
+	}
+	if entry.ProviderType != "doctor" && entry.ProviderType != "home_care_provider" {
+		return errors.New("provider type must be doctor or home_care_provider")
+	}
+	if entry.FromDate.IsZero() || entry.ToDate.IsZero() {
+		return errors.New("from and to dates are required")
+	}
+	if entry.ToDate.Before(entry.FromDate) {
+		return errors.New("to date must not be before from date")
+	}
+	if entry.ToDate.Before(dayRange(time.Now()).Start) {
+		return errors.New("to date must not be in the past")
+	}
+
+	entry.Status = models.WaitlistStatusWaiting
+	if err := s.waitlistRepo.CreateEntry(ctx, entry); err != nil {
+		s.logger.WithError(err).Error("Failed to create waitlist entry")
+		return fmt.Errorf("failed to join waitlist: %w", err)
+	}
+
+	s.logger.Infof("Patient %d joined waitlist for %s %d", entry.PatientID, entry.ProviderType, entry.ProviderID)
+	return nil
+}
+
+func (s *WaitlistService) ListPatientEntries(ctx context.Context, patientID int) ([]*models.WaitlistEntry, error) {
+	entries, err := s.waitlistRepo.ListEntriesByPatient(ctx, patientID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list waitlist entries for patient ID: %d", patientID)
+		return nil, fmt.Errorf("failed to list waitlist entries: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *WaitlistService) ListPatientOffers(ctx context.Context, patientID int) ([]*models.WaitlistOffer, error) {
+	offers, err := s.waitlistRepo.ListOffersByPatient(ctx, patientID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to list waitlist offers for patient ID: %d", patientID)
+		return nil, fmt.Errorf("failed to list waitlist offers: %w", err)
+	}
+	return offers, nil
+}
+
+// LeaveWaitlist removes the patient's waiting entry from the queue
+func (s *WaitlistService) LeaveWaitlist(ctx context.Context, entryID, patientID int) error {
+	entry, err := s.waitlistRepo.GetEntryByID(ctx, entryID)
+	if err != nil {
+		return err
+	}
+	if entry.PatientID != patientID {
+		return ErrNotWaitlisted
+	}
+	switch entry.Status {
+	case models.WaitlistStatusOffered:
+		return errors.New("waitlist entry has a pending offer; accept or decline it first")
+	case models.WaitlistStatusWaiting:
+	default:
+		return fmt.Errorf("waitlist entry is already %s", entry.Status)
+	}
+
+	if err := s.waitlistRepo.UpdateEntryStatus(ctx, entryID, models.WaitlistStatusCancelled); err != nil {
+		s.logger.WithError(err).Errorf("Failed to cancel waitlist entry with ID: %d", entryID)
+		return fmt.Errorf("failed to leave waitlist: %w", err)
+	}
+	return nil
+}
+
+// SlotReleased offers the slot of a cancelled appointment to the next waitlisted patient
+func (s *WaitlistService) SlotReleased(ctx context.Context, appointment *models.Appointment) error {
+	providerID := appointmentProviderID(appointment)
+	if providerID == 0 {
+		return nil
+	}
+	if appointmentRange(appointment).Start.Before(time.Now()) {
+		return nil
+	}
+
+	return s.offerSlot(ctx, &models.WaitlistOffer{
+		ProviderID:      providerID,
+		ProviderType:    appointment.ProviderType,
+		AppointmentDate: appointment.AppointmentDate,
+		StartTime:       appointment.StartTime,
+		EndTime:         appointment.EndTime,
+	})
+}
+
+// AcceptOffer books the held slot for the offered patient through the appointment service
+func (s *WaitlistService) AcceptOffer(ctx context.Context, offerID, patientID int) (*models.Appointment, error) {
+	offer, err := s.pendingOffer(ctx, offerID, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(offer.ExpiresAt) {
+		if err := s.expireOffer(ctx, offer); err != nil {
+			s.logger.WithError(err).Errorf("Failed to expire waitlist offer with ID: %d", offer.ID)
+		}
+		return nil, ErrOfferExpired
+	}
+
+	appointment := &models.Appointment{
+		PatientID:       offer.PatientID,
+		ProviderType:    offer.ProviderType,
+		AppointmentDate: offer.AppointmentDate,
+		StartTime:       offer.StartTime,
+		EndTime:         offer.EndTime,
+		Status:          "scheduled",
+	}
+	providerID := offer.ProviderID
+	if offer.ProviderType == "doctor" {
+		appointment.DoctorID = &providerID
+	} else {
+		appointment.HomeCareProviderID = &providerID
+	}
+
+	created, err := s.appointmentService.CreateAppointment(ctx, appointment)
+	if err != nil {
+		return nil, err
+	}
+
+	offer.Status = models.OfferStatusAccepted
+	offer.AppointmentID = &created.ID
+	if err := s.waitlistRepo.UpdateOffer(ctx, offer); err != nil {
+		s.logger.WithError(err).Errorf("Failed to mark waitlist offer %d as accepted", offer.ID)
+		return nil, fmt.Errorf("failed to update waitlist offer: %w", err)
+	}
+	if err := s.waitlistRepo.UpdateEntryStatus(ctx, offer.EntryID, models.WaitlistStatusBooked); err != nil {
+		s.logger.WithError(err).Errorf("Failed to mark waitlist entry %d as booked", offer.EntryID)
+		return nil, fmt.Errorf("failed to update waitlist entry: %w", err)
+	}
+
+	s.logger.Infof("Waitlist offer %d accepted, appointment %d booked", offer.ID, created.ID)
+	return created, nil
+}
+
+// DeclineOffer releases the held slot, returns the patient to the queue and offers the slot onward
+func (s *WaitlistService) DeclineOffer(ctx context.Context, offerID, patientID int) error {
+	offer, err := s.pendingOffer(ctx, offerID, patientID)
+	if err != nil {
+		return err
+	}
+	return s.closeOffer(ctx, offer, models.OfferStatusDeclined)
+}
+
+// ExpireOffers closes every offer whose hold has run out and passes each slot to the next
+// patient in line. It returns the number of offers expired.
+func (s *WaitlistService) ExpireOffers(ctx context.Context) (int, error) {
+	offers, err := s.waitlistRepo.ListExpiredOffers(ctx, time.Now())
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list expired waitlist offers")
+		return 0, fmt.Errorf("failed to list expired waitlist offers: %w", err)
+	}
+
+	expired := 0
+	for _, offer := range offers {
+		if err := s.expireOffer(ctx, offer); err != nil {
+			s.logger.WithError(err).Errorf("Failed to expire waitlist offer with ID: %d", offer.ID)
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// CheckHold rejects a booking that overlaps a slot currently held for a different patient
+func (s *WaitlistService) CheckHold(ctx context.Context, appointment *models.Appointment) error {
+	providerID := appointmentProviderID(appointment)
+	if providerID == 0 {
+		return nil
+	}
+
+	offers, err := s.waitlistRepo.ListActiveOffers(ctx, providerID, appointment.ProviderType, appointment.AppointmentDate, time.Now())
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load waitlist holds for provider ID: %d", providerID)
+		return fmt.Errorf("failed to check waitlist holds: %w", err)
+	}
+
+	requested := appointmentRange(appointment)
+	loc := loadLocation(appointment.TimeZone)
+	for _, offer := range offers {
+		if offer.PatientID == appointment.PatientID {
+			continue
+		}
+		if requested.overlaps(offerRange(offer, loc)) {
+			return ErrSlotHeld
+		}
+	}
+	return nil
+}
+
+// pendingOffer returns the patient's offer while it awaits their answer
+func (s *WaitlistService) pendingOffer(ctx context.Context, offerID, patientID int) (*models.WaitlistOffer, error) {
+	offer, err := s.waitlistRepo.GetOfferByID(ctx, offerID)
+	if err != nil {
+		return nil, err
+	}
+	if offer.PatientID != patientID {
+		return nil, ErrNotOfferee
+	}
+	if offer.Status != models.OfferStatusPending {
+		return nil, fmt.Errorf("waitlist offer is already %s", offer.Status)
+	}
+	return offer, nil
+}
+
+func (s *WaitlistService) expireOffer(ctx context.Context, offer *models.WaitlistOffer) error {
+	return s.closeOffer(ctx, offer, models.OfferStatusExpired)
+}
+
+// closeOffer ends a pending offer without a booking, puts the entry back in the queue
+// and offers the same slot to the next eligible patient
+func (s *WaitlistService) closeOffer(ctx context.Context, offer *models.WaitlistOffer, status string) error {
+	offer.Status = status
+	if err := s.waitlistRepo.UpdateOffer(ctx, offer); err != nil {
+		return fmt.Errorf("failed to update waitlist offer: %w", err)
+	}
+	if err := s.waitlistRepo.UpdateEntryStatus(ctx, offer.EntryID, models.WaitlistStatusWaiting); err != nil {
+		return fmt.Errorf("failed to update waitlist entry: %w", err)
+	}
+
+	loc, err := s.timeZones.Location(ctx, offer.ProviderID)
+	if err != nil {
+		return err
+	}
+	if offerRange(offer, loc).Start.Before(time.Now()) {
+		return nil
+	}
+	return s.offerSlot(ctx, &models.WaitlistOffer{
+		ProviderID:      offer.ProviderID,
+		ProviderType:    offer.ProviderType,
+		AppointmentDate: offer.AppointmentDate,
+		StartTime:       offer.StartTime,
+		EndTime:         offer.EndTime,
+	})
+}
+
+// offerSlot holds the slot described by slot for the longest-waiting patient who has not
+// already been offered it, and notifies them. It does nothing when nobody is left in line.
+func (s *WaitlistService) offerSlot(ctx context.Context, slot *models.WaitlistOffer) error {
+	entries, err := s.waitlistRepo.ListWaitingForDate(ctx, slot.ProviderID, slot.ProviderType, slot.AppointmentDate)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load waitlist for provider ID: %d", slot.ProviderID)
+		return fmt.Errorf("failed to load waitlist: %w", err)
+	}
+	previous, err := s.waitlistRepo.ListOffersForSlot(ctx, slot.ProviderID, slot.ProviderType, slot.AppointmentDate, slot.StartTime.Time())
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load previous offers for provider ID: %d", slot.ProviderID)
+		return fmt.Errorf("failed to load waitlist offers: %w", err)
+	}
+
+	alreadyOffered := make(map[int]bool, len(previous))
+	for _, offer := range previous {
+		alreadyOffered[offer.EntryID] = true
+	}
+
+	var next *models.WaitlistEntry
+	for _, entry := range entries {
+		if !alreadyOffered[entry.ID] {
+			next = entry
+			break
+		}
+	}
+	if next == nil {
+		return nil
+	}
+
+	slot.EntryID = next.ID
+	slot.PatientID = next.PatientID
+	slot.Status = models.OfferStatusPending
+	slot.ExpiresAt = time.Now().Add(waitlistHoldDuration)
+	if err := s.waitlistRepo.CreateOffer(ctx, slot); err != nil {
+		s.logger.WithError(err).Errorf("Failed to create waitlist offer for entry ID: %d", next.ID)
+		return fmt.Errorf("failed to create waitlist offer: %w", err)
+	}
+	if err := s.waitlistRepo.UpdateEntryStatus(ctx, next.ID, models.WaitlistStatusOffered); err != nil {
+		s.logger.WithError(err).Errorf("Failed to mark waitlist entry %d as offered", next.ID)
+		return fmt.Errorf("failed to update waitlist entry: %w", err)
+	}
+
+	providerLoc, err := s.timeZones.Location(ctx, slot.ProviderID)
+	if err != nil {
+		providerLoc = time.UTC
+	}
+	notification := &models.Notification{
+		UserID:           next.PatientID,
+		NotificationType: "waitlist_offer",
+		Message: fmt.Sprintf("A slot opened on %s. It is held for you until %s; accept the offer to book it.",
+			s.timeZones.LocalTime(ctx, next.PatientID, offerRange(slot, providerLoc).Start).Format(notificationTimeLayout),
+			s.timeZones.LocalTime(ctx, next.PatientID, slot.ExpiresAt).Format("15:04 MST")),
+	}
+	if err := s.notificationService.CreateNotification(ctx, notification); err != nil {
+		s.logger.WithError(err).Errorf("Failed to notify patient %d of waitlist offer %d", next.PatientID, slot.ID)
+	}
+
+	s.logger.Infof("Waitlist offer %d created for entry %d", slot.ID, next.ID)
+	return nil
+}
+
+// offerRange returns the absolute time range of an offered slot, whose date and times are
+// wall-clock values in the provider's zone loc
+func offerRange(offer *models.WaitlistOffer, loc *time.Location) timeRange {
+	day := dateIn(offer.AppointmentDate, loc)
+	return timeRange{Start: onDate(day, offer.StartTime.Time()), End: onDate(day, offer.EndTime.Time())}
+}
+
+// appointmentProviderID returns the doctor or home care provider ID of an appointment, or 0 if neither is set
+func appointmentProviderID(appointment *models.Appointment) int {
+	if appointment.DoctorID != nil {
+		return *appointment.DoctorID
+	}
+	if appointment.HomeCareProviderID != nil {
+		return *appointment.HomeCareProviderID
+	}
+	return 0
+}
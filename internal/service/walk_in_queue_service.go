@@ -0,0 +1,382 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"shifa/internal/models"
+	"shifa/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultConsultationMinutes estimates waits until a doctor has completed consultations to average
+const defaultConsultationMinutes = 15.0
+
+// consultationAverageLookback limits the average to recent consultations, so it follows changes in pace
+const consultationAverageLookback = 30 * 24 * time.Hour
+
+var (
+	ErrAlreadyQueued   = errors.New("patient is already in this doctor's queue")
+	ErrNotQueueDoctor  = errors.New("only the queue's doctor can call or serve its patients")
+	ErrNotQueuePatient = errors.New("only the patient can leave their place in the queue")
+)
+
+type WalkInQueueService struct {
+	queueRepo           repository.WalkInQueueRepository
+	appointmentRepo     repository.AppointmentRepository
+	doctorRepo          repository.DoctorRepository
+	notificationService NotificationService
+	timeZones           *TimeZoneService
+	hub                 *queueHub
+	logger              *logrus.Logger
+}
+
+func NewWalkInQueueService(
+	queueRepo repository.WalkInQueueRepository,
+	appointmentRepo repository.AppointmentRepository,
+	doctorRepo repository.DoctorRepository,
+	notificationService NotificationService,
+	timeZones *TimeZoneService,
+	logger *logrus.Logger,
+) *WalkInQueueService {
+	return &WalkInQueueService{
+		queueRepo:           queueRepo,
+		appointmentRepo:     appointmentRepo,
+		doctorRepo:          doctorRepo,
+		notificationService: notificationService,
+		timeZones:           timeZones,
+		hub:                 newQueueHub(),
+		logger:              logger,
+	}
+}
+
+// Today returns the current date in the doctor's time zone, which names the doctor's queue
+func (s *WalkInQueueService) Today(ctx context.Context, doctorID int) (time.Time, error) {
+	loc, err := s.timeZones.Location(ctx, doctorID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return civilDate(time.Now().In(loc)), nil
+}
+
+// Join adds a patient to the end of the doctor's queue for today and returns the entry
+// with its position and estimated wait
+func (s *WalkInQueueService) Join(ctx context.Context, doctorID, patientID int, source string) (*models.QueueEntry, error) {
+	if patientID == 0 {
+		return nil, errors.New("patient ID is required")
+	}
+	if source == "" {
+		source = models.QueueSourceRemote
+	}
+	if source != models.QueueSourceRemote && source != models.QueueSourceReception {
+		return nil, fmt.Errorf("source must be %q or %q", models.QueueSourceRemote, models.QueueSourceReception)
+	}
+
+	doctor, err := s.doctorRepo.GetByID(ctx, doctorID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid doctor_id: %w", err)
+	}
+	if !doctor.IsAvailable || doctor.Status != "active" {
+		return nil, errors.New("doctor is not available")
+	}
+
+	today, err := s.Today(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.queueRepo.GetActiveForPatient(ctx, doctorID, today, patientID)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to check queue of doctor ID: %d", doctorID)
+		return nil, fmt.Errorf("failed to check queue: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrAlreadyQueued
+	}
+
+	entry := &models.QueueEntry{
+		DoctorID:  doctorID,
+		PatientID: patientID,
+		QueueDate: today,
+		Source:    source,
+		Status:    models.QueueStatusWaiting,
+	}
+	if err := s.queueRepo.Join(ctx, entry); err != nil {
+		s.logger.WithError(err).Errorf("Failed to join queue of doctor ID: %d", doctorID)
+		return nil, fmt.Errorf("failed to join queue: %w", err)
+	}
+
+	s.logger.Infof("Patient %d joined the queue of doctor %d with ticket %d", patientID, doctorID, entry.TicketNumber)
+	s.queueChanged(ctx, doctorID, today)
+	return s.GetEntry(ctx, entry.ID)
+}
+
+// GetEntry returns a queue entry; active entries carry their current position and estimated wait
+func (s *WalkInQueueService) GetEntry(ctx context.Context, id int) (*models.QueueEntry, error) {
+	entry, err := s.queueRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Status != models.QueueStatusWaiting {
+		return entry, nil
+	}
+
+	snapshot, err := s.Snapshot(ctx, entry.DoctorID, entry.QueueDate)
+	if err != nil {
+		return nil, err
+	}
+	for _, waiting := range snapshot.Waiting {
+		if waiting.ID == entry.ID {
+			return waiting, nil
+		}
+	}
+	return entry, nil
+}
+
+// Snapshot returns the doctor's queue on date with positions and estimated waits. Waits
+// assume each patient takes the doctor's average consultation time and skip over booked
+// appointments, which keep their slots.
+func (s *WalkInQueueService) Snapshot(ctx context.Context, doctorID int, date time.Time) (*models.QueueSnapshot, error) {
+	entries, err := s.queueRepo.ListActive(ctx, doctorID, date)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load queue of doctor ID: %d", doctorID)
+		return nil, fmt.Errorf("failed to load queue: %w", err)
+	}
+
+	now := time.Now()
+	average, err := s.queueRepo.AverageConsultationMinutes(ctx, doctorID, now.Add(-consultationAverageLookback))
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to average consultations of doctor ID: %d", doctorID)
+		return nil, fmt.Errorf("failed to estimate wait times: %w", err)
+	}
+	if average <= 0 {
+		average = defaultConsultationMinutes
+	}
+	consultation := time.Duration(average * float64(time.Minute))
+
+	booked, err := s.bookedRanges(ctx, doctorID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &models.QueueSnapshot{
+		DoctorID:                   doctorID,
+		QueueDate:                  date,
+		AverageConsultationMinutes: math.Round(average*10) / 10,
+		Waiting:                    []*models.QueueEntry{},
+	}
+
+	next := now
+	for _, entry := range entries {
+		if entry.Status == models.QueueStatusCalled {
+			snapshot.Serving = entry
+			if ends := entry.CalledAt.Time.Add(consultation); ends.After(next) {
+				next = ends
+			}
+		}
+	}
+	for _, entry := range entries {
+		if entry.Status != models.QueueStatusWaiting {
+			continue
+		}
+		next = firstFreeStart(next, consultation, booked)
+		entry.Position = len(snapshot.Waiting) + 1
+		entry.EstimatedWaitMinutes = int(math.Ceil(next.Sub(now).Minutes()))
+		snapshot.Waiting = append(snapshot.Waiting, entry)
+		next = next.Add(consultation)
+	}
+	return snapshot, nil
+}
+
+// CallNext finishes the patient currently being seen and calls the next one in line, as the
+// queue's doctor. It returns nil when nobody is waiting.
+func (s *WalkInQueueService) CallNext(ctx context.Context, doctorID, callerID int) (*models.QueueEntry, error) {
+	if callerID != doctorID {
+		return nil, ErrNotQueueDoctor
+	}
+	today, err := s.Today(ctx, doctorID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := s.queueRepo.CallNext(ctx, doctorID, today)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to call next patient of doctor ID: %d", doctorID)
+		return nil, fmt.Errorf("failed to call next patient: %w", err)
+	}
+
+	if entry != nil {
+		notification := &models.Notification{
+			UserID:           entry.PatientID,
+			NotificationType: "queue_called",
+			Message:          fmt.Sprintf("Ticket %d: the doctor is ready to see you now.", entry.TicketNumber),
+		}
+		if err := s.notificationService.CreateNotification(ctx, notification); err != nil {
+			s.logger.WithError(err).Errorf("Failed to notify patient %d of queue call", entry.PatientID)
+		}
+	}
+
+	s.queueChanged(ctx, doctorID, today)
+	return entry, nil
+}
+
+// Leave removes the patient from the queue before they are seen
+func (s *WalkInQueueService) Leave(ctx context.Context, id, patientID int) error {
+	entry, err := s.queueRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if entry.PatientID != patientID {
+		return ErrNotQueuePatient
+	}
+	return s.finish(ctx, entry, models.QueueStatusLeft)
+}
+
+// Complete marks the patient being seen as served without calling the next one, as the
+// queue's doctor
+func (s *WalkInQueueService) Complete(ctx context.Context, id, doctorID int) error {
+	entry, err := s.queueRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if entry.DoctorID != doctorID {
+		return ErrNotQueueDoctor
+	}
+	return s.finish(ctx, entry, models.QueueStatusServed)
+}
+
+// Subscribe returns a channel signalled whenever the doctor's queue on date changes, and a
+// function that ends the subscription
+func (s *WalkInQueueService) Subscribe(doctorID int, date time.Time) (<-chan struct{}, func()) {
+	key := queueKey{doctorID: doctorID, date: date.Format("2006-01-02")}
+	ch := s.hub.subscribe(key)
+	return ch, func() { s.hub.unsubscribe(key, ch) }
+}
+
+func (s *WalkInQueueService) finish(ctx context.Context, entry *models.QueueEntry, status string) error {
+	if err := s.queueRepo.Finish(ctx, entry.ID, status); err != nil {
+		s.logger.WithError(err).Errorf("Failed to close queue entry with ID: %d", entry.ID)
+		return fmt.Errorf("failed to update queue entry: %w", err)
+	}
+	s.queueChanged(ctx, entry.DoctorID, entry.QueueDate)
+	return nil
+}
+
+// queueChanged pushes the change to live subscribers and tells the patient now first in
+// line that they are next, once
+func (s *WalkInQueueService) queueChanged(ctx context.Context, doctorID int, date time.Time) {
+	s.hub.publish(queueKey{doctorID: doctorID, date: date.Format("2006-01-02")})
+
+	entries, err := s.queueRepo.ListActive(ctx, doctorID, date)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load queue of doctor ID: %d", doctorID)
+		return
+	}
+	for _, entry := range entries {
+		if entry.Status != models.QueueStatusWaiting {
+			continue
+		}
+		first, err := s.queueRepo.MarkNextNotified(ctx, entry.ID)
+		if err != nil {
+			s.logger.WithError(err).Errorf("Failed to mark queue entry %d as notified", entry.ID)
+			return
+		}
+		if first {
+			notification := &models.Notification{
+				UserID:           entry.PatientID,
+				NotificationType: "queue_next",
+				Message:          fmt.Sprintf("Ticket %d: you're next. Please be ready to see the doctor.", entry.TicketNumber),
+			}
+			if err := s.notificationService.CreateNotification(ctx, notification); err != nil {
+				s.logger.WithError(err).Errorf("Failed to notify patient %d that they are next", entry.PatientID)
+			}
+		}
+		return
+	}
+}
+
+// bookedRanges returns the doctor's scheduled appointments on date, in start order
+func (s *WalkInQueueService) bookedRanges(ctx context.Context, doctorID int, date time.Time) ([]timeRange, error) {
+	appointments, err := s.appointmentRepo.GetByProviderAndDateRange(ctx, doctorID, "doctor", date, date)
+	if err != nil {
+		s.logger.WithError(err).Errorf("Failed to load appointments for doctor ID: %d", doctorID)
+		return nil, fmt.Errorf("failed to load appointments: %w", err)
+	}
+
+	var booked []timeRange
+	for _, appointment := range appointments {
+		if holdsSlot(appointment) {
+			booked = append(booked, appointmentRange(appointment))
+		}
+	}
+	sort.Slice(booked, func(i, j int) bool { return booked[i].Start.Before(booked[j].Start) })
+	return booked, nil
+}
+
+// firstFreeStart returns the earliest time from start at which a consultation of the given
+// length fits between the sorted booked ranges
+func firstFreeStart(start time.Time, length time.Duration, booked []timeRange) time.Time {
+	for _, b := range booked {
+		if (timeRange{Start: start, End: start.Add(length)}).overlaps(b) {
+			start = b.End
+		}
+	}
+	return start
+}
+
+type queueKey struct {
+	doctorID int
+	date     string
+}
+
+// queueHub fans change signals out to live subscribers of a queue. It is in-process, so
+// subscribers only hear about changes made through this instance; streams also refresh
+// periodically to pick up the rest.
+type queueHub struct {
+	mu          sync.Mutex
+	subscribers map[queueKey]map[chan struct{}]struct{}
+}
+
+func newQueueHub() *queueHub {
+	return &queueHub{subscribers: make(map[queueKey]map[chan struct{}]struct{})}
+}
+
+func (h *queueHub) subscribe(key queueKey) chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// A buffer of one coalesces bursts of changes into a single refresh
+	ch := make(chan struct{}, 1)
+	if h.subscribers[key] == nil {
+		h.subscribers[key] = make(map[chan struct{}]struct{})
+	}
+	h.subscribers[key][ch] = struct{}{}
+	return ch
+}
+
+func (h *queueHub) unsubscribe(key queueKey, ch chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subscribers[key], ch)
+	if len(h.subscribers[key]) == 0 {
+		delete(h.subscribers, key)
+	}
+}
+
+func (h *queueHub) publish(key queueKey) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[key] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
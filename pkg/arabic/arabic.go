@@ -0,0 +1,352 @@
+// pkg/arabic/arabic.go
+
+// Package arabic prepares Arabic text for renderers that draw glyphs one by one from left to
+// right, such as gofpdf: Shape picks the contextual letter forms and Visual reorders a line
+// for display.
+package arabic
+
+import (
+	"sort"
+	"unicode"
+)
+
+// form holds a letter's isolated presentation form; the final, initial and medial forms
+// follow it in that order. Letters that do not join to the next letter only have the
+// isolated and final forms.
+type form struct {
+	isolated rune
+	dual     bool
+}
+
+var forms = map[rune]form{
+	'ء': {0xFE80, false}, // hamza, which never joins; see joinsPrevious
+	'آ': {0xFE81, false},
+	'أ': {0xFE83, false},
+	'ؤ': {0xFE85, false},
+	'إ': {0xFE87, false},
+	'ئ': {0xFE89, true},
+	'ا': {0xFE8D, false},
+	'ب': {0xFE8F, true},
+	'ة': {0xFE93, false},
+	'ت': {0xFE95, true},
+	'ث': {0xFE99, true},
+	'ج': {0xFE9D, true},
+	'ح': {0xFEA1, true},
+	'خ': {0xFEA5, true},
+	'د': {0xFEA9, false},
+	'ذ': {0xFEAB, false},
+	'ر': {0xFEAD, false},
+	'ز': {0xFEAF, false},
+	'س': {0xFEB1, true},
+	'ش': {0xFEB5, true},
+	'ص': {0xFEB9, true},
+	'ض': {0xFEBD, true},
+	'ط': {0xFEC1, true},
+	'ظ': {0xFEC5, true},
+	'ع': {0xFEC9, true},
+	'غ': {0xFECD, true},
+	'ف': {0xFED1, true},
+	'ق': {0xFED5, true},
+	'ك': {0xFED9, true},
+	'ل': {0xFEDD, true},
+	'م': {0xFEE1, true},
+	'ن': {0xFEE5, true},
+	'ه': {0xFEE9, true},
+	'و': {0xFEED, false},
+	'ى': {0xFEEF, false},
+	'ي': {0xFEF1, true},
+}
+
+// lamAlef maps the alef that follows a lam to the isolated form of their ligature; the final
+// form follows it
+var lamAlef = map[rune]rune{
+	'آ': 0xFEF5,
+	'أ': 0xFEF7,
+	'إ': 0xFEF9,
+	'ا': 0xFEFB,
+}
+
+const (
+	lam     = 'ل'
+	tatweel = 'ـ'
+)
+
+// Shape replaces Arabic letters with the presentation form their neighbours call for and
+// joins lam-alef pairs into their ligature. Other text is returned unchanged.
+func Shape(s string) string {
+	runes := []rune(s)
+	out := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		prev := joinsPrevious(runes, i)
+
+		if r == lam {
+			if next := nextLetter(runes, i); next >= 0 {
+				if ligature, ok := lamAlef[runes[next]]; ok {
+					if prev {
+						ligature++
+					}
+					out = append(out, ligature)
+					// Keep the marks between lam and alef; the alef itself is consumed
+					out = append(out, runes[i+1:next]...)
+					i = next
+					continue
+				}
+			}
+		}
+
+		f, ok := forms[r]
+		if !ok {
+			out = append(out, r)
+			continue
+		}
+		next := f.dual && joinsNext(runes, i)
+		switch {
+		case prev && next:
+			out = append(out, f.isolated+3)
+		case next:
+			out = append(out, f.isolated+2)
+		case prev:
+			out = append(out, f.isolated+1)
+		default:
+			out = append(out, f.isolated)
+		}
+	}
+	return string(out)
+}
+
+// joinsPrevious reports whether the letter at i connects to the letter before it
+func joinsPrevious(runes []rune, i int) bool {
+	if _, ok := forms[runes[i]]; !ok && runes[i] != tatweel || runes[i] == 'ء' {
+		return false
+	}
+	for j := i - 1; j >= 0; j-- {
+		if isMark(runes[j]) {
+			continue
+		}
+		if runes[j] == tatweel {
+			return true
+		}
+		f, ok := forms[runes[j]]
+		return ok && f.dual
+	}
+	return false
+}
+
+// joinsNext reports whether a letter that can join forward has a letter after it to join to
+func joinsNext(runes []rune, i int) bool {
+	next := nextLetter(runes, i)
+	if next < 0 {
+		return false
+	}
+	if runes[next] == tatweel {
+		return true
+	}
+	_, ok := forms[runes[next]]
+	return ok && runes[next] != 'ء'
+}
+
+// nextLetter returns the index of the first non-mark after i, or -1
+func nextLetter(runes []rune, i int) int {
+	for j := i + 1; j < len(runes); j++ {
+		if !isMark(runes[j]) {
+			return j
+		}
+	}
+	return -1
+}
+
+// isMark reports whether r is a vowel mark, which sits on its letter without affecting joining
+func isMark(r rune) bool {
+	return unicode.Is(unicode.Mn, r)
+}
+
+// StartsRTL reports whether the first letter of s is right-to-left, which makes it a
+// right-to-left paragraph. Digits do not count as letters; fallback is returned when s has
+// no letters.
+func StartsRTL(s string, fallback bool) bool {
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			continue
+		}
+		switch direction(r) {
+		case rtl:
+			return true
+		case ltr:
+			return false
+		}
+	}
+	return fallback
+}
+
+type class int
+
+const (
+	neutral class = iota
+	ltr
+	rtl
+)
+
+func direction(r rune) class {
+	switch {
+	case unicode.IsDigit(r):
+		// Numbers, Arabic-Indic ones included, always read left to right
+		return ltr
+	case unicode.In(r, unicode.Arabic, unicode.Hebrew):
+		if isMark(r) || unicode.IsPunct(r) {
+			return neutral
+		}
+		return rtl
+	case unicode.IsLetter(r):
+		return ltr
+	}
+	return neutral
+}
+
+var mirrored = map[rune]rune{'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{', '<': '>', '>': '<', '«': '»', '»': '«'}
+
+// Visual reorders one line of logical text for left-to-right drawing, in a right-to-left
+// paragraph when rtlParagraph is set. Runs in the other direction keep their own reading
+// order, and neutral characters between two runs take the paragraph's direction. Lines must
+// be shaped first and wrapped before reordering; this is a simplified form of the Unicode
+// bidirectional algorithm without explicit embeddings.
+func Visual(line string, rtlParagraph bool) string {
+	runes := []rune(line)
+	base := ltr
+	if rtlParagraph {
+		base = rtl
+	}
+
+	levels := make([]class, len(runes))
+	for i, r := range runes {
+		levels[i] = direction(r)
+	}
+	resolveBrackets(runes, levels, base)
+	for i := 0; i < len(levels); {
+		if levels[i] != neutral {
+			i++
+			continue
+		}
+		j := i
+		for j < len(levels) && levels[j] == neutral {
+			j++
+		}
+		before, after := base, base
+		if i > 0 {
+			before = levels[i-1]
+		}
+		if j < len(levels) {
+			after = levels[j]
+		}
+		resolved := base
+		if before == after {
+			resolved = before
+		}
+		for k := i; k < j; k++ {
+			levels[k] = resolved
+		}
+		i = j
+	}
+
+	out := make([]rune, 0, len(runes))
+	if !rtlParagraph {
+		for i := 0; i < len(runes); {
+			j := i
+			for j < len(runes) && levels[j] == levels[i] {
+				j++
+			}
+			if levels[i] == rtl {
+				out = appendReversed(out, runes[i:j])
+			} else {
+				out = append(out, runes[i:j]...)
+			}
+			i = j
+		}
+		return string(out)
+	}
+
+	for j := len(runes); j > 0; {
+		i := j
+		for i > 0 && levels[i-1] == levels[j-1] {
+			i--
+		}
+		if levels[j-1] == ltr {
+			out = append(out, runes[i:j]...)
+		} else {
+			out = appendReversed(out, runes[i:j])
+		}
+		j = i
+	}
+	return string(out)
+}
+
+// resolveBrackets gives both brackets of a pair one direction, so they mirror together: the
+// paragraph's when it occurs inside them, otherwise the other direction when both the
+// content and the text before the opening bracket have it
+func resolveBrackets(runes []rune, levels []class, base class) {
+	var openers []int
+	var pairs [][2]int
+	for i, r := range runes {
+		switch r {
+		case '(', '[', '{':
+			openers = append(openers, i)
+		case ')', ']', '}':
+			for k := len(openers) - 1; k >= 0; k-- {
+				if mirrored[runes[openers[k]]] == r {
+					pairs = append(pairs, [2]int{openers[k], i})
+					openers = openers[:k]
+					break
+				}
+			}
+		}
+	}
+	// Resolve outer pairs first, as they are the context of the pairs inside them
+	sort.Slice(pairs, func(a, b int) bool { return pairs[a][0] < pairs[b][0] })
+
+	for _, pair := range pairs {
+		opening, closing := pair[0], pair[1]
+		var inside [3]bool
+		for _, c := range levels[opening+1 : closing] {
+			inside[c] = true
+		}
+		resolved := neutral
+		switch {
+		case inside[base]:
+			resolved = base
+		case inside[ltr] || inside[rtl]:
+			resolved = base
+			context := base
+			for k := opening - 1; k >= 0; k-- {
+				if levels[k] != neutral {
+					context = levels[k]
+					break
+				}
+			}
+			if context != base {
+				resolved = context
+			}
+		}
+		if resolved != neutral {
+			levels[opening], levels[closing] = resolved, resolved
+		}
+	}
+}
+
+// appendReversed appends run in reverse order, mirroring brackets. Vowel marks stay after
+// their letter, where the renderer draws them over it.
+func appendReversed(out, run []rune) []rune {
+	for k := len(run) - 1; k >= 0; {
+		base := k
+		for base > 0 && isMark(run[base]) {
+			base--
+		}
+		r := run[base]
+		if m, ok := mirrored[r]; ok {
+			r = m
+		}
+		out = append(out, r)
+		out = append(out, run[base+1:k+1]...)
+		k = base - 1
+	}
+	return out
+}
@@ -16,6 +16,15 @@ const (
 	UploadDir     = "uploads"
 	ProfileImages = "profile_images" // This will create uploads/profile_images/
 	MaxFileSize   = 5 << 20          // 5MB
+
+	// PrivateUploadDir holds files that are only served through authenticated handlers,
+	// unlike UploadDir which is served as static files
+	PrivateUploadDir      = "private_uploads"
+	LabReports            = "lab_reports"            // This will create private_uploads/lab_reports/
+	ConsultationSummaries = "consultation_summaries" // Generated take-home summaries
+	ChatAttachments       = "chat_attachments"       // Files sent in consultation chats
+	ChatTranscripts       = "chat_transcripts"       // Signed chat transcripts
+	MaxDocumentSize       = 20 << 20                 // 20MB
 )
 
 var validImageTypes = map[string]bool{
@@ -27,23 +36,34 @@ var validImageTypes = map[string]bool{
 
 // SaveFile saves an uploaded file to the specified directory
 func SaveFile(file io.Reader, filename string, directory string) (string, error) {
+	return saveFile(UploadDir, file, filename, directory)
+}
+
+// SavePrivateFile saves an uploaded file to the specified directory under PrivateUploadDir
+func SavePrivateFile(file io.Reader, filename string, directory string) (string, error) {
+	return saveFile(PrivateUploadDir, file, filename, directory)
+}
+
+func saveFile(root string, file io.Reader, filename string, directory string) (string, error) {
 	// Create the upload directory if it doesn't exist
-	uploadPath := filepath.Join(UploadDir, directory)
+	uploadPath := filepath.Join(root, directory)
 	if err := os.MkdirAll(uploadPath, 0755); err != nil {
 		return "", fmt.Errorf("failed to create directory: %v", err)
 	}
 
-	// Generate a unique filename to prevent overwrites
+	// Generate a unique filename to prevent overwrites. Files with the same name saved in
+	// the same second, such as several attachments of one message, get a counter.
 	ext := filepath.Ext(filename)
 	baseFilename := strings.TrimSuffix(filename, ext)
 	timestamp := time.Now().Format("20060102150405")
 	newFilename := fmt.Sprintf("%s_%s%s", baseFilename, timestamp, ext)
 
-	// Create the full file path
-	filePath := filepath.Join(uploadPath, newFilename)
-
-	// Create the destination file
-	dst, err := os.Create(filePath)
+	// Create the destination file, never replacing an existing one
+	dst, err := os.OpenFile(filepath.Join(uploadPath, newFilename), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	for n := 2; os.IsExist(err); n++ {
+		newFilename = fmt.Sprintf("%s_%s_%d%s", baseFilename, timestamp, n, ext)
+		dst, err = os.OpenFile(filepath.Join(uploadPath, newFilename), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to create file: %v", err)
 	}
@@ -69,6 +89,16 @@ func GetFullPath(relativePath string) string {
 	return filepath.Join(UploadDir, relativePath)
 }
 
+// DeletePrivateFile removes a file from the private uploads directory
+func DeletePrivateFile(relativePath string) error {
+	return os.Remove(GetPrivatePath(relativePath))
+}
+
+// GetPrivatePath returns the full system path for a relative path stored by SavePrivateFile
+func GetPrivatePath(relativePath string) string {
+	return filepath.Join(PrivateUploadDir, relativePath)
+}
+
 // ValidateImage validates the image file
 func ValidateImage(file io.Reader, size int64, contentType string) error {
 	if size > MaxFileSize {
@@ -93,3 +123,54 @@ func ValidateImage(file io.Reader, size int64, contentType string) error {
 
 	return nil
 }
+
+// ValidatePDF validates a PDF document. The reader must be seekable, as it is rewound after
+// the header is checked so the whole file can be saved.
+func ValidatePDF(file io.ReadSeeker, size int64) error {
+	if size > MaxDocumentSize {
+		return fmt.Errorf("file size exceeds maximum limit of %d bytes", MaxDocumentSize)
+	}
+
+	buffer := make([]byte, 512)
+	n, err := file.Read(buffer)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read file header: %v", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind file: %v", err)
+	}
+
+	if detectedType := http.DetectContentType(buffer[:n]); detectedType != "application/pdf" {
+		return fmt.Errorf("invalid file content type: %s", detectedType)
+	}
+
+	return nil
+}
+
+// ValidateAttachment checks a chat attachment's size and sniffed content, which must be an
+// image (up to MaxFileSize) or a PDF (up to MaxDocumentSize), and returns its content type.
+// The declared type is ignored. The reader is rewound so the whole file can be saved.
+func ValidateAttachment(file io.ReadSeeker, size int64) (string, error) {
+	buffer := make([]byte, 512)
+	n, err := file.Read(buffer)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read file header: %v", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind file: %v", err)
+	}
+
+	detectedType := http.DetectContentType(buffer[:n])
+	limit := int64(MaxFileSize)
+	switch {
+	case detectedType == "application/pdf":
+		limit = MaxDocumentSize
+	case !validImageTypes[detectedType]:
+		return "", fmt.Errorf("invalid file content type: %s", detectedType)
+	}
+	if size > limit {
+		return "", fmt.Errorf("file size exceeds maximum limit of %d bytes", limit)
+	}
+
+	return detectedType, nil
+}
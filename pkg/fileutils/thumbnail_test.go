@@ -0,0 +1,142 @@
+package fileutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodeGIF(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("encode gif: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func filled(w, h int, c color.Color) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestThumbnailSize(t *testing.T) {
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	tests := []struct {
+		name         string
+		data         func(t *testing.T) []byte
+		wantW, wantH int
+	}{
+		{"landscape", func(t *testing.T) []byte { return encodePNG(t, filled(640, 480, red)) }, 320, 240},
+		{"portrait", func(t *testing.T) []byte { return encodePNG(t, filled(480, 640, red)) }, 240, 320},
+		{"small image keeps its size", func(t *testing.T) []byte { return encodePNG(t, filled(100, 50, red)) }, 100, 50},
+		{"thin strip keeps a pixel", func(t *testing.T) []byte { return encodePNG(t, filled(1000, 2, red)) }, 320, 1},
+		{"gif", func(t *testing.T) []byte { return encodeGIF(t, filled(400, 400, red)) }, 320, 320},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Thumbnail(bytes.NewReader(tt.data(t)), ThumbnailSize)
+			if err != nil {
+				t.Fatalf("Thumbnail: %v", err)
+			}
+			img, err := jpeg.Decode(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("thumbnail is not a JPEG: %v", err)
+			}
+			if got := img.Bounds().Size(); got.X != tt.wantW || got.Y != tt.wantH {
+				t.Errorf("size = %dx%d, want %dx%d", got.X, got.Y, tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestThumbnailColours(t *testing.T) {
+	tests := []struct {
+		name string
+		fill color.Color
+		want color.NRGBA
+	}{
+		{"opaque", color.NRGBA{R: 0x20, G: 0x80, B: 0xc0, A: 0xff}, color.NRGBA{R: 0x20, G: 0x80, B: 0xc0}},
+		{"transparent becomes white", color.NRGBA{}, color.NRGBA{R: 0xff, G: 0xff, B: 0xff}},
+		{"half transparent black becomes grey", color.NRGBA{A: 0x80}, color.NRGBA{R: 0x7f, G: 0x7f, B: 0x7f}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Thumbnail(bytes.NewReader(encodePNG(t, filled(64, 64, tt.fill))), 16)
+			if err != nil {
+				t.Fatalf("Thumbnail: %v", err)
+			}
+			img, err := jpeg.Decode(bytes.NewReader(out))
+			if err != nil {
+				t.Fatalf("thumbnail is not a JPEG: %v", err)
+			}
+			got := color.NRGBAModel.Convert(img.At(8, 8)).(color.NRGBA)
+			// JPEG is lossy, so allow a little drift
+			near := func(a, b uint8) bool { return int(a)-int(b) <= 4 && int(b)-int(a) <= 4 }
+			if !near(got.R, tt.want.R) || !near(got.G, tt.want.G) || !near(got.B, tt.want.B) {
+				t.Errorf("pixel = %v, want about %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThumbnailRejects(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            func(t *testing.T) []byte
+		wantNoThumbnail bool
+	}{
+		{"webp", func(t *testing.T) []byte { return []byte("RIFF\x24\x00\x00\x00WEBPVP8 ") }, true},
+		{"not an image", func(t *testing.T) []byte { return []byte("%PDF-1.7\n") }, true},
+		{
+			// The GIF header claims 8000x8000, past the pixel limit, without the pixels behind it
+			"too many pixels",
+			func(t *testing.T) []byte {
+				data := encodeGIF(t, filled(2, 2, color.Black))
+				binary.LittleEndian.PutUint16(data[6:], 8000)
+				binary.LittleEndian.PutUint16(data[8:], 8000)
+				return data
+			},
+			false,
+		},
+		{
+			"truncated",
+			func(t *testing.T) []byte {
+				data := encodePNG(t, filled(64, 64, color.White))
+				return data[:len(data)/2]
+			},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Thumbnail(bytes.NewReader(tt.data(t)), ThumbnailSize)
+			if err == nil {
+				t.Fatalf("Thumbnail returned %d bytes, want an error", len(out))
+			}
+			if got := errors.Is(err, ErrNoThumbnail); got != tt.wantNoThumbnail {
+				t.Errorf("err = %v, ErrNoThumbnail %v, want %v", err, got, tt.wantNoThumbnail)
+			}
+		})
+	}
+}
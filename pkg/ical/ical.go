@@ -0,0 +1,123 @@
+// pkg/ical/ical.go
+
+// Package ical writes RFC 5545 iCalendar documents with VEVENT components.
+package ical
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+
+	productID     = "-//Shifa//Appointments//EN"
+	utcFormat     = "20060102T150405Z"
+	localFormat   = "20060102T150405"
+	maxLineOctets = 75
+)
+
+// Event is one calendar entry. Clients match events by UID and apply the copy with the
+// highest Sequence, so Sequence must grow every time the event is rescheduled or cancelled.
+type Event struct {
+	UID          string
+	Sequence     int
+	Start        time.Time
+	End          time.Time
+	Summary      string
+	Description  string
+	Location     string
+	Status       string
+	LastModified time.Time
+	// TZID names the zone Start and End are expressed in; when empty they are written in UTC
+	TZID string
+}
+
+// Calendar is a VCALENDAR document
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// String renders the calendar with CRLF line endings and folded long lines
+func (c Calendar) String() string {
+	var b strings.Builder
+	stamp := time.Now().UTC().Format(utcFormat)
+
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:"+productID)
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	writeLine(&b, "METHOD:PUBLISH")
+	if c.Name != "" {
+		writeLine(&b, "X-WR-CALNAME:"+escapeText(c.Name))
+	}
+
+	for _, e := range c.Events {
+		writeLine(&b, "BEGIN:VEVENT")
+		writeLine(&b, "UID:"+e.UID)
+		writeLine(&b, fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+		writeLine(&b, "DTSTAMP:"+stamp)
+		writeLine(&b, formatDateTime("DTSTART", e.Start, e.TZID))
+		writeLine(&b, formatDateTime("DTEND", e.End, e.TZID))
+		writeLine(&b, "SUMMARY:"+escapeText(e.Summary))
+		if e.Description != "" {
+			writeLine(&b, "DESCRIPTION:"+escapeText(e.Description))
+		}
+		if e.Location != "" {
+			writeLine(&b, "LOCATION:"+escapeText(e.Location))
+		}
+		if e.Status != "" {
+			writeLine(&b, "STATUS:"+e.Status)
+		}
+		if !e.LastModified.IsZero() {
+			writeLine(&b, "LAST-MODIFIED:"+e.LastModified.UTC().Format(utcFormat))
+		}
+		writeLine(&b, "END:VEVENT")
+	}
+
+	writeLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+func formatDateTime(name string, t time.Time, tzid string) string {
+	if tzid == "" {
+		return name + ":" + t.UTC().Format(utcFormat)
+	}
+	return name + ";TZID=" + tzid + ":" + t.Format(localFormat)
+}
+
+// escapeText escapes the characters that are special in TEXT property values
+func escapeText(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, ";", `\;`)
+	s = strings.ReplaceAll(s, ",", `\,`)
+	s = strings.ReplaceAll(s, "\r\n", `\n`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return s
+}
+
+// writeLine writes a content line, folding it so no physical line exceeds 75 octets
+// and multi-byte UTF-8 characters are never split
+func writeLine(b *strings.Builder, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// continuation lines start with a space, which counts towards the limit
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
@@ -0,0 +1,115 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "Check-up with Dr. Amal", "Check-up with Dr. Amal"},
+		{"comma", "Riyadh, Saudi Arabia", `Riyadh\, Saudi Arabia`},
+		{"semicolon", "fasting; bring results", `fasting\; bring results`},
+		{"backslash", `C:\reports`, `C:\\reports`},
+		{"newline", "line one\nline two", `line one\nline two`},
+		{"crlf", "line one\r\nline two", `line one\nline two`},
+		{"backslash before comma", `a\,b`, `a\\\,b`},
+		{"arabic", "موعد، الرياض", "موعد، الرياض"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeText(tt.in); got != tt.want {
+				t.Errorf("escapeText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"short", "SUMMARY:Visit", []string{"SUMMARY:Visit"}},
+		{"exactly 75 octets", strings.Repeat("a", 75), []string{strings.Repeat("a", 75)}},
+		{"76 octets", strings.Repeat("a", 76), []string{strings.Repeat("a", 75), " a"}},
+		{
+			"two folds",
+			strings.Repeat("a", 75+74+10),
+			[]string{strings.Repeat("a", 75), " " + strings.Repeat("a", 74), " " + strings.Repeat("a", 10)},
+		},
+		{
+			// 74 ASCII octets leave one octet, too few for the two-octet rune that follows
+			"multi-byte rune at the fold",
+			strings.Repeat("a", 74) + "éb",
+			[]string{strings.Repeat("a", 74), " éb"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			writeLine(&b, tt.line)
+			want := strings.Join(tt.want, "\r\n") + "\r\n"
+			if got := b.String(); got != want {
+				t.Errorf("writeLine = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteLineKeepsLinesShortAndRunesWhole(t *testing.T) {
+	lines := []string{
+		"DESCRIPTION:" + strings.Repeat("موعد ", 60),
+		"LOCATION:" + strings.Repeat("x€", 100),
+		"SUMMARY:" + strings.Repeat("🙂", 40),
+	}
+	for _, line := range lines {
+		var b strings.Builder
+		writeLine(&b, line)
+		out := strings.TrimSuffix(b.String(), "\r\n")
+
+		var unfolded strings.Builder
+		for i, physical := range strings.Split(out, "\r\n") {
+			if len(physical) > maxLineOctets {
+				t.Errorf("physical line of %d octets: %q", len(physical), physical)
+			}
+			if !utf8.ValidString(physical) {
+				t.Errorf("physical line splits a rune: %q", physical)
+			}
+			if i > 0 {
+				physical = strings.TrimPrefix(physical, " ")
+			}
+			unfolded.WriteString(physical)
+		}
+		if unfolded.String() != line {
+			t.Errorf("unfolding does not restore the line")
+		}
+	}
+}
+
+func TestFormatDateTime(t *testing.T) {
+	riyadh := time.FixedZone("AST", 3*60*60)
+	start := time.Date(2024, 3, 4, 9, 30, 0, 0, riyadh)
+	tests := []struct {
+		name string
+		tzid string
+		want string
+	}{
+		{"utc", "", "DTSTART:20240304T063000Z"},
+		{"local", "Asia/Riyadh", "DTSTART;TZID=Asia/Riyadh:20240304T093000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatDateTime("DTSTART", start, tt.tzid); got != tt.want {
+				t.Errorf("formatDateTime = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
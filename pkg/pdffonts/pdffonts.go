@@ -0,0 +1,28 @@
+// pkg/pdffonts/pdffonts.go
+
+// Package pdffonts embeds the Unicode fonts used for generated PDFs. The PDF core fonts only
+// cover Latin-1, so documents with Arabic text are set in DejaVu Sans, which also covers the
+// Arabic presentation forms. See LICENSE for the font licence.
+package pdffonts
+
+import (
+	_ "embed"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Family is the font family name Register adds
+const Family = "DejaVu"
+
+var (
+	//go:embed DejaVuSans.ttf
+	regular []byte
+	//go:embed DejaVuSans-Bold.ttf
+	bold []byte
+)
+
+// Register adds the regular and bold styles of Family to the document
+func Register(pdf *gofpdf.Fpdf) {
+	pdf.AddUTF8FontFromBytes(Family, "", regular)
+	pdf.AddUTF8FontFromBytes(Family, "B", bold)
+}
@@ -551,7 +551,8 @@ BEGIN
         SELECT COUNT(*) INTO v_provider_available
         FROM doctor_availability
         WHERE doctor_id = p_provider_id
-          AND day_of_week = WEEKDAY(p_appointment_date)
+          -- day_of_week counts from 0 = Sunday, like Go's time.Weekday
+          AND day_of_week = DAYOFWEEK(p_appointment_date) - 1
           AND start_time <= p_start_time
           AND end_time >= p_end_time;
     ELSE
@@ -576,11 +577,13 @@ BEGIN
             SET p_error_message = 'There is a conflicting appointment at the selected time.';
         ELSE
             -- Book the appointment
-            INSERT INTO appointments (patient_id, provider_type, doctor_id, home_care_provider_id, appointment_date, start_time, end_time)
+            -- The date and times are wall-clock values in the provider's zone
+            INSERT INTO appointments (patient_id, provider_type, doctor_id, home_care_provider_id, appointment_date, start_time, end_time, time_zone)
             VALUES (p_patient_id, p_provider_type, 
                     IF(p_provider_type = 'doctor', p_provider_id, NULL),
                     IF(p_provider_type = 'home_care_provider', p_provider_id, NULL),
-                    p_appointment_date, p_start_time, p_end_time);
+                    p_appointment_date, p_start_time, p_end_time,
+                    (SELECT time_zone FROM users WHERE id = p_provider_id));
             
             SET p_appointment_id = LAST_INSERT_ID();
             SET p_error_message = NULL;
@@ -979,4 +982,615 @@ BEGIN
         END ASC,
         d.rating DESC
     LIMIT 100;
-END;
\ No newline at end of file
+END;
+
+-- Availability exceptions: date-specific blocked ranges (vacations, leave) and extra open ranges
+CREATE TABLE doctor_availability_exceptions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    doctor_id INT NOT NULL,
+    exception_type ENUM('blocked', 'extra') NOT NULL,
+    start_at DATETIME NOT NULL,
+    end_at DATETIME NOT NULL,
+    reason VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (doctor_id) REFERENCES doctors(user_id) ON DELETE CASCADE,
+    INDEX idx_doctor_exception_range (doctor_id, start_at, end_at)
+);
+-- Relationship: Many-to-One with doctors
+
+-- Clinic-wide holiday calendar; weekly availability does not apply on these dates
+CREATE TABLE holidays (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    holiday_date DATE NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE notifications
+MODIFY COLUMN notification_type ENUM('consultation_request', 'chat_message', 'appointment_reminder', 'appointment_cancelled');
+
+-- Weekly working hours for home care providers
+CREATE TABLE home_care_availability (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    provider_id INT NOT NULL,
+    day_of_week TINYINT NOT NULL,
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    FOREIGN KEY (provider_id) REFERENCES home_care_providers(user_id) ON DELETE CASCADE,
+    INDEX idx_home_care_availability_provider (provider_id, day_of_week)
+);
+-- Relationship: Many-to-One with home_care_providers
+
+-- Date-specific blocked and extra ranges for home care providers
+CREATE TABLE home_care_availability_exceptions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    provider_id INT NOT NULL,
+    exception_type ENUM('blocked', 'extra') NOT NULL,
+    start_at DATETIME NOT NULL,
+    end_at DATETIME NOT NULL,
+    reason VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (provider_id) REFERENCES home_care_providers(user_id) ON DELETE CASCADE,
+    INDEX idx_home_care_exception_range (provider_id, start_at, end_at)
+);
+-- Relationship: Many-to-One with home_care_providers
+
+-- Service radius and travel buffer between consecutive visits
+CREATE TABLE home_care_service_areas (
+    provider_id INT PRIMARY KEY,
+    radius_km DECIMAL(6,2) NOT NULL,
+    travel_buffer_minutes INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (provider_id) REFERENCES home_care_providers(user_id) ON DELETE CASCADE
+);
+-- Relationship: One-to-One with home_care_providers
+
+ALTER TABLE home_care_visits
+ADD COLUMN scheduled_start DATETIME NULL,
+ADD INDEX idx_home_care_visits_schedule (provider_id, scheduled_start);
+
+-- Patients waiting for a freed slot with a fully booked provider
+CREATE TABLE waitlist_entries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    patient_id INT NOT NULL,
+    provider_id INT NOT NULL,
+    provider_type ENUM('doctor', 'home_care_provider') NOT NULL,
+    from_date DATE NOT NULL,
+    to_date DATE NOT NULL,
+    status ENUM('waiting', 'offered', 'booked', 'cancelled') NOT NULL DEFAULT 'waiting',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (patient_id) REFERENCES patients(user_id) ON DELETE CASCADE,
+    INDEX idx_waitlist_provider (provider_id, provider_type, status, from_date, to_date)
+);
+-- Relationship: Many-to-One with patients
+
+-- Time-limited holds of freed slots offered to waitlisted patients
+CREATE TABLE waitlist_offers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    entry_id INT NOT NULL,
+    patient_id INT NOT NULL,
+    provider_id INT NOT NULL,
+    provider_type ENUM('doctor', 'home_care_provider') NOT NULL,
+    appointment_date DATE NOT NULL,
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    status ENUM('pending', 'accepted', 'declined', 'expired') NOT NULL DEFAULT 'pending',
+    expires_at DATETIME NOT NULL,
+    appointment_id INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (entry_id) REFERENCES waitlist_entries(id) ON DELETE CASCADE,
+    FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE SET NULL,
+    INDEX idx_waitlist_offer_slot (provider_id, provider_type, appointment_date, start_time),
+    INDEX idx_waitlist_offer_expiry (status, expires_at)
+);
+-- Relationship: Many-to-One with waitlist_entries
+
+ALTER TABLE notifications
+MODIFY COLUMN notification_type ENUM('consultation_request', 'chat_message', 'appointment_reminder', 'appointment_cancelled', 'waitlist_offer');
+
+-- No-show handling
+ALTER TABLE appointments
+MODIFY COLUMN status ENUM('scheduled', 'completed', 'cancelled', 'no_show') NOT NULL DEFAULT 'scheduled';
+
+-- Single-row policy: grace period after start time and consequence thresholds (0 disables)
+CREATE TABLE no_show_policy (
+    id TINYINT PRIMARY KEY,
+    grace_period_minutes INT NOT NULL DEFAULT 15,
+    warning_threshold INT NOT NULL DEFAULT 1,
+    prepayment_threshold INT NOT NULL DEFAULT 3,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+CREATE TABLE no_show_records (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    appointment_id INT NOT NULL UNIQUE,
+    patient_id INT NOT NULL,
+    provider_id INT NOT NULL,
+    provider_type ENUM('doctor', 'home_care_provider') NOT NULL,
+    appointment_date DATE NOT NULL,
+    recorded_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE CASCADE,
+    FOREIGN KEY (patient_id) REFERENCES patients(user_id) ON DELETE CASCADE,
+    INDEX idx_no_show_patient (patient_id),
+    INDEX idx_no_show_provider (provider_id, provider_type)
+);
+-- Relationship: One-to-One with appointments, Many-to-One with patients
+
+CREATE TABLE patient_booking_restrictions (
+    patient_id INT PRIMARY KEY,
+    prepayment_required BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (patient_id) REFERENCES patients(user_id) ON DELETE CASCADE
+);
+-- Relationship: One-to-One with patients
+
+ALTER TABLE notifications
+MODIFY COLUMN notification_type ENUM('consultation_request', 'chat_message', 'appointment_reminder', 'appointment_cancelled', 'waitlist_offer', 'no_show_warning');
+
+-- Leases that let only one server instance run each scheduled job per interval
+CREATE TABLE job_leases (
+    job_name VARCHAR(100) PRIMARY KEY,
+    holder VARCHAR(255) NOT NULL,
+    lease_until DATETIME NOT NULL
+);
+
+-- Appointment reminders already sent, one row per appointment, reminder type and recipient
+CREATE TABLE appointment_reminders (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    appointment_id INT NOT NULL,
+    reminder_type ENUM('24h', '1h') NOT NULL,
+    recipient_id INT NOT NULL,
+    sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE CASCADE,
+    UNIQUE KEY uq_appointment_reminder (appointment_id, reminder_type, recipient_id)
+);
+-- Relationship: Many-to-One with appointments
+
+-- iCalendar feeds: sequence numbers grow on every update so calendar clients replace stale events
+ALTER TABLE appointments ADD COLUMN sequence INT NOT NULL DEFAULT 0;
+ALTER TABLE home_care_visits ADD COLUMN sequence INT NOT NULL DEFAULT 0;
+
+CREATE TABLE calendar_feed_tokens (
+    user_id INT PRIMARY KEY,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- Relationship: One-to-One with users
+
+-- Time zones: users pick an IANA zone; appointments keep the provider's wall-clock date and
+-- times alongside the absolute instants they resolve to
+ALTER TABLE users ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+ALTER TABLE appointments
+    ADD COLUMN starts_at DATETIME NULL,
+    ADD COLUMN ends_at DATETIME NULL,
+    ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    ADD INDEX idx_appointments_starts_at (starts_at);
+
+-- Existing appointments were booked with UTC wall-clock times
+UPDATE appointments
+SET starts_at = TIMESTAMP(appointment_date, start_time),
+    ends_at = TIMESTAMP(appointment_date, end_time)
+WHERE starts_at IS NULL;
+
+-- Walk-in virtual queue: one queue per doctor per day, in ticket order
+CREATE TABLE walk_in_queue_entries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    doctor_id INT NOT NULL,
+    patient_id INT NOT NULL,
+    queue_date DATE NOT NULL,
+    ticket_number INT NOT NULL,
+    source ENUM('remote', 'reception') NOT NULL DEFAULT 'remote',
+    status ENUM('waiting', 'called', 'served', 'left') NOT NULL DEFAULT 'waiting',
+    joined_at DATETIME NOT NULL,
+    called_at DATETIME NULL,
+    finished_at DATETIME NULL,
+    next_notified_at DATETIME NULL,
+    FOREIGN KEY (doctor_id) REFERENCES doctors(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (patient_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE KEY uq_queue_ticket (doctor_id, queue_date, ticket_number),
+    INDEX idx_queue_status (doctor_id, queue_date, status)
+);
+-- Relationship: Many-to-One with doctors and users
+
+ALTER TABLE notifications
+MODIFY COLUMN notification_type ENUM('consultation_request', 'chat_message', 'appointment_reminder', 'appointment_cancelled', 'waitlist_offer', 'no_show_warning', 'queue_next', 'queue_called');
+
+-- Consultations are held for an appointment; the appointment is in progress meanwhile
+ALTER TABLE consultations
+    ADD COLUMN appointment_id INT NULL AFTER id,
+    ADD UNIQUE KEY uq_consultation_appointment (appointment_id),
+    ADD FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE SET NULL;
+
+ALTER TABLE appointments
+MODIFY COLUMN status ENUM('scheduled', 'in_progress', 'completed', 'cancelled', 'no_show') NOT NULL DEFAULT 'scheduled';
+
+-- A completed consultation can be reviewed once
+ALTER TABLE reviews
+    ADD UNIQUE KEY uq_review_consultation (consultation_id);
+
+-- Video room of a consultation; duration counts the time both participants were connected
+CREATE TABLE video_sessions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    consultation_id INT NOT NULL,
+    status ENUM('waiting', 'connected', 'ended') NOT NULL DEFAULT 'waiting',
+    connected_at DATETIME NULL,
+    ended_at DATETIME NULL,
+    duration_seconds INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (consultation_id) REFERENCES consultations(id) ON DELETE CASCADE,
+    UNIQUE KEY uq_video_session_consultation (consultation_id)
+);
+-- Relationship: One-to-One with consultations
+
+ALTER TABLE consultations
+    ADD COLUMN video_duration_seconds INT NOT NULL DEFAULT 0;
+
+-- SOAP clinical notes; every draft, signature and amendment is its own version row
+CREATE TABLE clinical_notes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    consultation_id INT NOT NULL,
+    author_id INT NOT NULL,
+    version INT NOT NULL,
+    status ENUM('draft', 'signed', 'amended') NOT NULL DEFAULT 'draft',
+    amends_note_id INT NULL,
+    amendment_reason TEXT NOT NULL,
+    subjective JSON NOT NULL,
+    objective JSON NOT NULL,
+    assessment JSON NOT NULL,
+    plan JSON NOT NULL,
+    signed_by INT NULL,
+    signer_name VARCHAR(255) NULL,
+    signed_at DATETIME NULL,
+    content_hash CHAR(64) NULL,
+    signer_ip VARCHAR(64) NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    FOREIGN KEY (consultation_id) REFERENCES consultations(id) ON DELETE CASCADE,
+    FOREIGN KEY (author_id) REFERENCES users(id),
+    FOREIGN KEY (signed_by) REFERENCES users(id),
+    FOREIGN KEY (amends_note_id) REFERENCES clinical_notes(id),
+    UNIQUE KEY uq_clinical_note_version (consultation_id, version)
+);
+-- Relationship: Many-to-One with consultations
+
+-- E-prescriptions; prescriber and patient are copied at issue so the printed copy stays verifiable
+CREATE TABLE prescriptions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    number VARCHAR(32) NOT NULL,
+    verification_code VARCHAR(16) NOT NULL,
+    consultation_id INT NOT NULL,
+    doctor_id INT NOT NULL,
+    patient_id INT NOT NULL,
+    prescriber_name VARCHAR(255) NOT NULL,
+    prescriber_license VARCHAR(100) NOT NULL,
+    patient_name VARCHAR(255) NOT NULL,
+    status ENUM('active', 'dispensed', 'void') NOT NULL DEFAULT 'active',
+    notes TEXT NOT NULL,
+    issued_at DATETIME NOT NULL,
+    dispensed_at DATETIME NULL,
+    dispensed_by VARCHAR(255) NULL,
+    voided_at DATETIME NULL,
+    void_reason TEXT NULL,
+    FOREIGN KEY (consultation_id) REFERENCES consultations(id) ON DELETE CASCADE,
+    FOREIGN KEY (doctor_id) REFERENCES doctors(user_id),
+    FOREIGN KEY (patient_id) REFERENCES users(id),
+    UNIQUE KEY uq_prescription_number (number),
+    INDEX idx_prescription_consultation (consultation_id)
+);
+-- Relationship: Many-to-One with consultations
+
+CREATE TABLE prescription_items (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    prescription_id INT NOT NULL,
+    drug_name VARCHAR(255) NOT NULL,
+    strength VARCHAR(100) NOT NULL,
+    form VARCHAR(100) NOT NULL,
+    dose VARCHAR(100) NOT NULL,
+    frequency VARCHAR(100) NOT NULL,
+    duration_days INT NOT NULL,
+    quantity INT NOT NULL,
+    refills INT NOT NULL DEFAULT 0,
+    instructions TEXT NOT NULL,
+    FOREIGN KEY (prescription_id) REFERENCES prescriptions(id) ON DELETE CASCADE
+);
+-- Relationship: Many-to-One with prescriptions
+
+-- Documented allergies are medical history entries naming the drug or drug class
+ALTER TABLE medical_history
+    ADD COLUMN allergen VARCHAR(100) NOT NULL DEFAULT '';
+
+-- Locally imported drug interaction dataset; names are lower case, drug_a sorts before drug_b,
+-- and either side may be a drug class
+CREATE TABLE drug_interactions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    drug_a VARCHAR(150) NOT NULL,
+    drug_b VARCHAR(150) NOT NULL,
+    severity ENUM('minor', 'moderate', 'major', 'contraindicated') NOT NULL,
+    description TEXT NOT NULL,
+    UNIQUE KEY uq_drug_interaction (drug_a, drug_b)
+);
+
+-- Therapeutic classes of drugs, for duplicate therapy and class allergies
+CREATE TABLE drug_classes (
+    drug_name VARCHAR(150) NOT NULL,
+    class_name VARCHAR(150) NOT NULL,
+    PRIMARY KEY (drug_name, class_name)
+);
+
+-- Safety warnings a prescriber chose to override, with the reason given
+CREATE TABLE prescription_warning_overrides (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    prescription_id INT NOT NULL,
+    warning_key VARCHAR(400) NOT NULL,
+    warning_type ENUM('interaction', 'duplicate_therapy', 'allergy') NOT NULL,
+    severity ENUM('minor', 'moderate', 'major', 'contraindicated') NOT NULL,
+    description TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    doctor_id INT NOT NULL,
+    overridden_at DATETIME NOT NULL,
+    FOREIGN KEY (prescription_id) REFERENCES prescriptions(id) ON DELETE CASCADE,
+    FOREIGN KEY (doctor_id) REFERENCES doctors(user_id)
+);
+-- Relationship: Many-to-One with prescriptions
+
+-- Locally imported ICD-10 catalogue; codes keep their dot, category is the three-character block
+CREATE TABLE icd10_codes (
+    code VARCHAR(8) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL,
+    category CHAR(3) NOT NULL,
+    INDEX idx_icd10_category (category)
+);
+
+-- Coded diagnoses on consultation details and medical history
+ALTER TABLE consultation_details
+    ADD COLUMN diagnosis_code VARCHAR(8) NOT NULL DEFAULT '' AFTER diagnosis,
+    ADD INDEX idx_consultation_details_diagnosis_code (diagnosis_code);
+
+ALTER TABLE medical_history
+    ADD COLUMN condition_code VARCHAR(8) NOT NULL DEFAULT '' AFTER condition_name,
+    ADD COLUMN consultation_id INT NULL,
+    ADD INDEX idx_medical_history_condition_code (patient_id, condition_code),
+    ADD FOREIGN KEY (consultation_id) REFERENCES consultations(id) ON DELETE SET NULL;
+
+-- Referrals from a consultation to one receiver, or to the doctors of a specialty or the
+-- home care providers of a service type
+CREATE TABLE referrals (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    consultation_id INT NOT NULL,
+    patient_id INT NOT NULL,
+    referring_doctor_id INT NOT NULL,
+    target_type ENUM('doctor', 'home_care_provider') NOT NULL,
+    target_specialty VARCHAR(100) NOT NULL DEFAULT '',
+    target_service_type_id INT NOT NULL DEFAULT 0,
+    recipient_id INT NULL,
+    reason TEXT NOT NULL,
+    urgency ENUM('routine', 'urgent', 'emergency') NOT NULL DEFAULT 'routine',
+    clinical_summary TEXT NOT NULL,
+    status ENUM('pending', 'accepted', 'declined', 'booked', 'cancelled') NOT NULL DEFAULT 'pending',
+    responder_id INT NULL,
+    response_note TEXT,
+    responded_at TIMESTAMP NULL,
+    appointment_id INT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (consultation_id) REFERENCES consultations(id),
+    FOREIGN KEY (patient_id) REFERENCES users(id),
+    FOREIGN KEY (referring_doctor_id) REFERENCES users(id),
+    FOREIGN KEY (recipient_id) REFERENCES users(id),
+    FOREIGN KEY (responder_id) REFERENCES users(id),
+    FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE SET NULL,
+    INDEX idx_referrals_referring_doctor (referring_doctor_id, status),
+    INDEX idx_referrals_recipient (recipient_id, status),
+    INDEX idx_referrals_pool (status, target_type, target_specialty, target_service_type_id),
+    INDEX idx_referrals_patient (patient_id)
+);
+
+ALTER TABLE notifications
+MODIFY COLUMN notification_type ENUM('consultation_request', 'chat_message', 'appointment_reminder', 'appointment_cancelled', 'waitlist_offer', 'no_show_warning', 'queue_next', 'queue_called', 'referral_received', 'referral_accepted', 'referral_declined', 'referral_cancelled', 'referral_booked');
+
+-- Lab and imaging orders; number and access_code are printed on the requisition
+CREATE TABLE lab_orders (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    number VARCHAR(32) NOT NULL UNIQUE,
+    access_code VARCHAR(16) NOT NULL,
+    consultation_id INT NOT NULL,
+    doctor_id INT NOT NULL,
+    patient_id INT NOT NULL,
+    kind ENUM('lab', 'imaging') NOT NULL DEFAULT 'lab',
+    priority ENUM('routine', 'urgent', 'stat') NOT NULL DEFAULT 'routine',
+    clinical_indication TEXT NOT NULL,
+    notes TEXT NOT NULL,
+    status ENUM('ordered', 'resulted', 'cancelled') NOT NULL DEFAULT 'ordered',
+    ordered_at TIMESTAMP NOT NULL,
+    resulted_at TIMESTAMP NULL,
+    cancelled_at TIMESTAMP NULL,
+    cancel_reason TEXT,
+    FOREIGN KEY (consultation_id) REFERENCES consultations(id),
+    FOREIGN KEY (doctor_id) REFERENCES users(id),
+    FOREIGN KEY (patient_id) REFERENCES users(id),
+    INDEX idx_lab_orders_consultation (consultation_id),
+    INDEX idx_lab_orders_patient (patient_id, ordered_at)
+);
+
+CREATE TABLE lab_order_tests (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    code VARCHAR(32) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL,
+    specimen VARCHAR(100) NOT NULL DEFAULT '',
+    instructions TEXT NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES lab_orders(id) ON DELETE CASCADE
+);
+
+-- Structured numeric results; flag is set against the reference range on ingestion
+CREATE TABLE lab_results (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    test_id INT NULL,
+    analyte VARCHAR(255) NOT NULL,
+    value DECIMAL(14, 4) NOT NULL,
+    unit VARCHAR(32) NOT NULL DEFAULT '',
+    reference_low DECIMAL(14, 4) NULL,
+    reference_high DECIMAL(14, 4) NULL,
+    flag ENUM('', 'normal', 'low', 'high') NOT NULL DEFAULT '',
+    comment TEXT NOT NULL,
+    resulted_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES lab_orders(id) ON DELETE CASCADE,
+    FOREIGN KEY (test_id) REFERENCES lab_order_tests(id) ON DELETE SET NULL,
+    INDEX idx_lab_results_order (order_id, resulted_at)
+);
+
+-- Uploaded result documents, stored under the private uploads directory
+CREATE TABLE lab_reports (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    file_path VARCHAR(255) NOT NULL,
+    uploaded_by VARCHAR(255) NOT NULL,
+    uploaded_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES lab_orders(id) ON DELETE CASCADE
+);
+
+ALTER TABLE notifications
+MODIFY COLUMN notification_type ENUM('consultation_request', 'chat_message', 'appointment_reminder', 'appointment_cancelled', 'waitlist_offer', 'no_show_warning', 'queue_next', 'queue_called', 'referral_received', 'referral_accepted', 'referral_declined', 'referral_cancelled', 'referral_booked', 'lab_results');
+
+-- Follow-ups recommended when a consultation is completed; due_date is in the doctor's time zone
+CREATE TABLE follow_ups (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    consultation_id INT NOT NULL UNIQUE,
+    patient_id INT NOT NULL,
+    doctor_id INT NOT NULL,
+    interval_days INT NOT NULL,
+    due_date DATE NOT NULL,
+    note TEXT NOT NULL,
+    status ENUM('pending', 'booked', 'dismissed') NOT NULL DEFAULT 'pending',
+    appointment_id INT NULL,
+    reminders_sent INT NOT NULL DEFAULT 0,
+    last_reminded_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (consultation_id) REFERENCES consultations(id),
+    FOREIGN KEY (patient_id) REFERENCES users(id),
+    FOREIGN KEY (doctor_id) REFERENCES users(id),
+    FOREIGN KEY (appointment_id) REFERENCES appointments(id) ON DELETE SET NULL,
+    INDEX idx_follow_ups_doctor (doctor_id, status, due_date),
+    INDEX idx_follow_ups_patient (patient_id)
+);
+
+ALTER TABLE notifications
+MODIFY COLUMN notification_type ENUM('consultation_request', 'chat_message', 'appointment_reminder', 'appointment_cancelled', 'waitlist_offer', 'no_show_warning', 'queue_next', 'queue_called', 'referral_received', 'referral_accepted', 'referral_declined', 'referral_cancelled', 'referral_booked', 'lab_results', 'follow_up');
+
+-- Preferred language for documents such as consultation summaries
+ALTER TABLE users ADD COLUMN language ENUM('en', 'ar') NOT NULL DEFAULT 'en';
+
+-- Take-home consultation summaries, stored under the private uploads directory. content_hash
+-- identifies the record a document was rendered from.
+CREATE TABLE consultation_summaries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    consultation_id INT NOT NULL,
+    language ENUM('en', 'ar') NOT NULL,
+    format ENUM('pdf', 'html') NOT NULL,
+    file_path VARCHAR(255) NOT NULL,
+    content_hash CHAR(64) NOT NULL,
+    generated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (consultation_id) REFERENCES consultations(id) ON DELETE CASCADE,
+    UNIQUE KEY uq_consultation_summaries (consultation_id, language, format)
+);
+
+-- Files sent in consultation chats, stored under the private uploads directory
+CREATE TABLE chat_attachments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    message_id INT NOT NULL,
+    consultation_id INT NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    file_path VARCHAR(255) NOT NULL,
+    thumbnail_path VARCHAR(255) NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (message_id) REFERENCES chat_messages(id) ON DELETE CASCADE,
+    FOREIGN KEY (consultation_id) REFERENCES consultations(id) ON DELETE CASCADE,
+    INDEX idx_chat_attachments_message (message_id)
+);
+
+-- Chat messages are edited and deleted in place; deleted messages keep their content for the
+-- medical record, and archived ones belong to chats closed by the retention policy
+ALTER TABLE chat_messages
+    ADD COLUMN edited_at TIMESTAMP NULL,
+    ADD COLUMN deleted_at TIMESTAMP NULL,
+    ADD COLUMN deleted_by INT NULL,
+    ADD COLUMN archived_at TIMESTAMP NULL,
+    ADD FOREIGN KEY (deleted_by) REFERENCES users(id);
+
+-- Text of chat messages before each edit
+CREATE TABLE chat_message_edits (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    message_id INT NOT NULL,
+    previous_message TEXT NOT NULL,
+    edited_by INT NOT NULL,
+    edited_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (message_id) REFERENCES chat_messages(id) ON DELETE CASCADE,
+    FOREIGN KEY (edited_by) REFERENCES users(id),
+    INDEX idx_chat_message_edits_message (message_id)
+);
+
+CREATE TABLE chat_retention_policy (
+    id TINYINT PRIMARY KEY,
+    edit_window_minutes INT NOT NULL DEFAULT 15,
+    delete_window_minutes INT NOT NULL DEFAULT 60,
+    archive_after_days INT NOT NULL DEFAULT 90,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- Full-text search over chat messages; words shorter than innodb_ft_min_token_size (3 by
+-- default) are not indexed
+ALTER TABLE chat_messages ADD FULLTEXT INDEX ft_chat_messages_message (message);
+
+-- Unread counts per conversation
+ALTER TABLE chat_messages ADD INDEX idx_chat_unread (consultation_id, is_read, sender_id);
+
+-- Signed chat transcripts, stored under the private uploads directory. content_hash is the
+-- SHA-256 of the JSON document and pdf_hash that of its PDF rendering.
+CREATE TABLE chat_transcripts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    consultation_id INT NOT NULL UNIQUE,
+    message_count INT NOT NULL,
+    signed_by INT NOT NULL,
+    signer_name VARCHAR(100) NOT NULL,
+    signed_at TIMESTAMP NOT NULL,
+    signer_ip VARCHAR(45) NULL,
+    content_hash CHAR(64) NOT NULL,
+    pdf_hash CHAR(64) NOT NULL,
+    json_path VARCHAR(255) NOT NULL,
+    pdf_path VARCHAR(255) NOT NULL,
+    FOREIGN KEY (consultation_id) REFERENCES consultations(id),
+    FOREIGN KEY (signed_by) REFERENCES users(id)
+);
+
+-- Each fill of a prescription. A prescription stays active until every line's refills are
+-- used; its dispensed_at and dispensed_by are those of the latest fill.
+ALTER TABLE prescriptions
+    ADD COLUMN fills_dispensed INT NOT NULL DEFAULT 0;
+
+CREATE TABLE prescription_fills (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    prescription_id INT NOT NULL,
+    fill_number INT NOT NULL,
+    dispensed_by VARCHAR(255) NOT NULL,
+    dispensed_at DATETIME NOT NULL,
+    FOREIGN KEY (prescription_id) REFERENCES prescriptions(id) ON DELETE CASCADE,
+    UNIQUE KEY uq_prescription_fill (prescription_id, fill_number)
+);
+-- Relationship: Many-to-One with prescriptions
+
+-- Prescriptions dispensed before fills were recorded had their first fill, and those with
+-- refills become dispensable again
+INSERT INTO prescription_fills (prescription_id, fill_number, dispensed_by, dispensed_at)
+SELECT id, 1, dispensed_by, dispensed_at FROM prescriptions WHERE status = 'dispensed';
+
+UPDATE prescriptions p
+SET p.fills_dispensed = 1,
+    p.status = IF((SELECT MAX(i.refills) FROM prescription_items i WHERE i.prescription_id = p.id) > 0, 'active', 'dispensed')
+WHERE p.status = 'dispensed';